@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	extract "github.com/ethicalhackingplayground/favlens/v2/pkg/extract"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// runExtract implements `favlens extract --apk app.apk | --ipa app.ipa`, saving app icons for use as -base images
+func runExtract(arguments []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	apk := fs.String("apk", "", "Android package (.apk or .aab) to pull launcher icons from")
+	ipa := fs.String("ipa", "", "iOS package (.ipa) to pull app icons from")
+	outDir := fs.String("o", ".", "Directory to write the extracted PNG icons to")
+	fs.Parse(arguments)
+
+	pkg := *apk
+	if pkg == "" {
+		pkg = *ipa
+	}
+	if pkg == "" || (*apk != "" && *ipa != "") {
+		fmt.Println(color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens extract --apk <app.apk> | --ipa <app.ipa> [-o <dir>]"))
+		os.Exit(1)
+	}
+
+	icons, err := extract.Package(pkg)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Extraction failed: %v", err))
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to create output directory: %v", err))
+	}
+
+	prefix := strings.TrimSuffix(filepath.Base(pkg), filepath.Ext(pkg))
+	var first string
+	for _, icon := range icons {
+		path := filepath.Join(*outDir, fmt.Sprintf("%s-%s.png", prefix, icon.Name))
+		if err := os.WriteFile(path, icon.PNG, 0o644); err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to write %s: %v", path, err))
+		}
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("%s (%dx%d) from %s", path, icon.Width, icon.Height, icon.Source))
+		fmt.Println(path)
+		if first == "" {
+			first = path
+		}
+	}
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Extracted %d icon(s); compare with: favlens -base %s -file urls.txt", len(icons), first))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"time"
+
+	stats "github.com/ethicalhackingplayground/favlens/v2/pkg/stats"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// hostStatsInterval is how often --stats-json is rewritten and failing hosts are logged while a run is in progress
+const hostStatsInterval = 15 * time.Second
+
+// hostReportCount is how many hosts each per-host debug report names
+const hostReportCount = 10
+
+// watchHosts writes per-host statistics to path, when set, and logs the failing hosts at debug level every
+// hostStatsInterval, so a CDN that is blocking the scan stands out before the run ends. The returned function
+// stops watching and writes and logs the final statistics.
+func watchHosts(hosts *stats.Hosts, path string, debug bool) func() {
+	report := func(final bool) {
+		if path != "" {
+			if err := hosts.WriteJSON(path); err != nil {
+				gologger.Warning().Msgf("Failed to write --stats-json %s: %v", path, err)
+			}
+		}
+		if !debug {
+			return
+		}
+		// Mid-run only hosts with failures are worth the noise; the final report covers the busiest hosts
+		list := hosts.Failing(hostReportCount)
+		if final {
+			list = hosts.Snapshot()
+			if len(list) > hostReportCount {
+				list = list[:hostReportCount]
+			}
+		}
+		for _, host := range list {
+			line := color.New(color.Italic, color.FgCyan).Sprintf("Host %s: %d attempts, %d ok, %d failed, %d/%d connections reused, avg %.0fms",
+				host.Host, host.Attempts, host.Successes, host.Failures, host.Reused, host.Attempts, host.AvgLatency)
+			if host.LastError != "" {
+				line += color.New(color.Italic, color.FgYellow).Sprintf(", last error: %s", host.LastError)
+			}
+			gologger.Debug().Msg(line)
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(hostStatsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				report(false)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		report(true)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	attest "github.com/ethicalhackingplayground/favlens/v2/pkg/attest"
+	notify "github.com/ethicalhackingplayground/favlens/v2/pkg/notify"
+	output "github.com/ethicalhackingplayground/favlens/v2/pkg/output"
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// exactHashConfidence is the confidence given to imported httpx results whose favicon hash equals a brand
+// hash: the icons are byte-identical, so no model judgement is involved
+const exactHashConfidence = 100
+
+// runImport implements `favlens import`, loading results produced elsewhere into the run store so `favlens runs`
+// works over combined datasets
+func runImport(arguments []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	cache := fs.String("cache", "", "Store to import into: a directory, sqlite:///path.db or redis://host:6379/0")
+	into := fs.String("into", "", "Merge into an existing run (an ID, latest or latest~N) instead of recording a new one")
+	brandHashes := map[string]bool{}
+	fs.Func("favicon-hash", "Favicon mmh3 hash of the brand; httpx results with this hash count as matches (repeatable)", func(value string) error {
+		brandHashes[strings.TrimSpace(value)] = true
+		return nil
+	})
+	fs.Parse(arguments)
+	if *cache == "" || fs.NArg() == 0 {
+		fmt.Println(color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens import --cache <store> [--into <run>] [--favicon-hash <mmh3>] <results.jsonl|-> [...]"))
+		os.Exit(1)
+	}
+
+	s, err := store.Open(*cache)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to open store: %v", err))
+	}
+	defer s.Close()
+	log := store.NewRunLog(s)
+
+	imported := &store.Run{}
+	inputs := attest.NewInput()
+	for _, path := range fs.Args() {
+		if err := importFile(path, imported, inputs, brandHashes); err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to import %s: %v", path, err))
+		}
+	}
+	if imported.Targets == 0 {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprint("No results found to import"))
+	}
+	imported.InputDigest = inputs.Sum()
+	if imported.Started.IsZero() {
+		imported.Started, imported.Finished = time.Now(), time.Now()
+	}
+
+	run := imported
+	if *into != "" {
+		if run, err = log.Load(*into); err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+		}
+		run.Merge(imported)
+	} else {
+		run.ID = store.NewRunID(run.Started)
+	}
+	if err := log.Save(run); err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to save run: %v", err))
+	}
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Imported %d result(s) with %d match(es) into run %s", imported.Targets, len(imported.Matches), run.ID))
+}
+
+// importFile adds the results in one JSON lines file, or stdin for "-", to run. Lines are favlens JSON output,
+// or httpx output whose favicon hash is compared with the brand hashes.
+func importFile(path string, run *store.Run, inputs *attest.Input, brandHashes map[string]bool) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("line %d is not JSON: %v", line, err)
+		}
+
+		var match store.RunMatch
+		var matched, failed bool
+		var at time.Time
+		if _, ok := fields["match"]; ok {
+			result, model, ts, err := output.ParseJSONResult(data)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			match = store.RunMatch{URL: result.URL, Confidence: notify.Confidence(result)}
+			matched, failed, at = result.Match, result.Err != nil, ts
+			if run.BaseURL == "" {
+				run.BaseURL, run.Model = result.BaseURL, model
+			}
+		} else if _, ok := fields["favicon"]; ok {
+			var record struct {
+				URL       string `json:"url"`
+				Favicon   string `json:"favicon"`
+				Timestamp string `json:"timestamp"`
+			}
+			if err := json.Unmarshal(data, &record); err != nil || record.URL == "" {
+				return fmt.Errorf("line %d is not an httpx result with a url", line)
+			}
+			if len(brandHashes) == 0 {
+				return fmt.Errorf("line %d is an httpx result; pass --favicon-hash to decide which hashes match", line)
+			}
+			match = store.RunMatch{URL: record.URL, Confidence: exactHashConfidence}
+			matched = brandHashes[record.Favicon]
+			at, _ = time.Parse(time.RFC3339, record.Timestamp)
+			if run.Model == "" {
+				run.Model = "httpx favicon hash"
+			}
+		} else {
+			return fmt.Errorf("line %d is neither favlens JSON output nor an httpx result", line)
+		}
+
+		run.Targets++
+		inputs.Add(match.URL, "")
+		if failed {
+			run.Errors++
+		}
+		if matched {
+			run.Matches = append(run.Matches, match)
+		}
+		if !at.IsZero() {
+			if run.Started.IsZero() || at.Before(run.Started) {
+				run.Started = at
+			}
+			if at.After(run.Finished) {
+				run.Finished = at
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	output "github.com/ethicalhackingplayground/favlens/v2/pkg/output"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// prompter asks questions on stdout and reads answers from stdin
+type prompter struct {
+	in *bufio.Reader
+}
+
+// ask prints a question with its default and returns the answer, or the default on an empty line
+func (p *prompter) ask(question, fallback string) string {
+	if fallback != "" {
+		fmt.Printf("%s [%s]: ", color.New(color.Bold).Sprint(question), fallback)
+	} else {
+		fmt.Printf("%s: ", color.New(color.Bold).Sprint(question))
+	}
+	line, _ := p.in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return fallback
+	}
+	return line
+}
+
+// askInt repeats the question until a positive number is given
+func (p *prompter) askInt(question string, fallback int) int {
+	for {
+		answer := p.ask(question, strconv.Itoa(fallback))
+		n, err := strconv.Atoi(answer)
+		if err == nil && n > 0 {
+			return n
+		}
+		fmt.Println(color.New(color.FgYellow).Sprint("Please enter a positive number"))
+	}
+}
+
+// askChoice repeats the question until one of choices is given
+func (p *prompter) askChoice(question string, choices []string, fallback string) string {
+	for {
+		answer := strings.ToLower(p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, "/")), fallback))
+		for _, choice := range choices {
+			if answer == choice {
+				return answer
+			}
+		}
+		fmt.Println(color.New(color.FgYellow).Sprintf("Please choose one of: %s", strings.Join(choices, ", ")))
+	}
+}
+
+// runInit is the `favlens init` wizard: it detects Ollama, picks a vision model and writes config.yaml
+func runInit() {
+	path, err := config.DefaultPath()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to locate config directory: %v", err))
+	}
+	current, err := config.Load(path)
+	if err != nil {
+		gologger.Warning().Msgf("Existing config could not be read and will be replaced: %v", err)
+		current = &config.File{}
+	}
+
+	p := &prompter{in: bufio.NewReader(os.Stdin)}
+	fmt.Println(color.New(color.Bold, color.FgGreen).Sprint("favlens first-run setup"))
+	fmt.Println(color.New(color.Italic, color.FgCyan).Sprintf("Answers are saved to %s; press Enter to accept the value in brackets.", path))
+	fmt.Println()
+
+	// Find a reachable Ollama host
+	file := &config.File{}
+	var models []ollama.Model
+	host := orDefault(current.OllamaHost, "http://localhost:11434")
+	for {
+		host = strings.TrimRight(p.ask("Ollama host", host), "/")
+		client := ollama.NewClient(host, "", 10*time.Second)
+		models, err = client.ListModels(false)
+		if err == nil {
+			fmt.Println(color.New(color.FgGreen).Sprintf("Connected to Ollama at %s (%d models installed)", host, len(models)))
+			break
+		}
+		fmt.Println(color.New(color.FgRed).Sprintf("Could not reach Ollama at %s: %v", host, err))
+		if p.askChoice("Try a different host?", []string{"y", "n"}, "y") == "n" {
+			break
+		}
+	}
+	file.OllamaHost = host
+
+	// Offer the installed vision-capable models
+	client := ollama.NewClient(host, "", 10*time.Second)
+	var vision []string
+	for _, model := range models {
+		if client.IsVisionModel(model, false) {
+			vision = append(vision, model.Name)
+		}
+	}
+	defaultModel := orDefault(current.Model, "gemma3:4b")
+	if len(vision) > 0 {
+		fmt.Println(color.New(color.Italic, color.FgCyan).Sprint("Vision-capable models:"))
+		for i, name := range vision {
+			fmt.Printf("  %d) %s\n", i+1, name)
+		}
+		if !containsString(vision, defaultModel) {
+			defaultModel = vision[0]
+		}
+		answer := p.ask("Model (number or name)", defaultModel)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(vision) {
+			answer = vision[n-1]
+		}
+		file.Model = answer
+	} else {
+		if len(models) > 0 {
+			fmt.Println(color.New(color.FgYellow).Sprint("No vision-capable models detected; pull one first, e.g. `ollama pull gemma3:4b`"))
+		}
+		file.Model = p.ask("Model", defaultModel)
+	}
+
+	file.Workers = p.askInt("Workers", orDefaultInt(current.Workers, 5))
+	file.Format = p.askChoice("Output format", output.Formats, orDefault(current.Format, output.FormatText))
+	profiles := append([]string{"none"}, config.ProfileNames()...)
+	if profile := p.askChoice("Scan profile", profiles, orDefault(current.Profile, "none")); profile != "none" {
+		file.Profile = profile
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if p.askChoice(fmt.Sprintf("Overwrite existing %s?", path), []string{"y", "n"}, "y") == "n" {
+			fmt.Println(color.New(color.FgYellow).Sprint("Setup cancelled, nothing was written"))
+			return
+		}
+	}
+	if err := config.Save(path, file); err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to write config: %v", err))
+	}
+	fmt.Println(color.New(color.Bold, color.FgGreen).Sprintf("Saved %s", path))
+	fmt.Println(color.New(color.Italic, color.FgCyan).Sprint("Next: favlens -base https://example.com/favicon.ico -file urls.txt"))
+}
+
+func orDefault(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
+
+func orDefaultInt(value, fallback int) int {
+	if value != 0 {
+		return value
+	}
+	return fallback
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
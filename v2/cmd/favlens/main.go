@@ -1,256 +1,1442 @@
 package main
 
 import (
+	"crypto"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	_ "image/gif"  // Register GIF format
-	_ "image/jpeg" // Register JPEG format
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
 	"os"
+	"os/signal"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	_ "golang.org/x/image/bmp"  // Register BMP format
-	_ "golang.org/x/image/webp" // Register WebP format
-
 	args "github.com/ethicalhackingplayground/favlens/v2/pkg/arguments"
+	attest "github.com/ethicalhackingplayground/favlens/v2/pkg/attest"
+	audit "github.com/ethicalhackingplayground/favlens/v2/pkg/audit"
+	auth "github.com/ethicalhackingplayground/favlens/v2/pkg/auth"
+	brandkit "github.com/ethicalhackingplayground/favlens/v2/pkg/brandkit"
+	cluster "github.com/ethicalhackingplayground/favlens/v2/pkg/cluster"
+	corpus "github.com/ethicalhackingplayground/favlens/v2/pkg/corpus"
+	deception "github.com/ethicalhackingplayground/favlens/v2/pkg/deception"
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+	egress "github.com/ethicalhackingplayground/favlens/v2/pkg/egress"
+	encryption "github.com/ethicalhackingplayground/favlens/v2/pkg/encryption"
+	imaging "github.com/ethicalhackingplayground/favlens/v2/pkg/imaging"
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+	notify "github.com/ethicalhackingplayground/favlens/v2/pkg/notify"
 	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	output "github.com/ethicalhackingplayground/favlens/v2/pkg/output"
+	probe "github.com/ethicalhackingplayground/favlens/v2/pkg/probe"
+	remote "github.com/ethicalhackingplayground/favlens/v2/pkg/remote"
+	runner "github.com/ethicalhackingplayground/favlens/v2/pkg/runner"
+	screenshot "github.com/ethicalhackingplayground/favlens/v2/pkg/screenshot"
+	signing "github.com/ethicalhackingplayground/favlens/v2/pkg/signing"
+	sources "github.com/ethicalhackingplayground/favlens/v2/pkg/sources"
+	stats "github.com/ethicalhackingplayground/favlens/v2/pkg/stats"
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+	supervisor "github.com/ethicalhackingplayground/favlens/v2/pkg/supervisor"
 	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
 	"github.com/fatih/color"
-	_ "github.com/mat/besticon/ico" // Register ICO format
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/gologger/levels"
 )
 
-type Job struct {
-	URL string
+// exitPartial is the exit status used when a guard stopped the scan before every target was processed
+const exitPartial = 2
+
+// deterministicSeed and deterministicTime pin the model seed and output timestamps for --deterministic runs
+const deterministicSeed = 42
+
+var deterministicTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// pdnsKeyEnv names the environment variable holding each passive DNS provider's API key
+var pdnsKeyEnv = map[string]string{
+	sources.ProviderSecurityTrails: "SECURITYTRAILS_API_KEY",
+	sources.ProviderDNSDB:          "DNSDB_API_KEY",
 }
 
-// Worker function that processes jobs from the job channel
-func worker(id int, jobs <-chan Job, results chan<- types.Result, baseIcon string, ollamaClient *ollama.Client, args *args.Arguments, wg *sync.WaitGroup) {
-	defer wg.Done()
+// cleanups run before the process exits, including on fatal errors, so spawned services are torn down.
+// The signal handler, fatalf and main's deferred calls can race to run them, so cleanupsMu guards the list.
+var (
+	cleanupsMu sync.Mutex
+	cleanups   []func()
+)
 
-	if args.Debug {
-		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d started", id))
+// addCleanup registers f to run, in reverse order of registration, before the process exits
+func addCleanup(f func()) {
+	cleanupsMu.Lock()
+	defer cleanupsMu.Unlock()
+	cleanups = append(cleanups, f)
+}
+
+// runCleanups runs each registered cleanup once. A concurrent caller waits until they have finished,
+// so it can't exit the process halfway through a teardown.
+func runCleanups() {
+	cleanupsMu.Lock()
+	defer cleanupsMu.Unlock()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
 	}
+	cleanups = nil
+}
 
-	processedCount := 0
-	for job := range jobs {
-		processedCount++
-		if args.Debug {
-			gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d processing job %d: %s", id, processedCount, job.URL))
+// usage is printed after argument problems
+var usage = color.New(color.FgYellow, color.Italic).Sprint("Usage: go run main.go --base <base_favicon_url> --file <url_list_file|-> [--from-subfinder] [--model <model_name>] [--workers <num>] [--download-workers <num>] [--infer-workers <num>] [--cap-workers] [--show-queue] [--timeout <seconds>] [--cold-timeout <seconds>] [--delay <ms>] [--jitter <ms>] [--retries <num>] [--respect-robots] [--audit-log <file>] [--source-ip <ip>] [--interface <name>] [--ip-version 4|6|auto] [--max-bandwidth <rate>] [--tor [--tor-proxy <addr>] [--tor-rotate <num>]] [--suspect-checks] [--wildcard-filter collapse|skip] [--flatten-bg white|black|checker] [--autocrop] [--ignore-color] [--mode favicon|screenshot [--browser <path>]] [--match-mode equal|contains] [--brand <name>] [--brand-kit <dir>] [--strictness lenient|normal|strict] [--ocr] [--rate-limit-retries <num>] [--deferred-retries <num>] [--breaker-threshold <num>] [--breaker-cooldown <duration>] [--profile stealth|fast|thorough] [--deterministic] [--ordered] [--keep-duplicates] [--origins|--favicon-dir] [--retry-errors-from <results.jsonl>] [--log-level silent|error|info|verbose|debug] [--debug|--verbose|--silent] [-o <output_file|s3://bucket/key|gs://bucket/key> [--output-chunk-size <num>] [--errors-file <file|none>] [--sign-output <key.pem>] [--encrypt-output <recipient>] [--attest <file>]] [--stats-json <file>] [--hash-input b64-wrapped|raw] [--cluster-dir <dir>] [--fingerprints <clusters.json>] [--corpus <file>] [--min-confidence <0-100>] [--input-format auto|text|csv|json|email|httpx|hosts] [--urlscan-query <query> [--urlscan-key <key>]] [--vt-query <query> [--vt-key <key>]] [--pdns-domain <domain> [--pdns-provider securitytrails|dnsdb] [--pdns-key <key>]] [--permute-domain <domain> [--permute-resolvers <num>]] [--source-limit <num>] [--prioritize-regex <regex>] [--tag <name>=<regex>] [--notify-rule '<conditions> => <channel>:<target>'] [--brand-domain <domain>] [--auth <user:pass|token> [--auth-type basic|bearer|ntlm]] [--auth-file <path>] [--cookie <cookies>] [--cookie-file <cookies.txt>] [--cookie-session] [--client-cert <pem> [--client-key <pem>]] [--job-timeout <duration>] [--stall-timeout <duration> [--abort-on-stall]] [--max-runtime <duration>] [--max-targets <num>] [--sample <percent>|--sample-n <num> [--sample-seed <num>]] [--cache <dir|sqlite://path|redis://host> [--record-run [--keep-runs <90d|N>]]] [--ollama-host <host[,host...]>] [--ollama-endpoint chat|generate|embed [--similarity <0-1>]] [--lb-strategy sticky|round-robin|least-latency] [--spawn-ollama [--spawn-mode auto|process|docker]] [--k8s [--health-addr <addr>]] [--format text|json|sarif|defectdojo|faraday|parquet|opencti [--opencti-confidence <1-100>] [--opencti-marking <TLP:...>]] [--group-by apex] [--es-url <url> [--es-index <name>] [--es-auth <user:pass|api-key>]] [--splunk-url <url> [--splunk-token <token>] [--splunk-index <name>] [--splunk-sourcetype <name>]] [--syslog udp|tcp|tls://<host:port>]")
+
+// loggerLevel maps a --log-level name onto gologger's levels
+func loggerLevel(level string) levels.Level {
+	switch level {
+	case args.LogSilent:
+		return levels.LevelSilent
+	case args.LogError:
+		return levels.LevelError
+	case args.LogVerbose:
+		return levels.LevelVerbose
+	case args.LogDebug:
+		return levels.LevelDebug
+	default:
+		return levels.LevelInfo
+	}
+}
+
+// printProblems lists every argument problem by group, followed by the usage line. It prints even in silent
+// mode, since nothing else would explain the exit.
+func printProblems(err error) {
+	var invalid *args.ValidationError
+	if !errors.As(err, &invalid) {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold, color.FgRed).Sprintf("Invalid arguments: %v", err))
+		return
+	}
+	groups, problems := invalid.Grouped()
+	fmt.Fprintln(os.Stderr, color.New(color.Bold, color.FgRed).Sprintf("Invalid arguments (%d problem(s)):", len(invalid.Problems)))
+	for _, group := range groups {
+		fmt.Fprintln(os.Stderr, color.New(color.Bold).Sprintf("  %s:", group))
+		for _, problem := range problems[group] {
+			fmt.Fprintln(os.Stderr, color.New(color.FgRed).Sprintf("    - %s", problem))
 		}
+	}
+	fmt.Println(usage)
+}
+
+// fatalf runs cleanups, logs the message unless silent and exits with status 1
+func fatalf(silent bool, format string, a ...any) {
+	runCleanups()
+	if silent {
+		os.Exit(1)
+	}
+	gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf(format, a...))
+}
 
-		// Optional delay between requests
-		if args.DelayMs > 0 {
-			time.Sleep(time.Duration(args.DelayMs) * time.Millisecond)
+// sliceTargets serves buffered targets one at a time, then io.EOF
+func sliceTargets(targets []input.Target) func() (input.Target, error) {
+	return func() (input.Target, error) {
+		if len(targets) == 0 {
+			return input.Target{}, io.EOF
 		}
+		target := targets[0]
+		targets = targets[1:]
+		return target, nil
+	}
+}
 
-		targetIcon, err := ollamaClient.DownloadImageAsBase64(job.URL, args.Debug)
-		if err != nil {
-			if args.Debug {
-				gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Worker %d failed to download %s: %v", id, job.URL, err))
+// filterTargets serves the targets of next that keep accepts
+func filterTargets(next func() (input.Target, error), keep func(input.Target) bool) func() (input.Target, error) {
+	return func() (input.Target, error) {
+		for {
+			target, err := next()
+			if err != nil || keep(target) {
+				return target, err
 			}
-			results <- types.Result{URL: job.URL, Match: false, Err: err}
-			continue
 		}
+	}
+}
 
-		match, err := ollamaClient.CompareFaviconsChatAPI(baseIcon, targetIcon, args.Debug)
-		if args.Debug {
-			gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d completed comparison for %s: match=%v, err=%v", id, job.URL, match, err))
+// sampleTargets takes the --sample or --sample-n sample of targets, logging the seed so the sample can be repeated
+func sampleTargets(targets []input.Target, a *args.Arguments) []input.Target {
+	n := a.SampleN
+	if a.Sample != "" {
+		fraction, _ := input.ParseSampleFraction(a.Sample)
+		n = int(math.Ceil(fraction * float64(len(targets))))
+	}
+	seed := a.SampleSeed
+	if seed == 0 && a.Deterministic {
+		seed = deterministicSeed
+	} else if seed == 0 {
+		seed = rand.Uint64N(math.MaxUint32) + 1
+	}
+	sample, apexes := input.Sample(targets, n, seed)
+	if !a.Silent {
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sampled %d of %d targets across %d apex domain(s); repeat this sample with --sample-seed %d", len(sample), len(targets), apexes, seed))
+	}
+	return sample
+}
+
+// chainTargets serves every target of first, then those of second
+func chainTargets(first, second func() (input.Target, error)) func() (input.Target, error) {
+	return func() (input.Target, error) {
+		target, err := first()
+		if err == io.EOF {
+			return second()
 		}
-		results <- types.Result{URL: job.URL, Match: match, Err: err}
+		return target, err
 	}
+}
 
-	if args.Debug {
-		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d finished, processed %d jobs", id, processedCount))
+// createOutput opens an output file, or starts an upload for s3:// and gs:// URLs
+func createOutput(path string) (io.WriteCloser, error) {
+	if remote.IsURL(path) {
+		return remote.Create(path)
+	}
+	return os.Create(path)
+}
+
+// saveAttestation writes a provenance statement, signed like the output when a signing key is given
+func saveAttestation(p attest.Provenance, path string, key crypto.Signer) error {
+	file, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = file
+	var signed *signing.Writer
+	if key != nil {
+		signed = signing.NewWriter(file, key)
+		w = signed
+	}
+	if err := attest.Write(w, p); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if signed != nil {
+		return saveSignature(signed, path+signing.Extension)
+	}
+	return nil
+}
+
+// saveSignature writes the signature over everything written through signed
+func saveSignature(signed *signing.Writer, path string) error {
+	sig, err := signed.Sign()
+	if err != nil {
+		return err
 	}
+	file, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(sig); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
 func main() {
-	args.PrintBanner()
+	startTime := time.Now()
+	printBanner := args.PrintBanner
+
+	// Subcommands are dispatched before flag parsing
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "init":
+			printBanner()
+			runInit()
+			return
+		case "extract":
+			printBanner()
+			runExtract(os.Args[2:])
+			return
+		case "tune":
+			printBanner()
+			runTune(os.Args[2:])
+			return
+		case "version":
+			printBanner()
+			runVersion(os.Args[2:])
+			return
+		case "verify":
+			printBanner()
+			runVerify(os.Args[2:])
+			return
+		case "update":
+			printBanner()
+			runUpdate(os.Args[2:])
+			return
+		case "runs":
+			printBanner()
+			runRuns(os.Args[2:])
+			return
+		case "import":
+			printBanner()
+			runImport(os.Args[2:])
+			return
+		case "review":
+			printBanner()
+			runReview(os.Args[2:])
+			return
+		case "search":
+			printBanner()
+			runSearch(os.Args[2:])
+			return
+		case "permute":
+			// permute is a scan whose targets come from domain permutations, so it shares the scan flags
+			os.Args = permuteArgs(os.Args)
+		}
+	}
 
 	args := args.NewArguments()
 
-	if !args.IsValid() {
-		fmt.Println(color.New(color.FgYellow, color.Italic).Sprint("Usage: go run main.go --base <base_favicon_url> --file <url_list_file> [--model <model_name>] [--workers <num>] [--timeout <seconds>] [--delay <ms>] [--debug|--verbose|--silent] [-o <output_file>]"))
+	// In Kubernetes mode stdout carries nothing but NDJSON results
+	if !args.K8s {
+		printBanner()
+	}
+
+	if err := args.Validate(); err != nil {
+		printProblems(err)
 		os.Exit(1)
 	}
 
-	// Configure logger based on flags
-	if args.Silent {
-		gologger.DefaultLogger.SetMaxLevel(levels.LevelSilent)
-	} else if args.Debug {
-		gologger.DefaultLogger.SetMaxLevel(levels.LevelDebug)
+	// Configure logger from the resolved --log-level
+	gologger.DefaultLogger.SetMaxLevel(loggerLevel(args.LogLevel))
+	if args.Debug {
 		gologger.Info().Msg(color.New(color.Italic, color.FgMagenta).Sprint("Debug logging enabled"))
-	} else if args.Verbose {
-		gologger.DefaultLogger.SetMaxLevel(levels.LevelVerbose)
-	} else {
-		gologger.DefaultLogger.SetMaxLevel(levels.LevelInfo)
+	}
+
+	prioritizePatterns := make([]*regexp.Regexp, 0, len(args.PrioritizeRegex))
+	for _, pattern := range args.PrioritizeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fatalf(args.Silent, "Invalid --prioritize-regex '%s': %v", pattern, err)
+		}
+		prioritizePatterns = append(prioritizePatterns, re)
+	}
+
+	// Credentials for icons on authenticated hosts; per-host entries win over --auth
+	authStore := &auth.Store{}
+	if args.Auth != "" {
+		credential, err := auth.ParseCredential(args.AuthType, args.Auth)
+		if err != nil {
+			fatalf(args.Silent, "Invalid --auth: %v", err)
+		}
+		authStore.Default = credential
+	}
+	if args.AuthFile != "" {
+		if err := authStore.LoadFile(args.AuthFile); err != nil {
+			fatalf(args.Silent, "Failed to load auth file: %v", err)
+		}
+	}
+
+	// Session cookies for icons served behind SSO
+	var cookieJar *auth.CookieJar
+	if len(args.Cookies) > 0 || args.CookieFile != "" || args.CookieSession {
+		cookieJar = auth.NewCookieJar(args.CookieSession)
+		for _, cookie := range args.Cookies {
+			if err := cookieJar.AddFixed(cookie); err != nil {
+				fatalf(args.Silent, "Invalid --cookie: %v", err)
+			}
+		}
+		if args.CookieFile != "" {
+			if err := cookieJar.LoadFile(args.CookieFile); err != nil {
+				fatalf(args.Silent, "Failed to load cookie file: %v", err)
+			}
+		}
+	}
+
+	// Client certificate for mutual TLS; the key may live in the same PEM file
+	var clientCert *tls.Certificate
+	if args.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(args.ClientCert, orDefault(args.ClientKey, args.ClientCert))
+		if err != nil {
+			fatalf(args.Silent, "Failed to load client certificate: %v", err)
+		}
+		clientCert = &cert
+	}
+
+	tagRules := make([]input.TagRule, 0, len(args.Tags))
+	for _, rule := range args.Tags {
+		tagRule, err := input.ParseTagRule(rule)
+		if err != nil {
+			fatalf(args.Silent, "Invalid --tag: %v", err)
+		}
+		tagRules = append(tagRules, tagRule)
+	}
+
+	// A brand kit stands in for --base: its first logo is the base icon and the others are compared after it
+	var kit *brandkit.Kit
+	var kitVariants []string
+	if args.BrandKit != "" {
+		var err error
+		if kit, err = brandkit.Load(args.BrandKit); err != nil {
+			fatalf(args.Silent, "Failed to load brand kit: %v", err)
+		}
+		args.BaseURL = kit.Assets[0].Path
+		for _, asset := range kit.Assets[1:] {
+			kitVariants = append(kitVariants, asset.Path)
+		}
+		if args.Brand == "" {
+			args.Brand = kit.Name
+		}
+		if args.BrandDomain == "" && len(kit.Domains) > 0 {
+			args.BrandDomain = kit.Domains[0]
+		}
 	}
 
 	if !args.Silent {
 		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprint("Starting favicon comparison tool"))
-		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Base URL: %s", args.BaseURL))
+		if kit != nil {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Brand kit: %s (%d logos)", args.BrandKit, len(kit.Assets)))
+		} else {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Base URL: %s", args.BaseURL))
+		}
 		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Model: %s", args.Model))
-		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Workers: %d", args.Workers))
-		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Timeout: %ds", args.TimeoutSeconds))
+		if args.Profile != "" {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Profile: %s", args.Profile))
+		}
+		if args.Deterministic {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Deterministic mode: 1 worker, temperature 0, seed %d", deterministicSeed))
+		}
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Workers: %d download, %d inference", args.DownloadWorkers, args.InferWorkers))
+		if args.ColdTimeout > args.TimeoutSeconds {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Timeout: %ds (%ds until the model has loaded)", args.TimeoutSeconds, args.ColdTimeout))
+		} else {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Timeout: %ds", args.TimeoutSeconds))
+		}
 		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Delay: %dms", args.DelayMs))
+		if args.JitterMs > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Jitter: up to %dms", args.JitterMs))
+		}
+		if args.Retries > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Retries: %d", args.Retries))
+		}
+		if args.MaxRuntime > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Max runtime: %s", args.MaxRuntime))
+		}
+		if args.MaxTargets > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Max targets: %d", args.MaxTargets))
+		}
 		if args.Output != "" {
 			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Output file: %s", args.Output))
 		}
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Output format: %s", args.Format))
 	}
 
-	// Create a new Ollama client
-	ollamaClient := ollama.NewClient(args.OllamaHost, args.Model, time.Duration(args.TimeoutSeconds)*time.Second)
+	// stop is closed once no further jobs should be processed: on max runtime or a graceful SIGTERM
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	stopRun := func(reason string) {
+		stopOnce.Do(func() {
+			if !args.Silent {
+				gologger.Warning().Msg(color.New(color.Bold, color.FgYellow).Sprint(reason))
+			}
+			close(stop)
+		})
+	}
+
+	// Health probes come up first so liveness holds during model pulls; readiness follows once the scan can start
+	var probes *probe.Server
+	if args.K8s {
+		var err error
+		probes, err = probe.Start(args.HealthAddr)
+		if err != nil {
+			fatalf(args.Silent, "Failed to start health probes: %v", err)
+		}
+		addCleanup(func() { probes.Close() })
+		defer runCleanups()
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Health probes listening on %s", args.HealthAddr))
+		}
+	}
+
+	// In Kubernetes mode the first SIGTERM drains in-flight jobs within the pod's grace period; a second one,
+	// or any signal outside Kubernetes mode while Ollama is spawned, tears everything down immediately
+	if args.K8s || args.SpawnOllama {
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			if args.K8s {
+				if probes != nil {
+					probes.SetReady(false)
+				}
+				stopRun(fmt.Sprintf("Received %s, finishing in-flight jobs and stopping", sig))
+				<-signals
+			}
+			runCleanups()
+			os.Exit(130)
+		}()
+	}
+
+	// Optionally run our own Ollama for the duration of the scan
+	if args.SpawnOllama {
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprint("Spawning a local Ollama server..."))
+		}
+		spawned, err := supervisor.Start(supervisor.Options{Mode: args.SpawnMode, Image: args.SpawnImage, Debug: args.Debug})
+		if err != nil {
+			fatalf(args.Silent, "Failed to spawn Ollama: %v", err)
+		}
+		addCleanup(func() {
+			if err := spawned.Stop(); err != nil && !args.Silent {
+				gologger.Warning().Msgf("Failed to stop spawned Ollama: %v", err)
+			}
+		})
+		defer runCleanups()
+
+		if err := spawned.WaitHealthy(2 * time.Minute); err != nil {
+			fatalf(args.Silent, "Spawned Ollama is not healthy: %v", err)
+		}
+		args.OllamaHost = spawned.Host
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Ollama running at %s (%s mode)", spawned.Host, spawned.Mode))
+		}
+	}
+
+	// Create a client per Ollama host; comparisons are spread over them by the pool
+	hosts := ollama.SplitHosts(args.OllamaHost)
+	if len(hosts) == 0 {
+		fatalf(args.Silent, "Invalid arguments: no Ollama host given")
+	}
+	// Record every request when an audit trail of the engagement is wanted
+	var auditLog *audit.Log
+	if args.AuditLog != "" {
+		var err error
+		if auditLog, err = audit.Open(args.AuditLog); err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		addCleanup(func() {
+			if err := auditLog.Close(); err != nil && !args.Silent {
+				gologger.Warning().Msgf("Failed to close audit log: %v", err)
+			}
+		})
+		defer runCleanups()
+	}
+
+	pool := ollama.NewPool(hosts, args.Model, time.Duration(args.TimeoutSeconds)*time.Second, args.LBStrategy)
+	for _, client := range pool.Clients() {
+		client.Auth = authStore
+		client.Audit = auditLog
+		client.Mode = args.MatchMode
+		client.Brand = args.Brand
+		if kit != nil {
+			client.BrandDetails = kit.Describe()
+		}
+		client.Strictness = args.Strictness
+		client.Endpoint = args.OllamaEndpoint
+		client.Similarity = args.Similarity
+		client.ColdTimeout = time.Duration(args.ColdTimeout) * time.Second
+		client.Target = args.Mode
+		client.Normalize = imaging.Options{AutoCrop: args.AutoCrop, Background: args.FlattenBG, Greyscale: args.IgnoreColor}
+		client.Cookies = cookieJar
+		if clientCert != nil {
+			client.SetClientCertificate(*clientCert)
+		}
+		if args.Deterministic {
+			client.Options = map[string]any{"temperature": 0, "seed": deterministicSeed}
+		}
+	}
+	ollamaClient := pool.Clients()[0]
+	if args.Tor {
+		tor, err := egress.NewTor(args.TorProxy, args.TorRotate, time.Duration(args.TimeoutSeconds)*time.Second)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		if err := tor.Check(); err != nil {
+			fatalf(args.Silent, "Tor check failed: %v", err)
+		}
+		// Each download gets its own connection so circuits rotate every --tor-rotate requests
+		ollamaClient.SetDial(tor.Dial)
+		ollamaClient.FreshConnections = true
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sending icon downloads through Tor at %s", args.TorProxy))
+		}
+	}
+	if len(args.SourceIPs) > 0 || len(args.Interfaces) > 0 {
+		dialer, err := egress.New(args.SourceIPs, args.Interfaces, args.IPVersion, time.Duration(args.TimeoutSeconds)*time.Second)
+		if err != nil {
+			fatalf(args.Silent, "Invalid source address: %v", err)
+		}
+		// Only icon hosts see the bound addresses; the Ollama API is still reached over the default route
+		ollamaClient.SetDial(dialer.Dial)
+		if !args.Silent {
+			ips := make([]string, len(dialer.IPs()))
+			for i, ip := range dialer.IPs() {
+				ips[i] = ip.String()
+			}
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sending icon downloads from: %s", strings.Join(ips, ", ")))
+		}
+	}
+	if !args.Tor && len(args.SourceIPs) == 0 && len(args.Interfaces) == 0 {
+		// Without a bound address, downloads still dial both address families rather than fasthttp's IPv4 only
+		ollamaClient.SetDial(egress.FamilyDial(args.IPVersion, time.Duration(args.TimeoutSeconds)*time.Second))
+		if !args.Silent && args.IPVersion != egress.IPVersionAuto {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloading icons over IPv%s only", args.IPVersion))
+		}
+	}
+	if args.MaxBandwidth != "" {
+		// Wrapping whichever dialer is set keeps Tor and source addresses, with one budget shared by every connection
+		rate, _ := egress.ParseBandwidth(args.MaxBandwidth)
+		ollamaClient.SetDial(egress.NewLimiter(rate).Dial(ollamaClient.DownloadDial()))
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Limiting icon downloads to %s/s", stats.FormatBytes(uint64(rate))))
+		}
+	}
+	var hostStats *stats.Hosts
+	if args.Debug || args.StatsJSON != "" {
+		// Connections are counted as they are dialed, so each host's attempts show how often one was reused
+		hostStats = stats.NewHosts()
+		ollamaClient.Hosts = hostStats
+		ollamaClient.SetDial(hostStats.Dial(ollamaClient.DownloadDial()))
+	}
+	if strings.EqualFold(args.Mode, ollama.TargetScreenshot) {
+		browser, err := screenshot.Find(args.Browser)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		ollamaClient.Browser = &screenshot.Browser{Path: browser, Timeout: time.Duration(args.TimeoutSeconds) * time.Second}
+		if args.Tor {
+			ollamaClient.Browser.Proxy = "socks5://" + args.TorProxy
+		}
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Comparing page screenshots taken with %s", browser))
+		}
+	}
+	if args.RespectRobots {
+		// Icons are downloaded through the first client only, so that's where robots.txt is enforced
+		ollamaClient.RespectRobots()
+	}
+	if len(hosts) > 1 && !args.Silent {
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Ollama hosts: %s (strategy: %s)", strings.Join(hosts, ", "), args.LBStrategy))
+	}
+
+	// Resolve and connect to every host now, so an unreachable host fails the run before any work starts
+	// and the first comparisons don't wait for connection setup
+	if err := pool.Warm(args.InferWorkers); err != nil {
+		fatalf(args.Silent, "Ollama connection check failed: %v", err)
+	}
+	if args.Debug {
+		gologger.Debug().Msgf("Opened %d connection(s) to each Ollama host", args.InferWorkers)
+	}
 
-	// Check if the specified model exists before proceeding
+	// Check if the specified model exists on every host before proceeding
 	if !args.Silent {
 		gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("Validating model '%s' availability...", args.Model))
 	}
+	for _, client := range pool.Clients()[1:] {
+		if err := client.CheckModelExists(args.Debug); err != nil {
+			fatalf(args.Silent, "Model validation failed on %s: %v", client.Host, err)
+		}
+	}
 	if err := ollamaClient.CheckModelExists(args.Debug); err != nil {
-		if args.Silent {
-			os.Exit(1)
+		if !args.SpawnOllama {
+			fatalf(args.Silent, "Model validation failed: %v", err)
+		}
+		// A freshly spawned server usually has no models yet, so pull the requested one
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("Pulling model '%s', this may take a while...", args.Model))
+		}
+		if err := ollamaClient.PullModel(time.Hour, args.Debug); err != nil {
+			fatalf(args.Silent, "Model pull failed: %v", err)
+		}
+		if err := ollamaClient.CheckModelExists(args.Debug); err != nil {
+			fatalf(args.Silent, "Model validation failed: %v", err)
 		}
-		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Model validation failed: %v", err))
 	}
 	if !args.Silent {
 		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Model '%s' is available", args.Model))
 	}
+	checkParallelism(pool, args)
 
 	// Download base favicon
 	if !args.Silent {
 		gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprint("Downloading base favicon..."))
 	}
 
-	baseIcon, err := ollamaClient.DownloadImageAsBase64(args.BaseURL, args.Debug)
+	baseIcon, err := ollamaClient.DownloadIcon(args.BaseURL, args.Debug)
 	if err != nil {
-		if args.Silent {
-			os.Exit(1)
+		fatalf(args.Silent, "Failed to download base favicon: %v", err)
+	}
+	baseIcons := ollama.NewIconCache(ollamaClient)
+	baseIcons.Put(args.BaseURL, baseIcon.Base64)
+	// Targets httpx reports with one of these hashes serve the brand's own icon
+	brandHashes := []string{baseIcon.MMH3}
+	for _, variant := range kitVariants {
+		variantIcon, err := ollamaClient.DownloadIcon(variant, args.Debug)
+		if err != nil {
+			fatalf(args.Silent, "Failed to load brand kit logo: %v", err)
 		}
-		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to download base favicon: %v", err))
+		baseIcons.Put(variant, variantIcon.Base64)
+		brandHashes = append(brandHashes, variantIcon.MMH3)
 	}
 	if !args.Silent {
 		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprint("Base favicon downloaded successfully"))
 	}
-
-	// Read file with URLs
-	if !args.Silent {
-		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Reading URLs from file: %s", args.FilePath))
+	if probes != nil {
+		probes.SetReady(true)
 	}
-	content, err := os.ReadFile(args.FilePath)
-	if err != nil {
-		if args.Silent {
-			os.Exit(1)
+
+	// Open the verdict cache if one was configured
+	var verdicts *store.VerdictCache
+	var cacheStore store.Store
+	if args.Cache != "" {
+		cacheStore, err = store.Open(args.Cache)
+		if err != nil {
+			fatalf(args.Silent, "Failed to open cache: %v", err)
+		}
+		defer cacheStore.Close()
+		// Verdicts only carry over between runs that asked the model the same question
+		prompt := ollamaClient.Prompt()
+		if prompt == ollama.DefaultPrompt {
+			prompt = ""
+		}
+		if strings.EqualFold(args.OllamaEndpoint, ollama.EndpointEmbed) {
+			// Embedding verdicts depend on the threshold rather than a prompt
+			prompt = fmt.Sprintf("embed similarity %g", args.Similarity)
+		}
+		verdicts = store.NewVerdictCache(cacheStore, args.Model, prompt)
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Using verdict cache: %s", args.Cache))
 		}
-		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to read file: %v", err))
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
-	if !args.Silent {
-		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Found %d URLs to process", len(lines)))
+	// Candidates from search sources are scanned after the URL file
+	var searchSources []sources.Source
+	if args.URLScanQuery != "" {
+		searchSources = append(searchSources, &sources.URLScan{Query: args.URLScanQuery, APIKey: args.URLScanKey, Timeout: time.Duration(args.TimeoutSeconds) * time.Second})
+	}
+	if args.VTQuery != "" {
+		searchSources = append(searchSources, &sources.VirusTotal{Query: args.VTQuery, APIKey: args.VTKey, Timeout: time.Duration(args.TimeoutSeconds) * time.Second})
+	}
+	if args.PDNSDomain != "" {
+		key := args.PDNSKey
+		if key == "" {
+			key = os.Getenv(pdnsKeyEnv[strings.ToLower(args.PDNSProvider)])
+		}
+		source, err := sources.NewPassiveDNS(args.PDNSProvider, args.PDNSDomain, key, time.Duration(args.TimeoutSeconds)*time.Second)
+		if err != nil {
+			fatalf(args.Silent, "Invalid --pdns-domain: %v", err)
+		}
+		searchSources = append(searchSources, source)
+	}
+	if args.PermuteDomain != "" {
+		searchSources = append(searchSources, &sources.Permutations{Domain: args.PermuteDomain, Resolvers: args.PermuteResolvers})
+	}
+	var sourceTargets []input.Target
+	if len(searchSources) > 0 {
+		sourceTargets, err = sources.Collect(searchSources, args.SourceLimit)
+		if err != nil {
+			fatalf(args.Silent, "Failed to query search source: %v", err)
+		}
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Fetched %d candidate URLs from search sources", len(sourceTargets)))
+		}
 	}
 
-	// Create channels
-	jobs := make(chan Job, len(lines))
-	results := make(chan types.Result, len(lines))
+	// Open the URL file; targets are streamed rather than loaded up front
+	nextTarget := sliceTargets(sourceTargets)
+	prioritized := false
+	var hostProber *sources.HostProber
+	var reader *input.Reader
+	if args.FilePath != "" {
+		if !args.Silent && args.FilePath == "-" {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprint("Reading URLs from stdin"))
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Reading URLs from file: %s", args.FilePath))
+		}
+		reader, err = input.Open(args.FilePath, args.InputFormat)
+		if err != nil {
+			fatalf(args.Silent, "Failed to read file: %v", err)
+		}
+		defer reader.Close()
+		nextFile := reader.Next
+		// Bare hostnames have no scheme yet, so each is probed for one that answers before it is scanned
+		if strings.EqualFold(args.InputFormat, input.FormatHosts) {
+			hostProber = sources.ProbeHosts(reader.Next, ollamaClient.Probe, args.DownloadWorkers)
+			nextFile = hostProber.Next
+			if !args.Silent {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprint("Probing hosts for https and http"))
+			}
+		}
+		nextTarget = chainTargets(nextFile, nextTarget)
+		prioritized = reader.Prioritized()
+	}
 
-	// Start worker pool
-	if !args.Silent {
-		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Starting %d workers...", args.Workers))
+	// Targets that failed in an earlier run are scanned again, after any other input
+	var retries *input.RetryFilter
+	if args.RetryErrorsFrom != "" {
+		results, err := input.Open(args.RetryErrorsFrom, input.FormatJSON)
+		if err != nil {
+			fatalf(args.Silent, "Failed to read --retry-errors-from: %v", err)
+		}
+		defer results.Close()
+		retries = input.NewRetryFilter(args.BaseURL)
+		nextTarget = chainTargets(nextTarget, retries.Filter(results.Next))
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Retrying failed targets from: %s", args.RetryErrorsFrom))
+		}
 	}
-	var wg sync.WaitGroup
-	for i := 0; i < args.Workers; i++ {
-		wg.Add(1)
-		go worker(i, jobs, results, baseIcon, ollamaClient, args, &wg)
+
+	// Crawl dumps repeat each host across thousands of paths, so collapse them to origins before anything else
+	var origins *input.OriginReducer
+	if args.Origins {
+		origins = input.NewOriginReducer()
+		nextTarget = origins.Reduce(nextTarget)
 	}
 
-	// Send jobs
-	jobCount := 0
-	for _, url := range lines {
-		url = strings.TrimSpace(url)
-		if url == "" {
-			continue
+	// Drop wildcard-served targets before they reach a worker, so one parking page doesn't cost thousands of comparisons
+	var wildcards *deception.WildcardFilter
+	if args.WildcardFilter != "" {
+		wildcards, err = deception.NewWildcardFilter(args.WildcardFilter, net.DefaultResolver.LookupHost, time.Duration(args.TimeoutSeconds)*time.Second)
+		if err != nil {
+			fatalf(args.Silent, "Invalid arguments: %v", err)
 		}
+		nextTarget = filterTargets(nextTarget, func(target input.Target) bool {
+			return wildcards.Keep(runner.Hostname(target.URL))
+		})
+	}
 
-		// Append /favicon.ico if the URL doesn't have an image extension or favicon.ico
-		if !strings.HasSuffix(url, ".ico") && !strings.HasSuffix(url, ".png") &&
-			!strings.HasSuffix(url, ".jpg") && !strings.HasSuffix(url, ".jpeg") &&
-			!strings.HasSuffix(url, ".gif") && !strings.HasSuffix(url, ".svg") &&
-			!strings.Contains(url, "favicon") {
-			if strings.HasSuffix(url, "/") {
-				url = url + "favicon.ico"
-			} else {
-				url = url + "/favicon.ico"
+	// Prioritizing and sampling need the whole input in memory, so only buffer when they were asked for
+	sampling := args.Sample != "" || args.SampleN > 0
+	if len(prioritizePatterns) > 0 || prioritized || sampling {
+		var targets []input.Target
+		for {
+			target, err := nextTarget()
+			if err == io.EOF {
+				break
 			}
-			if args.Debug {
-				gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Appended /favicon.ico to URL: %s", url))
+			if err != nil {
+				fatalf(args.Silent, "Failed to read file: %v", err)
 			}
+			targets = append(targets, target)
+		}
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Found %d URLs to process", len(targets)))
+		}
+		if sampling {
+			targets = sampleTargets(targets, args)
 		}
 
-		jobs <- Job{URL: url}
-		jobCount++
+		// Schedule high-value targets before the long tail
+		input.Prioritize(targets, prioritizePatterns)
+		nextTarget = sliceTargets(targets)
+	}
+
+	// Bounded channels keep memory flat regardless of input size; the producer blocks until workers catch up
+	jobs := make(chan types.Job, args.DownloadWorkers*2)
+
+	// Track peak heap usage so memory behaviour at high concurrency is visible in the summary
+	memory := stats.StartMemoryTracker(250 * time.Millisecond)
+	stopHosts := func() {}
+	if hostStats != nil {
+		stopHosts = watchHosts(hostStats, args.StatsJSON, args.Debug)
 	}
-	close(jobs)
+
+	// Start worker pool
 	if !args.Silent {
-		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Dispatched %d jobs to workers", jobCount))
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Starting %d download and %d inference workers...", args.DownloadWorkers, args.InferWorkers))
+	}
+
+	// The runtime budget covers the whole run, including setup, so stop relative to the start time
+	if args.MaxRuntime > 0 {
+		timer := time.AfterFunc(time.Until(startTime.Add(args.MaxRuntime)), func() {
+			stopRun(fmt.Sprintf("Max runtime of %s reached, finishing in-flight jobs and stopping", args.MaxRuntime))
+		})
+		defer timer.Stop()
 	}
 
-	// Wait for all workers to finish and close results channel
+	breaker := ollama.NewBreaker(args.BreakerThreshold, args.BreakerCooldown, args.Silent)
+	options := runner.Options{
+		Args:        args,
+		BaseIcons:   baseIcons,
+		Client:      ollamaClient,
+		Pool:        pool,
+		Breaker:     breaker,
+		Verdicts:    verdicts,
+		Stop:        stop,
+		BrandHashes: brandHashes,
+	}
+	if args.ClusterDir != "" {
+		clusters, err := cluster.NewCollector(args.ClusterDir)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		options.Clusters = clusters
+	}
+	if args.Corpus != "" {
+		index, err := corpus.Open(args.Corpus)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		// Vectors from different models aren't comparable, so a corpus stays with the model that built it
+		if model := index.Model(); model != "" && !ollama.ModelMatches(model, args.Model) {
+			fatalf(args.Silent, "Corpus %s holds embeddings from model '%s', not '%s'", args.Corpus, model, args.Model)
+		}
+		index.SetModel(args.Model)
+		options.Corpus = index
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Indexing icon embeddings into %s (%d icon(s) so far)", args.Corpus, index.Len()))
+		}
+	}
+	if args.Fingerprints != "" {
+		labels, err := cluster.LoadLabels(args.Fingerprints)
+		if err != nil {
+			fatalf(args.Silent, "Failed to load fingerprints: %v", err)
+		}
+		options.Fingerprints = labels
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Tagging targets with %d labelled icon(s) from %s", labels.Len(), args.Fingerprints))
+		}
+	}
+	if args.SuspectChecks {
+		checks := deception.Options{FetchPage: ollamaClient.FetchPage, Timeout: time.Duration(args.TimeoutSeconds) * time.Second}
+		// Behind Tor every response comes from the proxy and local lookups would leak the targets, so those checks are skipped
+		if !args.Tor {
+			checks.Lookup = net.DefaultResolver.LookupHost
+			checks.CompareNetworks = true
+		}
+		options.Deception = deception.NewDetector(checks)
+	}
+	scan := runner.New(options)
+
+	// Send jobs from a producer goroutine so results can be consumed while the input is still being read.
+	// The counters below are only read after producerDone is closed.
+	jobCount := 0
+	overrideCount := 0
+	// Merged recon files repeat URLs a lot; each target and base pair only needs one result. Different pages
+	// that share a favicon are still reported separately, with the runner sharing their download and comparison.
+	var dedup *input.Dedup
+	if !args.KeepDuplicates {
+		dedup = input.NewDedup()
+	}
+	// Every dispatched target is digested so the summary and attestation pin down the exact input list
+	inputDigest := attest.NewInput()
+	truncated := false
+	var readErr error
+	producerDone := make(chan struct{})
 	go func() {
-		wg.Wait()
-		close(results)
+		defer close(producerDone)
+		defer close(jobs)
+
+		for {
+			target, err := nextTarget()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				readErr = err
+				break
+			}
+			if args.MaxTargets > 0 && jobCount >= args.MaxTargets {
+				truncated = true
+				if !args.Silent {
+					gologger.Warning().Msg(color.New(color.Bold, color.FgYellow).Sprintf("Max targets of %d reached, remaining input was not dispatched", args.MaxTargets))
+				}
+				break
+			}
+			inputDigest.Add(target.URL, target.BaseURL)
+
+			// Pages are scanned through the favicon at their origin root, whatever their path or query
+			urls := input.FaviconURLs(target.URL, target.Image, args.FaviconDir)
+			if strings.EqualFold(args.Mode, ollama.TargetScreenshot) && !target.Image {
+				// Screenshots are taken of the page itself
+				urls = []string{target.URL}
+			}
+			url := urls[0]
+			if args.Debug && url != target.URL {
+				gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Using favicon %s for %s", strings.Join(urls, ", then "), target.URL))
+			}
+
+			baseURL, variants := args.BaseURL, kitVariants
+			if target.BaseURL != "" {
+				baseURL, variants = target.BaseURL, nil
+			}
+			if dedup != nil && !dedup.Add(target.URL, baseURL) {
+				if args.Debug {
+					gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Skipping duplicate target: %s", target.URL))
+				}
+				continue
+			}
+			if target.BaseURL != "" {
+				overrideCount++
+			}
+			// A hash httpx computed only describes the icon, so it can't stand in for a screenshot
+			faviconHash := target.FaviconHash
+			if strings.EqualFold(args.Mode, ollama.TargetScreenshot) {
+				faviconHash = ""
+			}
+
+			select {
+			case jobs <- types.Job{URL: url, BaseURL: baseURL, Metadata: target.Metadata, Fallbacks: urls[1:], BaseVariants: variants, Tags: input.MatchTags(target.URL, tagRules), Index: jobCount, FaviconHash: faviconHash}:
+				jobCount++
+			case <-stop:
+				truncated = true
+				return
+			}
+		}
+
 		if !args.Silent {
-			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprint("All workers finished"))
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Dispatched %d jobs to workers", jobCount))
+			if overrideCount > 0 {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("%d jobs use a per-target base favicon", overrideCount))
+			}
+			if retries != nil {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Found %d failed target(s) among %d earlier result(s)", retries.Failed(), retries.Results()))
+			}
+			if origins != nil && origins.URLs() > 0 {
+				reduction := 100 * float64(origins.URLs()-origins.Origins()) / float64(origins.URLs())
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Reduced %d crawled URL(s) to %d unique origin(s) (%.1f%% fewer fetches)", origins.URLs(), origins.Origins(), reduction))
+			}
+			if reader != nil {
+				logSkipped(reader.Skipped())
+			}
+			if hostProber != nil && hostProber.Dead() > 0 {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Dropped %d host(s) that answered on neither https nor http", hostProber.Dead()))
+			}
+			if wildcards != nil && wildcards.Dropped() > 0 {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Dropped %d wildcard-served targets (%s)", wildcards.Dropped(), args.WildcardFilter))
+			}
 		}
 	}()
 
-	// Prepare output file if specified
-	var outFile *os.File
-	if args.Output != "" {
-		outFile, err = os.Create(args.Output)
+	// Keys are loaded first so a bad key doesn't leave an empty output behind
+	var recipients *encryption.Recipients
+	if len(args.EncryptOutput) > 0 {
+		if recipients, err = encryption.ParseRecipients(args.EncryptOutput); err != nil {
+			fatalf(args.Silent, "Invalid --encrypt-output: %v", err)
+		}
+	}
+	var signingKey crypto.Signer
+	if args.SignOutput != "" {
+		if signingKey, err = signing.LoadKey(args.SignOutput); err != nil {
+			fatalf(args.Silent, "Failed to load signing key: %v", err)
+		}
+	}
+
+	// Prepare output file if specified; s3:// and gs:// outputs are uploaded as they are written
+	var outFile io.WriteCloser
+	if args.Output != "" && args.OutputChunkSize > 0 {
+		// Chunks are created as results arrive, see the chunk writer below
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Writing output in chunks of %d result(s): %s, %s, ...", args.OutputChunkSize, output.ChunkPath(args.Output, 1), output.ChunkPath(args.Output, 2)))
+		}
+	} else if args.Output != "" {
+		outFile, err = createOutput(args.Output)
 		if err != nil {
-			if args.Silent {
-				os.Exit(1)
+			fatalf(args.Silent, "Failed to create output file: %v", err)
+		}
+		if !args.Silent && remote.IsURL(args.Output) {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Streaming output to %s", args.Output))
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Created output file: %s", args.Output))
+		}
+	}
+	// Output is hashed on its way to the file, so it can be signed and attested without reading it back
+	var out io.Writer = outFile
+	var attested *attest.Digest
+	if args.Attest != "" {
+		attested = attest.NewDigest(outFile)
+		out = attested
+	}
+	var signed *signing.Writer
+	if signingKey != nil {
+		signed = signing.NewWriter(out, signingKey)
+		out = signed
+	}
+	// Encryption happens before signing, so the signature covers the file as stored
+	var encrypted io.WriteCloser
+	if recipients != nil {
+		if encrypted, err = recipients.Encrypt(out); err != nil {
+			fatalf(args.Silent, "Failed to start output encryption: %v", err)
+		}
+		out = encrypted
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Encrypting output with %s to %d recipient(s)", recipients.Format(), recipients.Count()))
+		}
+	}
+
+	// Results are rendered to the output file when given, otherwise to stdout
+	scanInfo := output.ScanInfo{BaseURL: args.BaseURL, Model: args.Model, Confidence: args.OpenCTIConf, Markings: args.OpenCTIMarkings, HashInput: args.HashInput}
+	if args.Deterministic {
+		scanInfo.Clock = func() time.Time { return deterministicTime }
+	}
+	// Only the formatted output is put back in input order when asked. Early results wait in memory for slower
+	// ones, so streaming sinks like Elasticsearch, Splunk, syslog and the errors file are attached outside it.
+	ordered := func(w output.Writer) output.Writer {
+		if args.Ordered {
+			return output.NewOrderedWriter(w)
+		}
+		return w
+	}
+	var writer output.Writer
+	var chunks *output.ChunkWriter
+	if args.Output != "" && args.OutputChunkSize > 0 {
+		chunks = output.NewChunkWriter(args.Format, args.OutputChunkSize, args.Output, createOutput, scanInfo)
+	}
+	if args.K8s {
+		// Kubernetes mode always streams NDJSON to stdout for log collectors, plus the chosen format to the mounted -o path
+		writer = output.NewJSONWriter(os.Stdout, scanInfo)
+		if outFile != nil {
+			var fileWriter output.Writer
+			if args.GroupBy != "" {
+				fileWriter = output.NewGroupWriter(args.Format, out)
+			} else if fileWriter, err = output.NewWriter(args.Format, out, scanInfo); err != nil {
+				fatalf(args.Silent, "Failed to create output writer: %v", err)
 			}
-			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to create output file: %v", err))
+			writer = output.NewMultiWriter(ordered(fileWriter), writer)
+		} else if chunks != nil {
+			writer = output.NewMultiWriter(ordered(chunks), writer)
 		}
-		defer outFile.Close()
+	} else {
+		var dest io.Writer = os.Stdout
+		if outFile != nil {
+			dest = out
+		}
+		// Grouped output is written once the run ends, one entry per apex domain
+		if chunks != nil {
+			writer = chunks
+		} else if args.GroupBy != "" {
+			writer = output.NewGroupWriter(args.Format, dest)
+		} else if writer, err = output.NewWriter(args.Format, dest, scanInfo); err != nil {
+			fatalf(args.Silent, "Failed to create output writer: %v", err)
+		}
+		writer = ordered(writer)
+	}
+
+	// Results are also indexed into Elasticsearch or OpenSearch as they arrive when a cluster is given
+	if args.ESURL != "" {
+		esWriter, err := output.NewElasticWriter(output.ElasticOptions{URL: args.ESURL, Index: args.ESIndex, Auth: args.ESAuth, Timeout: time.Duration(args.TimeoutSeconds) * time.Second}, scanInfo)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		writer = output.NewMultiWriter(writer, esWriter)
 		if !args.Silent {
-			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Created output file: %s", args.Output))
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Indexing results into %s/%s", strings.TrimRight(args.ESURL, "/"), args.ESIndex))
+		}
+	}
+
+	// Splunk gets every result through the HTTP Event Collector, and syslog collectors get every match
+	if args.SplunkURL != "" {
+		splunkWriter, err := output.NewSplunkWriter(output.SplunkOptions{URL: args.SplunkURL, Token: args.SplunkToken, Index: args.SplunkIndex, SourceType: args.SplunkSource, Timeout: time.Duration(args.TimeoutSeconds) * time.Second}, scanInfo)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		writer = output.NewMultiWriter(writer, splunkWriter)
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sending results to Splunk HEC at %s", strings.TrimRight(args.SplunkURL, "/")))
+		}
+	}
+	if args.Syslog != "" {
+		syslogWriter, err := output.NewSyslogWriter(args.Syslog, time.Duration(args.TimeoutSeconds)*time.Second, scanInfo)
+		if err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		writer = output.NewMultiWriter(writer, syslogWriter)
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sending matches to syslog at %s", args.Syslog))
+		}
+	}
+
+	// Failed targets go to a requeue file beside the output, so a follow-up pass can scan just those
+	var failed *output.ErrorsWriter
+	errorsPath := args.ErrorsFile
+	if errorsPath == "" && args.Output != "" && !remote.IsURL(args.Output) {
+		errorsPath = output.ErrorsPath(args.Output)
+	}
+	if errorsPath != "" && errorsPath != output.ErrorsFileNone {
+		failed = output.NewErrorsWriter(errorsPath, createOutput, args.BaseURL, runner.ErrorClass)
+		writer = output.NewMultiWriter(writer, failed)
+	}
+
+	// Matches meeting a --notify-rule are sent to its channel as they arrive
+	var notifier *notify.Notifier
+	if len(args.NotifyRules) > 0 {
+		rules := make([]notify.Rule, 0, len(args.NotifyRules))
+		for _, raw := range args.NotifyRules {
+			rule, _ := notify.ParseRule(raw)
+			rules = append(rules, rule)
+		}
+		if notifier, err = notify.New(rules, time.Duration(args.TimeoutSeconds)*time.Second); err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Routing matches with %d notification rule(s)", len(rules)))
+		}
+	}
+
+	// The config file's email section sends matches by SMTP, one per match or as an end-of-run digest
+	var mailer *notify.Mailer
+	if args.Email != nil {
+		if mailer, err = notify.NewMailer(*args.Email, time.Duration(args.TimeoutSeconds)*time.Second); err != nil {
+			fatalf(args.Silent, "%v", err)
+		}
+		if !args.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Emailing matches to %s", strings.Join(args.Email.To, ", ")))
 		}
 	}
 
-	// Collect and print results
+	// Collect and print results as the runner streams them
+	var runMatches []store.RunMatch
 	matchCount := 0
 	errorCount := 0
-	for result := range results {
-		if result.Err != nil {
-			errorCount++
-			// Only show errors in debug mode
+	// Matches per registered domain, for the closing summary
+	matchedApexes := make(map[string]int)
+	scan.OnError(func(result types.Result) {
+		errorCount++
+		// Only show errors in debug mode
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Error processing %s: %v", result.URL, result.Err))
+		}
+	})
+	scan.OnMatch(func(result types.Result) {
+		matchCount++
+		if host := domain.Host(result.URL); host != "" {
+			matchedApexes[domain.Apex(host)]++
+		}
+		// Matched URLs are always echoed to stdout when the formatted output goes to a file
+		if (outFile != nil || chunks != nil) && !args.K8s {
+			fmt.Println(result.URL)
+		}
+		if notifier != nil {
+			notifier.Notify(result)
+		}
+		if mailer != nil {
+			mailer.Notify(result)
+		}
+		if args.RecordRun {
+			runMatches = append(runMatches, store.RunMatch{URL: result.URL, Confidence: notify.Confidence(result)})
+		}
+	})
+	scan.OnResult(func(result types.Result) {
+		if err := writer.Write(result); err != nil {
 			if args.Debug {
-				gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Error processing %s: %v", result.URL, result.Err))
+				gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Failed to write result: %v", err))
 			}
-			continue
 		}
-		if result.Match {
-			matchCount++
-			fmt.Println(result.URL)
+	})
+	if err := scan.Start(jobs); err != nil {
+		fatalf(args.Silent, "Failed to start workers: %v", err)
+	}
+	var stalled atomic.Bool
+	stopStalls := func() {}
+	if args.StallTimeout > 0 {
+		var abort func(string)
+		if args.AbortOnStall {
+			abort = stopRun
+		}
+		stopStalls = watchStalls(scan, pool, args.StallTimeout, abort, scan.Abandon, &stalled)
+	}
+	scan.Wait()
+	stopStalls()
+	if !args.Silent && !scan.Abandoned() {
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprint("All workers finished"))
+	}
 
-			// Write to output file if specified
-			if outFile != nil {
-				if _, err := fmt.Fprintln(outFile, result.URL); err != nil {
-					if args.Debug {
-						gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Failed to write to output file: %v", err))
-					}
-				}
+	if err := writer.Close(); err != nil {
+		if !args.Silent {
+			gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to write output: %v", err))
+		}
+	}
+	if notifier != nil {
+		if err := notifier.Close(); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to send notifications: %v", err))
 			}
 		}
+		if !args.Silent && notifier.Sent() > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sent %d notification(s)", notifier.Sent()))
+		}
+	}
+	// Uploads only complete once the output is closed
+	outputSaved := true
+	if encrypted != nil {
+		if err := encrypted.Close(); err != nil {
+			outputSaved = false
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to encrypt output: %v", err))
+			}
+		}
+	}
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			outputSaved = false
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to save output: %v", err))
+			}
+		}
+	}
+	if signed != nil && outputSaved {
+		if err := saveSignature(signed, args.Output+signing.Extension); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to sign output: %v", err))
+			}
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Output signature saved to: %s", args.Output+signing.Extension))
+		}
 	}
 
+	<-producerDone
+	if readErr != nil && !args.Silent {
+		gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Stopped reading input early: %v", readErr))
+	}
+	configDigest := args.ConfigDigest()
+	partial := truncated || readErr != nil || scan.Skipped() > 0 || (args.AbortOnStall && stalled.Load())
+	if args.RecordRun {
+		run := &store.Run{
+			ID:           store.NewRunID(startTime),
+			Started:      startTime,
+			Finished:     time.Now(),
+			BaseURL:      args.BaseURL,
+			Model:        args.Model,
+			ConfigDigest: configDigest,
+			InputDigest:  inputDigest.Sum(),
+			Targets:      jobCount - int(scan.Skipped()),
+			Errors:       errorCount,
+			Partial:      partial,
+			Matches:      runMatches,
+		}
+		runLog := store.NewRunLog(cacheStore)
+		if err := runLog.Save(run); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to record run: %v", err))
+			}
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Recorded run %s; compare runs with `favlens runs diff --cache %s latest~1 latest`", run.ID, args.Cache))
+		}
+		// Retention is applied on every recorded run so long-running monitoring never grows the store unbounded
+		if args.KeepRuns != "" {
+			retention, _ := store.ParseRetention(args.KeepRuns)
+			deleted, err := runLog.Prune(retention, time.Now())
+			if err != nil && !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to prune runs: %v", err))
+			} else if deleted > 0 && !args.Silent {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Pruned %d run(s) outside --keep-runs %s", deleted, args.KeepRuns))
+			}
+		}
+	}
+	if attested != nil && outputSaved {
+		provenance := attest.Provenance{
+			Output:       args.Output,
+			OutputDigest: attested.Sum(),
+			Settings:     args.Settings(),
+			ConfigDigest: configDigest,
+			Input:        inputDigest,
+			Model:        args.Model,
+			Version:      version,
+			Started:      startTime,
+			Finished:     time.Now(),
+		}
+		if err := saveAttestation(provenance, args.Attest, signingKey); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to write attestation: %v", err))
+			}
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Attestation saved to: %s", args.Attest))
+		}
+	}
+	if mailer != nil {
+		summary := notify.Summary{BaseURL: args.BaseURL, Model: args.Model, Started: startTime, Duration: time.Since(startTime).Round(time.Second), Total: jobCount - int(scan.Skipped()), Errors: errorCount, Partial: partial, GroupBy: args.GroupBy}
+		if err := mailer.Close(summary); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to send email: %v", err))
+			}
+		} else if !args.Silent && mailer.Sent() > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Sent %d email(s)", mailer.Sent()))
+		}
+	}
+	stopHosts()
+	if clusters := options.Clusters; clusters != nil {
+		if err := clusters.Close(); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to export clusters: %v", err))
+			}
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Exported %d icon cluster(s) to %s; label them and pass it back with --fingerprints", len(clusters.Clusters()), clusters.Path()))
+		}
+	}
+	if index := options.Corpus; index != nil {
+		if err := index.Save(); err != nil {
+			if !args.Silent {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to save corpus: %v", err))
+			}
+		} else if !args.Silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Added %d new icon(s) to %s (%d in total); query it with `favlens search`", index.Added(), index.Path(), index.Len()))
+		}
+	}
+	peakMemory := memory.Stop()
 	if !args.Silent {
-		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Processing complete. Matches: %d, Errors: %d, Total: %d", matchCount, errorCount, jobCount))
-		if args.Output != "" {
-			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Matched URLs saved to: %s", args.Output))
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Processing complete. Matches: %d, Errors: %d, Total: %d", matchCount, errorCount, jobCount-int(scan.Skipped())))
+		if dedup != nil && dedup.Duplicates() > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Skipped %d duplicate target(s)", dedup.Duplicates()))
+		}
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Peak heap usage: %s", stats.FormatBytes(peakMemory)))
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Configuration SHA-256: %s", configDigest))
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Input SHA-256: %s (%d targets)", inputDigest.Sum(), inputDigest.Count()))
+		scan.ReportPanics(args.Debug)
+		if shared := scan.Shared(); shared > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Shared the favicon download and comparison of %d target(s) with an earlier page", shared))
+		}
+		if decided := scan.HashDecided(); decided > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Decided %d target(s) by their httpx favicon hash without downloading", decided))
+		}
+		if requeued := scan.Requeued(); requeued > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("Requeued %d rate-limited target(s)", requeued))
+		}
+		if deferred, recovered := scan.Deferred(); deferred > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("Retried %d transient failure(s) at the end of the run, %d recovered", deferred, recovered))
+		}
+		if demoted := scan.Demoted(); demoted > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("%d match(es) scored below --min-confidence %d and were reported as non-matches", demoted, args.MinConfidence))
+		}
+		if timedOut := scan.TimedOut(); timedOut > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("%d job attempt(s) overran the %s job timeout", timedOut, args.JobTimeout))
+		}
+		if trips := breaker.Trips(); trips > 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("Circuit breaker tripped %d time(s)", trips))
+		}
+		if len(hosts) > 1 {
+			for _, host := range pool.Stats() {
+				gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Host %s: %d comparisons, avg %s", host.Host, host.Requests, host.AvgLatency.Round(time.Millisecond)))
+			}
+		}
+		if partial {
+			gologger.Warning().Msg(color.New(color.Bold, color.FgYellow).Sprint("Partial run: not every target in the input was processed"))
+		}
+		var reports []string
+		if chunks != nil {
+			reports = chunks.Paths()
+		} else if args.Output != "" && outputSaved {
+			reports = []string{args.Output}
+		}
+		printNextSteps(args, matchedApexes, errorCount, reports, failed)
+	}
+
+	if partial {
+		// Deferred calls don't run on os.Exit, so tear down first
+		runCleanups()
+		os.Exit(exitPartial)
+	}
+}
+
+// logSkipped reports the input lines that held no target; unreadable text is a warning since it usually means the file's
+// encoding is wrong and targets were lost
+func logSkipped(skipped []input.Skip) {
+	for _, skip := range skipped {
+		lines := strings.Join(skip.Lines, ", ")
+		if skip.Count > len(skip.Lines) {
+			lines += ", ..."
+		}
+		if skip.Reason == input.SkipInvalid {
+			gologger.Warning().Msg(color.New(color.Bold, color.FgYellow).Sprintf("Skipped %d input line(s) that aren't valid UTF-8 text (%s)", skip.Count, lines))
+			continue
 		}
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Skipped %d %s input line(s) (%s)", skip.Count, skip.Reason, lines))
 	}
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	args "github.com/ethicalhackingplayground/favlens/v2/pkg/arguments"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// oversubscribed is how many times the estimated capacity of the Ollama hosts --infer-workers may reach before
+// the run warns; a little queueing keeps the GPU busy, but far more than it serves only piles up timeouts
+const oversubscribed = 2
+
+// checkParallelism estimates from /api/ps how many comparisons the Ollama hosts serve at once and warns when
+// --infer-workers far exceeds it, or lowers --infer-workers to it with --cap-workers. Nothing is estimated when
+// a host doesn't report where the model is loaded, as older servers and proxies don't.
+func checkParallelism(pool *ollama.Pool, a *args.Arguments) {
+	total := 0
+	for _, client := range pool.Clients() {
+		capacity, err := client.EstimateCapacity(a.Debug)
+		if err != nil || !capacity.Loaded {
+			if a.Debug {
+				if err == nil {
+					gologger.Debug().Msgf("Model '%s' isn't loaded on %s yet, so its parallelism can't be estimated", a.Model, client.Host)
+				} else {
+					gologger.Debug().Msgf("Could not estimate the parallelism of %s: %v", client.Host, err)
+				}
+			}
+			return
+		}
+		if a.Debug {
+			gologger.Debug().Msgf("%s holds %.0f%% of '%s' in VRAM and serves about %d request(s) at once", client.Host, capacity.VRAM*100, a.Model, capacity.Parallel)
+		}
+		total += capacity.Parallel
+	}
+
+	switch {
+	case a.CapWorkers && a.InferWorkers > total:
+		if !a.Silent {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Capping inference workers at %d, the parallel requests the Ollama host(s) can serve (was %d)", total, a.InferWorkers))
+		}
+		a.InferWorkers = total
+	case a.InferWorkers > oversubscribed*total:
+		// Logged as an error, like the circuit breaker, so it shows at the default log level
+		gologger.Error().Msg(color.New(color.Bold, color.FgYellow).Sprintf("%d inference workers far exceed the ~%d parallel requests the Ollama host(s) can serve; expect queued requests to time out. Lower --infer-workers or add --cap-workers", a.InferWorkers, total))
+	}
+}
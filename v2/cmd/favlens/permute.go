@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+)
+
+// permuteArgs rewrites `favlens permute --domain acme.com [scan flags]` into a regular scan with
+// -permute-domain. Without -base the legitimate site's own /favicon.ico is used as the reference.
+func permuteArgs(osArgs []string) []string {
+	rewritten := []string{osArgs[0]}
+	domain := ""
+	hasBase := false
+	rest := osArgs[2:]
+	for i := 0; i < len(rest); i++ {
+		arg := rest[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case strings.HasPrefix(arg, "-") && name == "domain":
+			if !hasValue && i+1 < len(rest) {
+				i++
+				value = rest[i]
+			}
+			domain = value
+			continue
+		case strings.HasPrefix(arg, "-") && name == "base":
+			hasBase = true
+		}
+		rewritten = append(rewritten, arg)
+	}
+	if domain != "" {
+		rewritten = append(rewritten, "-permute-domain", domain)
+		if !hasBase {
+			rewritten = append(rewritten, "-base", "https://"+domain+"/favicon.ico")
+		}
+	}
+	return rewritten
+}
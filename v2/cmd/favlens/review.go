@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+	tune "github.com/ethicalhackingplayground/favlens/v2/pkg/tune"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// reviewUsage lists the `favlens review` actions
+var reviewUsage = color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens review accept|reject --cache <store> [--run <run>] [--note <text>] <url>... | favlens review list --cache <store> | favlens review thresholds --cache <store> [--save]  (runs are IDs, latest or latest~N)")
+
+// runReview implements `favlens review`, recording analysts' verdicts on the matches of runs recorded with
+// --record-run and learning the --min-confidence that best separates the accepted matches from the rejected ones
+func runReview(arguments []string) {
+	if len(arguments) == 0 {
+		fmt.Println(reviewUsage)
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("review "+arguments[0], flag.ExitOnError)
+	cache := fs.String("cache", "", "Store the runs were recorded in: a directory, sqlite:///path.db or redis://host:6379/0")
+	runID := fs.String("run", "latest", "Run the reviewed matches were found in")
+	note := fs.String("note", "", "Why the match was accepted or rejected")
+	save := fs.Bool("save", false, "Save the recommended minimum confidence to config.yaml")
+	fs.Parse(arguments[1:])
+	if *cache == "" {
+		fmt.Println(reviewUsage)
+		os.Exit(1)
+	}
+
+	s, err := store.Open(*cache)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to open store: %v", err))
+	}
+	defer s.Close()
+	decisions := store.NewDecisionLog(s)
+
+	switch arguments[0] {
+	case "accept", "reject":
+		if fs.NArg() == 0 {
+			fmt.Println(reviewUsage)
+			os.Exit(1)
+		}
+		run, err := store.NewRunLog(s).Load(*runID)
+		if err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+		}
+		confidence := make(map[string]int, len(run.Matches))
+		for _, m := range run.Matches {
+			confidence[m.URL] = m.Confidence
+		}
+		accepted := arguments[0] == "accept"
+		for _, url := range fs.Args() {
+			score, ok := confidence[url]
+			if !ok {
+				gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%s is not a match of run %s", url, run.ID))
+			}
+			decision := store.Decision{URL: url, Run: run.ID, Confidence: score, Accepted: accepted, Note: *note, At: time.Now().UTC()}
+			if err := decisions.Record(decision); err != nil {
+				gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to record decision: %v", err))
+			}
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Recorded %sed %s (confidence %d)", arguments[0], url, score))
+		}
+	case "list":
+		list, err := decisions.List()
+		if err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to list decisions: %v", err))
+		}
+		if len(list) == 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("No decisions recorded in %s", *cache))
+		}
+		for _, d := range list {
+			verdict := color.New(color.FgGreen).Sprint("accepted")
+			if !d.Accepted {
+				verdict = color.New(color.FgRed).Sprint("rejected")
+			}
+			line := fmt.Sprintf("%s  %s  confidence %3d  %s  run %s", d.At.Local().Format("2006-01-02 15:04"), verdict, d.Confidence, d.URL, d.Run)
+			if d.Note != "" {
+				line += "  (" + d.Note + ")"
+			}
+			fmt.Println(line)
+		}
+	case "thresholds":
+		reviewThresholds(decisions, *save)
+	default:
+		fmt.Println(reviewUsage)
+		os.Exit(1)
+	}
+}
+
+// reviewThresholds recommends the minimum confidence that best fits the recorded decisions, comparing it with
+// the one currently configured, and saves it to config.yaml when save is set
+func reviewThresholds(decisions *store.DecisionLog, save bool) {
+	list, err := decisions.List()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to list decisions: %v", err))
+	}
+	labelled := make([]tune.Labelled, 0, len(list))
+	accepted := 0
+	for _, d := range list {
+		labelled = append(labelled, tune.Labelled{Confidence: d.Confidence, Accepted: d.Accepted})
+		if d.Accepted {
+			accepted++
+		}
+	}
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Learning from %d decision(s): %d accepted, %d rejected", len(list), accepted, len(list)-accepted))
+
+	best, ok := tune.RecommendThreshold(labelled)
+	if !ok {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprint("Need at least one accepted and one rejected match; review more matches with `favlens review accept|reject`"))
+	}
+
+	path, err := config.DefaultPath()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to locate config directory: %v", err))
+	}
+	defaults, err := config.Load(path)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to read %s: %v", path, err))
+	}
+	current := tune.Evaluate(labelled, defaults.MinConfidence)
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Current: --min-confidence %d keeps %d accepted and %d rejected match(es) (precision %.2f, recall %.2f)",
+		current.MinConfidence, current.KeptAccepted, current.KeptRejected, current.Precision, current.Recall))
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Recommended: --min-confidence %d keeps %d accepted and %d rejected match(es) (precision %.2f, recall %.2f, F1 %.2f)",
+		best.MinConfidence, best.KeptAccepted, best.KeptRejected, best.Precision, best.Recall, best.F1))
+
+	if save {
+		defaults.MinConfidence = best.MinConfidence
+		if err := config.Save(path, defaults); err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to save config: %v", err))
+		}
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Saved min_confidence: %d to %s; later scans report matches below it as non-matches", best.MinConfidence, path))
+	}
+}
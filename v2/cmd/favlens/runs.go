@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// runsUsage lists the `favlens runs` actions
+var runsUsage = color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens runs list --cache <store> | favlens runs diff --cache <store> [--json] <from_run> <to_run> | favlens runs prune --cache <store> --keep <90d|N>  (runs are IDs, latest or latest~N)")
+
+// runRuns implements `favlens runs`, listing, comparing and pruning runs recorded with --record-run
+func runRuns(arguments []string) {
+	if len(arguments) == 0 {
+		fmt.Println(runsUsage)
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("runs "+arguments[0], flag.ExitOnError)
+	cache := fs.String("cache", "", "Store the runs were recorded in: a directory, sqlite:///path.db or redis://host:6379/0")
+	asJSON := fs.Bool("json", false, "Print the diff as JSON")
+	keep := fs.String("keep", "", "Runs to keep when pruning: an age such as 90d, 12w or 72h, or a number of runs")
+	fs.Parse(arguments[1:])
+	if *cache == "" {
+		fmt.Println(runsUsage)
+		os.Exit(1)
+	}
+
+	s, err := store.Open(*cache)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to open store: %v", err))
+	}
+	defer s.Close()
+	log := store.NewRunLog(s)
+
+	switch arguments[0] {
+	case "list":
+		runs, err := log.List()
+		if err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to list runs: %v", err))
+		}
+		if len(runs) == 0 {
+			gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("No runs recorded in %s", *cache))
+		}
+		for _, run := range runs {
+			partial := ""
+			if run.Partial {
+				partial = " (partial)"
+			}
+			fmt.Printf("%s  %s  %d match(es) of %d target(s)  %s%s\n", run.ID, run.Started.Local().Format("2006-01-02 15:04"), len(run.Matches), run.Targets, run.BaseURL, partial)
+		}
+	case "diff":
+		if fs.NArg() != 2 {
+			fmt.Println(runsUsage)
+			os.Exit(1)
+		}
+		from, err := log.Load(fs.Arg(0))
+		if err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+		}
+		to, err := log.Load(fs.Arg(1))
+		if err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+		}
+		diff := store.Diff(from, to)
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(diff)
+			return
+		}
+		printRunDiff(diff)
+	case "prune":
+		retention, err := store.ParseRetention(*keep)
+		if *keep == "" || err != nil {
+			if err != nil && *keep != "" {
+				gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+			}
+			fmt.Println(runsUsage)
+			os.Exit(1)
+		}
+		deleted, err := log.Prune(retention, time.Now())
+		if err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to prune runs: %v", err))
+		}
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Pruned %d run(s) from %s", deleted, *cache))
+	default:
+		fmt.Println(runsUsage)
+		os.Exit(1)
+	}
+}
+
+// printRunDiff prints newly matched hosts, hosts that stopped matching and confidence drift
+func printRunDiff(diff store.RunDiff) {
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Comparing run %s with %s", diff.From, diff.To))
+	if !diff.SameInput {
+		gologger.Warning().Msg(color.New(color.Bold, color.FgYellow).Sprint("The runs scanned different input lists, so some changes may come from the scope rather than the hosts"))
+	}
+	if !diff.SameConfig {
+		gologger.Warning().Msg(color.New(color.Bold, color.FgYellow).Sprint("The runs used different settings"))
+	}
+	fmt.Println(color.New(color.Bold, color.FgRed).Sprintf("Newly matched hosts (%d)", len(diff.New)))
+	for _, h := range diff.New {
+		fmt.Printf("  + %s  confidence %d  %s\n", h.Host, h.After, strings.Join(h.URLs, ", "))
+	}
+	fmt.Println(color.New(color.Bold, color.FgGreen).Sprintf("Hosts that stopped matching (%d)", len(diff.Gone)))
+	for _, h := range diff.Gone {
+		fmt.Printf("  - %s  confidence was %d\n", h.Host, h.Before)
+	}
+	fmt.Println(color.New(color.Bold, color.FgYellow).Sprintf("Confidence drift (%d)", len(diff.Drift)))
+	for _, h := range diff.Drift {
+		fmt.Printf("  ~ %s  %d -> %d (%+d)\n", h.Host, h.Before, h.After, h.After-h.Before)
+	}
+}
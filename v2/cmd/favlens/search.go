@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
+	corpus "github.com/ethicalhackingplayground/favlens/v2/pkg/corpus"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/gologger/levels"
+)
+
+// searchURLs is how many of an icon's URLs the text output lists
+const searchURLs = 3
+
+// runSearch implements `favlens search`, finding the icons in a --corpus built by earlier scans whose
+// embeddings are nearest to a given icon
+func runSearch(arguments []string) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to locate config directory: %v", err))
+	}
+	defaults, err := config.Load(path)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to read %s: %v", path, err))
+	}
+
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	iconPath := fs.String("icon", "", "Icon to look up: a local image file or a URL")
+	corpusPath := fs.String("corpus", "", "Corpus built by scans run with --corpus")
+	top := fs.Int("top", 10, "Number of similar icons to return")
+	minSimilarity := fs.Float64("min-similarity", 0, "Leave out icons less similar than this cosine similarity")
+	host := fs.String("ollama-host", orDefault(defaults.OllamaHost, "http://localhost:11434"), "Ollama host that embeds the icon")
+	model := fs.String("model", "", "Embedding model (default: the model the corpus was built with)")
+	timeout := fs.Int("timeout", orDefaultInt(defaults.TimeoutSeconds, 30), "HTTP timeout in seconds")
+	asJSON := fs.Bool("json", false, "Print the results as JSON")
+	debug := fs.Bool("debug", false, "Enable debug logging")
+	fs.Parse(arguments)
+
+	hosts := ollama.SplitHosts(*host)
+	if *iconPath == "" || *corpusPath == "" || *top < 1 || len(hosts) == 0 {
+		fmt.Println(color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens search --icon <file|url> --corpus <file> [--top <num>] [--min-similarity <0-1>] [--ollama-host <host>] [--model <model_name>] [--timeout <seconds>] [--json]"))
+		os.Exit(1)
+	}
+	if *debug {
+		gologger.DefaultLogger.SetMaxLevel(levels.LevelDebug)
+	}
+
+	index, err := corpus.Open(*corpusPath)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+	}
+	if index.Len() == 0 {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Corpus %s is empty; build it by scanning with --ollama-endpoint embed --corpus %s", *corpusPath, *corpusPath))
+	}
+	if *model == "" {
+		*model = index.Model()
+	} else if !ollama.ModelMatches(index.Model(), *model) {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Corpus %s holds embeddings from model '%s', not '%s'", *corpusPath, index.Model(), *model))
+	}
+
+	client := ollama.NewClient(hosts[0], *model, time.Duration(*timeout)*time.Second)
+	// The one embedding a search needs usually has to load the model first
+	client.ColdTimeout = ollama.DefaultColdTimeout
+	icon, err := client.DownloadIcon(*iconPath, *debug)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to load icon: %v", err))
+	}
+	embedding, err := client.Embed(icon.Base64, *debug)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to embed icon: %v", err))
+	}
+
+	start := time.Now()
+	hits, err := index.Search(embedding, *top)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Search failed: %v", err))
+	}
+	elapsed := time.Since(start)
+	kept := make([]corpus.Hit, 0, len(hits))
+	for _, hit := range hits {
+		if hit.Similarity >= *minSimilarity {
+			kept = append(kept, hit)
+		}
+	}
+	hits = kept
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(hits)
+		return
+	}
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Searched %d icon(s) in %s", index.Len(), elapsed.Round(time.Microsecond)))
+	for _, hit := range hits {
+		urls := hit.URLs
+		more := ""
+		if len(urls) > searchURLs {
+			more = fmt.Sprintf(" (+%d more)", len(urls)-searchURLs)
+			urls = urls[:searchURLs]
+		}
+		fmt.Printf("%.4f  %s  seen %d, matched %d, last %s  %s%s\n", hit.Similarity, hit.SHA256[:12], hit.Seen, hit.Matches, hit.LastSeen.Local().Format("2006-01-02"), strings.Join(urls, ", "), more)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	runner "github.com/ethicalhackingplayground/favlens/v2/pkg/runner"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// minStallPoll and maxStallPoll bound how often the stall watchdog checks for progress
+const (
+	minStallPoll = time.Second
+	maxStallPoll = 30 * time.Second
+)
+
+// stallSlowest is how many outstanding targets a stall warning names
+const stallSlowest = 3
+
+// watchStalls warns, with what the scan and the Ollama hosts are doing, whenever no result has completed for
+// timeout while jobs are outstanding. A scan waiting for input isn't stalled, so an idle pipeline restarts the
+// clock. With abort set, the first stall stops the run as --max-runtime would, and if in-flight jobs still
+// haven't finished one timeout later, abandon gives up on them so the run shuts down with the results it has. stalled is set once a stall has been reported.
+// The returned function stops watching.
+func watchStalls(scan *runner.Runner, pool *ollama.Pool, timeout time.Duration, abort func(reason string), abandon func(), stalled *atomic.Bool) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(max(min(timeout/4, maxStallPoll), minStallPoll))
+		defer ticker.Stop()
+		var warned, aborted time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			queue := scan.Queue()
+			idle := queue.Queued+queue.Fetched+queue.Downloading+queue.Comparing == 0
+			since := scan.SinceResult()
+			if idle || since < timeout {
+				warned = time.Time{}
+				continue
+			}
+			if !aborted.IsZero() {
+				if time.Since(aborted) >= timeout {
+					gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("In-flight jobs still haven't finished %s after the stall abort, abandoning them", timeout))
+					abandon()
+					return
+				}
+				continue
+			}
+			// Repeat the warning once per timeout while the scan stays stuck
+			if !warned.IsZero() && time.Since(warned) < timeout {
+				continue
+			}
+			warned = time.Now()
+			stalled.Store(true)
+			reportStall(scan, pool, queue, since)
+			if abort != nil {
+				aborted = time.Now()
+				abort(fmt.Sprintf("No result for %s, aborting: finishing in-flight jobs and stopping", since.Round(time.Second)))
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// reportStall logs the diagnostics of a stalled scan: the pipeline, each Ollama host's load and latency, the
+// targets outstanding longest and the latest errors. They are logged as errors so unattended runs keep them.
+func reportStall(scan *runner.Runner, pool *ollama.Pool, queue runner.QueueStats, since time.Duration) {
+	warn := color.New(color.Bold, color.FgYellow)
+	detail := color.New(color.Italic, color.FgYellow)
+	gologger.Error().Msg(warn.Sprintf("No result has completed for %s: %d queued, %d downloading, %d awaiting the model, %d comparing, %d completed",
+		since.Round(time.Second), queue.Queued, queue.Downloading, queue.Fetched, queue.Comparing, queue.Completed))
+	for _, host := range pool.Stats() {
+		line := fmt.Sprintf("  Ollama %s: %d in flight, %d answered", host.Host, host.InFlight, host.Requests)
+		if host.Requests > 0 {
+			line += fmt.Sprintf(", avg %s", host.AvgLatency.Round(time.Millisecond))
+		}
+		gologger.Error().Msg(detail.Sprint(line))
+	}
+	for _, job := range queue.Slowest[:min(len(queue.Slowest), stallSlowest)] {
+		gologger.Error().Msg(detail.Sprintf("  %s on %s for %s (%s)", job.Worker, job.URL, job.Elapsed.Round(time.Second), job.Stage))
+	}
+	for _, result := range scan.RecentErrors() {
+		gologger.Error().Msg(detail.Sprintf("  Recent error: %s: %v", result.URL, result.Err))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	args "github.com/ethicalhackingplayground/favlens/v2/pkg/arguments"
+	output "github.com/ethicalhackingplayground/favlens/v2/pkg/output"
+	remote "github.com/ethicalhackingplayground/favlens/v2/pkg/remote"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// topApexCount is how many matched domains the closing summary names
+const topApexCount = 5
+
+// printNextSteps closes a run with what to look at next: the domains with the most matches, where the report was
+// written and, when targets failed, where they were written and the command that scans only those again
+func printNextSteps(a *args.Arguments, apexes map[string]int, errors int, reports []string, failed *output.ErrorsWriter) {
+	heading := color.New(color.Bold, color.FgGreen)
+	gologger.Info().Msg(heading.Sprint("Next steps"))
+	if top := topApexes(apexes, topApexCount); len(top) > 0 {
+		more := ""
+		if len(apexes) > len(top) {
+			more = fmt.Sprintf(" and %d more", len(apexes)-len(top))
+		}
+		gologger.Info().Msg(heading.Sprintf("  Top matched domains: %s%s", strings.Join(top, ", "), more))
+	}
+	switch len(reports) {
+	case 0:
+	case 1:
+		gologger.Info().Msg(heading.Sprintf("  Report: %s", reports[0]))
+	default:
+		gologger.Info().Msg(heading.Sprintf("  Report: %d chunks, %s to %s", len(reports), reports[0], reports[len(reports)-1]))
+	}
+	if errors == 0 {
+		return
+	}
+
+	hint := color.New(color.Bold, color.FgYellow)
+	written := failed != nil && failed.Path() != ""
+	if written {
+		gologger.Info().Msg(hint.Sprintf("  Failed targets (%s): %s, scan them again with -file %s", failed.Summary(), failed.Path(), failed.Path()))
+	}
+	// Only a single local JSON file holds the failed targets in a form --retry-errors-from can read back
+	if !strings.EqualFold(a.Format, output.FormatJSON) || len(reports) != 1 || remote.IsURL(reports[0]) {
+		if !written {
+			gologger.Info().Msg(hint.Sprintf("  %d target(s) failed; write -format json to a local -o file, or pass --errors-file, to scan them again", errors))
+		}
+		return
+	}
+	command, omitted := args.RetryCommand(reports[0])
+	gologger.Info().Msg(hint.Sprintf("  Re-run the %d failed target(s): %s", errors, command))
+	if len(omitted) > 0 {
+		gologger.Info().Msg(hint.Sprintf("  Credentials were left out of the command; add %s again or set them in the environment", strings.Join(omitted, ", ")))
+	}
+}
+
+// topApexes returns the n domains with the most matches as "apex (count)", most matches first
+func topApexes(apexes map[string]int, n int) []string {
+	names := make([]string, 0, len(apexes))
+	for apex := range apexes {
+		names = append(names, apex)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if apexes[names[i]] != apexes[names[j]] {
+			return apexes[names[i]] > apexes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	top := make([]string, len(names))
+	for i, name := range names {
+		top[i] = fmt.Sprintf("%s (%d)", name, apexes[name])
+	}
+	return top
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	tune "github.com/ethicalhackingplayground/favlens/v2/pkg/tune"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/gologger/levels"
+)
+
+// runTune implements `favlens tune`, benchmarking the backend at increasing concurrency and recommending
+// how many inference workers to run
+func runTune(arguments []string) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to locate config directory: %v", err))
+	}
+	defaults, err := config.Load(path)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to read %s: %v", path, err))
+	}
+
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	host := fs.String("ollama-host", orDefault(defaults.OllamaHost, "http://localhost:11434"), "Ollama host, or a comma-separated list of hosts, to benchmark")
+	model := fs.String("model", orDefault(defaults.Model, "gemma3:4b"), "Ollama model to benchmark")
+	endpoint := fs.String("ollama-endpoint", ollama.EndpointChat, "Ollama API used for comparisons: chat, generate, embed")
+	maxWorkers := fs.Int("max-workers", 16, "Highest concurrency to try; levels double from 1")
+	step := fs.Duration("step", 15*time.Second, "How long to run each concurrency level")
+	timeout := fs.Int("timeout", orDefaultInt(defaults.TimeoutSeconds, 30), "HTTP timeout in seconds")
+	save := fs.Bool("save", false, "Save the recommended worker count to config.yaml")
+	debug := fs.Bool("debug", false, "Enable debug logging")
+	fs.Parse(arguments)
+
+	if err := ollama.ValidateEndpoint(*endpoint); err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%v", err))
+	}
+	if *debug {
+		gologger.DefaultLogger.SetMaxLevel(levels.LevelDebug)
+	}
+	hosts := ollama.SplitHosts(*host)
+	if len(hosts) == 0 || *maxWorkers < 1 || *step <= 0 {
+		fmt.Println(color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens tune [--ollama-host <host[,host...]>] [--model <model_name>] [--ollama-endpoint chat|generate|embed] [--max-workers <num>] [--step <duration>] [--timeout <seconds>] [--save]"))
+		os.Exit(1)
+	}
+
+	pool := ollama.NewPool(hosts, *model, time.Duration(*timeout)*time.Second, ollama.StrategySticky)
+	for _, client := range pool.Clients() {
+		client.Endpoint = *endpoint
+		// The unmeasured first comparison loads the model, which can outlast --timeout
+		client.ColdTimeout = ollama.DefaultColdTimeout
+		if err := client.CheckModelExists(*debug); err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Model validation failed on %s: %v", client.Host, err))
+		}
+	}
+
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Benchmarking %s on %d host(s), %s per level up to %d workers", *model, len(hosts), *step, *maxWorkers))
+	// There is no batch size to measure: each comparison is its own request
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprint("favlens sends one icon pair per request, so concurrency is the only knob to tune"))
+	steps, err := tune.Run(pool, tune.Options{
+		MaxWorkers: *maxWorkers,
+		Step:       *step,
+		Debug:      *debug,
+		Progress: func(s tune.Step) {
+			line := fmt.Sprintf("%3d workers: %6.2f comparisons/s, p50 %s, p95 %s", s.Workers, s.Throughput, s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond))
+			if s.Errors > 0 {
+				line += fmt.Sprintf(", %d error(s)", s.Errors)
+				gologger.Info().Msg(color.New(color.FgYellow).Sprint(line))
+				return
+			}
+			gologger.Info().Msg(line)
+		},
+	})
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Benchmark failed: %v", err))
+	}
+
+	best, ok := tune.Recommend(steps)
+	if !ok {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprint("Every level failed; check the Ollama logs and try a lower --max-workers"))
+	}
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Recommended: --infer-workers %d (%.2f comparisons/s, p95 %s)", best.Workers, best.Throughput, best.P95.Round(time.Millisecond)))
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Or --workers %d when downloads and comparisons share one pool", best.Workers))
+
+	if *save {
+		defaults.Workers = best.Workers
+		if err := config.Save(path, defaults); err != nil {
+			gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to save config: %v", err))
+		}
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Saved workers: %d to %s", best.Workers, path))
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	signing "github.com/ethicalhackingplayground/favlens/v2/pkg/signing"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// runVerify implements `favlens verify`, checking a results file against the signature written by --sign-output
+func runVerify(arguments []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Public key, certificate or private key of the signer (PEM)")
+	sigPath := fs.String("sig", "", "Signature file (default: the results file with .sig appended)")
+	fs.Parse(arguments)
+
+	if *keyPath == "" || fs.NArg() != 1 {
+		fmt.Println(color.New(color.FgYellow, color.Italic).Sprint("Usage: favlens verify --key <public.pem> [--sig <file.sig>] <results_file>"))
+		os.Exit(1)
+	}
+	path := fs.Arg(0)
+	if *sigPath == "" {
+		*sigPath = path + signing.Extension
+	}
+
+	pub, err := signing.LoadPublicKey(*keyPath)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to load key: %v", err))
+	}
+	sig, err := os.ReadFile(*sigPath)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to read signature: %v", err))
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Failed to open results: %v", err))
+	}
+	defer file.Close()
+	if err := signing.Verify(file, sig, pub); err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("%s: %v", path, err))
+	}
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("%s: signature OK", path))
+}
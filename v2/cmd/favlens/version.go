@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	update "github.com/ethicalhackingplayground/favlens/v2/pkg/update"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// version is set at build time by goreleaser (-X main.version=...)
+var version = "dev"
+
+// runVersion implements `favlens version [--check]`
+func runVersion(arguments []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	check := fs.Bool("check", false, "Check GitHub for a newer release")
+	fs.Parse(arguments)
+
+	fmt.Printf("favlens %s\n", version)
+	if !*check {
+		return
+	}
+
+	release, err := update.LatestRelease()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Update check failed: %v", err))
+	}
+	if update.IsNewer(release.TagName, version) {
+		gologger.Info().Msg(color.New(color.Bold, color.FgYellow).Sprintf("A newer version is available: %s (%s)", release.TagName, release.HTMLURL))
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprint("Run `favlens update` to install it"))
+		return
+	}
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("favlens is up to date (latest release: %s)", release.TagName))
+}
+
+// runUpdate implements `favlens update`, replacing the running binary with the latest release
+func runUpdate(arguments []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	force := fs.Bool("force", false, "Reinstall even if already on the latest release")
+	fs.Parse(arguments)
+
+	release, err := update.LatestRelease()
+	if err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Update check failed: %v", err))
+	}
+	if !*force && !update.IsNewer(release.TagName, version) {
+		gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("favlens is already up to date (%s)", version))
+		return
+	}
+
+	gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("Updating favlens %s -> %s...", version, release.TagName))
+	if err := release.Apply(); err != nil {
+		gologger.Fatal().Msg(color.New(color.Bold, color.FgRed).Sprintf("Update failed: %v", err))
+	}
+	gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprintf("Updated to %s (checksum verified)", release.TagName))
+}
@@ -2,65 +2,509 @@ package args
 
 import (
 	"flag"
-	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
 	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
 )
 
+// stringSlice is a flag.Value that collects every occurrence of a repeatable flag
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 // Arguments struct to hold command line arguments
 type Arguments struct {
-	BaseURL        string
-	OllamaHost     string
-	FilePath       string
-	Model          string
-	Workers        int
-	Debug          bool
-	Verbose        bool
-	Silent         bool
-	Output         string
-	TimeoutSeconds int
-	DelayMs        int
+	BaseURL          string
+	OllamaHost       string
+	FilePath         string
+	InputFormat      string
+	FromSubfinder    bool
+	Model            string
+	Workers          int
+	DownloadWorkers  int
+	InferWorkers     int
+	CapWorkers       bool
+	ShowQueue        bool
+	LogLevel         string
+	Debug            bool
+	Verbose          bool
+	Silent           bool
+	Output           string
+	OutputChunkSize  int
+	ErrorsFile       string
+	StatsJSON        string
+	HashInput        string
+	ClusterDir       string
+	Fingerprints     string
+	Corpus           string
+	MinConfidence    int
+	Format           string
+	SignOutput       string
+	EncryptOutput    []string
+	Attest           string
+	OpenCTIConf      int
+	OpenCTIMarkings  []string
+	ESURL            string
+	ESIndex          string
+	ESAuth           string
+	SplunkURL        string
+	SplunkToken      string
+	SplunkIndex      string
+	SplunkSource     string
+	Syslog           string
+	TimeoutSeconds   int
+	ColdTimeout      int
+	DelayMs          int
+	JitterMs         int
+	Retries          int
+	Profile          string
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	MaxRuntime       time.Duration
+	JobTimeout       time.Duration
+	StallTimeout     time.Duration
+	AbortOnStall     bool
+	MaxTargets       int
+	Sample           string
+	SampleN          int
+	SampleSeed       uint64
+	Cache            string
+	RecordRun        bool
+	KeepRuns         string
+	Auth             string
+	AuthType         string
+	AuthFile         string
+	Cookies          []string
+	CookieFile       string
+	CookieSession    bool
+	ClientCert       string
+	ClientKey        string
+	LBStrategy       string
+	SpawnOllama      bool
+	SpawnMode        string
+	SpawnImage       string
+	K8s              bool
+	Deterministic    bool
+	Ordered          bool
+	HealthAddr       string
+	PrioritizeRegex  []string
+	Tags             []string
+	NotifyRules      []string
+	Email            *config.Email
+	URLScanQuery     string
+	URLScanKey       string
+	VTQuery          string
+	VTKey            string
+	SourceLimit      int
+	PDNSDomain       string
+	PDNSProvider     string
+	PDNSKey          string
+	PermuteDomain    string
+	PermuteResolvers int
+	BrandDomain      string
+	RateLimitRetries int
+	DeferredRetries  int
+	RespectRobots    bool
+	AuditLog         string
+	SourceIPs        []string
+	Interfaces       []string
+	MaxBandwidth     string
+	IPVersion        string
+	Tor              bool
+	TorProxy         string
+	TorRotate        int
+	SuspectChecks    bool
+	WildcardFilter   string
+	FlattenBG        string
+	AutoCrop         bool
+	IgnoreColor      bool
+	MatchMode        string
+	OCR              bool
+	Brand            string
+	Strictness       string
+	OllamaEndpoint   string
+	Similarity       float64
+	KeepDuplicates   bool
+	Origins          bool
+	RetryErrorsFrom  string
+	GroupBy          string
+	FaviconDir       bool
+	Mode             string
+	Browser          string
+	BrandKit         string
+
+	// levelFlags are the legacy logging flags that were set, and levelExplicit whether --log-level was;
+	// both are kept for Validate to report conflicts
+	levelFlags    []string
+	levelExplicit bool
+}
+
+// loadConfigFile reads the user's config.yaml written by `favlens init`; problems are reported but never fatal
+func loadConfigFile() *config.File {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return &config.File{}
+	}
+	file, err := config.Load(path)
+	if err != nil {
+		gologger.Warning().Msgf("Ignoring config file: %v", err)
+		return &config.File{}
+	}
+	return file
+}
+
+func orString(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
+
+func orInt(value, fallback int) int {
+	if value != 0 {
+		return value
+	}
+	return fallback
 }
 
 func NewArguments() *Arguments {
+	// Values from the config file replace the built-in flag defaults
+	defaults := loadConfigFile()
+
 	// CLI flags
-	baseURL := flag.String("base", "", "Base favicon URL to compare against (required)")
-	ollamaHost := flag.String("ollama-host", "http://localhost:11434", "Ollama host (default: http://localhost:11434)")
-	filePath := flag.String("file", "", "Path to file containing URLs to check (required)")
-	model := flag.String("model", "gemma3:4b", "Ollama model to use (default: gemma3:4b)")
-	workers := flag.Int("workers", 5, "Number of concurrent workers (default: 5)")
-	debug := flag.Bool("debug", false, "Enable debug logging (shows everything)")
-	verbose := flag.Bool("verbose", false, "Enable verbose logging (shows info without errors)")
-	silent := flag.Bool("silent", false, "Silent mode (only shows matched URLs)")
-	output := flag.String("o", "", "Output file to save matched URLs (optional)")
-	timeoutSeconds := flag.Int("timeout", 30, "HTTP timeout in seconds (default: 30)")
+	baseURL := flag.String("base", "", "Base favicon URL, or a local image file, to compare against (required unless --brand-kit is given)")
+	ollamaHost := flag.String("ollama-host", orString(defaults.OllamaHost, "http://localhost:11434"), "Ollama host, or a comma-separated list of hosts to spread comparisons over (default: http://localhost:11434)")
+	filePath := flag.String("file", "", "Path to a file, or a directory of files such as a mounted ConfigMap, containing URLs to check; gzip, zstd and zip files are read without unpacking, and - reads stdin (required unless a search source is given)")
+	inputFormat := flag.String("input-format", "auto", "Input file format: auto, text, csv, json, email, httpx, hosts (default: auto, detected from the file extension)")
+	fromSubfinder := flag.Bool("from-subfinder", false, "Read subfinder/amass hostnames from stdin (or --file), probe each for https and http, and scan with the fast profile (default: false)")
+	model := flag.String("model", orString(defaults.Model, "gemma3:4b"), "Ollama model to use (default: gemma3:4b)")
+	workers := flag.Int("workers", orInt(defaults.Workers, 5), "Number of concurrent workers (default: 5)")
+	downloadWorkers := flag.Int("download-workers", 0, "Concurrent icon downloads, sized for the network (default: --workers)")
+	inferWorkers := flag.Int("infer-workers", 0, "Concurrent model comparisons, sized for the GPU (default: --workers)")
+	showQueue := flag.Bool("show-queue", false, "Log queued, in-flight and completed jobs and the slowest outstanding targets every 10s, to tell whether the network or the model is the bottleneck")
+	capWorkers := flag.Bool("cap-workers", false, "Lower --infer-workers to the parallel requests the Ollama hosts can serve, as estimated from /api/ps")
+	logLevel := flag.String("log-level", "", "Log level: silent, error, info, verbose, debug; replaces --silent, --verbose and --debug (default: info)")
+	debug := flag.Bool("debug", false, "Enable debug logging (shows everything), same as --log-level debug")
+	verbose := flag.Bool("verbose", false, "Enable verbose logging (adds warnings and library detail), same as --log-level verbose")
+	silent := flag.Bool("silent", false, "Silent mode (only shows matched URLs), same as --log-level silent")
+	output := flag.String("o", "", "Output file to save matched URLs, or an s3://bucket/key or gs://bucket/key URL to upload it to (optional)")
+	outputChunkSize := flag.Int("output-chunk-size", 0, "Roll the -o file over to numbered chunks (results.0001.json, ...) of this many recorded results (default: 0, one file)")
+	clusterDir := flag.String("cluster-dir", "", "Directory to write one representative PNG per icon equivalence class to, plus a clusters.json mapping to label (optional)")
+	fingerprints := flag.String("fingerprints", "", "Labelled clusters.json from --cluster-dir; targets with a labelled icon are tagged with its label (optional)")
+	corpusPath := flag.String("corpus", "", "Index file the embedding of every compared icon is added to, for `favlens search` over past scans; needs --ollama-endpoint embed (optional)")
+	minConfidence := flag.Int("min-confidence", orInt(defaults.MinConfidence, 0), "Report matches whose confidence score (0-100) is below this as non-matches; `favlens review thresholds --save` learns it from review decisions (default: 0, keep every match)")
+	hashInput := flag.String("hash-input", "b64-wrapped", "What the reported mmh3 favicon hash is computed over: b64-wrapped (Shodan and httpx) or raw icon bytes (default: b64-wrapped)")
+	statsJSON := flag.String("stats-json", "", "File per-host download statistics (attempts, successes, reused connections, average latency, last error) are written to every 15s during the run and at its end (optional)")
+	errorsFile := flag.String("errors-file", "", "File failed targets are written to with their error class, readable with --file; none disables it (default: <-o file>.errors.txt)")
+	format := flag.String("format", orString(defaults.Format, "text"), "Output format: text, json, sarif, defectdojo, faraday, parquet, opencti (default: text)")
+	signOutput := flag.String("sign-output", "", "Private key (PEM: RSA, ECDSA or Ed25519) to sign the -o file with, written alongside it as <file>.sig; check it with `favlens verify` (optional)")
+	var encryptOutput stringSlice
+	flag.Var(&encryptOutput, "encrypt-output", "Encrypt the -o file to a recipient: an age public key (age1...), SSH public key, age recipients file or OpenPGP public key file (repeatable)")
+	attest := flag.String("attest", "", "Write an in-toto statement with SLSA provenance for the -o file to this path: the output's digest plus the settings and input list that produced it (optional)")
+	openCTIConf := flag.Int("opencti-confidence", 0, "Confidence (1-100) given to every --format opencti indicator (default: each match's own confidence score)")
+	var openCTIMarkings stringSlice
+	flag.Var(&openCTIMarkings, "opencti-marking", "Marking for --format opencti objects: TLP:CLEAR, TLP:GREEN, TLP:AMBER, TLP:AMBER+STRICT, TLP:RED or a marking-definition--<uuid> id (repeatable, default: TLP:AMBER)")
+	esURL := flag.String("es-url", "", "Elasticsearch or OpenSearch URL to bulk-index every result into as the scan runs, e.g. https://localhost:9200 (optional)")
+	esIndex := flag.String("es-index", "favlens", "Index that --es-url results are written to (default: favlens)")
+	esAuth := flag.String("es-auth", os.Getenv("ELASTICSEARCH_AUTH"), "Credentials for --es-url: user:pass for basic auth, or an API key (default: $ELASTICSEARCH_AUTH)")
+	splunkURL := flag.String("splunk-url", "", "Splunk HTTP Event Collector URL to send every result to as the scan runs, e.g. https://splunk:8088 (optional)")
+	splunkToken := flag.String("splunk-token", os.Getenv("SPLUNK_HEC_TOKEN"), "HEC token for --splunk-url (default: $SPLUNK_HEC_TOKEN)")
+	splunkIndex := flag.String("splunk-index", "", "Index for --splunk-url events (default: the token's default index)")
+	splunkSource := flag.String("splunk-sourcetype", "favlens", "Sourcetype for --splunk-url events (default: favlens)")
+	syslog := flag.String("syslog", "", "Syslog collector to send every match to as RFC 5424 messages: udp://host:514, tcp://host:514 or tls://host:6514 (optional)")
+	timeoutSeconds := flag.Int("timeout", orInt(defaults.TimeoutSeconds, 30), "HTTP timeout in seconds (default: 30)")
+	coldTimeout := flag.Int("cold-timeout", 120, "Timeout in seconds for model calls until the model has loaded on each Ollama host, 0 to use --timeout throughout (default: 120)")
 	delayMs := flag.Int("delay", 0, "Delay between requests in milliseconds (default: 0)")
+	jitterMs := flag.Int("jitter", 0, "Random extra delay of up to this many milliseconds per request (default: 0)")
+	retries := flag.Int("retries", 0, "Retries for failed downloads and model calls (default: 0)")
+	respectRobots := flag.Bool("respect-robots", false, "Fetch robots.txt once per host and skip icons it disallows for the favlens user agent")
+	var sourceIPs, interfaces stringSlice
+	flag.Var(&sourceIPs, "source-ip", "Local address icon downloads are sent from; several rotate per connection (repeatable, comma-separated)")
+	flag.Var(&interfaces, "interface", "Network interface whose addresses icon downloads are sent from, e.g. eth1 (repeatable, comma-separated)")
+	tor := flag.Bool("tor", false, "Route icon and robots.txt downloads through a local Tor SOCKS proxy; Ollama traffic stays local")
+	torProxy := flag.String("tor-proxy", "127.0.0.1:9050", "Address of the Tor SOCKS5 proxy used by --tor (default: 127.0.0.1:9050)")
+	torRotate := flag.Int("tor-rotate", 10, "Switch to a new Tor circuit every N downloads, 0 to keep one circuit (default: 10)")
+	suspectChecks := flag.Bool("suspect-checks", false, "Flag matches from parked, wildcard DNS or mass hosting with suspect_wildcard; probes DNS and each matched site's front page")
+	flattenBG := flag.String("flatten-bg", "", "Flatten transparent icons onto a background before comparison: white, black, checker (default: keep transparency)")
+	autoCrop := flag.Bool("autocrop", false, "Trim uniform borders and transparent padding from icons before comparison")
+	ignoreColor := flag.Bool("ignore-color", false, "Convert icons to greyscale before comparison so recolored logos still match")
+	mode := flag.String("mode", "favicon", "What is compared against the base icon: favicon, or screenshot to ask whether a headless-browser screenshot of each page impersonates the brand (default: favicon)")
+	browser := flag.String("browser", "", "Chrome or Chromium binary used by --mode screenshot (default: the first one found on PATH)")
+	matchMode := flag.String("match-mode", "equal", "What the model is asked: equal (same icon or brand) or contains (base logo appears anywhere in the target image) (default: equal)")
+	faviconDir := flag.Bool("favicon-dir", false, "For page URLs, also try favicon.ico in the page's own directory when the origin root has none, e.g. /app/favicon.ico for /app/login")
+	ocr := flag.Bool("ocr", false, "Have the model read text inside each target icon and fuzzy-match it against the brand name (one extra model call per icon)")
+	brand := flag.String("brand", "", "Brand name the model is told to look for and --ocr matches icon text against, e.g. 'Acme Corp' (default: the brand domain's name for --ocr only)")
+	brandKit := flag.String("brand-kit", "", "Directory of brand logo variants, with an optional brand.yaml of names, colors and domains, used as the reference instead of --base; a match on any logo counts")
+	strictness := flag.String("strictness", "normal", "How close a target must be to match: lenient (also different artwork of the brand), normal, strict (visually identical only) (default: normal)")
+	ollamaEndpoint := flag.String("ollama-endpoint", "chat", "Ollama API used for comparisons: chat, generate for vision models that behave better without a chat template, or embed to compare image embeddings by cosine similarity (default: chat)")
+	similarity := flag.Float64("similarity", 0.9, "Cosine similarity (0-1] at or above which --ollama-endpoint embed counts a target as a match (default: 0.9)")
+	wildcardFilter := flag.String("wildcard-filter", "", "Before scanning, resolve a random name per zone and collapse (scan one per zone) or skip wildcard-served targets: collapse, skip (optional)")
+	auditLog := flag.String("audit-log", "", "Append a JSON line per network request (timestamp, URL, status, bytes, source IP) to this file")
+	rateLimitRetries := flag.Int("rate-limit-retries", 5, "Consecutive 429/503 Retry-After responses from one host before its targets are marked failed; rate-limited targets are requeued until then (default: 5)")
+	ipVersion := flag.String("ip-version", "auto", "IP version for icon downloads: 4, 6, or auto to try both happy-eyeballs style so broken IPv6 falls back to IPv4 quickly (default: auto)")
+	maxBandwidth := flag.String("max-bandwidth", "", "Cap the combined traffic of all icon downloads, e.g. 5mbps, 500kbps or 2MB/s; bps units are bits, /s units bytes (optional)")
+	deferredRetries := flag.Int("deferred-retries", 1, "Extra attempts for targets that timed out, had their connection reset or got a 5xx, made once the input is exhausted at a quarter of the download concurrency; 0 disables (default: 1)")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "Consecutive Ollama failures that pause dispatch (default: 5, 0 disables the circuit breaker)")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "Pause before probing a failing Ollama backend again; doubles while it keeps failing (default: 30s)")
+	profile := flag.String("profile", defaults.Profile, "Scan profile: "+strings.Join(config.ProfileNames(), ", ")+" (explicit flags override profile values)")
+	jobTimeout := flag.Duration("job-timeout", 5*time.Minute, "Hard deadline for one target, independent of HTTP timeouts; overrunning jobs are requeued once, then failed (0 disables) (default: 5m)")
+	stallTimeout := flag.Duration("stall-timeout", 10*time.Minute, "Warn with diagnostics when no result completes for this long while jobs are outstanding (0 disables) (default: 10m)")
+	abortOnStall := flag.Bool("abort-on-stall", false, "Stop the scan when --stall-timeout is reached, exiting with status 2, instead of only warning")
+	maxRuntime := flag.Duration("max-runtime", 0, "Stop dispatching new jobs after this duration, e.g. 2h (default: 0, unlimited)")
+	maxTargets := flag.Int("max-targets", 0, "Stop after dispatching this many targets (default: 0, unlimited)")
+	sample := flag.String("sample", "", "Scan a random sample of this percentage of the input, e.g. 10%, stratified per apex domain (optional)")
+	sampleN := flag.Int("sample-n", 0, "Scan a random sample of this many targets, stratified per apex domain (default: 0, no sampling)")
+	sampleSeed := flag.Uint64("sample-seed", 0, "Seed for --sample and --sample-n, to repeat a sample (default: 0, a random seed that is logged)")
+	cache := flag.String("cache", "", "Verdict cache store: a directory, sqlite:///path.db or redis://host:6379/0 (optional)")
+	recordRun := flag.Bool("record-run", false, "Record this run's matches in the --cache store for `favlens runs diff`")
+	keepRuns := flag.String("keep-runs", "", "After recording, prune runs outside this retention: an age such as 90d, 12w or 72h, or a number of runs (default: keep all)")
+	authValue := flag.String("auth", "", "Credentials sent with every icon download: user:pass for basic/ntlm, a token for bearer (optional)")
+	authType := flag.String("auth-type", "basic", "Scheme for --auth: basic, bearer, ntlm (default: basic)")
+	authFile := flag.String("auth-file", "", "File of per-host credentials, one '<host> <type> <credentials>' per line; hosts may use *.example.com (optional)")
+	var cookies stringSlice
+	flag.Var(&cookies, "cookie", "Cookie header sent with every icon download, e.g. 'sid=abc; theme=dark' (repeatable)")
+	cookieFile := flag.String("cookie-file", "", "Netscape/curl cookies.txt file; cookies are sent to their own domains (optional)")
+	cookieSession := flag.Bool("cookie-session", false, "Keep a cookie jar per host and replay cookies set by earlier responses")
+	clientCert := flag.String("client-cert", "", "PEM client certificate for targets that require mutual TLS (optional)")
+	clientKey := flag.String("client-key", "", "PEM private key for --client-cert (default: read from the certificate file)")
+	lbStrategy := flag.String("lb-strategy", "sticky", "How comparisons are spread over several Ollama hosts: sticky, round-robin, least-latency (default: sticky)")
+	spawnOllama := flag.Bool("spawn-ollama", false, "Launch a local Ollama server for this run, pull the model and tear it down afterwards")
+	spawnMode := flag.String("spawn-mode", "auto", "How --spawn-ollama runs Ollama: auto, process, docker (default: auto)")
+	spawnImage := flag.String("spawn-image", "ollama/ollama", "Container image used by --spawn-mode docker (default: ollama/ollama)")
+	k8s := flag.Bool("k8s", false, "Kubernetes mode: NDJSON results on stdout, graceful SIGTERM drain and health probes on -health-addr")
+	healthAddr := flag.String("health-addr", ":8080", "Listen address for /healthz and /readyz in -k8s mode (default: :8080)")
+	deterministic := flag.Bool("deterministic", false, "Reproducible run: one worker, results in input order, no jitter, temperature 0, fixed seed and fixed timestamps")
+	ordered := flag.Bool("ordered", false, "Write results in input order instead of completion order (buffers results that finish early)")
+	keepDuplicates := flag.Bool("keep-duplicates", false, "Scan and report every input line, even targets repeated earlier in the input (default: each target and base pair is reported once)")
+	groupBy := flag.String("group-by", "", "Aggregate matches in text and json output, and in the emailed report, per registered domain: apex (optional)")
+	retryErrorsFrom := flag.String("retry-errors-from", "", "favlens -format json output whose failed targets are scanned again, e.g. results.jsonl (optional)")
+	origins := flag.Bool("origins", false, "Reduce crawled URL dumps, e.g. from katana or gau, to one target per origin before fetching favicons (default: false)")
+	var prioritizeRegex stringSlice
+	flag.Var(&prioritizeRegex, "prioritize-regex", "Regex for target URLs to scan before all others (repeatable)")
+	var tags stringSlice
+	flag.Var(&tags, "tag", "Label results whose target URL matches, as name=regex, e.g. 'login=/login|signin/' (repeatable)")
+	var notifyRules stringSlice
+	flag.Var(&notifyRules, "notify-rule", "Route matches to a channel, e.g. 'confidence>90 && tag==login => slack:#incident'; channels are slack:#channel, webhook:<url> and file:<path> (repeatable)")
+
+	urlscanQuery := flag.String("urlscan-query", "", "urlscan.io search whose result pages are scanned, e.g. 'hash:<sha256>' or 'page.title:acme' (optional)")
+	urlscanKey := flag.String("urlscan-key", os.Getenv("URLSCAN_API_KEY"), "urlscan.io API key (default: $URLSCAN_API_KEY)")
+	vtQuery := flag.String("vt-query", "", "VirusTotal Intelligence search whose URLs and domains are scanned, e.g. 'entity:url main_icon_dhash:<dhash>' (optional)")
+	vtKey := flag.String("vt-key", os.Getenv("VT_API_KEY"), "VirusTotal API key (default: $VT_API_KEY)")
+	pdnsDomain := flag.String("pdns-domain", "", "Legitimate domain whose historical subdomains and lookalikes are pulled from passive DNS and scanned (optional)")
+	pdnsProvider := flag.String("pdns-provider", "securitytrails", "Passive DNS provider for --pdns-domain: securitytrails, dnsdb (default: securitytrails)")
+	pdnsKey := flag.String("pdns-key", "", "Passive DNS API key (default: $SECURITYTRAILS_API_KEY or $DNSDB_API_KEY)")
+	permuteDomain := flag.String("permute-domain", "", "Generate typosquat, homoglyph and bitflip permutations of this domain and scan the ones that resolve (optional)")
+	permuteResolvers := flag.Int("permute-resolvers", 50, "Concurrent DNS lookups when resolving --permute-domain candidates (default: 50)")
+	brandDomain := flag.String("brand-domain", "", "Legitimate domain that matched hosts are checked against for IDN/homoglyph lookalikes (default: the base favicon's host)")
+	sourceLimit := flag.Int("source-limit", 1000, "Maximum URLs taken from each search source (default: 1000, 0 for unlimited)")
 
 	// Parse flags before returning values
 	flag.Parse()
 
-	return &Arguments{
-		BaseURL:        *baseURL,
-		OllamaHost:     *ollamaHost,
-		FilePath:       *filePath,
-		Model:          *model,
-		Workers:        *workers,
-		Debug:          *debug,
-		Verbose:        *verbose,
-		Silent:         *silent,
-		Output:         *output,
-		TimeoutSeconds: *timeoutSeconds,
-		DelayMs:        *delayMs,
+	a := &Arguments{
+		BaseURL:          *baseURL,
+		OllamaHost:       *ollamaHost,
+		FilePath:         *filePath,
+		InputFormat:      *inputFormat,
+		FromSubfinder:    *fromSubfinder,
+		Model:            *model,
+		Workers:          *workers,
+		DownloadWorkers:  *downloadWorkers,
+		InferWorkers:     *inferWorkers,
+		CapWorkers:       *capWorkers,
+		ShowQueue:        *showQueue,
+		Debug:            *debug,
+		Verbose:          *verbose,
+		Silent:           *silent,
+		Output:           *output,
+		OutputChunkSize:  *outputChunkSize,
+		ErrorsFile:       *errorsFile,
+		StatsJSON:        *statsJSON,
+		HashInput:        strings.ToLower(*hashInput),
+		ClusterDir:       *clusterDir,
+		Fingerprints:     *fingerprints,
+		Corpus:           *corpusPath,
+		MinConfidence:    *minConfidence,
+		Format:           *format,
+		SignOutput:       *signOutput,
+		EncryptOutput:    encryptOutput,
+		Attest:           *attest,
+		OpenCTIConf:      *openCTIConf,
+		OpenCTIMarkings:  openCTIMarkings,
+		ESURL:            *esURL,
+		ESIndex:          *esIndex,
+		ESAuth:           *esAuth,
+		SplunkURL:        *splunkURL,
+		SplunkToken:      *splunkToken,
+		SplunkIndex:      *splunkIndex,
+		SplunkSource:     *splunkSource,
+		Syslog:           *syslog,
+		TimeoutSeconds:   *timeoutSeconds,
+		ColdTimeout:      *coldTimeout,
+		DelayMs:          *delayMs,
+		JitterMs:         *jitterMs,
+		Retries:          *retries,
+		Profile:          *profile,
+		BreakerThreshold: *breakerThreshold,
+		BreakerCooldown:  *breakerCooldown,
+		MaxRuntime:       *maxRuntime,
+		JobTimeout:       *jobTimeout,
+		StallTimeout:     *stallTimeout,
+		AbortOnStall:     *abortOnStall,
+		MaxTargets:       *maxTargets,
+		Sample:           *sample,
+		SampleN:          *sampleN,
+		SampleSeed:       *sampleSeed,
+		Cache:            *cache,
+		RecordRun:        *recordRun,
+		KeepRuns:         *keepRuns,
+		Auth:             *authValue,
+		AuthType:         *authType,
+		AuthFile:         *authFile,
+		Cookies:          cookies,
+		CookieFile:       *cookieFile,
+		CookieSession:    *cookieSession,
+		ClientCert:       *clientCert,
+		ClientKey:        *clientKey,
+		LBStrategy:       *lbStrategy,
+		SpawnOllama:      *spawnOllama,
+		SpawnMode:        *spawnMode,
+		SpawnImage:       *spawnImage,
+		K8s:              *k8s,
+		Deterministic:    *deterministic,
+		Ordered:          *ordered,
+		HealthAddr:       *healthAddr,
+		PrioritizeRegex:  prioritizeRegex,
+		Tags:             tags,
+		NotifyRules:      notifyRules,
+		Email:            defaults.Email,
+		URLScanQuery:     *urlscanQuery,
+		URLScanKey:       *urlscanKey,
+		VTQuery:          *vtQuery,
+		VTKey:            *vtKey,
+		SourceLimit:      *sourceLimit,
+		PDNSDomain:       *pdnsDomain,
+		PDNSProvider:     *pdnsProvider,
+		PDNSKey:          *pdnsKey,
+		PermuteDomain:    *permuteDomain,
+		PermuteResolvers: *permuteResolvers,
+		BrandDomain:      *brandDomain,
+		RateLimitRetries: *rateLimitRetries,
+		DeferredRetries:  *deferredRetries,
+		MaxBandwidth:     *maxBandwidth,
+		IPVersion:        strings.ToLower(*ipVersion),
+		RespectRobots:    *respectRobots,
+		AuditLog:         *auditLog,
+		SourceIPs:        sourceIPs,
+		Interfaces:       interfaces,
+		Tor:              *tor,
+		TorProxy:         *torProxy,
+		TorRotate:        *torRotate,
+		SuspectChecks:    *suspectChecks,
+		WildcardFilter:   *wildcardFilter,
+		FlattenBG:        *flattenBG,
+		AutoCrop:         *autoCrop,
+		IgnoreColor:      *ignoreColor,
+		MatchMode:        *matchMode,
+		OCR:              *ocr,
+		Brand:            *brand,
+		Strictness:       *strictness,
+		OllamaEndpoint:   *ollamaEndpoint,
+		Similarity:       *similarity,
+		KeepDuplicates:   *keepDuplicates,
+		Origins:          *origins,
+		RetryErrorsFrom:  *retryErrorsFrom,
+		GroupBy:          *groupBy,
+		FaviconDir:       *faviconDir,
+		Mode:             *mode,
+		Browser:          *browser,
+		BrandKit:         *brandKit,
+	}
+	a.resolveLogLevel(*logLevel)
+	if a.FromSubfinder {
+		// The pipeline preset: hostnames piped in, probed for a live scheme and swept quickly since most are dead
+		if a.FilePath == "" {
+			a.FilePath = "-"
+		}
+		if strings.EqualFold(a.InputFormat, "auto") {
+			a.InputFormat = "hosts"
+		}
+		if a.Profile == "" {
+			a.Profile = "fast"
+		}
+	}
+	a.applyProfile(defaults)
+	if a.Deterministic {
+		// A single worker processes targets in input order, but download failures, hash-decided targets and
+		// deferred retries still finish out of it, so the formatted output is written in input order too, as
+		// with --ordered; streaming sinks still get results as they complete. Jitter is the only other source
+		// of randomness.
+		a.Workers = 1
+		a.DownloadWorkers = 1
+		a.InferWorkers = 1
+		a.JitterMs = 0
+		a.Ordered = true
+	}
+	if a.DownloadWorkers <= 0 {
+		a.DownloadWorkers = a.Workers
 	}
+	if a.InferWorkers <= 0 {
+		a.InferWorkers = a.Workers
+	}
+	if len(a.OpenCTIMarkings) == 0 {
+		a.OpenCTIMarkings = []string{"TLP:AMBER"}
+	}
+	return a
 }
 
-func (a *Arguments) IsValid() bool {
-	return a.BaseURL != "" && a.FilePath != "" && a.Model != ""
+// applyProfile fills in the selected profile's settings for every flag the user did not set explicitly,
+// either on the command line or in the config file. Unknown profile names are left for the caller to report.
+func (a *Arguments) applyProfile(defaults *config.File) {
+	if a.Profile == "" {
+		return
+	}
+	profile, err := config.LookupProfile(a.Profile)
+	if err != nil {
+		return
+	}
+
+	explicit := map[string]bool{
+		"workers": defaults.Workers != 0,
+		"timeout": defaults.TimeoutSeconds != 0,
+	}
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	if !explicit["workers"] {
+		a.Workers = profile.Workers
+	}
+	if !explicit["delay"] {
+		a.DelayMs = profile.DelayMs
+	}
+	if !explicit["jitter"] {
+		a.JitterMs = profile.JitterMs
+	}
+	if !explicit["retries"] {
+		a.Retries = profile.Retries
+	}
+	if !explicit["timeout"] {
+		a.TimeoutSeconds = profile.TimeoutSeconds
+	}
 }
 
 func (a *Arguments) Parse() (Arguments, error) {
-	if !a.IsValid() {
-		return Arguments{}, fmt.Errorf("invalid arguments")
+	if err := a.Validate(); err != nil {
+		return Arguments{}, err
 	}
 	// Already parsed in NewArguments(); keep for backward compatibility
 	return *a, nil
@@ -0,0 +1,84 @@
+package args
+
+import (
+	"strings"
+)
+
+// Log levels accepted by --log-level, from quietest to most verbose
+const (
+	// LogSilent only prints matched URLs
+	LogSilent = "silent"
+	// LogError adds errors
+	LogError = "error"
+	// LogInfo adds progress and the run summary
+	LogInfo = "info"
+	// LogVerbose adds warnings and library detail
+	LogVerbose = "verbose"
+	// LogDebug shows everything, including per-job detail
+	LogDebug = "debug"
+)
+
+// LogLevels lists every level accepted by --log-level
+var LogLevels = []string{LogSilent, LogError, LogInfo, LogVerbose, LogDebug}
+
+// legacyLevelFlags maps the older boolean logging flags onto log levels, in precedence order
+var legacyLevelFlags = []struct{ flag, level string }{
+	{"--silent", LogSilent},
+	{"--debug", LogDebug},
+	{"--verbose", LogVerbose},
+}
+
+// resolveLogLevel makes --log-level the one source of truth. Without it the level comes from --silent,
+// --debug or --verbose, and Silent, Debug and Verbose are then set from the resolved level.
+func (a *Arguments) resolveLogLevel(level string) {
+	set := map[string]bool{"--silent": a.Silent, "--debug": a.Debug, "--verbose": a.Verbose}
+	a.levelFlags = nil
+	for _, legacy := range legacyLevelFlags {
+		if set[legacy.flag] {
+			a.levelFlags = append(a.levelFlags, legacy.flag)
+		}
+	}
+
+	a.levelExplicit = level != ""
+	if !a.levelExplicit {
+		level = LogInfo
+		if len(a.levelFlags) > 0 {
+			level = legacyLevel(a.levelFlags[0])
+		}
+	}
+	a.LogLevel = strings.ToLower(level)
+	a.Silent = a.LogLevel == LogSilent
+	a.Debug = a.LogLevel == LogDebug
+	a.Verbose = a.LogLevel == LogVerbose
+}
+
+func legacyLevel(flag string) string {
+	for _, legacy := range legacyLevelFlags {
+		if legacy.flag == flag {
+			return legacy.level
+		}
+	}
+	return ""
+}
+
+// validateLogLevel reports an unknown level and logging flags that ask for different levels
+func (a *Arguments) validateLogLevel(v *validator) {
+	known := false
+	for _, level := range LogLevels {
+		known = known || a.LogLevel == level
+	}
+	if !known {
+		v.add(GroupValue, "unsupported log level '%s' (supported: %s)", a.LogLevel, strings.Join(LogLevels, ", "))
+	}
+
+	if len(a.levelFlags) > 1 {
+		v.add(GroupConflict, "%s can't be combined; pick one or use --log-level", strings.Join(a.levelFlags, ", "))
+	}
+	if a.levelExplicit && known {
+		for _, flag := range a.levelFlags {
+			if legacyLevel(flag) != a.LogLevel {
+				v.add(GroupConflict, "--log-level %s can't be combined with %s", a.LogLevel, flag)
+			}
+		}
+	}
+}
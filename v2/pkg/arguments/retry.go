@@ -0,0 +1,92 @@
+package args
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// inputFlags choose a run's targets or where its output goes; a retry replaces them
+var inputFlags = map[string]bool{
+	"file":              true,
+	"input-format":      true,
+	"from-subfinder":    true,
+	"urlscan-query":     true,
+	"vt-query":          true,
+	"pdns-domain":       true,
+	"permute-domain":    true,
+	"source-limit":      true,
+	"origins":           true,
+	"sample":            true,
+	"sample-n":          true,
+	"sample-seed":       true,
+	"max-targets":       true,
+	"retry-errors-from": true,
+	"o":                 true,
+	"output-chunk-size": true,
+	"stats-json":        true,
+	"cluster-dir":       true,
+}
+
+// secretFlags carry credentials, which are left out of suggested commands so they don't end up in logs and shell
+// history; their environment variables or the config file supply them instead
+var secretFlags = map[string]bool{
+	"auth":         true,
+	"cookie":       true,
+	"es-auth":      true,
+	"urlscan-key":  true,
+	"vt-key":       true,
+	"pdns-key":     true,
+	"splunk-token": true,
+}
+
+// RetryCommand returns the command that scans the failed targets in results again with the flags this run was
+// given, writing to a new file beside results. Credentials given on the command line are left out and listed
+// in omitted.
+func RetryCommand(results string) (command string, omitted []string) {
+	parts := []string{"favlens"}
+	flag.Visit(func(f *flag.Flag) {
+		switch {
+		case inputFlags[f.Name]:
+			return
+		case secretFlags[f.Name]:
+			omitted = append(omitted, "-"+f.Name)
+			return
+		}
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if on, ok := getter.Get().(bool); ok {
+				if on {
+					parts = append(parts, "-"+f.Name)
+				} else {
+					parts = append(parts, "-"+f.Name+"=false")
+				}
+				return
+			}
+		}
+		// Repeatable flags are given once per value, as they were set
+		if values, ok := f.Value.(*stringSlice); ok {
+			for _, value := range *values {
+				parts = append(parts, "-"+f.Name, shellQuote(value))
+			}
+			return
+		}
+		parts = append(parts, "-"+f.Name, shellQuote(f.Value.String()))
+	})
+	parts = append(parts, "-retry-errors-from", shellQuote(results), "-o", shellQuote(retryOutput(results)))
+	return strings.Join(parts, " "), omitted
+}
+
+// retryOutput names the output of a retry after the results it retries: results.jsonl becomes results.retry.jsonl
+func retryOutput(results string) string {
+	ext := filepath.Ext(results)
+	return fmt.Sprintf("%s.retry%s", strings.TrimSuffix(results, ext), ext)
+}
+
+// shellQuote quotes value for POSIX shells when it holds anything but safe characters
+func shellQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:,=@%+") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
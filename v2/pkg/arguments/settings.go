@@ -0,0 +1,55 @@
+package args
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// secretFields hold credentials; attestations record that they were set but never their values
+var secretFields = map[string]bool{
+	"Auth":        true,
+	"Cookies":     true,
+	"ESAuth":      true,
+	"URLScanKey":  true,
+	"VTKey":       true,
+	"PDNSKey":     true,
+	"SplunkToken": true,
+}
+
+// redacted replaces a credential that was set
+const redacted = "[redacted]"
+
+// Settings returns the effective configuration keyed by field name, with credentials redacted, so a report
+// can record exactly which settings produced it
+func (a *Arguments) Settings() map[string]any {
+	settings := map[string]any{}
+	v := reflect.ValueOf(*a)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		if secretFields[field.Name] && !value.IsZero() {
+			settings[field.Name] = redacted
+			continue
+		}
+		if d, ok := value.Interface().(time.Duration); ok {
+			settings[field.Name] = d.String()
+			continue
+		}
+		settings[field.Name] = value.Interface()
+	}
+	return settings
+}
+
+// ConfigDigest is the hex SHA-256 of Settings as canonical JSON; map keys are sorted, so equal settings always
+// hash the same
+func (a *Arguments) ConfigDigest() string {
+	data, _ := json.Marshal(a.Settings())
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,361 @@
+package args
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
+	egress "github.com/ethicalhackingplayground/favlens/v2/pkg/egress"
+	favhash "github.com/ethicalhackingplayground/favlens/v2/pkg/favhash"
+	imaging "github.com/ethicalhackingplayground/favlens/v2/pkg/imaging"
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+	notify "github.com/ethicalhackingplayground/favlens/v2/pkg/notify"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	output "github.com/ethicalhackingplayground/favlens/v2/pkg/output"
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+)
+
+// Problem groups, in the order they are reported
+const (
+	GroupMissing  = "Missing flags"
+	GroupRange    = "Out of range"
+	GroupValue    = "Invalid values"
+	GroupConflict = "Conflicting flags"
+	GroupFile     = "Unreadable files"
+)
+
+var groupOrder = []string{GroupMissing, GroupRange, GroupValue, GroupConflict, GroupFile}
+
+// Problem is one thing wrong with the arguments
+type Problem struct {
+	Group   string
+	Message string
+}
+
+// ValidationError lists every problem Validate found, so they can all be fixed in one go
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Message
+	}
+	return fmt.Sprintf("%d invalid argument(s): %s", len(e.Problems), strings.Join(messages, "; "))
+}
+
+// Grouped returns the problem messages by group, along with the groups that have problems in report order
+func (e *ValidationError) Grouped() ([]string, map[string][]string) {
+	byGroup := make(map[string][]string)
+	for _, problem := range e.Problems {
+		byGroup[problem.Group] = append(byGroup[problem.Group], problem.Message)
+	}
+	groups := make([]string, 0, len(byGroup))
+	for _, group := range groupOrder {
+		if len(byGroup[group]) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, byGroup
+}
+
+// validator collects problems as the checks run
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(group, format string, a ...any) {
+	v.problems = append(v.problems, Problem{Group: group, Message: fmt.Sprintf(format, a...)})
+}
+
+// check records err, if any, as an invalid value
+func (v *validator) check(err error) {
+	if err != nil {
+		v.add(GroupValue, "%v", err)
+	}
+}
+
+// atLeast records a flag whose value is below min
+func (v *validator) atLeast(name string, value, min int) {
+	if value < min {
+		v.add(GroupRange, "--%s must be at least %d (got %d)", name, min, value)
+	}
+}
+
+// readable records a file or directory that can't be opened
+func (v *validator) readable(name, path string) {
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		v.add(GroupFile, "--%s %s: %v", name, path, errorReason(err))
+		return
+	}
+	f.Close()
+}
+
+// flagGiven reports whether the named flag was set on the command line
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// errorReason drops the path that os errors repeat, since the flag and path are already named
+func errorReason(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
+
+// Validate checks the arguments as a whole and returns a *ValidationError listing every problem, or nil
+func (a *Arguments) Validate() error {
+	v := &validator{}
+
+	// Required flags
+	if a.BaseURL == "" && a.BrandKit == "" {
+		v.add(GroupMissing, "--base or --brand-kit is required")
+	}
+	if a.FilePath == "" && a.RetryErrorsFrom == "" && a.URLScanQuery == "" && a.VTQuery == "" && a.PDNSDomain == "" && a.PermuteDomain == "" {
+		v.add(GroupMissing, "--file, --retry-errors-from or a search source (--urlscan-query, --vt-query, --pdns-domain, --permute-domain) is required")
+	}
+	if a.Model == "" {
+		v.add(GroupMissing, "--model is required")
+	}
+	if strings.EqualFold(a.Format, output.FormatParquet) && a.Output == "" {
+		v.add(GroupMissing, "--format parquet writes a binary file and requires -o")
+	}
+
+	// Numeric ranges
+	v.atLeast("workers", a.Workers, 1)
+	v.atLeast("timeout", a.TimeoutSeconds, 1)
+	v.atLeast("cold-timeout", a.ColdTimeout, 0)
+	v.atLeast("delay", a.DelayMs, 0)
+	v.atLeast("jitter", a.JitterMs, 0)
+	v.atLeast("retries", a.Retries, 0)
+	v.atLeast("rate-limit-retries", a.RateLimitRetries, 0)
+	v.atLeast("deferred-retries", a.DeferredRetries, 0)
+	v.atLeast("breaker-threshold", a.BreakerThreshold, 0)
+	v.atLeast("max-targets", a.MaxTargets, 0)
+	v.atLeast("sample-n", a.SampleN, 0)
+	v.atLeast("output-chunk-size", a.OutputChunkSize, 0)
+	v.atLeast("permute-resolvers", a.PermuteResolvers, 1)
+	v.atLeast("tor-rotate", a.TorRotate, 0)
+	if a.JobTimeout < 0 {
+		v.add(GroupRange, "--job-timeout can't be negative (got %s)", a.JobTimeout)
+	}
+	if a.StallTimeout < 0 || (a.StallTimeout > 0 && a.StallTimeout < time.Second) {
+		v.add(GroupRange, "--stall-timeout must be 0 or at least 1s (got %s)", a.StallTimeout)
+	}
+	if a.MaxRuntime < 0 {
+		v.add(GroupRange, "--max-runtime can't be negative (got %s)", a.MaxRuntime)
+	}
+	if a.Similarity <= 0 || a.Similarity > 1 {
+		v.add(GroupRange, "--similarity must be above 0 and at most 1 (got %g)", a.Similarity)
+	}
+	if a.MinConfidence < 0 || a.MinConfidence > 100 {
+		v.add(GroupRange, "--min-confidence must be between 0 and 100 (got %d)", a.MinConfidence)
+	}
+	// Unset, the confidence is 0 and each match keeps its own score; given explicitly it must be 1-100
+	if (a.OpenCTIConf < 1 && flagGiven("opencti-confidence")) || a.OpenCTIConf < 0 || a.OpenCTIConf > 100 {
+		v.add(GroupRange, "--opencti-confidence must be between 1 and 100 (got %d)", a.OpenCTIConf)
+	}
+
+	// Enumerated and parsed values
+	v.check(input.ValidateFormat(a.InputFormat))
+	v.check(output.ValidateFormat(a.Format))
+	if a.Profile != "" {
+		_, err := config.LookupProfile(a.Profile)
+		v.check(err)
+	}
+	v.check(ollama.ValidateStrategy(a.LBStrategy))
+	v.check(ollama.ValidateMode(a.MatchMode))
+	v.check(ollama.ValidateStrictness(a.Strictness))
+	v.check(ollama.ValidateEndpoint(a.OllamaEndpoint))
+	v.check(ollama.ValidateTarget(a.Mode))
+	v.check(imaging.ValidateBackground(a.FlattenBG))
+	v.check(egress.ValidateIPVersion(a.IPVersion))
+	v.check(favhash.ValidateInput(a.HashInput))
+	if a.ESURL != "" {
+		if u, err := url.Parse(a.ESURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add(GroupValue, "--es-url must be an http(s) URL (got '%s')", a.ESURL)
+		}
+		if a.ESIndex == "" || a.ESIndex != strings.ToLower(a.ESIndex) || strings.ContainsAny(a.ESIndex, ` ,"*\/<>|?#`) {
+			v.add(GroupValue, "--es-index '%s' must be a lowercase index name without spaces or any of , \" * \\ / < > | ? #", a.ESIndex)
+		}
+	}
+	if a.SplunkURL != "" {
+		if u, err := url.Parse(a.SplunkURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add(GroupValue, "--splunk-url must be an http(s) URL (got '%s')", a.SplunkURL)
+		}
+		if a.SplunkToken == "" {
+			v.add(GroupMissing, "--splunk-url requires --splunk-token or $SPLUNK_HEC_TOKEN")
+		}
+	}
+	if a.Syslog != "" {
+		if _, _, _, err := output.ParseSyslogURL(a.Syslog); err != nil {
+			v.add(GroupValue, "--syslog: %v", err)
+		}
+	}
+	for _, pattern := range a.PrioritizeRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.add(GroupValue, "--prioritize-regex '%s': %v", pattern, err)
+		}
+	}
+	for _, rule := range a.Tags {
+		if _, err := input.ParseTagRule(rule); err != nil {
+			v.add(GroupValue, "--tag: %v", err)
+		}
+	}
+	if a.Email != nil {
+		if err := notify.CheckEmail(*a.Email); err != nil {
+			v.add(GroupValue, "config file %v", err)
+		}
+	}
+	v.check(output.ValidateGroupBy(a.GroupBy, a.Format))
+	if a.Sample != "" {
+		if _, err := input.ParseSampleFraction(a.Sample); err != nil {
+			v.add(GroupValue, "--sample: %v", err)
+		}
+	}
+	if a.MaxBandwidth != "" {
+		if _, err := egress.ParseBandwidth(a.MaxBandwidth); err != nil {
+			v.add(GroupValue, "--max-bandwidth: %v", err)
+		}
+	}
+	if a.KeepRuns != "" {
+		if _, err := store.ParseRetention(a.KeepRuns); err != nil {
+			v.add(GroupValue, "--keep-runs: %v", err)
+		}
+	}
+	for _, marking := range a.OpenCTIMarkings {
+		if err := output.ValidateMarking(marking); err != nil {
+			v.add(GroupValue, "--opencti-marking: %v", err)
+		}
+	}
+	for _, rule := range a.NotifyRules {
+		if _, err := notify.ParseRule(rule); err != nil {
+			v.add(GroupValue, "--notify-rule: %v", err)
+		}
+	}
+
+	// Flags that can't be used together
+	a.validateLogLevel(v)
+	if a.Tor && (len(a.SourceIPs) > 0 || len(a.Interfaces) > 0) {
+		v.add(GroupConflict, "--tor can't be combined with --source-ip or --interface")
+	}
+	if a.Tor && a.IPVersion != egress.IPVersionAuto {
+		v.add(GroupConflict, "--ip-version can't be combined with --tor, whose exit node resolves and connects to targets")
+	}
+	if a.Tor && a.WildcardFilter != "" {
+		v.add(GroupConflict, "--wildcard-filter resolves targets locally and can't be combined with --tor")
+	}
+	if strings.EqualFold(a.Mode, ollama.TargetScreenshot) {
+		if len(a.SourceIPs) > 0 || len(a.Interfaces) > 0 {
+			v.add(GroupConflict, "--mode screenshot loads pages in a browser that can't be bound to --source-ip or --interface")
+		}
+		if strings.EqualFold(a.MatchMode, ollama.ModeContains) {
+			v.add(GroupConflict, "--match-mode contains only applies to favicons, not --mode screenshot")
+		}
+	}
+	if strings.EqualFold(a.OllamaEndpoint, ollama.EndpointEmbed) {
+		// Embeddings compare whole images; they can't answer a question or read text
+		if strings.EqualFold(a.Mode, ollama.TargetScreenshot) || strings.EqualFold(a.MatchMode, ollama.ModeContains) || a.OCR {
+			v.add(GroupConflict, "--ollama-endpoint embed compares favicons as whole images and can't be combined with --mode screenshot, --match-mode contains or --ocr")
+		}
+	}
+	// --deterministic implies --ordered, which only reorders the formatted output; streaming sinks would still
+	// receive results in completion order, so their output couldn't be reproduced
+	if a.Deterministic && (a.ESURL != "" || a.SplunkURL != "" || a.Syslog != "") {
+		v.add(GroupConflict, "--deterministic writes the formatted output in input order (as --ordered), but --es-url, --splunk-url and --syslog stream results as they complete and can't be reproduced; drop them or --deterministic")
+	}
+	if a.AbortOnStall && a.StallTimeout == 0 {
+		v.add(GroupConflict, "--abort-on-stall needs a --stall-timeout above 0")
+	}
+	if a.Corpus != "" && !strings.EqualFold(a.OllamaEndpoint, ollama.EndpointEmbed) {
+		v.add(GroupConflict, "--corpus indexes image embeddings and requires --ollama-endpoint embed")
+	}
+	if a.BaseURL != "" && a.BrandKit != "" {
+		v.add(GroupConflict, "--base and --brand-kit both set the reference; pick one")
+	}
+	if len(a.EncryptOutput) > 0 && a.Output == "" {
+		v.add(GroupConflict, "--encrypt-output encrypts the -o file and requires -o")
+	}
+	if a.SignOutput != "" && a.Output == "" {
+		v.add(GroupConflict, "--sign-output signs the -o file and requires -o")
+	}
+	if a.Attest != "" && a.Output == "" {
+		v.add(GroupConflict, "--attest describes the -o file and requires -o")
+	}
+	if a.Attest != "" && a.Attest == a.Output {
+		v.add(GroupConflict, "--attest must be written to a different path than -o")
+	}
+	if a.OutputChunkSize > 0 {
+		if a.Output == "" {
+			v.add(GroupConflict, "--output-chunk-size splits the -o file and requires -o")
+		}
+		if a.SignOutput != "" || len(a.EncryptOutput) > 0 || a.Attest != "" {
+			v.add(GroupConflict, "--output-chunk-size writes several files and can't be combined with --sign-output, --encrypt-output or --attest")
+		}
+		if a.GroupBy != "" {
+			v.add(GroupConflict, "--output-chunk-size can't be combined with --group-by, which writes one entry per domain once the run ends")
+		}
+	}
+	if a.RecordRun && a.Cache == "" {
+		v.add(GroupConflict, "--record-run stores runs in the --cache store and requires --cache")
+	}
+	if a.KeepRuns != "" && !a.RecordRun {
+		v.add(GroupConflict, "--keep-runs prunes recorded runs and requires --record-run")
+	}
+	if a.Sample != "" && a.SampleN > 0 {
+		v.add(GroupConflict, "--sample and --sample-n both size the sample; pick one")
+	}
+	if a.Origins && a.FaviconDir {
+		v.add(GroupConflict, "--origins reduces targets to their origin root and can't be combined with --favicon-dir")
+	}
+	if a.FromSubfinder && !strings.EqualFold(a.InputFormat, input.FormatHosts) {
+		v.add(GroupConflict, "--from-subfinder reads hostnames and can't be combined with --input-format %s", a.InputFormat)
+	}
+	if a.ErrorsFile != "" && (a.ErrorsFile == a.Output || a.ErrorsFile == a.FilePath) {
+		v.add(GroupConflict, "--errors-file must be a different file than -o and --file")
+	}
+	if a.StatsJSON != "" && (a.StatsJSON == a.Output || a.StatsJSON == a.FilePath || a.StatsJSON == a.ErrorsFile) {
+		v.add(GroupConflict, "--stats-json must be a different file than -o, --file and --errors-file")
+	}
+	if a.RetryErrorsFrom != "" && (a.RetryErrorsFrom == a.Output || a.RetryErrorsFrom == a.FilePath) {
+		v.add(GroupConflict, "--retry-errors-from must be a different file than -o and --file")
+	}
+	if a.ClientKey != "" && a.ClientCert == "" {
+		v.add(GroupConflict, "--client-key requires --client-cert")
+	}
+
+	// Files read at startup
+	if a.FilePath != "-" {
+		v.readable("file", a.FilePath)
+	}
+	v.readable("retry-errors-from", a.RetryErrorsFrom)
+	v.readable("fingerprints", a.Fingerprints)
+	v.readable("brand-kit", a.BrandKit)
+	v.readable("auth-file", a.AuthFile)
+	v.readable("cookie-file", a.CookieFile)
+	v.readable("client-cert", a.ClientCert)
+	v.readable("client-key", a.ClientKey)
+	v.readable("sign-output", a.SignOutput)
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
@@ -0,0 +1,149 @@
+// Package attest records which scope and settings produced a result set: digests of the configuration and
+// input list for the run summary, and an in-toto statement with a SLSA provenance predicate over the output file.
+package attest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"time"
+)
+
+// Statement and predicate types written by Write
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	BuildType     = "https://github.com/ethicalhackingplayground/favlens/scan/v1"
+	BuilderID     = "https://github.com/ethicalhackingplayground/favlens"
+)
+
+// Input digests the scan's targets in the order they were read, so two runs over the same list can be told
+// apart from runs over a different one
+type Input struct {
+	h     hash.Hash
+	count int
+}
+
+func NewInput() *Input {
+	return &Input{h: sha256.New()}
+}
+
+// Add records one target and its per-target base favicon, if any
+func (i *Input) Add(url, base string) {
+	io.WriteString(i.h, url+"\t"+base+"\n")
+	i.count++
+}
+
+// Sum is the hex SHA-256 of every target added so far
+func (i *Input) Sum() string {
+	return hex.EncodeToString(i.h.Sum(nil))
+}
+
+// Count is the number of targets added
+func (i *Input) Count() int {
+	return i.count
+}
+
+// Digest passes output through to an underlying writer while taking its SHA-256, which becomes the
+// attestation's subject digest
+type Digest struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func NewDigest(w io.Writer) *Digest {
+	return &Digest{w: w, h: sha256.New()}
+}
+
+// Write writes p and hashes the part of it that was written
+func (d *Digest) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.h.Write(p[:n])
+	return n, err
+}
+
+// Sum is the hex SHA-256 of everything written so far
+func (d *Digest) Sum() string {
+	return hex.EncodeToString(d.h.Sum(nil))
+}
+
+// Provenance describes one scan
+type Provenance struct {
+	// Output names the attested result file and OutputDigest is its SHA-256
+	Output       string
+	OutputDigest string
+	// Settings are the redacted effective settings and ConfigDigest their SHA-256
+	Settings     map[string]any
+	ConfigDigest string
+	Input        *Input
+	Model        string
+	Version      string
+	Started      time.Time
+	Finished     time.Time
+}
+
+type statement struct {
+	Type          string        `json:"_type"`
+	Subject       []subject     `json:"subject"`
+	PredicateType string        `json:"predicateType"`
+	Predicate     slsaPredicate `json:"predicate"`
+}
+
+type subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaPredicate struct {
+	BuildDefinition struct {
+		BuildType            string           `json:"buildType"`
+		ExternalParameters   map[string]any   `json:"externalParameters"`
+		ResolvedDependencies []resourceDigest `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID      string            `json:"id"`
+			Version map[string]string `json:"version"`
+		} `json:"builder"`
+		Metadata struct {
+			StartedOn  string `json:"startedOn"`
+			FinishedOn string `json:"finishedOn"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+type resourceDigest struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Write encodes p as an indented in-toto statement
+func Write(w io.Writer, p Provenance) error {
+	st := statement{
+		Type:          StatementType,
+		Subject:       []subject{{Name: p.Output, Digest: map[string]string{"sha256": p.OutputDigest}}},
+		PredicateType: PredicateType,
+	}
+	build := &st.Predicate.BuildDefinition
+	build.BuildType = BuildType
+	build.ExternalParameters = map[string]any{
+		"settings":     p.Settings,
+		"configDigest": map[string]string{"sha256": p.ConfigDigest},
+		"input": map[string]any{
+			"targets": p.Input.Count(),
+			"digest":  map[string]string{"sha256": p.Input.Sum()},
+		},
+	}
+	build.ResolvedDependencies = []resourceDigest{{Name: "model:" + p.Model}}
+	run := &st.Predicate.RunDetails
+	run.Builder.ID = BuilderID
+	run.Builder.Version = map[string]string{"favlens": p.Version}
+	run.Metadata.StartedOn = p.Started.UTC().Format(time.RFC3339)
+	run.Metadata.FinishedOn = p.Finished.UTC().Format(time.RFC3339)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(st)
+}
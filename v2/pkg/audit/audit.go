@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kinds of request recorded in the audit log
+const (
+	KindIcon   = "icon"
+	KindRobots = "robots"
+	KindPage   = "page"
+	KindOllama = "ollama"
+	KindProbe  = "probe"
+	// KindScreenshot is a page loaded by the headless browser; status and addresses aren't known for it
+	KindScreenshot = "screenshot"
+)
+
+// Entry records a single network request
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status,omitempty"`
+	// Bytes is the size of the response body
+	Bytes int `json:"bytes"`
+	// SourceIP is the local address the request left from
+	SourceIP   string `json:"source_ip,omitempty"`
+	RemoteIP   string `json:"remote_ip,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Log appends entries to a JSON lines file; a nil *Log records nothing
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// Open creates or appends to the audit log at path, so several runs of one engagement can share a file
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &Log{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Record writes entry, filling in the duration from start; write errors are returned but never fatal to a scan
+func (l *Log) Record(entry Entry, start time.Time, err error) error {
+	if l == nil {
+		return nil
+	}
+	entry.Timestamp = start.UTC()
+	entry.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(entry)
+}
+
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// IP returns the host part of a connection address, or "" when it is unknown
+func IP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Supported authentication schemes
+const (
+	TypeBasic  = "basic"
+	TypeBearer = "bearer"
+	TypeNTLM   = "ntlm"
+)
+
+// Types lists every scheme accepted by ParseCredential
+var Types = []string{TypeBasic, TypeBearer, TypeNTLM}
+
+// Credential authenticates icon downloads from one or more hosts
+type Credential struct {
+	Type string
+	// Username and Password are used by basic and NTLM; NTLM usernames may carry a domain as DOMAIN\user
+	Username string
+	Password string
+	// Token is used by bearer
+	Token string
+}
+
+// ParseCredential builds a credential from "user:pass" (basic, ntlm) or a token (bearer)
+func ParseCredential(authType, value string) (*Credential, error) {
+	authType = strings.ToLower(strings.TrimSpace(authType))
+	switch authType {
+	case TypeBasic, TypeNTLM:
+		username, password, ok := strings.Cut(value, ":")
+		if !ok || username == "" {
+			return nil, fmt.Errorf("%s credentials must look like user:pass", authType)
+		}
+		return &Credential{Type: authType, Username: username, Password: password}, nil
+	case TypeBearer:
+		if value == "" {
+			return nil, fmt.Errorf("bearer credentials need a token")
+		}
+		return &Credential{Type: authType, Token: value}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type '%s' (supported: %s)", authType, strings.Join(Types, ", "))
+	}
+}
+
+// Header returns the Authorization header value for basic and bearer credentials; NTLM needs a handshake instead
+func (c *Credential) Header() string {
+	switch c.Type {
+	case TypeBasic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+	case TypeBearer:
+		return "Bearer " + c.Token
+	default:
+		return ""
+	}
+}
+
+// hostCredential binds a credential to a host pattern
+type hostCredential struct {
+	// pattern is a host, host:port, or a "*.example.com" wildcard
+	pattern    string
+	credential *Credential
+}
+
+// Store picks the credential for a URL: the first matching per-host entry, otherwise the default
+type Store struct {
+	Default *Credential
+	hosts   []hostCredential
+}
+
+// Add binds credential to a host, host:port, or "*.example.com" wildcard
+func (s *Store) Add(pattern string, credential *Credential) {
+	s.hosts = append(s.hosts, hostCredential{pattern: strings.ToLower(pattern), credential: credential})
+}
+
+// Empty reports whether the store holds no credentials at all
+func (s *Store) Empty() bool {
+	return s == nil || (s.Default == nil && len(s.hosts) == 0)
+}
+
+// Lookup returns the credential for rawURL, or nil when it should be fetched anonymously
+func (s *Store) Lookup(rawURL string) *Credential {
+	if s.Empty() {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return s.Default
+	}
+	host := strings.ToLower(u.Host)
+	hostname := strings.ToLower(u.Hostname())
+	for _, entry := range s.hosts {
+		if entry.pattern == host || entry.pattern == hostname {
+			return entry.credential
+		}
+		if suffix, ok := strings.CutPrefix(entry.pattern, "*"); ok && strings.HasSuffix(hostname, suffix) {
+			return entry.credential
+		}
+	}
+	return s.Default
+}
+
+// LoadFile reads per-host credentials, one "<host> <type> <credentials>" entry per line.
+// Blank lines and lines starting with # are ignored.
+func (s *Store) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return fmt.Errorf("%s:%d: expected '<host> <type> <credentials>'", path, line)
+		}
+		// Passwords may contain spaces, so everything after the type is the credential
+		rest := strings.TrimSpace(text[len(fields[0]):])
+		value := strings.TrimSpace(rest[len(fields[1]):])
+		credential, err := ParseCredential(fields[1], value)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		s.Add(fields[0], credential)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookieJar supplies cookies for icon downloads so assets gated behind a session can be fetched.
+// Fixed cookies go to every host, cookies from a cookies.txt file go to their own domains, and with
+// session tracking enabled cookies set by responses are replayed to the same host.
+type CookieJar struct {
+	fixed   []*http.Cookie
+	jar     *cookiejar.Jar
+	session bool
+}
+
+// NewCookieJar returns an empty jar; session enables remembering Set-Cookie responses per host
+func NewCookieJar(session bool) *CookieJar {
+	// cookiejar.New only fails on invalid options
+	jar, _ := cookiejar.New(nil)
+	return &CookieJar{jar: jar, session: session}
+}
+
+// AddFixed parses a Cookie header value such as "sid=abc; theme=dark" and sends it to every host
+func (c *CookieJar) AddFixed(header string) error {
+	cookies, err := http.ParseCookie(header)
+	if err != nil {
+		return fmt.Errorf("invalid cookie '%s': %v", header, err)
+	}
+	c.fixed = append(c.fixed, cookies...)
+	return nil
+}
+
+// LoadFile imports a Netscape/curl cookies.txt file, as exported by browsers and `curl -c`
+func (c *CookieJar) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		// curl marks HttpOnly cookies with a prefix on an otherwise commented-looking line
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("%s:%d: expected 7 tab-separated fields", path, line)
+		}
+		domain, includeSubdomains, cookiePath, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+		cookie := &http.Cookie{Name: name, Value: value, Path: cookiePath, Secure: strings.EqualFold(secure, "TRUE")}
+		if strings.EqualFold(includeSubdomains, "TRUE") {
+			cookie.Domain = domain
+		}
+		if seconds, err := strconv.ParseInt(expires, 10, 64); err == nil && seconds > 0 {
+			cookie.Expires = time.Unix(seconds, 0)
+		}
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		c.jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: cookiePath}, []*http.Cookie{cookie})
+	}
+	return scanner.Err()
+}
+
+// Header returns the Cookie header value for rawURL, or "" when there is nothing to send
+func (c *CookieJar) Header(rawURL string) string {
+	if c == nil {
+		return ""
+	}
+	cookies := c.fixed
+	if u, err := url.Parse(rawURL); err == nil {
+		cookies = append(append([]*http.Cookie{}, c.fixed...), c.jar.Cookies(u)...)
+	}
+	parts := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		parts = append(parts, cookie.Name+"="+cookie.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Learn stores the Set-Cookie values a response for rawURL returned, when session tracking is enabled
+func (c *CookieJar) Learn(rawURL string, setCookies []string) {
+	if c == nil || !c.session || len(setCookies) == 0 {
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	cookies := make([]*http.Cookie, 0, len(setCookies))
+	for _, line := range setCookies {
+		if cookie, err := http.ParseSetCookie(line); err == nil {
+			cookies = append(cookies, cookie)
+		}
+	}
+	c.jar.SetCookies(u, cookies)
+}
@@ -0,0 +1,97 @@
+// Package brandkit loads brand asset kits: a directory of logo variants plus a brand.yaml naming the brand and
+// its colors, so targets can be compared against every variant a brand-protection team tracks.
+package brandkit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DescriptorFile is the name of the YAML file describing a kit's brand
+const DescriptorFile = "brand.yaml"
+
+// assetExtensions are the image files picked up from a kit directory when brand.yaml doesn't list its assets
+var assetExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true, ".webp": true, ".bmp": true}
+
+// Kit is a brand's reference material: logo variants plus what the brand is called and looks like
+type Kit struct {
+	Dir         string
+	Name        string   `yaml:"name"`
+	Aliases     []string `yaml:"aliases"`
+	Colors      []string `yaml:"colors"`
+	Domains     []string `yaml:"domains"`
+	Description string   `yaml:"description"`
+	// Files lists the kit's logo files relative to Dir; empty means every image in Dir
+	Files  []string `yaml:"assets"`
+	Assets []Asset  `yaml:"-"`
+}
+
+// Asset is one logo variant in a kit, e.g. the full-colour mark or the monochrome wordmark
+type Asset struct {
+	// Name is the file name, reported as the asset a target matched
+	Name string
+	Path string
+}
+
+// Load reads the kit in dir. brand.yaml is optional, but the kit must contain at least one logo.
+func Load(dir string) (*Kit, error) {
+	kit := &Kit{}
+	data, err := os.ReadFile(filepath.Join(dir, DescriptorFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, kit); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filepath.Join(dir, DescriptorFile), err)
+		}
+	}
+	kit.Dir = dir
+
+	files := kit.Files
+	if len(files) == 0 {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && assetExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				files = append(files, entry.Name())
+			}
+		}
+		sort.Strings(files)
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("brand kit asset %s: %v", file, err)
+		}
+		kit.Assets = append(kit.Assets, Asset{Name: file, Path: path})
+	}
+	if len(kit.Assets) == 0 {
+		return nil, fmt.Errorf("brand kit %s has no logo images", dir)
+	}
+	return kit, nil
+}
+
+// Describe returns what the model should know about the brand beyond its name, or "" when brand.yaml says nothing more
+func (k *Kit) Describe() string {
+	var details []string
+	if len(k.Aliases) > 0 {
+		details = append(details, fmt.Sprintf("It is also known as %s.", strings.Join(k.Aliases, ", ")))
+	}
+	if len(k.Colors) > 0 {
+		details = append(details, fmt.Sprintf("Its brand colors are %s.", strings.Join(k.Colors, ", ")))
+	}
+	if description := strings.TrimSpace(k.Description); description != "" {
+		if !strings.HasSuffix(description, ".") {
+			description += "."
+		}
+		details = append(details, description)
+	}
+	return strings.Join(details, " ")
+}
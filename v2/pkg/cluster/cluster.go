@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MappingFile is the name of the mapping written beside the representative icons
+const MappingFile = "clusters.json"
+
+// maxMembers caps the target URLs listed per cluster; Size still counts them all
+const maxMembers = 100
+
+// Cluster is one equivalence class: every target whose icon has the same bytes
+type Cluster struct {
+	ID string `json:"id"`
+	// Representative is the PNG, as sent to the model, of the first icon seen in the class
+	Representative string `json:"representative"`
+	SHA256         string `json:"sha256"`
+	MMH3           string `json:"mmh3,omitempty"`
+	Size           int    `json:"size"`
+	Matches        int    `json:"matches"`
+	// Label is left empty for the analyst to fill in, e.g. "brand", "parking page" or "default cms icon"
+	Label   string   `json:"label"`
+	Members []string `json:"members"`
+}
+
+// Collector puts target icons into equivalence classes as they are compared and writes one representative PNG
+// per class to its directory straight away, so icons don't pile up in memory. The mapping is written by Close.
+// It is safe for concurrent use.
+type Collector struct {
+	dir string
+
+	mu       sync.Mutex
+	clusters map[string]*Cluster
+	err      error
+}
+
+// NewCollector writes to dir, creating it when needed
+func NewCollector(dir string) (*Collector, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cluster directory %s: %v", dir, err)
+	}
+	return &Collector{dir: dir, clusters: make(map[string]*Cluster)}, nil
+}
+
+// Add records a target whose icon hashed to sha256 and mmh3; png is the icon as a base64 PNG, only written
+// when it starts a new class
+func (c *Collector) Add(url, sha256, mmh3, png string, match bool) {
+	if c == nil || sha256 == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster, ok := c.clusters[sha256]
+	if !ok {
+		id := fmt.Sprintf("c%04d", len(c.clusters)+1)
+		cluster = &Cluster{ID: id, Representative: id + ".png", SHA256: sha256, MMH3: mmh3}
+		c.clusters[sha256] = cluster
+		if err := c.writePNG(cluster.Representative, png); err != nil && c.err == nil {
+			c.err = err
+		}
+	}
+	cluster.Size++
+	if match {
+		cluster.Matches++
+	}
+	if len(cluster.Members) < maxMembers {
+		cluster.Members = append(cluster.Members, url)
+	}
+}
+
+func (c *Collector) writePNG(name, png string) error {
+	data, err := base64.StdEncoding.DecodeString(png)
+	if err != nil {
+		return fmt.Errorf("failed to decode representative icon %s: %v", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write representative icon: %v", err)
+	}
+	return nil
+}
+
+// Clusters returns the classes found so far, largest first
+func (c *Collector) Clusters() []Cluster {
+	c.mu.Lock()
+	clusters := make([]Cluster, 0, len(c.clusters))
+	for _, cluster := range c.clusters {
+		clusters = append(clusters, *cluster)
+	}
+	c.mu.Unlock()
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Size != clusters[j].Size {
+			return clusters[i].Size > clusters[j].Size
+		}
+		return clusters[i].ID < clusters[j].ID
+	})
+	return clusters
+}
+
+// Path returns where the mapping is written
+func (c *Collector) Path() string {
+	return filepath.Join(c.dir, MappingFile)
+}
+
+// Close writes the mapping, largest class first, and reports the first icon that couldn't be written
+func (c *Collector) Close() error {
+	data, err := json.MarshalIndent(struct {
+		Clusters []Cluster `json:"clusters"`
+	}{c.Clusters()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Path(), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cluster mapping: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Labels are the labels given to clusters in a mapping file, looked up by icon hash so later scans can tag
+// targets with a known icon
+type Labels struct {
+	bySHA256 map[string]string
+	byMMH3   map[string]string
+}
+
+// LoadLabels reads a mapping written by Collector, keeping the clusters that were given a label
+func LoadLabels(path string) (*Labels, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mapping struct {
+		Clusters []Cluster `json:"clusters"`
+	}
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("invalid cluster mapping %s: %v", path, err)
+	}
+	labels := &Labels{bySHA256: make(map[string]string), byMMH3: make(map[string]string)}
+	for _, cluster := range mapping.Clusters {
+		if cluster.Label == "" {
+			continue
+		}
+		if cluster.SHA256 != "" {
+			labels.bySHA256[cluster.SHA256] = cluster.Label
+		}
+		if cluster.MMH3 != "" {
+			labels.byMMH3[cluster.MMH3] = cluster.Label
+		}
+	}
+	return labels, nil
+}
+
+// Len returns the number of labelled icons
+func (l *Labels) Len() int {
+	return len(l.bySHA256)
+}
+
+// Label returns the label of the icon with these hashes, or "" when it has none. The sha256 is preferred; the
+// mmh3 lets targets decided by their httpx hash, which have no sha256, be labelled too.
+func (l *Labels) Label(sha256, mmh3 string) string {
+	if l == nil {
+		return ""
+	}
+	if label, ok := l.bySHA256[sha256]; ok && sha256 != "" {
+		return label
+	}
+	if mmh3 == "" {
+		return ""
+	}
+	return l.byMMH3[mmh3]
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File holds user defaults loaded from config.yaml; zero values mean "not set"
+type File struct {
+	OllamaHost     string `yaml:"ollama_host,omitempty"`
+	Model          string `yaml:"model,omitempty"`
+	Workers        int    `yaml:"workers,omitempty"`
+	Format         string `yaml:"format,omitempty"`
+	TimeoutSeconds int    `yaml:"timeout,omitempty"`
+	Profile        string `yaml:"profile,omitempty"`
+	// MinConfidence is the lowest confidence a match may score, usually learned by `favlens review thresholds --save`
+	MinConfidence int    `yaml:"min_confidence,omitempty"`
+	Email         *Email `yaml:"email,omitempty"`
+}
+
+// Email configures SMTP notifications; they are sent whenever To is set
+type Email struct {
+	Host string `yaml:"smtp_host"`
+	// Port defaults to 587 with STARTTLS; 465 uses implicit TLS
+	Port     int    `yaml:"smtp_port,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	// Password falls back to $FAVLENS_SMTP_PASSWORD; it is never recorded in attestations
+	Password string   `yaml:"password,omitempty" json:"-"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	// Mode is "summary" for one digest at the end of the run with the HTML report attached, or "immediate"
+	// for an email per match as it is found
+	Mode string `yaml:"mode,omitempty"`
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/favlens/config.yaml, falling back to ~/.config/favlens/config.yaml
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "favlens", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "favlens", "config.yaml"), nil
+}
+
+// Load reads a config file; a missing file is not an error and yields empty defaults
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return &file, nil
+}
+
+// Save writes the config file, creating its directory when needed
+func Save(path string, file *File) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	header := []byte("# favlens defaults, written by `favlens init`. Command-line flags override these values.\n")
+	return os.WriteFile(path, append(header, data...), 0o644)
+}
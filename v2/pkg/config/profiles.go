@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a curated bundle of scan settings selected with --profile.
+// Any flag given explicitly on the command line overrides the profile value.
+type Profile struct {
+	Description    string
+	Workers        int
+	DelayMs        int
+	JitterMs       int
+	Retries        int
+	TimeoutSeconds int
+}
+
+// Profiles holds the built-in presets
+var Profiles = map[string]Profile{
+	"stealth": {
+		Description:    "Low and slow: few workers with randomised delays to stay under rate limits and WAF thresholds",
+		Workers:        2,
+		DelayMs:        1500,
+		JitterMs:       1000,
+		Retries:        1,
+		TimeoutSeconds: 45,
+	},
+	"fast": {
+		Description:    "High concurrency with no delays and short timeouts for quick sweeps of large lists",
+		Workers:        20,
+		DelayMs:        0,
+		JitterMs:       0,
+		Retries:        0,
+		TimeoutSeconds: 15,
+	},
+	"thorough": {
+		Description:    "Moderate concurrency with retries and generous timeouts to minimise missed targets",
+		Workers:        5,
+		DelayMs:        250,
+		JitterMs:       250,
+		Retries:        3,
+		TimeoutSeconds: 60,
+	},
+}
+
+// ProfileNames returns the built-in profile names in sorted order
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProfile returns the named profile
+func LookupProfile(name string) (Profile, error) {
+	profile, ok := Profiles[strings.ToLower(name)]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile '%s' (available: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return profile, nil
+}
@@ -0,0 +1,365 @@
+// Package corpus keeps the image embeddings of every distinct icon seen by past scans in an on-disk HNSW index,
+// so an icon can be looked up against all of them in milliseconds.
+package corpus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// fileMagic starts every corpus file, followed by the format version
+const fileMagic = "favlens-corpus\n"
+
+const fileVersion = 1
+
+// maxURLs caps the URLs kept per icon, most recent first; Seen still counts them all
+const maxURLs = 20
+
+// Icon is one distinct icon in the corpus, identified by its sha256
+type Icon struct {
+	SHA256    string    `json:"sha256"`
+	MMH3      string    `json:"mmh3,omitempty"`
+	URLs      []string  `json:"urls"`
+	Seen      int       `json:"seen"`
+	Matches   int       `json:"matches"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Hit is an icon found by Search with the cosine similarity of its embedding to the query
+type Hit struct {
+	Icon
+	Similarity float64 `json:"similarity"`
+}
+
+// header describes the whole file; it is written before the nodes
+type header struct {
+	Model          string `json:"model"`
+	Dimensions     int    `json:"dimensions"`
+	M              int    `json:"m"`
+	EfConstruction int    `json:"ef_construction"`
+	Entry          int32  `json:"entry"`
+	Top            int    `json:"top"`
+	Count          int    `json:"count"`
+}
+
+// Corpus is an HNSW index of icon embeddings, all made by one model, held in memory while in use and written
+// back by Save. Scans add to it and `favlens search` queries it. It is safe for concurrent use, but only one
+// process should write a corpus file at a time.
+type Corpus struct {
+	path string
+
+	mu         sync.Mutex
+	model      string
+	dimensions int
+	graph      *graph
+	icons      []Icon
+	bySHA256   map[string]uint32
+	added      int
+}
+
+// Open loads the corpus at path; a missing file is not an error and yields an empty corpus saved there
+func Open(path string) (*Corpus, error) {
+	c := &Corpus{path: path, graph: newGraph(), bySHA256: make(map[string]uint32)}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := c.read(bufio.NewReader(file)); err != nil {
+		return nil, fmt.Errorf("invalid corpus %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Model returns the embedding model the corpus was built with, "" while it is empty
+func (c *Corpus) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
+
+// SetModel records the embedding model of a new corpus; a corpus that already has one keeps it, so callers
+// check Model first
+func (c *Corpus) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.model == "" {
+		c.model = model
+	}
+}
+
+// Len returns the number of distinct icons
+func (c *Corpus) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.icons)
+}
+
+// Added returns the number of icons added since the corpus was opened
+func (c *Corpus) Added() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.added
+}
+
+// Add records that url served the icon with these hashes and embedding. An icon already in the corpus only
+// has its sightings updated, so the index grows with the number of distinct icons rather than targets.
+func (c *Corpus) Add(url, sha256, mmh3 string, match bool, embedding []float64) error {
+	if c == nil || sha256 == "" {
+		return nil
+	}
+	now := time.Now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, ok := c.bySHA256[sha256]
+	if !ok {
+		vector, err := c.normalize(embedding)
+		if err != nil {
+			return err
+		}
+		id = c.graph.insert(vector, c.graph.drawLevel(sha256))
+		c.icons = append(c.icons, Icon{SHA256: sha256, MMH3: mmh3, FirstSeen: now})
+		c.bySHA256[sha256] = id
+		c.added++
+	}
+	icon := &c.icons[id]
+	icon.Seen++
+	if match {
+		icon.Matches++
+	}
+	icon.LastSeen = now
+	if icon.MMH3 == "" {
+		icon.MMH3 = mmh3
+	}
+	icon.URLs = slices.DeleteFunc(icon.URLs, func(u string) bool { return u == url })
+	icon.URLs = append([]string{url}, icon.URLs[:min(len(icon.URLs), maxURLs-1)]...)
+	return nil
+}
+
+// normalize scales embedding to unit length, so the graph can compare vectors by dot product; the caller
+// holds the lock
+func (c *Corpus) normalize(embedding []float64) ([]float32, error) {
+	if c.dimensions == 0 {
+		c.dimensions = len(embedding)
+	}
+	if len(embedding) != c.dimensions {
+		return nil, fmt.Errorf("embedding has %d dimensions but corpus %s holds %d", len(embedding), c.path, c.dimensions)
+	}
+	var norm float64
+	for _, v := range embedding {
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil, errors.New("embedding is all zeros")
+	}
+	norm = math.Sqrt(norm)
+	vector := make([]float32, len(embedding))
+	for i, v := range embedding {
+		vector[i] = float32(v / norm)
+	}
+	return vector, nil
+}
+
+// Search returns up to k icons whose embeddings are most similar to embedding, most similar first
+func (c *Corpus) Search(embedding []float64, k int) ([]Hit, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.icons) == 0 {
+		return nil, nil
+	}
+	query, err := c.normalize(embedding)
+	if err != nil {
+		return nil, err
+	}
+	found := c.graph.search(query, k)
+	hits := make([]Hit, len(found))
+	for i, f := range found {
+		icon := c.icons[f.id]
+		icon.URLs = slices.Clone(icon.URLs)
+		hits[i] = Hit{Icon: icon, Similarity: math.Round(float64(1-f.dist)*10000) / 10000}
+	}
+	return hits, nil
+}
+
+// Path returns where the corpus is saved
+func (c *Corpus) Path() string {
+	return c.path
+}
+
+// Save writes the corpus to its path. The file is written beside it and renamed over it, so a search running
+// at the same time never reads a partial index.
+func (c *Corpus) Save() error {
+	if dir := filepath.Dir(c.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "."+filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	c.mu.Lock()
+	err = c.write(w)
+	c.mu.Unlock()
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write corpus %s: %v", c.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// write encodes the corpus: the magic, version and a JSON header, then for every node its icon as JSON, its
+// vector and its neighbours per layer, all little endian. The caller holds the lock.
+func (c *Corpus) write(w io.Writer) error {
+	h := header{Model: c.model, Dimensions: c.dimensions, M: c.graph.m, EfConstruction: c.graph.efConstruction, Entry: c.graph.entry, Top: c.graph.top, Count: len(c.icons)}
+	if _, err := io.WriteString(w, fileMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(fileVersion)); err != nil {
+		return err
+	}
+	if err := writeJSON(w, h); err != nil {
+		return err
+	}
+	for i, n := range c.graph.nodes {
+		if err := writeJSON(w, c.icons[i]); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, n.vector); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(n.friends))); err != nil {
+			return err
+		}
+		for _, friends := range n.friends {
+			if err := binary.Write(w, binary.LittleEndian, uint32(len(friends))); err != nil {
+				return err
+			}
+			if err := binary.Write(w, binary.LittleEndian, friends); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// read decodes a corpus written by write
+func (c *Corpus) read(r io.Reader) error {
+	magic := make([]byte, len(fileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != fileMagic {
+		return errors.New("not a favlens corpus")
+	}
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return err
+	}
+	if version != fileVersion {
+		return fmt.Errorf("unsupported corpus version %d", version)
+	}
+	var h header
+	if err := readJSON(r, &h); err != nil {
+		return err
+	}
+	if h.Count < 0 || h.Dimensions < 0 || h.M < 2 || (h.Count > 0 && (h.Entry < 0 || int(h.Entry) >= h.Count)) {
+		return errors.New("corrupt header")
+	}
+	c.model, c.dimensions = h.Model, h.Dimensions
+	c.graph = &graph{m: h.M, efConstruction: h.EfConstruction, entry: h.Entry, top: h.Top, nodes: make([]node, h.Count)}
+	c.icons = make([]Icon, h.Count)
+	for i := range h.Count {
+		if err := readJSON(r, &c.icons[i]); err != nil {
+			return err
+		}
+		c.bySHA256[c.icons[i].SHA256] = uint32(i)
+		n := &c.graph.nodes[i]
+		n.vector = make([]float32, h.Dimensions)
+		if err := binary.Read(r, binary.LittleEndian, n.vector); err != nil {
+			return err
+		}
+		var levels uint32
+		if err := binary.Read(r, binary.LittleEndian, &levels); err != nil {
+			return err
+		}
+		if levels == 0 || levels > maxLevel+1 {
+			return fmt.Errorf("node %d has %d layers", i, levels)
+		}
+		n.friends = make([][]uint32, levels)
+		for l := range n.friends {
+			var count uint32
+			if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+				return err
+			}
+			if int(count) > 2*h.M {
+				return fmt.Errorf("node %d has %d neighbours", i, count)
+			}
+			n.friends[l] = make([]uint32, count)
+			if err := binary.Read(r, binary.LittleEndian, n.friends[l]); err != nil {
+				return err
+			}
+			for _, friend := range n.friends[l] {
+				if int(friend) >= h.Count {
+					return fmt.Errorf("node %d links to missing node %d", i, friend)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeJSON writes v as JSON prefixed with its length
+func writeJSON(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// maxJSON bounds a length-prefixed JSON record, so a corrupt length can't allocate gigabytes
+const maxJSON = 1 << 20
+
+func readJSON(r io.Reader, v any) error {
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return err
+	}
+	if size > maxJSON {
+		return fmt.Errorf("record of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,243 @@
+package corpus
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// HNSW parameters: each node keeps up to m neighbours per layer and 2*m on the bottom layer, and inserts
+// search efConstruction candidates for them, which keeps an insert to a few milliseconds for embeddings of a
+// few hundred dimensions
+const (
+	defaultM              = 16
+	defaultEfConstruction = 100
+	// minEfSearch keeps the search wide enough for good recall when few results are asked for
+	minEfSearch = 100
+	// maxLevel caps the layer a node is drawn for; with m = 16 a layer above it would need 16^16 nodes
+	maxLevel = 16
+)
+
+// node is one vector in the graph with its neighbours on every layer it was drawn for
+type node struct {
+	vector  []float32
+	friends [][]uint32
+}
+
+func (n *node) level() int {
+	return len(n.friends) - 1
+}
+
+// graph is a hierarchical navigable small world graph over unit vectors: every node is on the bottom layer
+// and exponentially fewer on each layer above, so a search descends greedily from the sparse top layer and
+// only explores the dense bottom layer around the query. Distances are 1 - cosine similarity.
+type graph struct {
+	m              int
+	efConstruction int
+	nodes          []node
+	// entry is the node searches start from, on the top layer; -1 while the graph is empty
+	entry int32
+	top   int
+}
+
+func newGraph() *graph {
+	return &graph{m: defaultM, efConstruction: defaultEfConstruction, entry: -1}
+}
+
+// maxFriends is how many neighbours a node keeps on level
+func (g *graph) maxFriends(level int) int {
+	if level == 0 {
+		return 2 * g.m
+	}
+	return g.m
+}
+
+// drawLevel picks the top layer of the node for key. It hashes the key instead of drawing a random number, so
+// rebuilding a corpus from the same icons gives the same graph.
+func (g *graph) drawLevel(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+	return min(int(math.Floor(-math.Log(u)/math.Log(float64(g.m)))), maxLevel)
+}
+
+func distance(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return 1 - dot
+}
+
+// insert adds a unit vector as a node whose top layer is level and returns its ID
+func (g *graph) insert(vector []float32, level int) uint32 {
+	id := uint32(len(g.nodes))
+	g.nodes = append(g.nodes, node{vector: vector, friends: make([][]uint32, level+1)})
+	if g.entry < 0 {
+		g.entry, g.top = int32(id), level
+		return id
+	}
+
+	entries := []candidate{{id: uint32(g.entry), dist: distance(vector, g.nodes[g.entry].vector)}}
+	for l := g.top; l > level; l-- {
+		entries = g.searchLayer(vector, entries, 1, l)
+	}
+	for l := min(level, g.top); l >= 0; l-- {
+		found := g.searchLayer(vector, entries, g.efConstruction, l)
+		friends := g.selectNeighbours(found, g.maxFriends(l))
+		g.nodes[id].friends[l] = friends
+		for _, friend := range friends {
+			g.connect(friend, id, l)
+		}
+		entries = found
+	}
+	if level > g.top {
+		g.entry, g.top = int32(id), level
+	}
+	return id
+}
+
+// connect links from to to on level, dropping from's worst neighbours when it has too many
+func (g *graph) connect(from, to uint32, level int) {
+	n := &g.nodes[from]
+	n.friends[level] = append(n.friends[level], to)
+	if len(n.friends[level]) <= g.maxFriends(level) {
+		return
+	}
+	candidates := make([]candidate, len(n.friends[level]))
+	for i, friend := range n.friends[level] {
+		candidates[i] = candidate{id: friend, dist: distance(n.vector, g.nodes[friend].vector)}
+	}
+	sortCandidates(candidates)
+	n.friends[level] = g.selectNeighbours(candidates, g.maxFriends(level))
+}
+
+// selectNeighbours picks up to n of candidates, sorted nearest first, with the HNSW heuristic: a candidate
+// closer to an already picked neighbour than to the node is skipped, so links reach out in every direction
+// instead of bunching up in one cluster. Skipped candidates fill any places left.
+func (g *graph) selectNeighbours(candidates []candidate, n int) []uint32 {
+	picked := make([]uint32, 0, n)
+	var skipped []uint32
+	for _, c := range candidates {
+		if len(picked) == n {
+			break
+		}
+		diverse := true
+		for _, p := range picked {
+			if distance(g.nodes[c.id].vector, g.nodes[p].vector) < c.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			picked = append(picked, c.id)
+		} else {
+			skipped = append(skipped, c.id)
+		}
+	}
+	for _, id := range skipped {
+		if len(picked) == n {
+			break
+		}
+		picked = append(picked, id)
+	}
+	return picked
+}
+
+// searchLayer returns up to ef nodes on level nearest to query, nearest first, exploring outwards from entries
+func (g *graph) searchLayer(query []float32, entries []candidate, ef, level int) []candidate {
+	visited := make(map[uint32]bool, ef*4)
+	frontier := &minHeap{}
+	nearest := &maxHeap{}
+	for _, e := range entries {
+		visited[e.id] = true
+		heap.Push(frontier, e)
+		heap.Push(nearest, e)
+		if nearest.Len() > ef {
+			heap.Pop(nearest)
+		}
+	}
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(candidate)
+		if c.dist > (*nearest)[0].dist && nearest.Len() >= ef {
+			break
+		}
+		for _, friend := range g.nodes[c.id].friends[level] {
+			if visited[friend] {
+				continue
+			}
+			visited[friend] = true
+			d := distance(query, g.nodes[friend].vector)
+			if nearest.Len() < ef || d < (*nearest)[0].dist {
+				heap.Push(frontier, candidate{id: friend, dist: d})
+				heap.Push(nearest, candidate{id: friend, dist: d})
+				if nearest.Len() > ef {
+					heap.Pop(nearest)
+				}
+			}
+		}
+	}
+	found := []candidate(*nearest)
+	sortCandidates(found)
+	return found
+}
+
+// search returns up to k nodes nearest to query, nearest first
+func (g *graph) search(query []float32, k int) []candidate {
+	if g.entry < 0 || k < 1 {
+		return nil
+	}
+	entries := []candidate{{id: uint32(g.entry), dist: distance(query, g.nodes[g.entry].vector)}}
+	for l := g.top; l > 0; l-- {
+		entries = g.searchLayer(query, entries, 1, l)
+	}
+	found := g.searchLayer(query, entries, max(k, minEfSearch), 0)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+// candidate is a node and its distance from the vector being searched for
+type candidate struct {
+	id   uint32
+	dist float32
+}
+
+func sortCandidates(c []candidate) {
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].dist != c[j].dist {
+			return c[i].dist < c[j].dist
+		}
+		return c[i].id < c[j].id
+	})
+}
+
+// minHeap pops the nearest candidate first
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// maxHeap pops the farthest candidate first, so the worst of the results found so far is dropped
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
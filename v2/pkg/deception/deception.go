@@ -0,0 +1,239 @@
+package deception
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons a match is flagged as suspect
+const (
+	// ReasonSharedIcon: the same icon with the same response headers came from several unrelated networks
+	ReasonSharedIcon = "shared-icon"
+	// ReasonWildcardDNS: the host only resolves through its zone's wildcard record
+	ReasonWildcardDNS = "wildcard-dns"
+	// ReasonPlaceholder: the site's front page is a parking, hosting default or cloud placeholder page
+	ReasonPlaceholder = "placeholder-page"
+)
+
+// sharedNetworks is how many distinct networks must serve an identical icon response before it looks like mass hosting
+const sharedNetworks = 3
+
+// maxPageScan caps how much of a front page is searched for placeholder markers
+const maxPageScan = 64 << 10
+
+// placeholderMarkers are lowercase snippets of parking pages and default pages left by web servers and hosting platforms
+var placeholderMarkers = []string{
+	"sedoparking.com",
+	"parkingcrew.net",
+	"bodis.com",
+	"this domain is parked",
+	"this domain is for sale",
+	"buy this domain",
+	"parked free, courtesy of godaddy",
+	"future home of something quite cool",
+	"welcome to nginx!",
+	"apache2 ubuntu default page",
+	"apache2 debian default page",
+	"<h1>it works!</h1>",
+	"iis windows server",
+	"404 web site not found",
+	"there isn't a github pages site here",
+	"<code>nosuchbucket</code>",
+	"herokucdn.com/error-pages/no-such-app",
+	"default web site page",
+	"defaultwebpage.cgi",
+}
+
+// volatileHeaders change from response to response, so they are left out of header fingerprints
+var volatileHeaders = map[string]bool{
+	"Age":               true,
+	"Cf-Ray":            true,
+	"Connection":        true,
+	"Date":              true,
+	"Expires":           true,
+	"Keep-Alive":        true,
+	"Nel":               true,
+	"Report-To":         true,
+	"Set-Cookie":        true,
+	"Transfer-Encoding": true,
+	"Via":               true,
+	"X-Amz-Cf-Id":       true,
+	"X-Amz-Id-2":        true,
+	"X-Amz-Request-Id":  true,
+	"X-Cache":           true,
+	"X-Request-Id":      true,
+	"X-Served-By":       true,
+	"X-Timer":           true,
+}
+
+// Fetcher retrieves a page and reports its status code
+type Fetcher func(url string) (status int, body []byte, err error)
+
+// Lookup resolves a host name to its addresses
+type Lookup func(ctx context.Context, host string) ([]string, error)
+
+type Options struct {
+	// FetchPage retrieves front pages for the placeholder check; nil skips it
+	FetchPage Fetcher
+	// Lookup resolves names for the wildcard DNS check; nil skips it, e.g. so lookups can't leak outside Tor
+	Lookup Lookup
+	// CompareNetworks enables the shared icon check; leave it off when remote addresses are a proxy's
+	CompareNetworks bool
+	Timeout         time.Duration
+}
+
+// Sample describes the icon response behind a match
+type Sample struct {
+	URL      string
+	IconHash string
+	RemoteIP string
+	Headers  http.Header
+}
+
+// Detector recognises matches that come from parked, wildcard or mass hosting rather than a deliberate copy
+// of the brand's favicon. Zones and sites are each probed once, however many matches they have.
+type Detector struct {
+	opts Options
+
+	// wildcards is nil when the wildcard DNS check is off
+	wildcards *Wildcards
+
+	mu       sync.Mutex
+	networks map[string]map[string]bool
+	sites    map[string]*verdict
+}
+
+// verdict caches the outcome of a probe that runs once
+type verdict struct {
+	once    sync.Once
+	suspect bool
+}
+
+func NewDetector(opts Options) *Detector {
+	d := &Detector{
+		opts:     opts,
+		networks: make(map[string]map[string]bool),
+		sites:    make(map[string]*verdict),
+	}
+	if opts.Lookup != nil {
+		d.wildcards = NewWildcards(opts.Lookup, opts.Timeout)
+	}
+	return d
+}
+
+// Check returns why a match looks like hosting noise; an empty result means nothing suspicious was found
+func (d *Detector) Check(sample Sample) []string {
+	u, err := url.Parse(sample.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+
+	var reasons []string
+	if d.opts.CompareNetworks && d.sharedIcon(sample) {
+		reasons = append(reasons, ReasonSharedIcon)
+	}
+	if d.wildcards != nil {
+		if _, ok := d.wildcards.Served(u.Hostname()); ok {
+			reasons = append(reasons, ReasonWildcardDNS)
+		}
+	}
+	if d.opts.FetchPage != nil && d.placeholder(u.Scheme+"://"+u.Host) {
+		reasons = append(reasons, ReasonPlaceholder)
+	}
+	return reasons
+}
+
+// sharedIcon records the network a response came from and reports whether the same icon and headers have now
+// been seen from enough distinct networks. Matches seen before the threshold is reached are not flagged.
+func (d *Detector) sharedIcon(sample Sample) bool {
+	network := networkOf(sample.RemoteIP)
+	if network == "" || sample.IconHash == "" {
+		return false
+	}
+	key := sample.IconHash + "/" + headerFingerprint(sample.Headers)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	seen := d.networks[key]
+	if seen == nil {
+		seen = make(map[string]bool)
+		d.networks[key] = seen
+	}
+	seen[network] = true
+	return len(seen) >= sharedNetworks
+}
+
+// placeholder reports whether the site's front page carries a parking or default page marker
+func (d *Detector) placeholder(origin string) bool {
+	return d.once(d.sites, origin, func() bool {
+		_, body, err := d.opts.FetchPage(origin + "/")
+		if err != nil {
+			return false
+		}
+		if len(body) > maxPageScan {
+			body = body[:maxPageScan]
+		}
+		body = bytes.ToLower(body)
+		for _, marker := range placeholderMarkers {
+			if bytes.Contains(body, []byte(marker)) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// once runs probe the first time key is seen in cache and returns its cached outcome afterwards
+func (d *Detector) once(cache map[string]*verdict, key string, probe func() bool) bool {
+	d.mu.Lock()
+	entry, ok := cache[key]
+	if !ok {
+		entry = &verdict{}
+		cache[key] = entry
+	}
+	d.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.suspect = probe()
+	})
+	return entry.suspect
+}
+
+// networkOf groups addresses by IPv4 /16 or IPv6 /48, so hosts in one provider block count as one network
+func networkOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return ""
+	case parsed.To4() != nil:
+		return parsed.Mask(net.CIDRMask(16, 32)).String()
+	default:
+		return parsed.Mask(net.CIDRMask(48, 128)).String()
+	}
+}
+
+// headerFingerprint hashes the stable response headers, names and values, in a fixed order
+func headerFingerprint(headers http.Header) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if !volatileHeaders[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(http.CanonicalHeaderKey(name) + ": " + strings.Join(headers[name], ", ") + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
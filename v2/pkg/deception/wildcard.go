@@ -0,0 +1,148 @@
+package deception
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+)
+
+// Wildcards detects wildcard DNS zones and recognises hosts that only exist because of the wildcard record.
+// Each zone is probed once by resolving a random name in it.
+type Wildcards struct {
+	lookup  Lookup
+	timeout time.Duration
+
+	mu    sync.Mutex
+	zones map[string]*wildcardZone
+}
+
+type wildcardZone struct {
+	once sync.Once
+	// addrs are the wildcard record's answers; empty when the zone has no wildcard
+	addrs map[string]bool
+}
+
+func NewWildcards(lookup Lookup, timeout time.Duration) *Wildcards {
+	return &Wildcards{lookup: lookup, timeout: timeout, zones: make(map[string]*wildcardZone)}
+}
+
+// Served reports whether host sits under a wildcard zone and resolves to nothing but the wildcard's addresses,
+// returning the zone. Registrable domains and IP addresses are never wildcard-served.
+func (w *Wildcards) Served(host string) (string, bool) {
+	zone, ok := zoneOf(host)
+	if !ok {
+		return "", false
+	}
+	wildcard := w.zone(zone)
+	if len(wildcard) == 0 {
+		return "", false
+	}
+
+	addrs, err := w.resolve(host)
+	if err != nil || len(addrs) == 0 {
+		return "", false
+	}
+	for _, addr := range addrs {
+		if !wildcard[addr] {
+			return "", false
+		}
+	}
+	return zone, true
+}
+
+// zone returns the wildcard addresses of zone, probing it the first time it is seen
+func (w *Wildcards) zone(name string) map[string]bool {
+	w.mu.Lock()
+	entry, ok := w.zones[name]
+	if !ok {
+		entry = &wildcardZone{}
+		w.zones[name] = entry
+	}
+	w.mu.Unlock()
+
+	entry.once.Do(func() {
+		label := make([]byte, 6)
+		if _, err := rand.Read(label); err != nil {
+			return
+		}
+		addrs, err := w.resolve("favlens-" + hex.EncodeToString(label) + "." + name)
+		if err != nil || len(addrs) == 0 {
+			return
+		}
+		entry.addrs = make(map[string]bool, len(addrs))
+		for _, addr := range addrs {
+			entry.addrs[addr] = true
+		}
+	})
+	return entry.addrs
+}
+
+func (w *Wildcards) resolve(host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	return w.lookup(ctx, host)
+}
+
+// zoneOf returns the zone a wildcard record for host would live in: host without its first label,
+// as long as host is below its registrable domain
+func zoneOf(host string) (string, bool) {
+	if net.ParseIP(host) != nil {
+		return "", false
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if domain.Apex(host) == host {
+		return "", false
+	}
+	_, zone, _ := strings.Cut(host, ".")
+	return zone, true
+}
+
+// Wildcard filter modes
+const (
+	// FilterCollapse scans one wildcard-served target per zone and drops the rest
+	FilterCollapse = "collapse"
+	// FilterSkip drops every wildcard-served target
+	FilterSkip = "skip"
+)
+
+// WildcardFilter drops wildcard-served targets before they are scanned, so a parking page answering for
+// thousands of names costs one comparison at most. It is not safe for concurrent use.
+type WildcardFilter struct {
+	wildcards *Wildcards
+	mode      string
+	kept      map[string]bool
+	dropped   int
+}
+
+func NewWildcardFilter(mode string, lookup Lookup, timeout time.Duration) (*WildcardFilter, error) {
+	if mode != FilterCollapse && mode != FilterSkip {
+		return nil, fmt.Errorf("unsupported wildcard filter '%s' (supported: %s, %s)", mode, FilterCollapse, FilterSkip)
+	}
+	return &WildcardFilter{wildcards: NewWildcards(lookup, timeout), mode: mode, kept: make(map[string]bool)}, nil
+}
+
+// Keep reports whether a target on host should be scanned
+func (f *WildcardFilter) Keep(host string) bool {
+	zone, ok := f.wildcards.Served(host)
+	if !ok {
+		return true
+	}
+	if f.mode == FilterCollapse && !f.kept[zone] {
+		f.kept[zone] = true
+		return true
+	}
+	f.dropped++
+	return false
+}
+
+// Dropped returns how many targets Keep turned away
+func (f *WildcardFilter) Dropped() int {
+	return f.dropped
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/weppos/publicsuffix-go/publicsuffix"
+)
+
+// findOptions look suffixes up in the full Public Suffix List, private section included, so every user site
+// on a shared host such as github.io or herokuapp.com is a registered domain of its own. Names under a TLD
+// the list doesn't know fall back to the implicit "*" rule.
+var findOptions = &publicsuffix.FindOptions{IgnorePrivate: false, DefaultRule: publicsuffix.DefaultRule}
+
+// Host returns the lowercased host of a target, which may be a URL or a bare host with an optional port and path
+func Host(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return normalize(u.Hostname())
+}
+
+// Apex returns the registered domain of host, e.g. example.co.uk for a.b.example.co.uk. IP addresses, and hosts
+// that are a public suffix themselves, are returned as they are.
+func Apex(host string) string {
+	parts, ok := split(host)
+	if !ok {
+		return normalize(host)
+	}
+	return parts.SLD + "." + parts.TLD
+}
+
+// Suffix returns the public suffix of host, e.g. co.uk for a.example.co.uk, or "" for IP addresses
+func Suffix(host string) string {
+	parts, ok := split(host)
+	if !ok {
+		if host = normalize(host); net.ParseIP(host) == nil {
+			return host
+		}
+		return ""
+	}
+	return parts.TLD
+}
+
+// Label returns the registrable label of host, e.g. "example" for www.example.co.uk, or "" for IP addresses
+// and bare public suffixes
+func Label(host string) string {
+	parts, _ := split(host)
+	return parts.SLD
+}
+
+// Subdomain returns the labels of host left of its registered domain, e.g. "login.eu" for login.eu.example.co.uk
+func Subdomain(host string) string {
+	parts, _ := split(host)
+	return parts.TRD
+}
+
+// split looks host up in the list; ok is false for IP addresses and hosts that are a public suffix themselves.
+// The list holds internationalized suffixes in punycode, so Unicode hosts are looked up in that form and
+// their parts returned in Unicode again.
+func split(host string) (publicsuffix.DomainName, bool) {
+	host = normalize(host)
+	if host == "" || net.ParseIP(host) != nil {
+		return publicsuffix.DomainName{}, false
+	}
+	name, unicode := host, false
+	for _, r := range host {
+		if r > 127 {
+			unicode = true
+			break
+		}
+	}
+	if unicode {
+		ascii, err := publicsuffix.ToASCII(host)
+		if err != nil {
+			return publicsuffix.DomainName{}, false
+		}
+		name = ascii
+	}
+	parsed, err := publicsuffix.ParseFromListWithOptions(publicsuffix.DefaultList, name, findOptions)
+	if err != nil {
+		return publicsuffix.DomainName{}, false
+	}
+	parts := *parsed
+	if unicode {
+		parts.TLD, parts.SLD, parts.TRD = toUnicode(parts.TLD), toUnicode(parts.SLD), toUnicode(parts.TRD)
+	}
+	return parts, true
+}
+
+func toUnicode(name string) string {
+	if converted, err := publicsuffix.ToUnicode(name); err == nil {
+		return converted
+	}
+	return name
+}
+
+func normalize(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"sort"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Group aggregates the matches under one registered domain
+type Group struct {
+	Apex string
+	// Hosts are the distinct matched hosts, lookalike hosts first and otherwise in the order they matched
+	Hosts []string
+	URLs  []string
+}
+
+// Representative returns up to n hosts to show for the group
+func (g Group) Representative(n int) []string {
+	if len(g.Hosts) <= n {
+		return g.Hosts
+	}
+	return g.Hosts[:n]
+}
+
+// GroupByApex groups matched results by the apex domain of their host, so hundreds of wildcard subdomains
+// read as one line. Groups are ordered by number of matches, then by apex.
+func GroupByApex(results []types.Result) []Group {
+	index := make(map[string]int)
+	var groups []Group
+	seen := make(map[string]bool)
+	var lookalikes []map[string]bool
+	for _, result := range results {
+		if !result.Match || result.Err != nil {
+			continue
+		}
+		host := Host(result.URL)
+		apex := Apex(host)
+		i, ok := index[apex]
+		if !ok {
+			i = len(groups)
+			index[apex] = i
+			groups = append(groups, Group{Apex: apex})
+			lookalikes = append(lookalikes, make(map[string]bool))
+		}
+		groups[i].URLs = append(groups[i].URLs, result.URL)
+		if result.Lookalike != nil {
+			lookalikes[i][host] = true
+		}
+		if !seen[host] {
+			seen[host] = true
+			groups[i].Hosts = append(groups[i].Hosts, host)
+		}
+	}
+	for i := range groups {
+		sort.SliceStable(groups[i].Hosts, func(a, b int) bool {
+			return lookalikes[i][groups[i].Hosts[a]] && !lookalikes[i][groups[i].Hosts[b]]
+		})
+	}
+	sort.SliceStable(groups, func(a, b int) bool {
+		if len(groups[a].URLs) != len(groups[b].URLs) {
+			return len(groups[a].URLs) > len(groups[b].URLs)
+		}
+		return groups[a].Apex < groups[b].Apex
+	})
+	return groups
+}
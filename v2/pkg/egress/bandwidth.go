@@ -0,0 +1,134 @@
+package egress
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bandwidthUnits are the suffixes --max-bandwidth accepts, longest first so "mbps" isn't read as "bps", with
+// their size in bytes per second: "bps" suffixes are bits as link speeds are quoted, "/s" suffixes are bytes
+var bandwidthUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"gib/s", 1 << 30},
+	{"mib/s", 1 << 20},
+	{"kib/s", 1 << 10},
+	{"gbps", 1e9 / 8},
+	{"mbps", 1e6 / 8},
+	{"kbps", 1e3 / 8},
+	{"gb/s", 1e9},
+	{"mb/s", 1e6},
+	{"kb/s", 1e3},
+	{"bps", 1.0 / 8},
+	{"b/s", 1},
+}
+
+// ParseBandwidth reads a rate such as 5mbps, 500kbps or 2MB/s and returns it in bytes per second. A bare number
+// is bytes per second.
+func ParseBandwidth(s string) (int64, error) {
+	value := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	unit := 1.0
+	for _, u := range bandwidthUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value, unit = strings.TrimSuffix(value, u.suffix), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth '%s' (use a rate like 5mbps, 500kbps or 2MB/s)", s)
+	}
+	rate := int64(n * unit)
+	if rate < 1 {
+		return 0, fmt.Errorf("bandwidth '%s' is below 1 byte per second", s)
+	}
+	return rate, nil
+}
+
+// minBurst keeps a very low rate from waking for every few bytes
+const minBurst = 4 << 10
+
+// Limiter is a token bucket on bytes shared by every connection it wraps, so the total traffic of all downloads
+// stays under one rate however many workers run. The bucket holds a quarter second of traffic, letting short
+// bursts through without exceeding the rate over any longer window.
+type Limiter struct {
+	rate  float64
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter allows rate bytes per second
+func NewLimiter(rate int64) *Limiter {
+	burst := max(int(rate/4), minBurst)
+	return &Limiter{rate: float64(rate), burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes n bytes from the bucket, sleeping until the rate allows them. The bucket may go into debt, so a
+// large read is paid for by the next callers instead of being split up.
+func (l *Limiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, float64(l.burst))
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// Dial wraps dial so every connection it opens reads and writes through the limiter
+func (l *Limiter) Dial(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &limitedConn{Conn: conn, limiter: l}, nil
+	}
+}
+
+// limitedConn paces a connection's traffic; reads are capped at the burst size so one large buffer can't take
+// more than its share at once
+type limitedConn struct {
+	net.Conn
+	limiter *Limiter
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	if len(p) > c.limiter.burst {
+		p = p[:c.limiter.burst]
+	}
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.limiter.wait(n)
+	}
+	return n, err
+}
+
+func (c *limitedConn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), c.limiter.burst)]
+		c.limiter.wait(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
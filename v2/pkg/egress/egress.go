@@ -0,0 +1,109 @@
+package egress
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Dialer opens outgoing connections from a fixed set of local addresses, rotating through them so
+// each new connection leaves from the next address
+type Dialer struct {
+	ips     []net.IP
+	timeout time.Duration
+	next    atomic.Uint64
+}
+
+// New collects the source addresses to bind: every --source-ip as given and every usable address of each
+// --interface in the family version allows. Either list may hold comma-separated values.
+func New(sourceIPs, interfaces []string, version string, timeout time.Duration) (*Dialer, error) {
+	d := &Dialer{timeout: timeout}
+	for _, value := range splitList(sourceIPs) {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", value)
+		}
+		if !inFamily(ip, version) {
+			return nil, fmt.Errorf("source IP %s is not an IPv%s address", value, version)
+		}
+		d.ips = append(d.ips, ip)
+	}
+	for _, name := range splitList(interfaces) {
+		ips, err := interfaceIPs(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if inFamily(ip, version) {
+				d.ips = append(d.ips, ip)
+			}
+		}
+	}
+	if len(d.ips) == 0 {
+		if version != IPVersionAuto && len(interfaces) > 0 {
+			return nil, fmt.Errorf("no IPv%s source addresses on %s", version, strings.Join(splitList(interfaces), ", "))
+		}
+		return nil, fmt.Errorf("no source addresses given")
+	}
+	return d, nil
+}
+
+// IPs returns the source addresses in rotation order
+func (d *Dialer) IPs() []net.IP {
+	return d.ips
+}
+
+// Dial connects to addr from the next source address. The destination is resolved in the source address's
+// family, so an IPv4 source only reaches hosts with an A record.
+func (d *Dialer) Dial(addr string) (net.Conn, error) {
+	ip := d.ips[(d.next.Add(1)-1)%uint64(len(d.ips))]
+	network := "tcp6"
+	if ip.To4() != nil {
+		network = "tcp4"
+	}
+	dialer := net.Dialer{Timeout: d.timeout, LocalAddr: &net.TCPAddr{IP: ip}}
+	conn, err := dialer.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s from %s: %v", addr, ip, err)
+	}
+	return conn, nil
+}
+
+// interfaceIPs returns the addresses of a network interface, leaving out IPv6 link-local ones that can't
+// reach anything beyond the local link
+func interfaceIPs(name string) ([]net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %v", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %v", name, err)
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("interface %s has no usable addresses", name)
+	}
+	return ips, nil
+}
+
+func splitList(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package egress
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// IP versions accepted by --ip-version
+const (
+	IPVersionAuto = "auto"
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+)
+
+// IPVersions lists every value accepted by --ip-version
+var IPVersions = []string{IPVersionAuto, IPVersion4, IPVersion6}
+
+// fallbackDelay is how long a dual-stack dial waits on the preferred family before racing the other one,
+// the RFC 8305 recommendation
+const fallbackDelay = 300 * time.Millisecond
+
+// ValidateIPVersion reports whether version is one of IPVersions
+func ValidateIPVersion(version string) error {
+	for _, v := range IPVersions {
+		if strings.EqualFold(version, v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported IP version '%s' (supported: %s)", version, strings.Join(IPVersions, ", "))
+}
+
+// dialNetwork returns the dial network for version: tcp4, tcp6, or tcp for both families
+func dialNetwork(version string) string {
+	switch version {
+	case IPVersion4:
+		return "tcp4"
+	case IPVersion6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// inFamily reports whether ip can be used for version
+func inFamily(ip net.IP, version string) bool {
+	switch version {
+	case IPVersion4:
+		return ip.To4() != nil
+	case IPVersion6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// FamilyDial returns a dial function that connects over version. With IPVersionAuto both families are tried
+// happy-eyeballs style: the first address family gets a head start of fallbackDelay, then the other races it,
+// so a dual-homed host with broken IPv6 connects over IPv4 instead of spending the whole timeout on IPv6.
+func FamilyDial(version string, timeout time.Duration) func(addr string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout, FallbackDelay: fallbackDelay}
+	network := dialNetwork(version)
+	return func(addr string) (net.Conn, error) {
+		return dialer.Dial(network, addr)
+	}
+}
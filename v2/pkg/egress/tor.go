@@ -0,0 +1,62 @@
+package egress
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Tor opens connections through a Tor SOCKS5 proxy. Tor isolates streams by SOCKS credentials, so
+// changing the username and password moves later connections onto a fresh circuit and exit node.
+type Tor struct {
+	proxy   string
+	rotate  int
+	timeout time.Duration
+	// session keeps this run's circuits apart from other Tor clients and earlier runs
+	session string
+	count   atomic.Uint64
+}
+
+// NewTor dials through the proxy at addr, switching circuits every rotate connections; 0 keeps one circuit
+func NewTor(addr string, rotate int, timeout time.Duration) (*Tor, error) {
+	session := make([]byte, 8)
+	if _, err := rand.Read(session); err != nil {
+		return nil, fmt.Errorf("failed to create Tor session: %v", err)
+	}
+	return &Tor{proxy: addr, rotate: rotate, timeout: timeout, session: hex.EncodeToString(session)}, nil
+}
+
+// Check confirms the proxy is accepting connections, so a stopped Tor daemon fails the scan up front
+// instead of turning every target into an error
+func (t *Tor) Check() error {
+	conn, err := net.DialTimeout("tcp", t.proxy, t.timeout)
+	if err != nil {
+		return fmt.Errorf("tor SOCKS proxy at %s is not reachable: %v", t.proxy, err)
+	}
+	return conn.Close()
+}
+
+// Dial connects to addr through Tor. Host names are passed to the proxy unresolved, so target DNS
+// lookups happen at the exit node rather than leaking from this machine.
+func (t *Tor) Dial(addr string) (net.Conn, error) {
+	circuit := uint64(0)
+	if t.rotate > 0 {
+		circuit = (t.count.Add(1) - 1) / uint64(t.rotate)
+	}
+	auth := &proxy.Auth{User: "favlens-" + t.session, Password: strconv.FormatUint(circuit, 10)}
+	dialer, err := proxy.SOCKS5("tcp", t.proxy, auth, &net.Dialer{Timeout: t.timeout})
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s through Tor: %v", addr, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,104 @@
+// Package encryption encrypts output files to age or OpenPGP recipients, since results can reveal a client's
+// scope and shouldn't sit around in plaintext.
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Recipients are the keys an output is encrypted to; they are either all age or all OpenPGP keys
+type Recipients struct {
+	age []age.Recipient
+	pgp openpgp.EntityList
+}
+
+// ParseRecipients reads recipients given as age public keys (age1...), SSH public keys (ssh-ed25519 or
+// ssh-rsa), age recipients files, or armored or binary OpenPGP public key files
+func ParseRecipients(values []string) (*Recipients, error) {
+	r := &Recipients{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "age1"):
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid age recipient '%s': %v", value, err)
+			}
+			r.age = append(r.age, recipient)
+		case strings.HasPrefix(value, "ssh-"):
+			recipient, err := agessh.ParseRecipient(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SSH recipient '%s': %v", value, err)
+			}
+			r.age = append(r.age, recipient)
+		default:
+			if err := r.parseFile(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(r.age) == 0 && len(r.pgp) == 0 {
+		return nil, errors.New("no recipients given")
+	}
+	if len(r.age) > 0 && len(r.pgp) > 0 {
+		return nil, errors.New("age and OpenPGP recipients can't be mixed; an output is encrypted in one format")
+	}
+	return r, nil
+}
+
+// parseFile adds the recipients in an OpenPGP public key file or an age recipients file
+func (r *Recipients) parseFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("recipient '%s' is not an age or SSH public key, and can't be read as a file: %v", path, err)
+	}
+	if bytes.Contains(data, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----")) {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("invalid OpenPGP key in %s: %v", path, err)
+		}
+		r.pgp = append(r.pgp, entities...)
+		return nil
+	}
+	if entities, err := openpgp.ReadKeyRing(bytes.NewReader(data)); err == nil {
+		r.pgp = append(r.pgp, entities...)
+		return nil
+	}
+	recipients, err := age.ParseRecipients(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s is neither an OpenPGP key nor an age recipients file: %v", path, err)
+	}
+	r.age = append(r.age, recipients...)
+	return nil
+}
+
+// Format names the encryption format, for logging
+func (r *Recipients) Format() string {
+	if len(r.pgp) > 0 {
+		return "OpenPGP"
+	}
+	return "age"
+}
+
+// Count is the number of recipients
+func (r *Recipients) Count() int {
+	return len(r.age) + len(r.pgp)
+}
+
+// Encrypt returns a writer that encrypts everything written to it into w. Close finishes the encrypted
+// stream; it does not close w.
+func (r *Recipients) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	if len(r.pgp) > 0 {
+		return openpgp.Encrypt(w, r.pgp, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	}
+	return age.Encrypt(w, r.age...)
+}
@@ -0,0 +1,39 @@
+package extract
+
+import (
+	"path"
+	"strings"
+)
+
+// apkIconNames are the resource names Android projects conventionally use for launcher icons
+var apkIconNames = []string{"ic_launcher", "ic_launcher_round", "ic_launcher_foreground", "app_icon", "icon"}
+
+// apkCandidate matches launcher icon bitmaps under res/mipmap-* and res/drawable-*.
+// Obfuscated resource names (e.g. res/a1.png after R8 shrinking) can't be recognised without parsing resources.arsc.
+func apkCandidate(name string) bool {
+	if !strings.HasPrefix(name, "res/") && !strings.HasPrefix(name, "base/res/") {
+		return false
+	}
+	dir := path.Base(path.Dir(name))
+	if !strings.HasPrefix(dir, "mipmap") && !strings.HasPrefix(dir, "drawable") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".webp":
+	default:
+		return false
+	}
+	base := apkName(name)
+	for _, icon := range apkIconNames {
+		if base == icon {
+			return true
+		}
+	}
+	return false
+}
+
+// apkName strips the density directory and extension, so every density of an icon shares one name
+func apkName(name string) string {
+	base := path.Base(name)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
@@ -0,0 +1,143 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	imaging "github.com/ethicalhackingplayground/favlens/v2/pkg/imaging"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// isCgBI reports whether data is an Apple "CgBI" PNG, which Xcode produces for icons inside .ipa bundles.
+// These store BGRA premultiplied pixels in a headerless deflate stream, so standard decoders reject them.
+func isCgBI(data []byte) bool {
+	return len(data) > 16 && bytes.Equal(data[:8], pngSignature) && string(data[12:16]) == "CgBI"
+}
+
+// uncrushCgBI converts a CgBI PNG into a standard PNG. Only 8-bit RGBA images are supported, which is what Xcode emits.
+func uncrushCgBI(data []byte) ([]byte, error) {
+	var width, height int
+	var idat bytes.Buffer
+	for pos := 8; pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		kind := string(data[pos+4 : pos+8])
+		if length < 0 || pos+12+length > len(data) {
+			return nil, fmt.Errorf("truncated %s chunk", kind)
+		}
+		body := data[pos+8 : pos+8+length]
+		switch kind {
+		case "IHDR":
+			if length < 13 {
+				return nil, fmt.Errorf("short IHDR chunk")
+			}
+			width = int(binary.BigEndian.Uint32(body[0:]))
+			height = int(binary.BigEndian.Uint32(body[4:]))
+			if body[8] != 8 || body[9] != 6 || body[12] != 0 {
+				return nil, fmt.Errorf("unsupported CgBI layout (bit depth %d, color type %d, interlace %d)", body[8], body[9], body[12])
+			}
+		case "IDAT":
+			idat.Write(body)
+		}
+		pos += 12 + length
+	}
+	if width <= 0 || height <= 0 || width*height > imaging.MaxPixels {
+		return nil, fmt.Errorf("invalid CgBI dimensions %dx%d", width, height)
+	}
+
+	stride := width * 4
+	raw, err := io.ReadAll(io.LimitReader(flate.NewReader(&idat), int64((stride+1)*height)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate CgBI data: %v", err)
+	}
+	if len(raw) != (stride+1)*height {
+		return nil, fmt.Errorf("CgBI data is %d bytes, expected %d", len(raw), (stride+1)*height)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	prev := make([]byte, stride)
+	for y := 0; y < height; y++ {
+		line := raw[y*(stride+1):]
+		cur := line[1 : stride+1]
+		if err := unfilter(line[0], cur, prev); err != nil {
+			return nil, err
+		}
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < stride; x += 4 {
+			b, g, r, a := cur[x], cur[x+1], cur[x+2], cur[x+3]
+			// Undo alpha premultiplication
+			if a != 0 && a != 255 {
+				r = uint8(min(255, int(r)*255/int(a)))
+				g = uint8(min(255, int(g)*255/int(a)))
+				b = uint8(min(255, int(b)*255/int(a)))
+			}
+			row[x], row[x+1], row[x+2], row[x+3] = r, g, b, a
+		}
+		prev = cur
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unfilter reverses a PNG scanline filter in place for 4-byte pixels
+func unfilter(filter byte, cur, prev []byte) error {
+	const bpp = 4
+	switch filter {
+	case 0:
+	case 1:
+		for i := bpp; i < len(cur); i++ {
+			cur[i] += cur[i-bpp]
+		}
+	case 2:
+		for i := range cur {
+			cur[i] += prev[i]
+		}
+	case 3:
+		for i := range cur {
+			var left int
+			if i >= bpp {
+				left = int(cur[i-bpp])
+			}
+			cur[i] += uint8((left + int(prev[i])) / 2)
+		}
+	case 4:
+		for i := range cur {
+			var a, c int
+			if i >= bpp {
+				a, c = int(cur[i-bpp]), int(prev[i-bpp])
+			}
+			cur[i] += paeth(a, int(prev[i]), c)
+		}
+	default:
+		return fmt.Errorf("invalid PNG filter type %d", filter)
+	}
+	return nil
+}
+
+func paeth(a, b, c int) uint8 {
+	p := a + b - c
+	pa, pb, pc := abs(p-a), abs(p-b), abs(p-c)
+	if pa <= pb && pa <= pc {
+		return uint8(a)
+	}
+	if pb <= pc {
+		return uint8(b)
+	}
+	return uint8(c)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
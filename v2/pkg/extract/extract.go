@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	imaging "github.com/ethicalhackingplayground/favlens/v2/pkg/imaging"
+)
+
+// Icon is an app icon pulled out of a package, re-encoded as a standard PNG
+type Icon struct {
+	// Name identifies the icon inside the package, e.g. "ic_launcher" or "AppIcon60x60"
+	Name   string
+	Source string
+	Width  int
+	Height int
+	PNG    []byte
+}
+
+// maxEntrySize bounds how much of a single archive entry is read
+const maxEntrySize = imaging.MaxInputSize
+
+// Package extracts the app icons from an .apk or .ipa file, picking the largest variant of each icon
+func Package(filename string) ([]Icon, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".apk", ".aab":
+		return fromArchive(filename, apkCandidate, apkName)
+	case ".ipa":
+		return fromArchive(filename, ipaCandidate, ipaName)
+	default:
+		return nil, fmt.Errorf("unsupported package '%s' (supported: .apk, .aab, .ipa)", filename)
+	}
+}
+
+// fromArchive walks the package's zip entries, decodes every candidate icon and keeps the largest per name
+func fromArchive(filename string, candidate func(string) bool, name func(string) string) ([]Icon, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %v", err)
+	}
+	defer archive.Close()
+
+	best := make(map[string]Icon)
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !candidate(entry.Name) {
+			continue
+		}
+		if entry.UncompressedSize64 > maxEntrySize {
+			continue
+		}
+		data, err := readEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		icon, err := decodeIcon(data)
+		if err != nil {
+			// Resource directories also hold XML drawables and other non-images; skip anything undecodable
+			continue
+		}
+		icon.Name = name(entry.Name)
+		icon.Source = entry.Name
+		if current, ok := best[icon.Name]; !ok || icon.Width*icon.Height > current.Width*current.Height {
+			best[icon.Name] = icon
+		}
+	}
+	if len(best) == 0 {
+		return nil, fmt.Errorf("no app icons found in %s", filename)
+	}
+
+	icons := make([]Icon, 0, len(best))
+	for _, icon := range best {
+		icons = append(icons, icon)
+	}
+	sort.Slice(icons, func(i, j int) bool { return icons[i].Name < icons[j].Name })
+	return icons, nil
+}
+
+func readEntry(entry *zip.File) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", entry.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxEntrySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", entry.Name, err)
+	}
+	return data, nil
+}
+
+// decodeIcon normalises an icon to a standard PNG, undoing Apple's CgBI optimisation when present
+func decodeIcon(data []byte) (Icon, error) {
+	if isCgBI(data) {
+		converted, err := uncrushCgBI(data)
+		if err != nil {
+			return Icon{}, err
+		}
+		data = converted
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Icon{}, err
+	}
+	if config.Width > imaging.MaxDimension || config.Height > imaging.MaxDimension {
+		return Icon{}, fmt.Errorf("icon too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Icon{}, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return Icon{}, err
+	}
+	return Icon{Width: config.Width, Height: config.Height, PNG: buf.Bytes()}, nil
+}
@@ -0,0 +1,26 @@
+package extract
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ipaIcon matches loose app icon files in the bundle, e.g. Payload/App.app/AppIcon60x60@3x.png.
+// Icons compiled only into Assets.car are not extracted.
+var ipaIcon = regexp.MustCompile(`^Payload/[^/]+\.app/(AppIcon|Icon)[^/]*\.png$`)
+
+// ipaScale drops the size and scale suffixes so every variant of an icon shares one name
+var ipaScale = regexp.MustCompile(`(\d+(\.\d+)?x\d+(\.\d+)?)?(@\dx)?(~ipad)?$`)
+
+func ipaCandidate(name string) bool {
+	return ipaIcon.MatchString(name)
+}
+
+func ipaName(name string) string {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	if trimmed := ipaScale.ReplaceAllString(base, ""); trimmed != "" {
+		return trimmed
+	}
+	return base
+}
@@ -0,0 +1,107 @@
+package favhash
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// What the mmh3 hash is computed over, as chosen with --hash-input
+const (
+	// InputB64Wrapped hashes the line-wrapped base64 of the icon, as Shodan and httpx do
+	InputB64Wrapped = "b64-wrapped"
+	// InputRaw hashes the icon bytes themselves, as some other scanners and datasets do
+	InputRaw = "raw"
+)
+
+// Inputs lists every value accepted by --hash-input
+var Inputs = []string{InputB64Wrapped, InputRaw}
+
+// ValidateInput reports whether input is one of Inputs
+func ValidateInput(input string) error {
+	for _, i := range Inputs {
+		if strings.EqualFold(input, i) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported hash input '%s' (supported: %s)", input, strings.Join(Inputs, ", "))
+}
+
+// Hash returns the mmh3 hash of data computed over input, as MMH3 or MMH3Raw
+func Hash(data []byte, input string) string {
+	if strings.EqualFold(input, InputRaw) {
+		return MMH3Raw(data)
+	}
+	return MMH3(data)
+}
+
+// lineLength is where Python's base64.encodebytes wraps its output, which the favicon hash is computed over
+const lineLength = 76
+
+// MMH3 returns the favicon hash used by Shodan and httpx: the signed 32-bit MurmurHash3 of the icon's base64
+// encoding, wrapped every 76 characters with a trailing newline. That is Python's
+// mmh3.hash(base64.encodebytes(body)): standard base64 with padding, a "\n" after every 76 characters and
+// after the last line, hashed with MurmurHash3 x86_32 and seed 0 and printed as a signed decimal. Hashing the
+// unwrapped base64 or the raw bytes gives a different value that won't match Shodan's http.favicon.hash.
+func MMH3(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	wrapped := make([]byte, 0, len(encoded)+len(encoded)/lineLength+1)
+	for len(encoded) > lineLength {
+		wrapped = append(wrapped, encoded[:lineLength]...)
+		wrapped = append(wrapped, '\n')
+		encoded = encoded[lineLength:]
+	}
+	wrapped = append(wrapped, encoded...)
+	wrapped = append(wrapped, '\n')
+	return strconv.Itoa(int(int32(murmur3(wrapped, 0))))
+}
+
+// MMH3Raw returns the signed 32-bit MurmurHash3 of the icon bytes themselves, with no base64 step. It never
+// equals the Shodan hash of the same icon.
+func MMH3Raw(data []byte) string {
+	return strconv.Itoa(int(int32(murmur3(data, 0))))
+}
+
+// murmur3 is MurmurHash3 x86_32
+func murmur3(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	h := seed
+	n := len(data)
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+		data = data[4:]
+	}
+
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
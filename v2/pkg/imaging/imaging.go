@@ -0,0 +1,266 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // Register GIF format
+	_ "image/jpeg" // Register JPEG format
+	"image/png"
+	"math"
+	"sync"
+
+	_ "github.com/mat/besticon/ico" // Register ICO format
+	_ "golang.org/x/image/bmp"      // Register BMP format
+	_ "golang.org/x/image/webp"     // Register WebP format
+)
+
+// Info describes a decoded icon
+type Info struct {
+	Format string
+	Width  int
+	Height int
+	// Bytes is the size of the icon as downloaded
+	Bytes int
+	// BitDepth is bits per pixel of the source color model, or 0 when it isn't known
+	BitDepth int
+	// Monochrome is true when every visible pixel is grey or shares one hue; such icons carry little
+	// brand detail and tend to produce low-confidence matches
+	Monochrome bool
+	// Converted is true when the icon was re-encoded to PNG
+	Converted bool
+}
+
+// maxAnalysisPixels bounds the PNGs decoded only to check for monochrome; larger ones are left unchecked
+const maxAnalysisPixels = 256 * 256
+
+// hueTolerance is how far apart, in degrees, hues may be for an icon to still count as one color
+const hueTolerance = 15
+
+// Limits applied to untrusted icons before they are fully decoded, so hostile targets can't serve
+// decompression bombs. Real favicons are a few hundred pixels at most.
+const (
+	// MaxInputSize is the largest icon accepted, in bytes
+	MaxInputSize = 8 * 1024 * 1024
+	// MaxDimension is the largest width or height accepted
+	MaxDimension = 4096
+	// MaxPixels bounds width*height, which is what drives decode memory
+	MaxPixels = 4096 * 4096
+)
+
+// pngEnd is the IEND chunk that closes every complete PNG
+var pngEnd = []byte{0, 0, 0, 0, 'I', 'E', 'N', 'D', 0xae, 0x42, 0x60, 0x82}
+
+// maxPooledBuffer keeps unusually large icons from pinning memory in the pool
+const maxPooledBuffer = 4 * 1024 * 1024
+
+// bufferPool recycles the buffers used to re-encode icons as PNG
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// encoderPool lets the PNG encoder reuse its internal compression buffers between icons
+type encoderPool struct {
+	pool sync.Pool
+}
+
+func (p *encoderPool) Get() *png.EncoderBuffer {
+	b, _ := p.pool.Get().(*png.EncoderBuffer)
+	return b
+}
+
+func (p *encoderPool) Put(b *png.EncoderBuffer) {
+	p.pool.Put(b)
+}
+
+var encoder = &png.Encoder{BufferPool: &encoderPool{}}
+
+// checkLimits rejects icons whose declared size is empty or too large to decode safely
+func checkLimits(info Info) error {
+	if info.Width <= 0 || info.Height <= 0 {
+		return fmt.Errorf("invalid image dimensions %dx%d", info.Width, info.Height)
+	}
+	if info.Width > MaxDimension || info.Height > MaxDimension || info.Width*info.Height > MaxPixels {
+		return fmt.Errorf("image dimensions %dx%d exceed the %dx%d limit", info.Width, info.Height, MaxDimension, MaxDimension)
+	}
+	return nil
+}
+
+// Base64PNG converts raw icon bytes into a base64-encoded PNG.
+// Only the image header is read before the size limits are checked, so oversized icons are rejected
+// without being decoded. Complete PNG input is encoded straight from data without being decoded in full; other
+// formats are decoded and re-encoded through pooled buffers, so only the final base64 string is retained.
+// Decoder panics on malformed input are returned as errors. Icons are normalised according to opts,
+// which always requires a full decode; Info describes the icon as downloaded.
+func Base64PNG(data []byte, opts Options) (b64 string, info Info, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b64, err = "", fmt.Errorf("error decoding image: decoder panic: %v", r)
+		}
+	}()
+
+	if len(data) > MaxInputSize {
+		return "", Info{}, fmt.Errorf("image is %d bytes, exceeding the %d byte limit", len(data), MaxInputSize)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", Info{}, fmt.Errorf("error decoding image: %v", err)
+	}
+	info = Info{Format: format, Width: config.Width, Height: config.Height, Bytes: len(data), BitDepth: bitDepth(config.ColorModel)}
+	if err := checkLimits(info); err != nil {
+		return "", info, err
+	}
+
+	// A PNG that doesn't end in IEND is truncated or carries trailing data, so it is decoded in full to find out
+	if format == "png" && !opts.transforms() && bytes.HasSuffix(data, pngEnd) {
+		// Grey and paletted PNGs answer the monochrome question from their header; small truecolor ones are decoded
+		switch model := config.ColorModel.(type) {
+		case color.Palette:
+			info.Monochrome = monochromeColors(model)
+		default:
+			if grey(model) {
+				info.Monochrome = true
+			} else if info.Width*info.Height <= maxAnalysisPixels {
+				if img, err := png.Decode(bytes.NewReader(data)); err == nil {
+					info.Monochrome = monochromeImage(img)
+				}
+			}
+		}
+		return base64.StdEncoding.EncodeToString(data), info, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", info, fmt.Errorf("error decoding image: %v", err)
+	}
+	// Some containers (ICO) report one entry in their header but decode another, so check again
+	if bounds := img.Bounds(); bounds.Dx() != info.Width || bounds.Dy() != info.Height {
+		if err := checkLimits(Info{Width: bounds.Dx(), Height: bounds.Dy()}); err != nil {
+			return "", info, err
+		}
+	}
+
+	info.Monochrome = monochromeImage(img)
+	img = opts.apply(img)
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := encoder.Encode(buf, img); err != nil {
+		return "", info, fmt.Errorf("error encoding PNG: %v", err)
+	}
+	info.Converted = true
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), info, nil
+}
+
+// bitDepth returns bits per pixel for the standard color models
+func bitDepth(model color.Model) int {
+	switch model {
+	case color.GrayModel, color.AlphaModel:
+		return 8
+	case color.Gray16Model, color.Alpha16Model:
+		return 16
+	case color.YCbCrModel:
+		return 24
+	case color.RGBAModel, color.NRGBAModel, color.CMYKModel:
+		return 32
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 64
+	}
+	if palette, ok := model.(color.Palette); ok && len(palette) > 0 {
+		return max(int(math.Ceil(math.Log2(float64(len(palette))))), 1)
+	}
+	return 0
+}
+
+func grey(model color.Model) bool {
+	return model == color.GrayModel || model == color.Gray16Model
+}
+
+// monochromeImage reports whether the visible pixels of img are all grey or of a single hue
+func monochromeImage(img image.Image) bool {
+	bounds := img.Bounds()
+	tracker := hueTracker{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !tracker.add(img.At(x, y)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// monochromeColors reports whether a palette only holds greys or shades of a single hue
+func monochromeColors(palette color.Palette) bool {
+	tracker := hueTracker{}
+	for _, c := range palette {
+		if !tracker.add(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// hueTracker follows the range of hues seen so far among saturated, visible colors
+type hueTracker struct {
+	seen      bool
+	low, high float64
+}
+
+// add records c and reports whether the colors seen so far still fit within one hue
+func (t *hueTracker) add(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	// Nearly transparent pixels don't contribute to how the icon looks
+	if a < 0x1000 {
+		return true
+	}
+	hue, ok := saturatedHue(r, g, b)
+	if !ok {
+		return true
+	}
+	if !t.seen {
+		t.seen, t.low, t.high = true, hue, hue
+		return true
+	}
+	// Hues wrap at 360 degrees, so measure against the nearest side of the range seen so far
+	for hue < t.low-180 {
+		hue += 360
+	}
+	for hue > t.high+180 {
+		hue -= 360
+	}
+	t.low, t.high = math.Min(t.low, hue), math.Max(t.high, hue)
+	return t.high-t.low <= hueTolerance
+}
+
+// saturatedHue returns the hue of a color in degrees, or false for greys whose hue is meaningless
+func saturatedHue(r, g, b uint32) (float64, bool) {
+	maxC, minC := max(r, g, b), min(r, g, b)
+	// Treat colors whose channels differ by under ~8% of full scale as grey
+	if maxC-minC < 0x1400 {
+		return 0, false
+	}
+	delta := float64(maxC - minC)
+	var hue float64
+	switch maxC {
+	case r:
+		hue = math.Mod((float64(g)-float64(b))/delta, 6)
+	case g:
+		hue = (float64(b)-float64(r))/delta + 2
+	default:
+		hue = (float64(r)-float64(g))/delta + 4
+	}
+	hue *= 60
+	if hue < 0 {
+		hue += 360
+	}
+	return hue, true
+}
@@ -0,0 +1,127 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// tinyPNG encodes a 1x1 opaque red PNG
+func tinyPNG(t testing.TB) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tinyICO wraps a 1x1 PNG in a single-entry ICO container
+func tinyICO(t testing.TB) []byte {
+	t.Helper()
+	payload := tinyPNG(t)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, 1})
+	buf.Write([]byte{1, 1, 0, 0})
+	binary.Write(&buf, binary.LittleEndian, [2]uint16{1, 32})
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(payload)), 22})
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+// hugePNGHeader is a PNG signature and IHDR chunk declaring a width x height image, with no pixel data
+func hugePNGHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 0, 17)
+	ihdr = append(ihdr, "IHDR"...)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
+
+func FuzzBase64PNG(f *testing.F) {
+	valid := tinyPNG(f)
+	f.Add(valid)
+	f.Add(valid[:len(valid)/2])
+	f.Add(tinyICO(f))
+	f.Add(hugePNGHeader(65535, 65535))
+	f.Add(append(hugePNGHeader(1, 1), make([]byte, MaxInputSize)...))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range []Options{{}, {AutoCrop: true, Background: "white", Greyscale: true}} {
+			b64, info, err := Base64PNG(data, opts)
+
+			if len(data) > MaxInputSize {
+				if err == nil || !strings.Contains(err.Error(), "byte limit") {
+					t.Fatalf("%d byte input not rejected by MaxInputSize: %v", len(data), err)
+				}
+				continue
+			}
+			config, _, configErr := image.DecodeConfig(bytes.NewReader(data))
+			if configErr == nil && (config.Width > MaxDimension || config.Height > MaxDimension) {
+				if err == nil || !strings.Contains(err.Error(), "exceed the") {
+					t.Fatalf("%dx%d header not rejected by MaxDimension: %v", config.Width, config.Height, err)
+				}
+				continue
+			}
+			if err != nil {
+				if b64 != "" {
+					t.Fatalf("error %v returned with output", err)
+				}
+				continue
+			}
+
+			if info.Width > MaxDimension || info.Height > MaxDimension {
+				t.Fatalf("accepted %dx%d icon", info.Width, info.Height)
+			}
+			encoded, err := base64.StdEncoding.DecodeString(b64)
+			if err != nil {
+				t.Fatalf("output isn't base64: %v", err)
+			}
+			if _, format, err := image.DecodeConfig(bytes.NewReader(encoded)); err != nil || format != "png" {
+				t.Fatalf("output isn't a PNG: format %q, %v", format, err)
+			}
+		}
+	})
+}
+
+func TestBase64PNGSeeds(t *testing.T) {
+	valid := tinyPNG(t)
+	tests := []struct {
+		name string
+		data []byte
+		// err is a substring of the expected error, "" when the icon should convert
+		err string
+	}{
+		{"png", valid, ""},
+		{"ico", tinyICO(t), ""},
+		{"truncated png", valid[:len(valid)/2], "error decoding image"},
+		{"huge dimensions", hugePNGHeader(65535, 65535), "exceed the 4096x4096 limit"},
+		{"huge body", append(hugePNGHeader(1, 1), make([]byte, MaxInputSize)...), "exceeding the 8388608 byte limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b64, info, err := Base64PNG(tt.data, Options{})
+			if tt.err == "" {
+				if err != nil || b64 == "" || info.Width != 1 || info.Height != 1 {
+					t.Fatalf("got %dx%d, %v; want a 1x1 PNG", info.Width, info.Height, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
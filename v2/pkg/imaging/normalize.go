@@ -0,0 +1,187 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// Backgrounds that transparent icons can be flattened onto
+const (
+	BackgroundWhite   = "white"
+	BackgroundBlack   = "black"
+	BackgroundChecker = "checker"
+)
+
+// Backgrounds lists every value accepted for Options.Background
+var Backgrounds = []string{BackgroundWhite, BackgroundBlack, BackgroundChecker}
+
+// checkerSize is the side of one checkerboard square in pixels
+const checkerSize = 8
+
+// cropTolerance is how far, per 16-bit channel, a pixel may differ from the border color and still be trimmed
+// as padding; it absorbs compression noise around otherwise uniform borders
+const cropTolerance = 0x0c00
+
+// Options controls how icons are normalised before they are sent to the model, so the same logo
+// compares the same way however it was exported
+type Options struct {
+	// AutoCrop trims uniform borders, including transparent padding, so a padded icon lines up with a tight one
+	AutoCrop bool
+	// Background flattens transparency onto white, black or a checkerboard; empty keeps the alpha channel
+	Background string
+	// Greyscale drops color so recolored variants of a logo, such as dark-mode or hue-shifted copies, still match
+	Greyscale bool
+}
+
+// ValidateBackground reports whether background is empty or one of Backgrounds
+func ValidateBackground(background string) error {
+	if background == "" {
+		return nil
+	}
+	for _, b := range Backgrounds {
+		if background == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported background '%s' (supported: %s)", background, strings.Join(Backgrounds, ", "))
+}
+
+// transforms reports whether any normalisation is enabled
+func (o Options) transforms() bool {
+	return o.AutoCrop || o.Background != "" || o.Greyscale
+}
+
+// apply runs the enabled normalisation steps on img
+func (o Options) apply(img image.Image) image.Image {
+	// Crop first so transparent padding is trimmed before it is filled with the background
+	if o.AutoCrop {
+		img = autoCrop(img)
+	}
+	if o.Background != "" {
+		img = flatten(img, o.Background)
+	}
+	if o.Greyscale {
+		img = greyscale(img)
+	}
+	return img
+}
+
+// flatten composites img over background; fully opaque icons are returned unchanged
+func flatten(img image.Image, background string) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	switch background {
+	case BackgroundBlack:
+		draw.Draw(dst, bounds, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	case BackgroundChecker:
+		draw.Draw(dst, bounds, checker{}, image.Point{}, draw.Src)
+	default:
+		draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
+
+// greyscale converts img to luminance, keeping its alpha channel so transparent areas stay transparent
+func greyscale(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			grey := color.GrayModel.Convert(color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}).(color.Gray).Y
+			dst.SetNRGBA(x, y, color.NRGBA{R: grey, G: grey, B: grey, A: c.A})
+		}
+	}
+	return dst
+}
+
+// checker is an endless checkerboard of white and light grey squares, the usual rendering of transparency
+type checker struct{}
+
+func (checker) ColorModel() color.Model { return color.GrayModel }
+
+func (checker) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (checker) At(x, y int) color.Color {
+	if (x/checkerSize+y/checkerSize)%2 == 0 {
+		return color.Gray{Y: 0xff}
+	}
+	return color.Gray{Y: 0xcc}
+}
+
+// autoCrop trims rows and columns on every side that match the top-left pixel. Icons that are uniform
+// throughout are returned unchanged.
+func autoCrop(img image.Image) image.Image {
+	bounds := img.Bounds()
+	border := img.At(bounds.Min.X, bounds.Min.Y)
+	padding := func(x, y int) bool { return sameColor(img.At(x, y), border) }
+
+	rowIsPadding := func(y int) bool {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !padding(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	colIsPadding := func(x, minY, maxY int) bool {
+		for y := minY; y < maxY; y++ {
+			if !padding(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	top, bottom := bounds.Min.Y, bounds.Max.Y
+	for top < bottom && rowIsPadding(top) {
+		top++
+	}
+	if top == bottom {
+		return img
+	}
+	for bottom > top && rowIsPadding(bottom-1) {
+		bottom--
+	}
+	left, right := bounds.Min.X, bounds.Max.X
+	for left < right && colIsPadding(left, top, bottom) {
+		left++
+	}
+	for right > left && colIsPadding(right-1, top, bottom) {
+		right--
+	}
+
+	crop := image.Rect(left, top, right, bottom)
+	if crop == bounds {
+		return img
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, crop.Min, draw.Src)
+	return dst
+}
+
+// sameColor compares two colors within cropTolerance; fully transparent pixels match whatever their color
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	if a1 <= cropTolerance && a2 <= cropTolerance {
+		return true
+	}
+	return near(r1, r2) && near(g1, g2) && near(b1, b2) && near(a1, a2)
+}
+
+func near(a, b uint32) bool {
+	if a > b {
+		return a-b <= cropTolerance
+	}
+	return b-a <= cropTolerance
+}
@@ -0,0 +1,131 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	textunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Reasons input lines are skipped
+const (
+	SkipBlank   = "blank"
+	SkipComment = "comment"
+	SkipInvalid = "invalid text"
+)
+
+var skipReasons = []string{SkipComment, SkipInvalid, SkipBlank}
+
+// maxSkipExamples bounds the line references kept per reason
+const maxSkipExamples = 5
+
+// trailingComment is a "#" comment after a URL; URLs can't contain raw whitespace, so a fragment is never matched
+var trailingComment = regexp.MustCompile(`\s+#.*$`)
+
+// Skip counts the input lines skipped for one reason, with the first few as file:line references
+type Skip struct {
+	Reason string
+	Count  int
+	Lines  []string
+}
+
+// skipLog records skipped lines as the input is read
+type skipLog struct {
+	counts   map[string]int
+	examples map[string][]string
+}
+
+func (s *skipLog) add(reason, name string, line int) {
+	s.init()
+	s.counts[reason]++
+	if len(s.examples[reason]) < maxSkipExamples {
+		s.examples[reason] = append(s.examples[reason], fmt.Sprintf("%s:%d", name, line))
+	}
+}
+
+// merge adds the lines other skipped, as when a directory input moves on to its next file
+func (s *skipLog) merge(other *skipLog) {
+	s.init()
+	for reason, count := range other.counts {
+		s.counts[reason] += count
+		examples := other.examples[reason]
+		if room := maxSkipExamples - len(s.examples[reason]); len(examples) > room {
+			examples = examples[:room]
+		}
+		s.examples[reason] = append(s.examples[reason], examples...)
+	}
+}
+
+func (s *skipLog) init() {
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+		s.examples = make(map[string][]string)
+	}
+}
+
+// Skipped returns the lines skipped so far by reason, comments first and blank lines last
+func (r *Reader) Skipped() []Skip {
+	var skipped []Skip
+	for _, reason := range skipReasons {
+		if count := r.skipped.counts[reason]; count > 0 {
+			skipped = append(skipped, Skip{Reason: reason, Count: count, Lines: r.skipped.examples[reason]})
+		}
+	}
+	return skipped
+}
+
+// sourceName names a file in skipped line references
+func sourceName(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return filepath.Base(path)
+}
+
+// newTextSource drops a UTF-8 byte order mark and decodes UTF-16 text, as Windows editors save "Unicode" files,
+// so the line-based formats only ever see UTF-8
+func newTextSource(file io.Reader) io.Reader {
+	buffered := bufio.NewReader(file)
+	bom, _ := buffered.Peek(3)
+	switch {
+	case len(bom) >= 3 && bom[0] == 0xEF && bom[1] == 0xBB && bom[2] == 0xBF:
+		buffered.Discard(3)
+	case len(bom) >= 2 && bom[0] == 0xFF && bom[1] == 0xFE:
+		return transform.NewReader(buffered, textunicode.UTF16(textunicode.LittleEndian, textunicode.ExpectBOM).NewDecoder())
+	case len(bom) >= 2 && bom[0] == 0xFE && bom[1] == 0xFF:
+		return transform.NewReader(buffered, textunicode.UTF16(textunicode.BigEndian, textunicode.ExpectBOM).NewDecoder())
+	}
+	return buffered
+}
+
+// cleanLine trims whitespace, including the invisible characters that survive copying from documents and
+// spreadsheets, from a line of input. reason is set when the line holds nothing to scan.
+func cleanLine(line string) (clean, reason string) {
+	line = strings.TrimFunc(line, isBlank)
+	switch {
+	case line == "":
+		return "", SkipBlank
+	case strings.HasPrefix(line, "#"):
+		return "", SkipComment
+	case !utf8.ValidString(line) || strings.IndexFunc(line, isControl) != -1:
+		// Typically UTF-16 without a byte order mark, or a binary file given by mistake
+		return "", SkipInvalid
+	}
+	return line, ""
+}
+
+// isBlank reports whitespace, carriage returns included, and the zero-width characters that look like nothing
+func isBlank(r rune) bool {
+	return unicode.IsSpace(r) || r == '\uFEFF' || r == '\u200B' || r == '\u200C' || r == '\u200D' || r == '\u2060'
+}
+
+func isControl(r rune) bool {
+	return r != '\t' && unicode.IsControl(r)
+}
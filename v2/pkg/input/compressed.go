@@ -0,0 +1,140 @@
+package input
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// File signatures of the supported compression formats
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// compressionExtensions are dropped from a file name before its format is detected
+var compressionExtensions = []string{".gz", ".gzip", ".zst", ".zstd", ".zip"}
+
+// trimCompression drops a compression extension, so targets.csv.gz is read as CSV
+func trimCompression(name string) string {
+	ext := filepath.Ext(name)
+	for _, compressed := range compressionExtensions {
+		if strings.EqualFold(ext, compressed) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// isZip reports whether stream starts with a zip archive's local file header
+func isZip(stream *bufio.Reader) bool {
+	head, _ := stream.Peek(len(zipMagic))
+	return bytes.Equal(head, zipMagic)
+}
+
+// decompress wraps stream in a gzip or zstd decoder when it starts with one of their signatures, so compressed files
+// are recognised by content whatever they are named, stdin included. closer releases the decoder and is nil when
+// stream is returned as it is.
+func decompress(stream *bufio.Reader) (io.Reader, io.Closer, error) {
+	head, _ := stream.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		decoder, err := gzip.NewReader(stream)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decoder, decoder, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		decoder, err := zstd.NewReader(stream)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decoder, zstdCloser{decoder}, nil
+	case bytes.HasPrefix(head, zipMagic):
+		return nil, nil, fmt.Errorf("zip archives can't be nested in other archives")
+	}
+	return stream, nil, nil
+}
+
+// zstdCloser adapts the zstd decoder, whose Close returns nothing, to io.Closer
+type zstdCloser struct {
+	decoder *zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.decoder.Close()
+	return nil
+}
+
+// openZip reads the files in a zip archive one after another in name order, skipping directories and hidden entries
+// such as __MACOSX metadata. Each entry may itself be gzip or zstd compressed.
+func openZip(file *os.File, path, format string) (*Reader, error) {
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// Zip archives are indexed from the end, so a pipe can't be read; stdin redirected from a file can
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("zip input must be a regular file; pipe gzip or zstd instead")
+	}
+	archive, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read zip archive %s: %v", path, err)
+	}
+
+	var entries []*zip.File
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || hiddenEntry(entry.Name) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		file.Close()
+		return nil, fmt.Errorf("no input files found in zip archive %s", path)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	openers := make([]func() (*Reader, error), len(entries))
+	for i, entry := range entries {
+		openers[i] = func() (*Reader, error) {
+			content, err := entry.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s in zip archive %s: %v", entry.Name, path, err)
+			}
+			return openStream(bufio.NewReader(content), []io.Closer{content}, entry.Name, sourceName(path)+":"+entry.Name, format)
+		}
+	}
+	r, err := openers[0]()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.rest = openers[1:]
+	r.archives = append(r.archives, file)
+	return r, nil
+}
+
+// hiddenEntry reports archive entries under a dot directory or __MACOSX, or named with a leading dot
+func hiddenEntry(name string) bool {
+	for _, part := range strings.Split(path.Clean(name), "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package input
+
+import "hash/fnv"
+
+// Dedup remembers which targets were already dispatched, so URLs repeated in merged recon files are only
+// compared once. Targets are keyed by a 64-bit hash of their input URL and base URL, keeping multi-million
+// line inputs cheap; the first occurrence wins, along with its metadata.
+type Dedup struct {
+	seen       map[uint64]struct{}
+	duplicates int
+}
+
+func NewDedup() *Dedup {
+	return &Dedup{seen: make(map[uint64]struct{})}
+}
+
+// Add reports whether the target url compared against baseURL is new, recording it if so
+func (d *Dedup) Add(url, baseURL string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(url))
+	h.Write([]byte{0})
+	h.Write([]byte(baseURL))
+	key := h.Sum64()
+	if _, ok := d.seen[key]; ok {
+		d.duplicates++
+		return false
+	}
+	d.seen[key] = struct{}{}
+	return true
+}
+
+// Duplicates returns how many targets Add turned away
+func (d *Dedup) Duplicates() int {
+	return d.duplicates
+}
@@ -0,0 +1,258 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	imaging "github.com/ethicalhackingplayground/favlens/v2/pkg/imaging"
+	"github.com/richardlehane/mscfb"
+)
+
+// imgSrc finds image references in HTML bodies
+var imgSrc = regexp.MustCompile(`(?i)<img[^>]+src\s*=\s*["']([^"']+)["']`)
+
+// imageExtensions are the attachment names treated as images when no MIME type says so
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".webp": true, ".ico": true}
+
+// emailExtractor collects the images an email embeds or links to as targets.
+// Embedded images are written to dir so they can be loaded like any other local icon.
+type emailExtractor struct {
+	source  string
+	dir     string
+	targets []Target
+	seen    map[string]bool
+	count   int
+}
+
+// openEmail reads an .eml or Outlook .msg file up front; messages are small, so the targets are buffered
+func (r *Reader) openEmail(path string) error {
+	dir, err := os.MkdirTemp("", "favlens-email-")
+	if err != nil {
+		return err
+	}
+	r.tempDirs = append(r.tempDirs, dir)
+
+	e := &emailExtractor{source: filepath.Base(path), dir: dir, seen: make(map[string]bool)}
+	if strings.EqualFold(filepath.Ext(path), ".msg") {
+		err = e.readMSG(r.raw)
+	} else {
+		err = e.readEML(r.raw)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse email %s: %v", path, err)
+	}
+
+	targets := e.targets
+	r.next = func() (Target, error) {
+		if len(targets) == 0 {
+			return Target{}, io.EOF
+		}
+		target := targets[0]
+		targets = targets[1:]
+		return target, nil
+	}
+	return nil
+}
+
+// addEmbedded saves an embedded image and queues it as a target
+func (e *emailExtractor) addEmbedded(data []byte, filename, contentID string) error {
+	e.count++
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !imageExtensions[ext] {
+		ext = ".img"
+	}
+	path := filepath.Join(e.dir, fmt.Sprintf("image-%03d%s", e.count, ext))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	metadata := map[string]any{"email": e.source, "source": "embedded"}
+	if filename != "" {
+		metadata["filename"] = filename
+	}
+	if contentID != "" {
+		metadata["content_id"] = strings.Trim(contentID, "<>")
+	}
+	e.targets = append(e.targets, Target{URL: path, Image: true, Metadata: metadata})
+	return nil
+}
+
+// addLinks queues every remote image referenced by an HTML body; cid: references are the embedded parts
+func (e *emailExtractor) addLinks(html string) {
+	for _, match := range imgSrc.FindAllStringSubmatch(html, -1) {
+		link := strings.TrimSpace(match[1])
+		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+			continue
+		}
+		if e.seen[link] {
+			continue
+		}
+		e.seen[link] = true
+		e.targets = append(e.targets, Target{URL: link, Image: true, Metadata: map[string]any{"email": e.source, "source": "linked"}})
+	}
+}
+
+func (e *emailExtractor) readEML(file io.Reader) error {
+	msg, err := mail.ReadMessage(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+	return e.walkPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", "", msg.Body)
+}
+
+// walkPart descends through multipart bodies and forwarded messages, collecting images and HTML links
+func (e *emailExtractor) walkPart(contentType, encoding, disposition, contentID string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	body = decodeTransfer(encoding, body)
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = e.walkPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part.Header.Get("Content-ID"), part)
+			if err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		return e.readEML(body)
+	case strings.HasPrefix(mediaType, "image/"):
+		data, err := io.ReadAll(io.LimitReader(body, imaging.MaxInputSize+1))
+		if err != nil {
+			return err
+		}
+		filename := params["name"]
+		if _, dispParams, err := mime.ParseMediaType(disposition); err == nil && dispParams["filename"] != "" {
+			filename = dispParams["filename"]
+		}
+		return e.addEmbedded(data, filename, contentID)
+	case mediaType == "text/html":
+		html, err := io.ReadAll(io.LimitReader(body, imaging.MaxInputSize))
+		if err != nil {
+			return err
+		}
+		e.addLinks(string(html))
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// MAPI property streams of interest inside an Outlook .msg compound file
+const (
+	msgHTMLBody       = "__substg1.0_10130102"
+	msgAttachPrefix   = "__attach_version1.0_"
+	msgAttachData     = "__substg1.0_37010102"
+	msgAttachFilename = "__substg1.0_3707001F"
+	msgAttachMIME     = "__substg1.0_370E001F"
+	msgAttachCID      = "__substg1.0_3712001F"
+)
+
+type msgAttachment struct {
+	data      []byte
+	filename  string
+	mimeType  string
+	contentID string
+}
+
+func (e *emailExtractor) readMSG(file io.Reader) error {
+	// Compound files are read at random offsets, so a stream that can't seek, like a decompressed file, is buffered
+	at, ok := file.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		at = bytes.NewReader(data)
+	}
+	doc, err := mscfb.New(at)
+	if err != nil {
+		return err
+	}
+
+	attachments := make(map[string]*msgAttachment)
+	var order []string
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		// Only the message's own body and first-level attachments; embedded messages are skipped
+		switch {
+		case len(entry.Path) == 0 && entry.Name == msgHTMLBody:
+			html, err := io.ReadAll(io.LimitReader(entry, imaging.MaxInputSize))
+			if err != nil {
+				return err
+			}
+			e.addLinks(string(html))
+		case len(entry.Path) == 1 && strings.HasPrefix(entry.Path[0], msgAttachPrefix):
+			attachment, ok := attachments[entry.Path[0]]
+			if !ok {
+				attachment = &msgAttachment{}
+				attachments[entry.Path[0]] = attachment
+				order = append(order, entry.Path[0])
+			}
+			value, err := io.ReadAll(io.LimitReader(entry, imaging.MaxInputSize+1))
+			if err != nil {
+				return err
+			}
+			switch entry.Name {
+			case msgAttachData:
+				attachment.data = value
+			case msgAttachFilename:
+				attachment.filename = decodeUTF16(value)
+			case msgAttachMIME:
+				attachment.mimeType = decodeUTF16(value)
+			case msgAttachCID:
+				attachment.contentID = decodeUTF16(value)
+			}
+		}
+	}
+
+	for _, key := range order {
+		attachment := attachments[key]
+		isImage := strings.HasPrefix(strings.ToLower(attachment.mimeType), "image/") || imageExtensions[strings.ToLower(filepath.Ext(attachment.filename))]
+		if !isImage || len(attachment.data) == 0 {
+			continue
+		}
+		if err := e.addEmbedded(attachment.data, attachment.filename, attachment.contentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeUTF16 converts a little-endian UTF-16 MAPI string property
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])|uint16(b[i+1])<<8)
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
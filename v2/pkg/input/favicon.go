@@ -0,0 +1,55 @@
+package input
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// iconExtensions mark URLs that already point at an icon rather than a page
+var iconExtensions = map[string]bool{".ico": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true}
+
+// FaviconURLs returns the icon URLs to try for a target, in order. Image targets and URLs whose path is
+// already an image are used as they are; for pages the favicon is fetched from the origin root, ignoring
+// the path, query and fragment. With pageDir, favicon.ico in the page's own directory is tried next, for
+// apps hosted under a path with their own icon.
+func FaviconURLs(raw string, image, pageDir bool) []string {
+	if image {
+		return []string{raw}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return []string{legacyFaviconURL(raw)}
+	}
+	if isIconPath(u.Path) {
+		return []string{raw}
+	}
+
+	origin := &url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}
+	urls := []string{origin.JoinPath("favicon.ico").String()}
+	if pageDir {
+		dir := u.Path
+		if !strings.HasSuffix(dir, "/") {
+			dir = path.Dir(dir)
+		}
+		if dir = strings.Trim(dir, "/"); dir != "" && dir != "." {
+			urls = append(urls, origin.JoinPath(dir, "favicon.ico").String())
+		}
+	}
+	return urls
+}
+
+// isIconPath reports whether a URL path names an image or a favicon
+func isIconPath(p string) bool {
+	p = strings.ToLower(p)
+	return strings.Contains(p, "favicon") || iconExtensions[path.Ext(p)]
+}
+
+// legacyFaviconURL appends /favicon.ico to targets that aren't http(s) URLs, such as bare hostnames,
+// unless they already look like an icon
+func legacyFaviconURL(raw string) string {
+	if isIconPath(raw) {
+		return raw
+	}
+	return strings.TrimSuffix(raw, "/") + "/favicon.ico"
+}
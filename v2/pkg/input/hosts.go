@@ -0,0 +1,76 @@
+package input
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// plainPorts usually serve plain http, so it is probed first; every other port tries https, then http
+var plainPorts = map[string]bool{"80": true, "8000": true, "8008": true, "8080": true, "8888": true}
+
+// openHosts reads bare hostnames, one per line, as subfinder, amass and assetfinder print them. Lines may carry
+// a port (host:port) and anything after the first field, such as amass's addresses, is ignored. Targets keep
+// the host as their URL; HostURLs gives the URLs to probe for it.
+func (r *Reader) openHosts() error {
+	scanner := bufio.NewScanner(r.src)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	number := 0
+	r.next = func() (Target, error) {
+		for scanner.Scan() {
+			number++
+			line, reason := cleanLine(scanner.Text())
+			if reason != "" {
+				r.skipped.add(reason, r.name, number)
+				continue
+			}
+			fields := strings.Fields(line)
+			host := strings.TrimSuffix(strings.TrimPrefix(fields[0], "*."), ".")
+			if host == "" {
+				continue
+			}
+			return Target{URL: host}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Target{}, err
+		}
+		return Target{}, io.EOF
+	}
+	return nil
+}
+
+// HostURLs returns the URLs to probe for a host read from hostname input, in the order to try them. Hosts
+// that already name a scheme are used as they are; the default port of each scheme is left out.
+func HostURLs(host string) []string {
+	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
+		return []string{host}
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = strings.Trim(host, "[]"), ""
+	}
+	schemes := []string{"https", "http"}
+	if plainPorts[port] {
+		schemes = []string{"http", "https"}
+	}
+	urls := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		u := &url.URL{Scheme: scheme, Host: hostPort(name, port, scheme), Path: "/"}
+		urls = append(urls, u.String())
+	}
+	return urls
+}
+
+// hostPort joins name and port, leaving out the scheme's default port
+func hostPort(name, port, scheme string) string {
+	if port == "" || (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		if strings.Contains(name, ":") {
+			return "[" + name + "]"
+		}
+		return name
+	}
+	return net.JoinHostPort(name, port)
+}
@@ -0,0 +1,94 @@
+package input
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// httpxFields are the httpx result fields kept as metadata; the rest of each record is mostly probe detail
+var httpxFields = []string{"title", "status_code", "webserver"}
+
+// httpxRecord is the part of an httpx JSON result favlens reads
+type httpxRecord struct {
+	URL        string          `json:"url"`
+	Favicon    json.RawMessage `json:"favicon"`
+	FaviconURL string          `json:"favicon_url"`
+}
+
+// openHTTPX reads httpx JSON lines output (`httpx -favicon -json`). The favicon hash httpx computed is kept on the
+// target so identical icons can be decided without downloading them again, and the icon httpx found is
+// scanned when it reports one.
+func (r *Reader) openHTTPX() error {
+	scanner := bufio.NewScanner(r.src)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	line := 0
+	r.next = func() (Target, error) {
+		for scanner.Scan() {
+			line++
+			text, reason := cleanLine(scanner.Text())
+			if reason != "" {
+				r.skipped.add(reason, r.name, line)
+				continue
+			}
+			data := []byte(text)
+			var record httpxRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return Target{}, fmt.Errorf("failed to parse httpx result on line %d: %v", line, err)
+			}
+			if strings.TrimSpace(record.URL) == "" {
+				continue
+			}
+			target := Target{URL: strings.TrimSpace(record.URL)}
+			if record.FaviconURL != "" {
+				target.URL, target.Image = record.FaviconURL, true
+			}
+			hash, err := httpxHash(record.Favicon)
+			if err != nil {
+				return Target{}, fmt.Errorf("invalid favicon hash on line %d: %v", line, err)
+			}
+			target.FaviconHash = hash
+
+			var fields map[string]any
+			if err := json.Unmarshal(data, &fields); err == nil {
+				for _, name := range httpxFields {
+					if value, ok := fields[name]; ok {
+						if target.Metadata == nil {
+							target.Metadata = make(map[string]any)
+						}
+						target.Metadata[name] = value
+					}
+				}
+			}
+			return target, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Target{}, err
+		}
+		return Target{}, io.EOF
+	}
+	return nil
+}
+
+// httpxHash accepts the favicon hash as httpx writes it, a quoted number, or as a plain number
+func httpxHash(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var hash string
+	if err := json.Unmarshal(raw, &hash); err != nil {
+		hash = string(raw)
+	}
+	hash = strings.TrimSpace(hash)
+	if hash == "" {
+		return "", nil
+	}
+	if _, err := strconv.ParseInt(hash, 10, 32); err != nil {
+		return "", fmt.Errorf("'%s' is not an mmh3 hash", hash)
+	}
+	return hash, nil
+}
@@ -0,0 +1,538 @@
+package input
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Supported input formats
+const (
+	FormatAuto  = "auto"
+	FormatText  = "text"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatEmail = "email"
+	FormatHTTPX = "httpx"
+	FormatHosts = "hosts"
+)
+
+// Formats lists every format accepted by Open
+var Formats = []string{FormatAuto, FormatText, FormatCSV, FormatJSON, FormatEmail, FormatHTTPX, FormatHosts}
+
+// priorityColumn is the CSV column / JSON field holding a target's scheduling priority
+const priorityColumn = "priority"
+
+// baseColumns are the CSV columns / JSON fields that override the base favicon for a single target
+var baseColumns = []string{"base", "base_url", "base_icon_url"}
+
+// maxLineSize bounds a single line of text input
+const maxLineSize = 1024 * 1024
+
+// Target is a single URL to scan plus any metadata supplied alongside it
+type Target struct {
+	URL string
+	// BaseURL overrides the global base favicon for this target when non-empty
+	BaseURL string
+	// Priority orders dispatch; higher values are scanned first
+	Priority int
+	// Image marks URLs that already point at an image, so no /favicon.ico is appended
+	Image bool
+	// FaviconHash is the mmh3 favicon hash reported for the target by httpx, empty when unknown
+	FaviconHash string
+	Metadata    map[string]any
+}
+
+func isBaseColumn(name string) bool {
+	for _, c := range baseColumns {
+		if strings.EqualFold(name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFormat reports whether format is supported by Open
+func ValidateFormat(format string) error {
+	for _, f := range Formats {
+		if strings.EqualFold(format, f) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported input format '%s' (supported: %s)", format, strings.Join(Formats, ", "))
+}
+
+// DetectFormat picks an input format from the file extension, looking past a compression extension such as .gz
+func DetectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(trimCompression(path))) {
+	case ".csv":
+		return FormatCSV
+	case ".json", ".jsonl", ".ndjson":
+		return FormatJSON
+	case ".eml", ".msg":
+		return FormatEmail
+	default:
+		return FormatText
+	}
+}
+
+// Reader streams targets from an input file one at a time so memory use does not grow with the file size
+type Reader struct {
+	// raw is the current file's content, decompressed; closers release the file when the reader moves on
+	raw     io.Reader
+	closers []io.Closer
+	// src is raw with any byte order mark dropped and UTF-16 decoded, for the line-based formats
+	src  io.Reader
+	name string
+	next func() (Target, error)
+	// prioritized is set when the input carries a priority column
+	prioritized bool
+	// pending holds a JSON target that was read ahead to detect the priority field
+	pending *Target
+	// rest queues the remaining files of a directory or zip archive
+	rest []func() (*Reader, error)
+	// archives stay open until Close, since the zip entries queued in rest read from them
+	archives []io.Closer
+	// tempDirs hold images extracted from emails; they live until Close since queued jobs still read them
+	tempDirs []string
+	// skipped records blank, comment and unreadable lines across every file read
+	skipped *skipLog
+}
+
+// Open prepares a streaming Reader for path using the given format.
+// A directory, such as a mounted Kubernetes ConfigMap, is read file by file in name order, and "-" reads stdin.
+// gzip and zstd files are decompressed as they stream, and a zip archive is read entry by entry like a directory.
+func Open(path, format string) (*Reader, error) {
+	if path == "-" {
+		return newReader(os.Stdin, path, format)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return openFile(path, format)
+	}
+
+	paths, err := listDir(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no input files found in directory %s", path)
+	}
+	r, err := openFile(paths[0], format)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths[1:] {
+		r.rest = append(r.rest, func() (*Reader, error) {
+			return openFile(path, format)
+		})
+	}
+	return r, nil
+}
+
+// listDir returns the regular files in dir, skipping hidden entries like the "..data" links ConfigMap volumes contain
+func listDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// ConfigMap keys are symlinks, so stat the target rather than trusting the entry type
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// openFile prepares a streaming Reader for a single file
+func openFile(path, format string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return newReader(file, path, format)
+}
+
+// newReader prepares a streaming Reader over an open file, which it closes when done
+func newReader(file *os.File, path, format string) (*Reader, error) {
+	buffered := bufio.NewReader(file)
+	if isZip(buffered) {
+		return openZip(file, path, format)
+	}
+	return openStream(buffered, []io.Closer{file}, path, sourceName(path), format)
+}
+
+// openStream prepares a streaming Reader over the content of a file, decompressing it first when it is gzip or zstd.
+// path picks the format when none is given, and name identifies the file in skipped line references.
+func openStream(stream *bufio.Reader, closers []io.Closer, path, name, format string) (*Reader, error) {
+	raw, closer, err := decompress(stream)
+	if err != nil {
+		closeAll(closers)
+		return nil, fmt.Errorf("failed to decompress %s: %v", name, err)
+	}
+	if closer != nil {
+		closers = append([]io.Closer{closer}, closers...)
+	}
+	path = trimCompression(path)
+	if format == "" || strings.EqualFold(format, FormatAuto) {
+		format = DetectFormat(path)
+	}
+
+	r := &Reader{raw: raw, closers: closers, name: name, skipped: &skipLog{}}
+	// Emails are parsed from the raw bytes; every other format is text
+	if !strings.EqualFold(format, FormatEmail) {
+		r.src = newTextSource(raw)
+	}
+	switch strings.ToLower(format) {
+	case FormatText:
+		err = r.openText()
+	case FormatCSV:
+		err = r.openCSV()
+	case FormatJSON:
+		err = r.openJSON()
+	case FormatEmail:
+		err = r.openEmail(path)
+	case FormatHTTPX:
+		err = r.openHTTPX()
+	case FormatHosts:
+		err = r.openHosts()
+	default:
+		err = ValidateFormat(format)
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Next returns the next target, or io.EOF once the input is exhausted
+func (r *Reader) Next() (Target, error) {
+	for {
+		if r.pending != nil {
+			target := *r.pending
+			r.pending = nil
+			return target, nil
+		}
+		target, err := r.next()
+		if err != io.EOF || len(r.rest) == 0 {
+			return target, err
+		}
+
+		// Move on to the next file of a directory or archive; a zip inside a directory queues its entries first
+		next, err := r.rest[0]()
+		if err != nil {
+			return Target{}, err
+		}
+		closeAll(r.closers)
+		rest, prioritized, archives, tempDirs, skipped := r.rest[1:], r.prioritized, r.archives, r.tempDirs, r.skipped
+		// next's line readers record skipped lines through its own pointer, so point it at the shared log
+		skipped.merge(next.skipped)
+		next.skipped = skipped
+		*r = *next
+		r.rest, r.prioritized, r.skipped = append(next.rest, rest...), prioritized, skipped
+		r.archives, r.tempDirs = append(archives, next.archives...), append(tempDirs, next.tempDirs...)
+	}
+}
+
+// Prioritized reports whether the input declares a priority column (CSV header or first JSON object).
+// For directory input only the first file is inspected.
+func (r *Reader) Prioritized() bool {
+	return r.prioritized
+}
+
+func (r *Reader) Close() error {
+	for _, dir := range r.tempDirs {
+		os.RemoveAll(dir)
+	}
+	err := closeAll(r.closers)
+	if archiveErr := closeAll(r.archives); err == nil {
+		err = archiveErr
+	}
+	return err
+}
+
+// closeAll closes every closer in order and returns the first error
+func closeAll(closers []io.Closer) error {
+	var first error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// ReadAll drains the reader into a slice
+func (r *Reader) ReadAll() ([]Target, error) {
+	var targets []Target
+	for {
+		target, err := r.Next()
+		if err == io.EOF {
+			return targets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+}
+
+// ReadTargets reads all targets from path using the given format
+func ReadTargets(path, format string) ([]Target, error) {
+	r, err := Open(path, format)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.ReadAll()
+}
+
+// openText treats every non-empty line as a URL, optionally followed by ",<base_icon_url>". Lines starting with
+// "#" are comments, as is anything after whitespace and "#" on a URL's line.
+func (r *Reader) openText() error {
+	scanner := bufio.NewScanner(r.src)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	number := 0
+	r.next = func() (Target, error) {
+		for scanner.Scan() {
+			number++
+			line, reason := cleanLine(trailingComment.ReplaceAllString(scanner.Text(), ""))
+			if reason != "" {
+				r.skipped.add(reason, r.name, number)
+				continue
+			}
+			target := Target{URL: line}
+			// Only split when the second field looks like a URL, since target URLs may contain commas themselves
+			if i := strings.LastIndex(line, ","); i != -1 {
+				base := strings.TrimSpace(line[i+1:])
+				if strings.HasPrefix(base, "http://") || strings.HasPrefix(base, "https://") {
+					target.URL = strings.TrimSpace(line[:i])
+					target.BaseURL = base
+				}
+			}
+			return target, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Target{}, err
+		}
+		return Target{}, io.EOF
+	}
+	return nil
+}
+
+// openCSV expects a header row with a "url" column; an optional base column overrides the base favicon and every other column is kept as metadata.
+// Rows whose first cell starts with "#" are comments, before the header as well as after it.
+func (r *Reader) openCSV() error {
+	reader := csv.NewReader(r.src)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var header []string
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read CSV header: %v", err)
+		}
+		if !csvComment(record) {
+			header = record
+			break
+		}
+		number, _ := reader.FieldPos(0)
+		r.skipped.add(SkipComment, r.name, number)
+	}
+	urlColumn := -1
+	for i, name := range header {
+		header[i] = strings.TrimFunc(name, isBlank)
+		if strings.EqualFold(header[i], "url") {
+			urlColumn = i
+		}
+		if strings.EqualFold(header[i], priorityColumn) {
+			r.prioritized = true
+		}
+	}
+	if urlColumn == -1 {
+		return fmt.Errorf("CSV header has no 'url' column")
+	}
+
+	line := 1
+	r.next = func() (Target, error) {
+		for {
+			line++
+			record, err := reader.Read()
+			if err == io.EOF {
+				return Target{}, io.EOF
+			}
+			if err != nil {
+				return Target{}, fmt.Errorf("failed to read CSV line %d: %v", line, err)
+			}
+			number, _ := reader.FieldPos(0)
+			if csvComment(record) {
+				r.skipped.add(SkipComment, r.name, number)
+				continue
+			}
+			url, reason := "", SkipBlank
+			if urlColumn < len(record) {
+				url, reason = cleanLine(record[urlColumn])
+			}
+			if reason != "" {
+				r.skipped.add(reason, r.name, number)
+				continue
+			}
+
+			target := Target{URL: url}
+			for i, value := range record {
+				if i == urlColumn || i >= len(header) || header[i] == "" {
+					continue
+				}
+				if isBaseColumn(header[i]) {
+					target.BaseURL = strings.TrimSpace(value)
+					continue
+				}
+				if strings.EqualFold(header[i], priorityColumn) {
+					if target.Priority, err = parsePriority(value); err != nil {
+						return Target{}, fmt.Errorf("invalid priority on CSV line %d: %v", line, err)
+					}
+					continue
+				}
+				if target.Metadata == nil {
+					target.Metadata = make(map[string]any)
+				}
+				target.Metadata[header[i]] = value
+			}
+			return target, nil
+		}
+	}
+	return nil
+}
+
+// csvComment reports a CSV row whose first cell starts with "#"
+func csvComment(record []string) bool {
+	return len(record) > 0 && strings.HasPrefix(strings.TrimFunc(record[0], isBlank), "#")
+}
+
+// openJSON accepts either a JSON array of objects or JSON lines; each object needs a "url" field
+func (r *Reader) openJSON() error {
+	buffered := bufio.NewReader(r.src)
+	decoder := json.NewDecoder(buffered)
+
+	// Skip leading whitespace to tell an array apart from JSON lines
+	isArray := false
+	for {
+		b, err := buffered.Peek(1)
+		if err != nil {
+			break
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			buffered.ReadByte()
+			continue
+		}
+		isArray = b[0] == '['
+		break
+	}
+	if isArray {
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("failed to parse JSON array: %v", err)
+		}
+	}
+
+	row := 0
+	first := true
+	r.next = func() (Target, error) {
+		for decoder.More() {
+			row++
+			var object map[string]any
+			if err := decoder.Decode(&object); err != nil {
+				return Target{}, fmt.Errorf("failed to parse JSON object %d: %v", row, err)
+			}
+			_, hasPriority := object[priorityColumn]
+			target, ok, err := targetFromObject(object)
+			if err != nil {
+				return Target{}, err
+			}
+			if ok {
+				if first {
+					r.prioritized = hasPriority
+					first = false
+				}
+				return target, nil
+			}
+		}
+		return Target{}, io.EOF
+	}
+
+	// Read the first target ahead so the presence of a priority field is known up front
+	target, err := r.next()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.pending = &target
+	return nil
+}
+
+// targetFromObject converts a decoded JSON object into a Target; ok is false when it has no url
+func targetFromObject(object map[string]any) (Target, bool, error) {
+	url, _ := object["url"].(string)
+	url = strings.TrimFunc(url, isBlank)
+	if url == "" {
+		return Target{}, false, nil
+	}
+	delete(object, "url")
+	target := Target{URL: url}
+	for _, column := range baseColumns {
+		if base, ok := object[column].(string); ok {
+			target.BaseURL = strings.TrimSpace(base)
+			delete(object, column)
+		}
+	}
+	if priority, ok := object[priorityColumn]; ok {
+		var err error
+		if target.Priority, err = parsePriority(priority); err != nil {
+			return Target{}, false, fmt.Errorf("invalid priority for %s: %v", url, err)
+		}
+		delete(object, priorityColumn)
+	}
+	if len(object) > 0 {
+		target.Metadata = object
+	}
+	return target, true, nil
+}
+
+// parsePriority accepts integers given either as numbers or strings; empty values mean priority 0
+func parsePriority(value any) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(v), nil
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return 0, nil
+		}
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("unexpected type %T", value)
+	}
+}
@@ -0,0 +1,83 @@
+package input
+
+import (
+	"hash/fnv"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// OriginReducer collapses crawled URL dumps, such as katana, gau or waybackurls output, to one target per
+// origin. Every path, query and asset URL on a host shares the icon at its root, so only the first URL of each
+// scheme, host and port is kept, rewritten to the origin itself. Origins are keyed by a 64-bit hash, so
+// multi-million line dumps stay cheap.
+type OriginReducer struct {
+	seen map[uint64]struct{}
+	// urls counts every URL read, origins the ones kept
+	urls, origins int
+}
+
+func NewOriginReducer() *OriginReducer {
+	return &OriginReducer{seen: make(map[uint64]struct{})}
+}
+
+// Reduce returns a target source yielding the first target of each origin from next. URLs that aren't
+// http(s), such as bare hosts, are passed through as they are.
+func (o *OriginReducer) Reduce(next func() (Target, error)) func() (Target, error) {
+	return func() (Target, error) {
+		for {
+			target, err := next()
+			if err != nil {
+				return target, err
+			}
+			o.urls++
+			origin, ok := Origin(target.URL)
+			if !ok {
+				o.origins++
+				return target, nil
+			}
+			h := fnv.New64a()
+			h.Write([]byte(origin))
+			h.Write([]byte{0})
+			h.Write([]byte(target.BaseURL))
+			key := h.Sum64()
+			if _, ok := o.seen[key]; ok {
+				continue
+			}
+			o.seen[key] = struct{}{}
+			o.origins++
+			target.URL, target.Image = origin, false
+			return target, nil
+		}
+	}
+}
+
+// URLs returns how many URLs were read
+func (o *OriginReducer) URLs() int {
+	return o.urls
+}
+
+// Origins returns how many targets were kept
+func (o *OriginReducer) Origins() int {
+	return o.origins
+}
+
+// Origin returns the scheme, host and port of an http(s) URL as a URL with an empty path, lowercasing the
+// host and leaving out default ports and credentials
+func Origin(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return (&url.URL{Scheme: scheme, Host: host, Path: "/"}).String(), true
+}
@@ -0,0 +1,40 @@
+package input
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Prioritize reorders targets in place so high-value targets are dispatched first.
+// Targets whose URL matches any of the patterns come before the rest; within each
+// group targets with a higher Priority come first, and input order is otherwise kept.
+func Prioritize(targets []Target, patterns []*regexp.Regexp) {
+	matched := make([]bool, len(targets))
+	for i, target := range targets {
+		for _, re := range patterns {
+			if re.MatchString(target.URL) {
+				matched[i] = true
+				break
+			}
+		}
+	}
+
+	// Sort an index permutation so the matched flags stay aligned with their targets
+	order := make([]int, len(targets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ia, ib := order[a], order[b]
+		if matched[ia] != matched[ib] {
+			return matched[ia]
+		}
+		return targets[ia].Priority > targets[ib].Priority
+	})
+
+	sorted := make([]Target, len(targets))
+	for i, idx := range order {
+		sorted[i] = targets[idx]
+	}
+	copy(targets, sorted)
+}
@@ -0,0 +1,53 @@
+package input
+
+import "strings"
+
+// RetryFilter picks the targets whose scan failed out of favlens JSON output, as read with FormatJSON, so a run
+// can be repeated for just those. Each is rebuilt from the result's url, base_url and metadata; the rest of the
+// result, such as its error and timestamp, is dropped.
+type RetryFilter struct {
+	base string
+	// results counts every result read, failed the ones that are scanned again
+	results, failed int
+}
+
+// NewRetryFilter drops base_url values equal to base, the run's own base favicon, so only real per-target
+// overrides are kept
+func NewRetryFilter(base string) *RetryFilter {
+	return &RetryFilter{base: base}
+}
+
+// Filter returns a target source yielding the failed results of next as targets
+func (f *RetryFilter) Filter(next func() (Target, error)) func() (Target, error) {
+	return func() (Target, error) {
+		for {
+			result, err := next()
+			if err != nil {
+				return result, err
+			}
+			f.results++
+			if message, _ := result.Metadata["error"].(string); strings.TrimSpace(message) == "" {
+				continue
+			}
+			f.failed++
+			target := Target{URL: result.URL, BaseURL: result.BaseURL}
+			if target.BaseURL == f.base {
+				target.BaseURL = ""
+			}
+			if metadata, ok := result.Metadata["metadata"].(map[string]any); ok && len(metadata) > 0 {
+				target.Metadata = metadata
+			}
+			return target, nil
+		}
+	}
+}
+
+// Results returns the number of results read so far
+func (f *RetryFilter) Results() int {
+	return f.results
+}
+
+// Failed returns the number of failed results turned back into targets so far
+func (f *RetryFilter) Failed() int {
+	return f.failed
+}
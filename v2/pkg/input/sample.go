@@ -0,0 +1,94 @@
+package input
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+)
+
+// ParseSampleFraction reads a --sample value such as 10% or 10, meaning the same, into a fraction of the input
+func ParseSampleFraction(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("'%s' is not a percentage between 0 and 100, e.g. 10%%", value)
+	}
+	return percent / 100, nil
+}
+
+// Sample picks n targets stratified by apex domain: each apex gets a share proportional to its number of
+// targets, and every apex gets at least one while n allows, so small domains aren't drowned out by a wildcard
+// domain with thousands of hosts. Targets are chosen at random with seed and keep their input order. It
+// returns the sample and the number of apex domains in the input.
+func Sample(targets []Target, n int, seed uint64) ([]Target, int) {
+	groups := make(map[string][]int)
+	var apexes []string
+	for i, target := range targets {
+		apex := domain.Apex(domain.Host(target.URL))
+		if _, ok := groups[apex]; !ok {
+			apexes = append(apexes, apex)
+		}
+		groups[apex] = append(groups[apex], i)
+	}
+	if n >= len(targets) {
+		return targets, len(apexes)
+	}
+
+	quotas := allocate(apexes, groups, len(targets), n)
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	var picked []int
+	for _, apex := range apexes {
+		indexes := groups[apex]
+		rng.Shuffle(len(indexes), func(i, j int) { indexes[i], indexes[j] = indexes[j], indexes[i] })
+		picked = append(picked, indexes[:quotas[apex]]...)
+	}
+	sort.Ints(picked)
+	sample := make([]Target, len(picked))
+	for i, index := range picked {
+		sample[i] = targets[index]
+	}
+	return sample, len(apexes)
+}
+
+// allocate splits n between the apexes in proportion to their sizes by the largest remainder method, then
+// moves single slots from the largest quotas to apexes left with none
+func allocate(apexes []string, groups map[string][]int, total, n int) map[string]int {
+	quotas := make(map[string]int, len(apexes))
+	remainders := make([]float64, len(apexes))
+	assigned := 0
+	for i, apex := range apexes {
+		share := float64(n) * float64(len(groups[apex])) / float64(total)
+		quotas[apex] = int(share)
+		remainders[i] = share - float64(quotas[apex])
+		assigned += quotas[apex]
+	}
+	order := make([]int, len(apexes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:n-assigned] {
+		quotas[apexes[i]]++
+	}
+
+	if n < len(apexes) {
+		return quotas
+	}
+	for _, apex := range apexes {
+		if quotas[apex] > 0 {
+			continue
+		}
+		largest := apexes[0]
+		for _, other := range apexes {
+			if quotas[other] > quotas[largest] {
+				largest = other
+			}
+		}
+		quotas[largest]--
+		quotas[apex]++
+	}
+	return quotas
+}
@@ -0,0 +1,51 @@
+package input
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TagRule labels every target whose URL matches Pattern with Name
+type TagRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParseTagRule parses "name=regex"; the regex may be wrapped in slashes, as in "login=/login|signin/"
+func ParseTagRule(rule string) (TagRule, error) {
+	name, expr, ok := strings.Cut(rule, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || expr == "" {
+		return TagRule{}, fmt.Errorf("tag rule '%s' must look like name=regex", rule)
+	}
+	if len(expr) >= 2 && strings.HasPrefix(expr, "/") && strings.HasSuffix(expr, "/") {
+		expr = expr[1 : len(expr)-1]
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return TagRule{}, fmt.Errorf("tag rule '%s': %v", rule, err)
+	}
+	return TagRule{Name: name, Pattern: re}, nil
+}
+
+// MatchTags returns the names of every rule matching url, in rule order and without duplicates
+func MatchTags(url string, rules []TagRule) []string {
+	var tags []string
+	for _, rule := range rules {
+		if !rule.Pattern.MatchString(url) {
+			continue
+		}
+		duplicate := false
+		for _, tag := range tags {
+			if tag == rule.Name {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			tags = append(tags, rule.Name)
+		}
+	}
+	return tags
+}
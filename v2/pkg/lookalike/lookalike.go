@@ -0,0 +1,142 @@
+package lookalike
+
+import (
+	"strings"
+	"unicode"
+
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Threshold is the confusability at which an ASCII host is reported as a lookalike; IDNs are always reported
+const Threshold = 0.8
+
+// embeddedScore is given to hosts that contain the brand label, e.g. "acme-login" for "acme"
+const embeddedScore = 0.85
+
+// confusables maps non-Latin characters to the ASCII letters they render like. Latin letters with
+// diacritics don't need entries since the skeleton strips combining marks.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y', 'х': 'x', 'ԝ': 'w', 'ь': 'b', 'ѵ': 'v', 'г': 'r',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'μ': 'u',
+	// Latin letters without a decomposition
+	'ı': 'i', 'ł': 'l', 'ɫ': 'l', 'ƒ': 'f', 'ɑ': 'a', 'ɢ': 'g', 'ʀ': 'r', 'ʏ': 'y', 'ø': 'o', 'ĸ': 'k', 'ŧ': 't', 'ʐ': 'z', 'ʠ': 'q', 'ʝ': 'j',
+	// Digits that pass for letters
+	'0': 'o', '1': 'l', '3': 'e', '5': 's',
+}
+
+// sequences are ASCII letter pairs that read as a single letter
+var sequences = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d", "nn", "m")
+
+// Result describes how closely a host imitates the brand domain
+type Result struct {
+	// Unicode is the host as users see it; it differs from the ASCII host only for IDNs
+	Unicode string
+	IDN     bool
+	// Confusability runs from 0 (unrelated) to 1 (renders identically to the brand)
+	Confusability float64
+}
+
+// Lookalike reports whether the host should be flagged
+func (r Result) Lookalike() bool {
+	return r.IDN || r.Confusability >= Threshold
+}
+
+// Analyze compares host against brandDomain. Hosts on the brand domain itself, or its subdomains, are never
+// lookalikes; ok is false for them and for hosts that are neither IDNs nor confusable with the brand.
+func Analyze(host, brandDomain string) (Result, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	brandDomain = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(brandDomain), "."), "www.")
+	if host == "" {
+		return Result{}, false
+	}
+	// Anything under the brand's registered domain is the brand's own, e.g. eu.acme.co.uk for login.acme.co.uk
+	brandApex := domain.Apex(brandDomain)
+	if brandApex != "" && (host == brandApex || strings.HasSuffix(host, "."+brandApex)) {
+		return Result{}, false
+	}
+
+	unicodeHost, err := idna.ToUnicode(host)
+	if err != nil {
+		unicodeHost = host
+	}
+	result := Result{Unicode: unicodeHost, IDN: unicodeHost != host || strings.Contains(host, "xn--")}
+
+	brand := domain.Label(brandDomain)
+	if brand == "" {
+		brand, _, _ = strings.Cut(brandDomain, ".")
+	}
+	if brand != "" {
+		brandSkeleton := skeleton(brand)
+		// The public suffix can't carry an imitation, but every other label can, e.g. "acme.com.evil.co.uk"
+		name := unicodeHost
+		if suffix := domain.Suffix(unicodeHost); suffix != "" && suffix != unicodeHost {
+			name = strings.TrimSuffix(unicodeHost, "."+suffix)
+		}
+		for _, label := range strings.Split(name, ".") {
+			result.Confusability = max(result.Confusability, score(label, brand, brandSkeleton))
+		}
+	}
+	return result, result.Lookalike()
+}
+
+// score rates one host label against the brand label
+func score(label, brand, brandSkeleton string) float64 {
+	labelSkeleton := skeleton(label)
+	if labelSkeleton == brandSkeleton {
+		return 1
+	}
+	s := similarity(labelSkeleton, brandSkeleton)
+	if len(brandSkeleton) >= 3 && strings.Contains(labelSkeleton, brandSkeleton) {
+		s = max(s, embeddedScore)
+	}
+	return s
+}
+
+// skeleton reduces a label to the ASCII letters it visually resembles
+func skeleton(label string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(label) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		if r == '-' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return sequences.Replace(b.String())
+}
+
+// similarity is one minus the normalised Levenshtein distance between a and b
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
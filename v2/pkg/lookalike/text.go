@@ -0,0 +1,53 @@
+package lookalike
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialsScore is given to icon text that spells the brand's initials, as letterform marks often do
+const initialsScore = 0.6
+
+// TextScore rates how closely text read from an icon spells the brand name, from 0 (unrelated) to 1.
+// Both are folded like host labels, so OCR slips such as "0" for "o" and homoglyph tricks still score high.
+func TextScore(text, brand string) float64 {
+	textSkeleton, brandSkeleton := skeleton(alphanumeric(text)), skeleton(alphanumeric(brand))
+	if textSkeleton == "" || brandSkeleton == "" {
+		return 0
+	}
+	if strings.Contains(textSkeleton, brandSkeleton) {
+		return 1
+	}
+
+	// Compare against every window about the brand's length, so extra words around it don't dilute the score
+	best := similarity(textSkeleton, brandSkeleton)
+	runes, n := []rune(textSkeleton), len([]rune(brandSkeleton))
+	for size := max(n-1, 1); size <= n+1; size++ {
+		for start := 0; start+size <= len(runes); start++ {
+			best = max(best, similarity(string(runes[start:start+size]), brandSkeleton))
+		}
+	}
+	if initials := skeleton(initialsOf(brand)); best < initialsScore && textSkeleton == initials {
+		best = initialsScore
+	}
+	return best
+}
+
+// alphanumeric lowercases s and keeps only its letters and digits
+func alphanumeric(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// initialsOf returns the first letter of every word in name, e.g. "ac" for "Acme Corp"
+func initialsOf(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		b.WriteRune(unicode.ToLower([]rune(word)[0]))
+	}
+	return b.String()
+}
@@ -0,0 +1,316 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	config "github.com/ethicalhackingplayground/favlens/v2/pkg/config"
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Email modes
+const (
+	EmailSummary   = "summary"
+	EmailImmediate = "immediate"
+)
+
+// Summary describes a finished run for the digest email
+type Summary struct {
+	BaseURL  string
+	Model    string
+	Started  time.Time
+	Duration time.Duration
+	Total    int
+	Errors   int
+	Partial  bool
+	// GroupBy is "apex" when the report aggregates matches per registered domain
+	GroupBy string
+}
+
+// Mailer sends matches by email: one message per match in immediate mode, or a digest with an HTML report
+// attached when the run ends
+type Mailer struct {
+	cfg      config.Email
+	password string
+	timeout  time.Duration
+
+	queue chan types.Result
+	done  chan struct{}
+
+	mu       sync.Mutex
+	matches  []types.Result
+	sent     int
+	failed   int
+	firstErr error
+}
+
+// NewMailer checks the email configuration and, in immediate mode, starts sending in the background
+func NewMailer(cfg config.Email, timeout time.Duration) (*Mailer, error) {
+	if err := CheckEmail(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = EmailSummary
+	}
+	m := &Mailer{cfg: cfg, password: cfg.Password, timeout: timeout, done: make(chan struct{})}
+	if m.password == "" {
+		m.password = os.Getenv("FAVLENS_SMTP_PASSWORD")
+	}
+	if cfg.Mode == EmailImmediate {
+		m.queue = make(chan types.Result, queueSize)
+		go m.deliver()
+	} else {
+		close(m.done)
+	}
+	return m, nil
+}
+
+// CheckEmail reports what is missing or wrong in an email configuration
+func CheckEmail(cfg config.Email) error {
+	var problems []string
+	if cfg.Host == "" {
+		problems = append(problems, "smtp_host is required")
+	}
+	if cfg.From == "" {
+		problems = append(problems, "from is required")
+	}
+	if len(cfg.To) == 0 {
+		problems = append(problems, "to needs at least one address")
+	}
+	if cfg.Mode != "" && cfg.Mode != EmailSummary && cfg.Mode != EmailImmediate {
+		problems = append(problems, fmt.Sprintf("mode must be %s or %s, not '%s'", EmailSummary, EmailImmediate, cfg.Mode))
+	}
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("smtp_port %d is out of range", cfg.Port))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("email: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Notify emails a match straight away in immediate mode and keeps it for the digest otherwise
+func (m *Mailer) Notify(result types.Result) {
+	if m.queue != nil {
+		m.queue <- result
+		return
+	}
+	m.mu.Lock()
+	m.matches = append(m.matches, result)
+	m.mu.Unlock()
+}
+
+// Close finishes sending immediate emails, or sends the digest for a summary of the run, and reports
+// emails that could not be sent
+func (m *Mailer) Close(summary Summary) error {
+	if m.queue != nil {
+		close(m.queue)
+	}
+	<-m.done
+	if m.cfg.Mode == EmailSummary {
+		m.record(m.sendDigest(summary))
+	}
+	if m.failed > 0 {
+		return fmt.Errorf("%d email(s) could not be sent: %v", m.failed, m.firstErr)
+	}
+	return nil
+}
+
+// Sent is the number of emails delivered
+func (m *Mailer) Sent() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sent
+}
+
+func (m *Mailer) deliver() {
+	defer close(m.done)
+	for result := range m.queue {
+		message := newMessage(result, Rule{})
+		body := message.Text() + "\n\nBase favicon: " + result.BaseURL + "\n"
+		m.record(m.send("favlens match: "+result.URL, body, nil))
+	}
+}
+
+func (m *Mailer) record(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failed++
+		if m.firstErr == nil {
+			m.firstErr = err
+		}
+		return
+	}
+	m.sent++
+}
+
+// reportTemplate renders the digest's HTML report
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>favlens report</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head><body>
+<h1>favlens report</h1>
+<p>Base favicon {{.Summary.BaseURL}}, model {{.Summary.Model}}, started {{.Started}} and ran for {{.Summary.Duration}}.</p>
+<p>{{len .Matches}} match(es), {{.Summary.Errors}} error(s), {{.Summary.Total}} target(s).{{if .Summary.Partial}} <strong>Partial run: not every target was processed.</strong>{{end}}</p>
+{{if .Groups}}<h2>Matches by domain</h2>
+<table>
+<tr><th>Domain</th><th>Matches</th><th>Hosts</th><th>Representative hosts</th></tr>
+{{range .Groups}}<tr><td>{{.Apex}}</td><td>{{len .URLs}}</td><td>{{len .Hosts}}</td><td>{{range $i, $h := .Representative 5}}{{if $i}}, {{end}}{{$h}}{{end}}</td></tr>
+{{end}}</table>
+<h2>All matches</h2>
+{{end}}{{if .Matches}}<table>
+<tr><th>URL</th><th>Confidence</th><th>Tags</th><th>Lookalike domain</th><th>Suspect</th><th>Matched asset</th></tr>
+{{range .Matches}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Confidence}}</td><td>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</td><td>{{.Lookalike}}</td><td>{{range $i, $r := .Suspect}}{{if $i}}; {{end}}{{$r}}{{end}}</td><td>{{.Asset}}</td></tr>
+{{end}}</table>{{end}}
+</body></html>
+`))
+
+// sendDigest emails the run summary with the HTML report attached
+func (m *Mailer) sendDigest(summary Summary) error {
+	m.mu.Lock()
+	messages := make([]Message, 0, len(m.matches))
+	for _, result := range m.matches {
+		messages = append(messages, newMessage(result, Rule{}))
+	}
+	var groups []domain.Group
+	if summary.GroupBy != "" {
+		groups = domain.GroupByApex(m.matches)
+	}
+	m.mu.Unlock()
+
+	var report bytes.Buffer
+	err := reportTemplate.Execute(&report, map[string]any{
+		"Summary": summary,
+		"Started": summary.Started.UTC().Format(time.RFC1123),
+		"Matches": messages,
+		"Groups":  groups,
+	})
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "favlens finished scanning %d target(s) against %s: %d match(es), %d error(s).\n", summary.Total, summary.BaseURL, len(messages), summary.Errors)
+	if summary.Partial {
+		body.WriteString("This was a partial run: not every target was processed.\n")
+	}
+	if len(groups) > 0 {
+		body.WriteString("\n")
+		for _, group := range groups {
+			fmt.Fprintf(&body, "%s: %d match(es) on %d host(s), e.g. %s\n", group.Apex, len(group.URLs), len(group.Hosts), strings.Join(group.Representative(3), ", "))
+		}
+	} else if len(messages) > 0 {
+		body.WriteString("\n")
+		for _, message := range messages {
+			body.WriteString(message.Text() + "\n")
+		}
+	}
+	body.WriteString("\nThe full report is attached.\n")
+	subject := fmt.Sprintf("favlens: %d match(es) for %s", len(messages), summary.BaseURL)
+	return m.send(subject, body.String(), report.Bytes())
+}
+
+// send delivers a plain text email, with report attached as favlens-report.html when given
+func (m *Mailer) send(subject, body string, report []byte) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+		m.cfg.From, strings.Join(m.cfg.To, ", "), mime.QEncoding.Encode("utf-8", subject), time.Now().Format(time.RFC1123Z))
+	parts := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", parts.Boundary())
+
+	text, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(text)
+	qp.Write([]byte(body))
+	qp.Close()
+	if report != nil {
+		attachment, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"text/html; charset=utf-8"},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {`attachment; filename="favlens-report.html"`},
+		})
+		encoded := base64.StdEncoding.EncodeToString(report)
+		for len(encoded) > 76 {
+			attachment.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		attachment.Write([]byte(encoded + "\r\n"))
+	}
+	parts.Close()
+	return m.deliverSMTP(msg.Bytes())
+}
+
+// deliverSMTP sends a message over implicit TLS on port 465, and otherwise upgrades with STARTTLS when the
+// server offers it
+func (m *Mailer) deliverSMTP(msg []byte) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: m.timeout}
+	var conn net.Conn
+	var err error
+	if m.cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.cfg.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("can't reach SMTP server %s: %v", addr, err)
+	}
+	if m.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(m.timeout))
+	}
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake with %s failed: %v", addr, err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && m.cfg.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("STARTTLS with %s failed: %v", addr, err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP login to %s failed: %v", addr, err)
+		}
+	}
+	if err := client.Mail(m.cfg.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %v", m.cfg.From, err)
+	}
+	for _, to := range m.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the message: %v", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,236 @@
+// Package notify routes matches to notification channels by rule, so high-confidence hits page responders
+// while weaker ones go to a review queue. Rules are shared by every mode that produces matches; see ParseRule
+// for their syntax.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// queueSize bounds matches waiting to be delivered; Notify blocks once it is full rather than growing memory
+const queueSize = 256
+
+// Message is what a notification carries: the JSON body for webhooks and the review queue line for files
+type Message struct {
+	Timestamp  string   `json:"timestamp"`
+	URL        string   `json:"url"`
+	BaseURL    string   `json:"base_url"`
+	Confidence int      `json:"confidence"`
+	Tags       []string `json:"tags,omitempty"`
+	Lookalike  string   `json:"lookalike_domain,omitempty"`
+	Suspect    []string `json:"suspect_reasons,omitempty"`
+	Asset      string   `json:"matched_asset,omitempty"`
+	Rule       string   `json:"rule"`
+}
+
+// Text is the one-line summary posted to Slack
+func (m Message) Text() string {
+	text := fmt.Sprintf("favlens match: %s (confidence %d)", m.URL, m.Confidence)
+	if len(m.Tags) > 0 {
+		text += ", tags: " + strings.Join(m.Tags, ", ")
+	}
+	if m.Lookalike != "" {
+		text += ", lookalike domain " + m.Lookalike
+	}
+	if len(m.Suspect) > 0 {
+		text += ", suspect: " + strings.Join(m.Suspect, "; ")
+	}
+	return text
+}
+
+type delivery struct {
+	rule    Rule
+	message Message
+}
+
+// Notifier delivers matches to the destinations of the rules they meet, in the background so slow channels
+// don't hold up the scan
+type Notifier struct {
+	rules  []Rule
+	client *http.Client
+	// Slack is reached through a bot token, which can post to any channel, or an incoming webhook
+	slackToken, slackWebhook string
+	files                    map[string]*os.File
+
+	queue chan delivery
+	done  chan struct{}
+
+	mu       sync.Mutex
+	sent     int
+	failed   int
+	firstErr error
+}
+
+// New checks that every rule's destination is usable, opening review queue files for appending, and starts
+// delivering. Slack destinations need SLACK_BOT_TOKEN or SLACK_WEBHOOK_URL.
+func New(rules []Rule, timeout time.Duration) (*Notifier, error) {
+	n := &Notifier{
+		rules:        rules,
+		client:       &http.Client{Timeout: timeout},
+		slackToken:   os.Getenv("SLACK_BOT_TOKEN"),
+		slackWebhook: os.Getenv("SLACK_WEBHOOK_URL"),
+		files:        map[string]*os.File{},
+		queue:        make(chan delivery, queueSize),
+		done:         make(chan struct{}),
+	}
+	for _, rule := range rules {
+		switch rule.Channel {
+		case ChannelSlack:
+			if n.slackToken == "" && n.slackWebhook == "" {
+				n.closeFiles()
+				return nil, errors.New("slack notification rules need SLACK_BOT_TOKEN or SLACK_WEBHOOK_URL")
+			}
+		case ChannelFile:
+			if n.files[rule.Target] != nil {
+				continue
+			}
+			file, err := os.OpenFile(rule.Target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				n.closeFiles()
+				return nil, fmt.Errorf("can't open review queue %s: %v", rule.Target, err)
+			}
+			n.files[rule.Target] = file
+		}
+	}
+	go n.deliver()
+	return n, nil
+}
+
+// Notify queues a match for every destination whose rule it meets; a destination named by several rules is
+// only notified once
+func (n *Notifier) Notify(result types.Result) {
+	seen := map[string]bool{}
+	for _, rule := range n.rules {
+		destination := rule.Channel + ":" + rule.Target
+		if seen[destination] || !rule.Matches(result) {
+			continue
+		}
+		seen[destination] = true
+		n.queue <- delivery{rule: rule, message: newMessage(result, rule)}
+	}
+}
+
+func newMessage(result types.Result, rule Rule) Message {
+	m := Message{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		URL:        result.URL,
+		BaseURL:    result.BaseURL,
+		Confidence: Confidence(result),
+		Tags:       result.Tags,
+		Suspect:    result.SuspectReasons,
+		Asset:      result.Asset,
+		Rule:       rule.Raw,
+	}
+	if result.Lookalike != nil {
+		m.Lookalike = result.Lookalike.Unicode
+	}
+	return m
+}
+
+// Close delivers what is queued and reports notifications that could not be sent
+func (n *Notifier) Close() error {
+	close(n.queue)
+	<-n.done
+	n.closeFiles()
+	if n.failed > 0 {
+		return fmt.Errorf("%d notification(s) could not be sent: %v", n.failed, n.firstErr)
+	}
+	return nil
+}
+
+// Sent is the number of notifications delivered
+func (n *Notifier) Sent() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.sent
+}
+
+func (n *Notifier) deliver() {
+	defer close(n.done)
+	for d := range n.queue {
+		err := n.send(d)
+		n.mu.Lock()
+		if err != nil {
+			n.failed++
+			if n.firstErr == nil {
+				n.firstErr = err
+			}
+		} else {
+			n.sent++
+		}
+		n.mu.Unlock()
+	}
+}
+
+func (n *Notifier) send(d delivery) error {
+	switch d.rule.Channel {
+	case ChannelFile:
+		_, err := n.files[d.rule.Target].Write(encode(d.message))
+		return err
+	case ChannelWebhook:
+		return n.post(d.rule.Target, "", encode(d.message))
+	}
+	payload := map[string]string{"channel": d.rule.Target, "text": d.message.Text()}
+	body := encode(payload)
+	if n.slackToken != "" {
+		return n.post("https://slack.com/api/chat.postMessage", n.slackToken, body)
+	}
+	return n.post(n.slackWebhook, "", body)
+}
+
+// post sends a JSON body. Slack's Web API answers 200 with ok:false on failure, so its responses are checked too.
+func (n *Notifier) post(target, token string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if token != "" {
+		var reply struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &reply) == nil && !reply.OK {
+			return fmt.Errorf("slack rejected the message: %s", reply.Error)
+		}
+	}
+	return nil
+}
+
+// encode renders v as a JSON line, leaving the => in rules unescaped
+func encode(v any) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+	return buf.Bytes()
+}
+
+func (n *Notifier) closeFiles() {
+	for _, file := range n.files {
+		file.Close()
+	}
+}
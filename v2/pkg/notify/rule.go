@@ -0,0 +1,242 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Channels a rule can route to
+const (
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+	ChannelFile    = "file"
+)
+
+// operators are tried longest first so >= isn't read as >
+var operators = []string{"==", "!=", ">=", "<=", "~=", ">", "<"}
+
+// fieldKinds lists the fields a condition can test and whether they compare as numbers, booleans or strings
+var fieldKinds = map[string]string{
+	"confidence":    "number",
+	"confusability": "number",
+	"text_score":    "number",
+	"lookalike":     "bool",
+	"suspect":       "bool",
+	"tag":           "string",
+	"host":          "string",
+	"url":           "string",
+	"asset":         "string",
+}
+
+// Rule sends matches meeting every condition to one destination
+type Rule struct {
+	Raw        string
+	conditions []condition
+	// Channel is slack, webhook or file, and Target the Slack channel, webhook URL or review queue file
+	Channel string
+	Target  string
+}
+
+type condition struct {
+	field, op, value string
+	number           float64
+	re               *regexp.Regexp
+}
+
+// ParseRule reads a rule of the form 'confidence>90 && tag==login => slack:#incident'. Conditions are joined
+// with && and compare a field with ==, !=, >, >=, <, <= or, for strings, the ~= regex match; a rule with no
+// conditions ('=> file:review.jsonl') takes every match.
+func ParseRule(rule string) (Rule, error) {
+	expr, dest, ok := strings.Cut(rule, "=>")
+	if !ok {
+		return Rule{}, fmt.Errorf("rule '%s' must look like '<conditions> => <channel>:<target>'", rule)
+	}
+	r := Rule{Raw: rule}
+	channel, target, ok := strings.Cut(strings.TrimSpace(dest), ":")
+	r.Channel, r.Target = strings.ToLower(strings.TrimSpace(channel)), strings.TrimSpace(target)
+	if !ok || r.Target == "" {
+		return Rule{}, fmt.Errorf("rule '%s': destination must look like slack:#channel, webhook:https://... or file:path", rule)
+	}
+	switch r.Channel {
+	case ChannelSlack, ChannelFile:
+	case ChannelWebhook:
+		if u, err := url.Parse(r.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Rule{}, fmt.Errorf("rule '%s': webhook target must be an http(s) URL", rule)
+		}
+	default:
+		return Rule{}, fmt.Errorf("rule '%s': unknown channel '%s' (supported: slack, webhook, file)", rule, r.Channel)
+	}
+
+	if strings.TrimSpace(expr) == "" {
+		return r, nil
+	}
+	for _, part := range strings.Split(expr, "&&") {
+		c, err := parseCondition(strings.TrimSpace(part))
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule '%s': %v", rule, err)
+		}
+		r.conditions = append(r.conditions, c)
+	}
+	return r, nil
+}
+
+func parseCondition(part string) (condition, error) {
+	for _, op := range operators {
+		field, value, ok := strings.Cut(part, op)
+		if !ok {
+			continue
+		}
+		c := condition{field: strings.ToLower(strings.TrimSpace(field)), op: op, value: strings.Trim(strings.TrimSpace(value), `"'`)}
+		kind, known := fieldKinds[c.field]
+		if !known {
+			return condition{}, fmt.Errorf("unknown field '%s' in '%s'", c.field, part)
+		}
+		switch kind {
+		case "number":
+			n, err := strconv.ParseFloat(c.value, 64)
+			if err != nil || op == "~=" {
+				return condition{}, fmt.Errorf("'%s' compares %s with a number", part, c.field)
+			}
+			c.number = n
+		case "bool":
+			if (op != "==" && op != "!=") || (c.value != "true" && c.value != "false") {
+				return condition{}, fmt.Errorf("'%s' must test %s with == or != true/false", part, c.field)
+			}
+		case "string":
+			switch op {
+			case "==", "!=":
+			case "~=":
+				re, err := regexp.Compile(c.value)
+				if err != nil {
+					return condition{}, fmt.Errorf("'%s': %v", part, err)
+				}
+				c.re = re
+			default:
+				return condition{}, fmt.Errorf("'%s' compares a string; use ==, != or ~=", part)
+			}
+		}
+		return c, nil
+	}
+	return condition{}, fmt.Errorf("condition '%s' has no operator", part)
+}
+
+// Matches reports whether a result meets every condition of the rule
+func (r Rule) Matches(result types.Result) bool {
+	for _, c := range r.conditions {
+		if !c.matches(result) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) matches(result types.Result) bool {
+	switch fieldKinds[c.field] {
+	case "number":
+		return compare(numberField(c.field, result), c.op, c.number)
+	case "bool":
+		want := c.value == "true"
+		if c.op == "!=" {
+			want = !want
+		}
+		return boolField(c.field, result) == want
+	}
+	values := stringField(c.field, result)
+	// Multi-valued fields like tag match when any value does, and != when none does
+	if c.op == "!=" {
+		for _, v := range values {
+			if v == c.value {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if (c.op == "==" && v == c.value) || (c.op == "~=" && c.re.MatchString(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	}
+	return a <= b
+}
+
+func numberField(field string, result types.Result) float64 {
+	switch field {
+	case "confidence":
+		return float64(Confidence(result))
+	case "confusability":
+		if result.Lookalike != nil {
+			return result.Lookalike.Confusability
+		}
+	case "text_score":
+		if result.Text != nil {
+			return result.Text.Score
+		}
+	}
+	return 0
+}
+
+func boolField(field string, result types.Result) bool {
+	if field == "lookalike" {
+		return result.Lookalike != nil
+	}
+	return len(result.SuspectReasons) > 0
+}
+
+func stringField(field string, result types.Result) []string {
+	switch field {
+	case "tag":
+		return result.Tags
+	case "host":
+		if u, err := url.Parse(result.URL); err == nil {
+			return []string{u.Hostname()}
+		}
+		return nil
+	case "asset":
+		return []string{result.Asset}
+	}
+	return []string{result.URL}
+}
+
+// Confidence scores a match from 0 to 100. The model's verdict is the baseline; brand text and lookalike
+// hosts corroborate it, while tiny or monochrome icons and parked or wildcard hosting undermine it.
+// Non-matches score 0.
+func Confidence(result types.Result) int {
+	if !result.Match {
+		return 0
+	}
+	score := 70.0
+	if result.Text != nil && result.Text.Match {
+		score += 15
+	}
+	if result.Lookalike != nil {
+		score += 15 * result.Lookalike.Confusability
+	}
+	if icon := result.Icon; icon != nil && (icon.Monochrome || max(icon.Width, icon.Height) <= 16) {
+		score -= 15
+	}
+	if len(result.SuspectReasons) > 0 {
+		score -= 25
+	}
+	return int(min(max(score, 0), 100))
+}
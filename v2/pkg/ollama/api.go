@@ -0,0 +1,33 @@
+package ollama
+
+import (
+	"strings"
+	"time"
+)
+
+// OllamaAPI is the part of the Ollama HTTP API that favlens depends on. Client implements it against a
+// real server; package ollamatest provides a fake server and an in-memory stub so comparison logic and
+// the runner can be exercised without a model.
+type OllamaAPI interface {
+	// ListModels returns the models installed on the host
+	ListModels(debug bool) ([]Model, error)
+	// CheckModelExists reports an error unless the configured model is installed
+	CheckModelExists(debug bool) error
+	// PullModel downloads the configured model, blocking until the pull completes
+	PullModel(timeout time.Duration, debug bool) error
+	// CompareFaviconsChatAPI asks the model whether two base64 icons match
+	CompareFaviconsChatAPI(base64Base, base64Target string, debug bool) (bool, error)
+	// ExtractText asks the model to read the text in a base64 icon; "" means there is none
+	ExtractText(base64Icon string, debug bool) (string, error)
+	// Embed returns the model's image embedding of a base64 icon
+	Embed(base64Icon string, debug bool) ([]float64, error)
+}
+
+var _ OllamaAPI = (*Client)(nil)
+
+// ModelMatches reports whether an installed model satisfies the requested name, treating a missing tag as :latest
+func ModelMatches(installed, requested string) bool {
+	return installed == requested ||
+		(strings.HasSuffix(installed, ":latest") && strings.TrimSuffix(installed, ":latest") == requested) ||
+		(strings.HasSuffix(requested, ":latest") && strings.TrimSuffix(requested, ":latest") == strings.TrimSuffix(installed, ":latest"))
+}
@@ -0,0 +1,129 @@
+package ollama
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// maxBreakerCooldown caps the pause between probes while the backend keeps failing
+const maxBreakerCooldown = 5 * time.Minute
+
+// halfOpenPoll is how often waiting workers check whether the probe request has settled
+const halfOpenPoll = 250 * time.Millisecond
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// Breaker pauses model calls once the backend fails consecutively. After a cooldown a single probe request
+// is let through: success resumes normal dispatch, failure reopens the breaker with a doubled cooldown.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	silent    bool
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	current   time.Duration
+	openUntil time.Time
+	trips     int
+}
+
+// NewBreaker returns a breaker that trips after threshold consecutive failures; a threshold of 0 disables it
+func NewBreaker(threshold int, cooldown time.Duration, silent bool) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, current: cooldown, silent: silent}
+}
+
+// Wait blocks while the breaker is open and returns false if stop is closed first
+func (b *Breaker) Wait(stop <-chan struct{}) bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+	for {
+		b.mu.Lock()
+		var wait time.Duration
+		switch b.state {
+		case breakerClosed:
+			b.mu.Unlock()
+			return true
+		case breakerOpen:
+			wait = time.Until(b.openUntil)
+			if wait <= 0 {
+				// This caller becomes the probe; everyone else waits for its outcome
+				b.state = breakerHalfOpen
+				b.mu.Unlock()
+				if !b.silent {
+					gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprint("Circuit breaker half-open, probing the Ollama backend"))
+				}
+				return true
+			}
+		case breakerHalfOpen:
+			wait = halfOpenPoll
+		}
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// Record feeds the outcome of a model call into the breaker
+func (b *Breaker) Record(err error) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != breakerClosed && !b.silent {
+			gologger.Info().Msg(color.New(color.Bold, color.FgGreen).Sprint("Ollama backend recovered, resuming dispatch"))
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		b.current = b.cooldown
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == breakerHalfOpen:
+		b.current = min(b.current*2, maxBreakerCooldown)
+		b.open(err)
+	case b.state == breakerClosed && b.failures >= b.threshold:
+		b.open(err)
+	}
+}
+
+// open pauses dispatch for the current cooldown; callers hold b.mu
+func (b *Breaker) open(err error) {
+	b.state = breakerOpen
+	b.openUntil = time.Now().Add(b.current)
+	b.trips++
+	if !b.silent {
+		gologger.Error().Msg(color.New(color.Bold, color.FgYellow).Sprintf("Ollama backend failing (%d consecutive errors, last: %v), pausing dispatch for %s", b.failures, err, b.current))
+	}
+}
+
+// Trips returns how many times the breaker opened
+func (b *Breaker) Trips() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.trips
+}
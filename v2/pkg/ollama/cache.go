@@ -0,0 +1,62 @@
+package ollama
+
+import (
+	"fmt"
+	"sync"
+)
+
+// IconCache downloads each icon URL at most once and shares the base64 result between workers
+type IconCache struct {
+	client  *Client
+	mu      sync.Mutex
+	entries map[string]*iconEntry
+}
+
+type iconEntry struct {
+	once sync.Once
+	icon string
+	err  error
+}
+
+func NewIconCache(client *Client) *IconCache {
+	return &IconCache{client: client, entries: make(map[string]*iconEntry)}
+}
+
+// Put seeds the cache with an icon that has already been downloaded
+func (c *IconCache) Put(url, icon string) {
+	entry := &iconEntry{icon: icon}
+	entry.once.Do(func() {})
+
+	c.mu.Lock()
+	c.entries[url] = entry
+	c.mu.Unlock()
+}
+
+// Get returns the base64 icon for url, downloading it on first use; concurrent callers wait for the same download
+func (c *IconCache) Get(url string, debug bool) (string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[url]
+	if !ok {
+		entry = &iconEntry{}
+		c.entries[url] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		// sync.Once treats a panic as done, so record it as an error instead of caching an empty icon
+		defer func() {
+			if r := recover(); r != nil {
+				entry.err = fmt.Errorf("panic while downloading icon: %v", r)
+			}
+		}()
+		entry.icon, entry.err = c.client.DownloadImageAsBase64(url, debug)
+	})
+	return entry.icon, entry.err
+}
+
+// Len returns the number of distinct icons held by the cache
+func (c *IconCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,93 @@
+package ollama_test
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	"github.com/ethicalhackingplayground/favlens/v2/pkg/ollama/ollamatest"
+)
+
+func TestCheckModelExists(t *testing.T) {
+	server := ollamatest.NewServer("gemma3:4b", "llava:latest")
+	defer server.Close()
+
+	for _, model := range []string{"gemma3:4b", "llava", "llava:latest"} {
+		if err := server.Client(model).CheckModelExists(false); err != nil {
+			t.Errorf("CheckModelExists(%q) = %v, want nil", model, err)
+		}
+	}
+	if err := server.Client("qwen2.5vl:7b").CheckModelExists(false); err == nil {
+		t.Error("CheckModelExists accepted a model that isn't installed")
+	}
+}
+
+func TestPullModel(t *testing.T) {
+	server := ollamatest.NewServer()
+	defer server.Close()
+	client := server.Client("gemma3:4b")
+
+	if err := client.CheckModelExists(false); err == nil {
+		t.Fatal("model exists before it was pulled")
+	}
+	if err := client.PullModel(time.Minute, false); err != nil {
+		t.Fatalf("PullModel: %v", err)
+	}
+	if err := client.CheckModelExists(false); err != nil {
+		t.Fatalf("model missing after pull: %v", err)
+	}
+	if pulls := server.Pulls(); !slices.Equal(pulls, []string{"gemma3:4b"}) {
+		t.Errorf("pulls = %v, want [gemma3:4b]", pulls)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	server := ollamatest.NewServer("gemma3:4b")
+	defer server.Close()
+
+	for _, endpoint := range []string{ollama.EndpointChat, ollama.EndpointGenerate, ollama.EndpointEmbed} {
+		client := server.Client("gemma3:4b")
+		client.Endpoint = endpoint
+
+		match, err := client.CompareFaviconsChatAPI("aWNvbg==", "aWNvbg==", false)
+		if err != nil || !match {
+			t.Errorf("%s: identical icons = %v, %v; want a match", endpoint, match, err)
+		}
+		match, err = client.CompareFaviconsChatAPI("aWNvbg==", "b3RoZXI=", false)
+		if err != nil || match {
+			t.Errorf("%s: different icons = %v, %v; want no match", endpoint, match, err)
+		}
+	}
+
+	chats := server.Chats()
+	if len(chats) != 4 {
+		t.Fatalf("server saw %d chat requests, want 4", len(chats))
+	}
+	if images := chats[0].Messages[0].Images; !slices.Equal(images, []string{"aWNvbg==", "aWNvbg=="}) {
+		t.Errorf("chat carried images %v", images)
+	}
+}
+
+func TestCompareFailure(t *testing.T) {
+	server := ollamatest.NewServer("gemma3:4b")
+	defer server.Close()
+	client := server.Client("gemma3:4b")
+
+	server.FailNext(1, http.StatusInternalServerError, "CUDA error: out of memory")
+	_, err := client.CompareFaviconsChatAPI("aWNvbg==", "aWNvbg==", false)
+	var apiErr *ollama.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want an APIError with status 500", err)
+	}
+	if !errors.Is(err, ollama.ErrOutOfMemory) {
+		t.Errorf("err = %v, want ErrOutOfMemory", err)
+	}
+
+	// Only the next request fails
+	if match, err := client.CompareFaviconsChatAPI("aWNvbg==", "aWNvbg==", false); err != nil || !match {
+		t.Errorf("after the failure = %v, %v; want a match", match, err)
+	}
+}
@@ -0,0 +1,33 @@
+package ollama
+
+import (
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultColdTimeout is how long the first model call on a host may take, since Ollama loads the model into
+// memory before answering it; loading a vision model from disk often takes longer than a whole comparison
+const DefaultColdTimeout = 2 * time.Minute
+
+// modelHTTP returns the client and timeout for a model call. Until the host has answered one, calls wait on
+// ColdTimeout through a client whose read timeout is just as long, since the host sends nothing while the model
+// loads; every call after that uses HTTPClient and Timeout.
+func (o *Client) modelHTTP(debug bool) (*fasthttp.Client, time.Duration) {
+	if o.ColdTimeout <= o.Timeout || o.warm.Load() {
+		return o.HTTPClient, o.Timeout
+	}
+	o.coldOnce.Do(func() {
+		o.cold = &fasthttp.Client{
+			ReadTimeout:         o.ColdTimeout,
+			WriteTimeout:        o.HTTPClient.WriteTimeout,
+			MaxResponseBodySize: o.HTTPClient.MaxResponseBodySize,
+			TLSConfig:           o.HTTPClient.TLSConfig,
+		}
+		if debug {
+			gologger.Debug().Msgf("Waiting up to %s for '%s' to load on %s", o.ColdTimeout, o.Model, o.Host)
+		}
+	})
+	return o.cold, o.ColdTimeout
+}
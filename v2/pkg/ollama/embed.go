@@ -0,0 +1,189 @@
+package ollama
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	audit "github.com/ethicalhackingplayground/favlens/v2/pkg/audit"
+	"github.com/projectdiscovery/gologger"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultSimilarity is the cosine similarity two icon embeddings need to count as a match when Client.Similarity
+// is unset. CLIP-style models put re-encoded or slightly altered copies of a logo well above it and unrelated
+// icons well below.
+const DefaultSimilarity = 0.9
+
+// EmbedRequest asks /api/embed for one embedding per image
+type EmbedRequest struct {
+	Model     string         `json:"model"`
+	Images    []string       `json:"images"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
+}
+
+// EmbedResponse carries one vector per image, in request order
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// EncodeTo streams the request as JSON into w without building an intermediate copy of the images.
+// The output is equivalent to json.Marshal(r).
+func (r EmbedRequest) EncodeTo(w io.Writer) error {
+	if _, err := io.WriteString(w, `{"model":`); err != nil {
+		return err
+	}
+	if err := writeJSONString(w, r.Model); err != nil {
+		return err
+	}
+	if err := writeImages(w, r.Images); err != nil {
+		return err
+	}
+	// writeSettings always writes a stream field, which /api/embed ignores
+	return writeSettings(w, false, r.KeepAlive, r.Options)
+}
+
+// recentEmbeddings is how many target vectors a client keeps; a corpus indexes targets straight after their
+// comparison, so only the last few are ever looked up
+const recentEmbeddings = 64
+
+// embeddingCache holds the most recently computed vectors by image hash, dropping the oldest when full
+type embeddingCache struct {
+	mu      sync.Mutex
+	order   [][sha256.Size]byte
+	vectors map[[sha256.Size]byte][]float64
+}
+
+func (c *embeddingCache) get(key [sha256.Size]byte) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vector, ok := c.vectors[key]
+	return vector, ok
+}
+
+func (c *embeddingCache) put(key [sha256.Size]byte, vector []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.vectors == nil {
+		c.vectors = make(map[[sha256.Size]byte][]float64, recentEmbeddings)
+	}
+	if _, ok := c.vectors[key]; ok {
+		return
+	}
+	if len(c.order) == recentEmbeddings {
+		delete(c.vectors, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.order = append(c.order, key)
+	c.vectors[key] = vector
+}
+
+// Embed returns the image embedding of a base64 icon, reusing the vector when the icon was embedded recently
+func (o *Client) Embed(base64Icon string, debug bool) ([]float64, error) {
+	key := sha256.Sum256([]byte(base64Icon))
+	if vector, ok := o.recent.get(key); ok {
+		return vector, nil
+	}
+	vector, err := o.embed(base64Icon, debug)
+	if err != nil {
+		return nil, err
+	}
+	o.recent.put(key, vector)
+	return vector, nil
+}
+
+// embed asks /api/embed for the embedding of one icon
+func (o *Client) embed(base64Icon string, debug bool) ([]float64, error) {
+	const endpoint = "/api/embed"
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(o.Host + endpoint)
+	req.Header.SetMethod("POST")
+	req.Header.SetContentType("application/json")
+	body := EmbedRequest{Model: o.Model, Images: []string{base64Icon}, KeepAlive: o.KeepAlive, Options: o.Options}
+	if err := body.EncodeTo(req.BodyWriter()); err != nil {
+		return nil, fmt.Errorf("failed to encode %s request: %v", endpoint, err)
+	}
+
+	client, timeout := o.modelHTTP(debug)
+	start := time.Now()
+	err := client.DoTimeout(req, resp, timeout)
+	o.record(audit.KindOllama, req, resp, start, err)
+	if err != nil {
+		if debug {
+			gologger.Debug().Msgf("Failed to connect to Ollama API at %s: %v", o.Host, err)
+		}
+		return nil, err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, apiError(endpoint, o.Model, resp.StatusCode(), resp.Body())
+	}
+	o.warm.Store(true)
+	var embedded EmbedResponse
+	if err := json.Unmarshal(resp.Body(), &embedded); err != nil {
+		return nil, fmt.Errorf("invalid %s response: %v", endpoint, err)
+	}
+	if len(embedded.Embeddings) == 0 || len(embedded.Embeddings[0]) == 0 {
+		return nil, fmt.Errorf("model '%s' returned no image embedding; --ollama-endpoint embed needs an embedding model that accepts images", o.Model)
+	}
+	if debug {
+		gologger.Debug().Msgf("Received %d-dimensional embedding in %s", len(embedded.Embeddings[0]), time.Since(start).Round(time.Millisecond))
+	}
+	return embedded.Embeddings[0], nil
+}
+
+// compareEmbeddings embeds both icons and matches them when their cosine similarity reaches the threshold.
+// The base icon's embedding is computed once and reused for every target.
+func (o *Client) compareEmbeddings(base64Base, base64Target string, debug bool) (bool, error) {
+	key := sha256.Sum256([]byte(base64Base))
+	var base []float64
+	if cached, ok := o.embeddings.Load(key); ok {
+		base = cached.([]float64)
+	} else {
+		var err error
+		if base, err = o.embed(base64Base, debug); err != nil {
+			return false, err
+		}
+		o.embeddings.Store(key, base)
+	}
+	target, err := o.Embed(base64Target, debug)
+	if err != nil {
+		return false, err
+	}
+	if len(base) != len(target) {
+		return false, fmt.Errorf("model '%s' returned embeddings of different sizes (%d and %d)", o.Model, len(base), len(target))
+	}
+
+	threshold := o.Similarity
+	if threshold == 0 {
+		threshold = DefaultSimilarity
+	}
+	similarity := Cosine(base, target)
+	match := similarity >= threshold
+	if debug {
+		gologger.Debug().Msgf("Cosine similarity %.4f (threshold %.2f), match: %v", similarity, threshold, match)
+	}
+	return match, nil
+}
+
+// Cosine returns the cosine similarity of two vectors of the same length, 0 when either is all zeros
+func Cosine(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
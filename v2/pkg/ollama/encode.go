@@ -0,0 +1,169 @@
+package ollama
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// requestEncoder is a model request that streams itself as JSON
+type requestEncoder interface {
+	EncodeTo(w io.Writer) error
+}
+
+// writeJSONString writes s as a JSON string. Base64 payloads only use characters that need no
+// escaping, so they are written as-is; anything else goes through the standard encoder.
+func writeJSONString(w io.Writer, s string) error {
+	if !isBase64Safe(s) {
+		b, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	if _, err := io.WriteString(w, `"`); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, `"`)
+	return err
+}
+
+// isBase64Safe reports whether s consists only of standard base64 characters
+func isBase64Safe(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' || c == '=') {
+			return false
+		}
+	}
+	return true
+}
+
+// writeMessage streams a chat message, writing each image directly into w
+func writeMessage(w io.Writer, msg ChatMessage) error {
+	if _, err := io.WriteString(w, `{"role":`); err != nil {
+		return err
+	}
+	if err := writeJSONString(w, msg.Role); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"content":`); err != nil {
+		return err
+	}
+	if err := writeJSONString(w, msg.Content); err != nil {
+		return err
+	}
+	if err := writeImages(w, msg.Images); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+// writeImages streams an "images" field, writing each image directly into w; nothing is written without images
+func writeImages(w io.Writer, images []string) error {
+	if len(images) == 0 {
+		return nil
+	}
+	if _, err := io.WriteString(w, `,"images":[`); err != nil {
+		return err
+	}
+	for i, image := range images {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := writeJSONString(w, image); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// writeSettings writes the fields shared by chat and generate requests and closes the object
+func writeSettings(w io.Writer, stream bool, keepAlive string, options map[string]any) error {
+	value := "false"
+	if stream {
+		value = "true"
+	}
+	if _, err := io.WriteString(w, `,"stream":`+value); err != nil {
+		return err
+	}
+	if keepAlive != "" {
+		if _, err := io.WriteString(w, `,"keep_alive":`); err != nil {
+			return err
+		}
+		if err := writeJSONString(w, keepAlive); err != nil {
+			return err
+		}
+	}
+	if len(options) > 0 {
+		// Options are small, so the standard encoder is fine; map keys are sorted, keeping the output stable
+		b, err := json.Marshal(options)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, `,"options":`); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+// EncodeTo streams the request as JSON into w without building an intermediate copy of the images.
+// The output is equivalent to json.Marshal(r).
+func (r ChatRequest) EncodeTo(w io.Writer) error {
+	if _, err := io.WriteString(w, `{"model":`); err != nil {
+		return err
+	}
+	if err := writeJSONString(w, r.Model); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"messages":[`); err != nil {
+		return err
+	}
+	for i, msg := range r.Messages {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := writeMessage(w, msg); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "]"); err != nil {
+		return err
+	}
+	return writeSettings(w, r.Stream, r.KeepAlive, r.Options)
+}
+
+// EncodeTo streams the request as JSON into w without building an intermediate copy of the images.
+// The output is equivalent to json.Marshal(r).
+func (r GenerateRequest) EncodeTo(w io.Writer) error {
+	if _, err := io.WriteString(w, `{"model":`); err != nil {
+		return err
+	}
+	if err := writeJSONString(w, r.Model); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"prompt":`); err != nil {
+		return err
+	}
+	if err := writeJSONString(w, r.Prompt); err != nil {
+		return err
+	}
+	if err := writeImages(w, r.Images); err != nil {
+		return err
+	}
+	return writeSettings(w, r.Stream, r.KeepAlive, r.Options)
+}
@@ -0,0 +1,94 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Kinds of Ollama API failure that have a known fix; match them with errors.Is
+var (
+	ErrModelNotLoaded = errorKind("model not loaded")
+	ErrOutOfMemory    = errorKind("out of memory")
+	ErrContextLength  = errorKind("context length exceeded")
+)
+
+type errorKind string
+
+func (k errorKind) Error() string { return string(k) }
+
+// errorPatterns recognise each kind from the messages Ollama and its runners produce, in lowercase. A kind
+// with a subject only matches messages that mention it, so a proxy's "404 page not found" isn't a missing model.
+var errorPatterns = []struct {
+	kind     error
+	subject  string
+	patterns []string
+}{
+	{ErrModelNotLoaded, "model", []string{"not found", "try pulling", "not loaded"}},
+	{ErrOutOfMemory, "", []string{"out of memory", "more system memory", "insufficient memory", "failed to allocate", "cudamalloc failed"}},
+	{ErrContextLength, "", []string{"context length", "context window", "exceeds the context", "too many tokens", "num_ctx"}},
+}
+
+// APIError is a failed Ollama API call, decoded from the {"error": "..."} body Ollama sends. Kind is one of
+// the Err values above, or nil when the failure isn't one favlens knows how to explain.
+type APIError struct {
+	Endpoint string
+	Model    string
+	Status   int
+	Message  string
+	Kind     error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("ollama API returned status %d for %s: %s", e.Status, e.Endpoint, e.Message)
+	if hint := e.Hint(); hint != "" {
+		msg += " (" + hint + ")"
+	}
+	return msg
+}
+
+// Unwrap exposes Kind, so callers can use errors.Is(err, ErrOutOfMemory)
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// Hint tells the user how to fix a recognised failure, or returns "" when there is no advice
+func (e *APIError) Hint() string {
+	switch e.Kind {
+	case ErrModelNotLoaded:
+		return fmt.Sprintf("run 'ollama pull %s' or choose an installed model with --model", e.Model)
+	case ErrOutOfMemory:
+		return "the model doesn't fit in memory on this host; lower --infer-workers, choose a smaller model or free GPU memory"
+	case ErrContextLength:
+		return "the icons don't fit in the model's context window; choose a model with a larger context or raise its num_ctx"
+	}
+	return ""
+}
+
+// apiError decodes an Ollama error response. Bodies that aren't Ollama's error JSON are kept verbatim.
+func apiError(endpoint, model string, status int, body []byte) *APIError {
+	message := strings.TrimSpace(string(body))
+	var decoded struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &decoded); err == nil && decoded.Error != "" {
+		message = decoded.Error
+	}
+	return &APIError{Endpoint: endpoint, Model: model, Status: status, Message: message, Kind: classifyError(message)}
+}
+
+// classifyError returns the kind of failure message describes, or nil
+func classifyError(message string) error {
+	message = strings.ToLower(message)
+	for _, kind := range errorPatterns {
+		if !strings.Contains(message, kind.subject) {
+			continue
+		}
+		for _, pattern := range kind.patterns {
+			if strings.Contains(message, pattern) {
+				return kind.kind
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/valyala/fasthttp"
+)
+
+// ShowResponse is the subset of /api/show used to inspect a model
+type ShowResponse struct {
+	Details      ModelDetails `json:"details"`
+	Capabilities []string     `json:"capabilities"`
+}
+
+// visionFamilies are model families known to accept images, used when /api/show has no capabilities list
+var visionFamilies = []string{"clip", "mllama", "llava", "gemma3", "qwen25vl", "mistral3", "llama4"}
+
+// visionNameHints catch vision models by name for older Ollama versions
+var visionNameHints = []string{"llava", "vision", "moondream", "bakllava", "minicpm-v", "gemma3", "qwen2.5vl", "qwen2-vl", "granite3.2-vision", "llama4"}
+
+// ShowModel returns details for a single model
+func (o *Client) ShowModel(name string, debug bool) (*ShowResponse, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	body, _ := json.Marshal(map[string]string{"model": name})
+	req.SetRequestURI(o.Host + "/api/show")
+	req.Header.SetMethod("POST")
+	req.Header.SetContentType("application/json")
+	req.SetBody(body)
+
+	if err := o.HTTPClient.DoTimeout(req, resp, o.Timeout); err != nil {
+		if debug {
+			gologger.Debug().Msgf("Failed to query /api/show for %s: %v", name, err)
+		}
+		return nil, fmt.Errorf("failed to connect to Ollama API: %v", err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, apiError("/api/show", name, resp.StatusCode(), resp.Body())
+	}
+
+	var show ShowResponse
+	if err := json.Unmarshal(resp.Body(), &show); err != nil {
+		return nil, fmt.Errorf("failed to parse show response: %v", err)
+	}
+	return &show, nil
+}
+
+// IsVisionModel reports whether a model can take images, preferring the capabilities Ollama reports
+func (o *Client) IsVisionModel(model Model, debug bool) bool {
+	if show, err := o.ShowModel(model.Name, debug); err == nil {
+		if len(show.Capabilities) > 0 {
+			for _, capability := range show.Capabilities {
+				if capability == "vision" {
+					return true
+				}
+			}
+			return false
+		}
+		model.Details = show.Details
+	}
+
+	families := append([]string{model.Details.Family}, model.Details.Families...)
+	for _, family := range families {
+		for _, vision := range visionFamilies {
+			if strings.EqualFold(family, vision) {
+				return true
+			}
+		}
+	}
+	name := strings.ToLower(model.Name)
+	for _, hint := range visionNameHints {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// PullModel asks Ollama to download the client's model, blocking until the pull completes
+func (o *Client) PullModel(timeout time.Duration, debug bool) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	body, _ := json.Marshal(map[string]any{"model": o.Model, "stream": false})
+	req.SetRequestURI(o.Host + "/api/pull")
+	req.Header.SetMethod("POST")
+	req.Header.SetContentType("application/json")
+	req.SetBody(body)
+
+	if debug {
+		gologger.Debug().Msgf("Pulling model '%s' from %s", o.Model, o.Host)
+	}
+	// Pulls can take far longer than a normal request, so the client-wide read timeout is bypassed
+	client := &fasthttp.Client{ReadTimeout: timeout, WriteTimeout: o.Timeout, TLSConfig: o.HTTPClient.TLSConfig}
+	if err := client.DoTimeout(req, resp, timeout); err != nil {
+		return fmt.Errorf("failed to pull model '%s': %v", o.Model, err)
+	}
+	if resp.StatusCode() != 200 {
+		return apiError("/api/pull", o.Model, resp.StatusCode(), resp.Body())
+	}
+
+	var status struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(resp.Body(), &status); err == nil && status.Error != "" {
+		return apiError("/api/pull", o.Model, resp.StatusCode(), resp.Body())
+	}
+	return nil
+}
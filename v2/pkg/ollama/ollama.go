@@ -1,16 +1,30 @@
 package ollama
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
-	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"image"
-	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Azure/go-ntlmssp"
+	audit "github.com/ethicalhackingplayground/favlens/v2/pkg/audit"
+	auth "github.com/ethicalhackingplayground/favlens/v2/pkg/auth"
+	favhash "github.com/ethicalhackingplayground/favlens/v2/pkg/favhash"
+	imaging "github.com/ethicalhackingplayground/favlens/v2/pkg/imaging"
+	robots "github.com/ethicalhackingplayground/favlens/v2/pkg/robots"
+	screenshot "github.com/ethicalhackingplayground/favlens/v2/pkg/screenshot"
+	stats "github.com/ethicalhackingplayground/favlens/v2/pkg/stats"
 	"github.com/projectdiscovery/gologger"
 	"github.com/valyala/fasthttp"
 )
@@ -26,6 +40,21 @@ type ChatRequest struct {
 	Model    string        `json:"model"`
 	Messages []ChatMessage `json:"messages"`
 	Stream   bool          `json:"stream"`
+	// KeepAlive asks Ollama to keep the model loaded for this long after the request, e.g. "30m"
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// Options carries model parameters such as temperature and seed
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// GenerateRequest is the /api/generate equivalent of a single-message ChatRequest. It skips the model's
+// chat template, which some vision models handle better.
+type GenerateRequest struct {
+	Model     string         `json:"model"`
+	Prompt    string         `json:"prompt"`
+	Images    []string       `json:"images,omitempty"`
+	Stream    bool           `json:"stream"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
 }
 
 // Model validation structs
@@ -57,15 +86,248 @@ type Client struct {
 	ChatMessage ChatMessage
 	Timeout     time.Duration
 	HTTPClient  *fasthttp.Client
+	// ColdTimeout replaces Timeout for model calls until the host has answered one, since the first call of a run
+	// waits for the model to load; it is ignored unless longer than Timeout
+	ColdTimeout time.Duration
+	// KeepAlive is sent with every chat request when set, so the model stays resident on the host
+	KeepAlive string
+	// Options are sent with every chat request when set, e.g. {"temperature": 0}
+	Options map[string]any
+	// Auth supplies credentials for icon downloads; it is never used for the Ollama API
+	Auth *auth.Store
+	// Cookies supplies session cookies for icon downloads
+	Cookies *auth.CookieJar
+	// Robots, when set, refuses icon downloads that the host's robots.txt disallows
+	Robots *robots.Checker
+	// Audit, when set, records every request the client sends
+	Audit *audit.Log
+	// Hosts, when set, aggregates icon and robots.txt downloads per target host
+	Hosts *stats.Hosts
+	// Mode selects the question asked about each icon pair, see Modes; empty means ModeEqual
+	Mode string
+	// Brand names the brand behind the base icon in the prompt; empty leaves it out
+	Brand string
+	// BrandDetails, such as aliases and colors from a brand kit, follow the brand name in the prompt
+	BrandDetails string
+	// Strictness sets how close a target must be to count as a match, see Strictnesses; empty means StrictnessNormal
+	Strictness string
+	// Endpoint selects the API prompts are sent to, see Endpoints; empty means EndpointChat
+	Endpoint string
+	// Similarity is the cosine similarity at or above which EndpointEmbed counts a match; 0 means DefaultSimilarity
+	Similarity float64
+	// Target says what target images are, see Targets; empty means TargetFavicon
+	Target string
+	// Browser captures pages when Target is TargetScreenshot
+	Browser *screenshot.Browser
+	// Normalize controls how icons are preprocessed before comparison, e.g. flattening transparency
+	Normalize imaging.Options
+	// FreshConnections sends each icon download on its own connection, so a rotating dialer sees every request
+	FreshConnections bool
+
+	// dial and downloads route icon and robots.txt fetches through a custom dialer; Ollama API calls keep HTTPClient
+	dial      fasthttp.DialFunc
+	downloads *fasthttp.Client
+	// downloadTLS is the TLS config for icon hosts, set apart from HTTPClient's once a client certificate is given
+	downloadTLS *tls.Config
+
+	ntlmOnce   sync.Once
+	ntlmClient *http.Client
+
+	// embeddings keeps the vectors of base icons, which are compared with every target
+	embeddings sync.Map
+	// recent keeps the latest target vectors, so a target indexed right after its comparison isn't embedded twice
+	recent embeddingCache
+
+	// warm is set once a model call succeeds; until then calls go through cold with ColdTimeout
+	warm     atomic.Bool
+	coldOnce sync.Once
+	cold     *fasthttp.Client
 }
 
 func NewClient(host, model string, timeout time.Duration) *Client {
+	// Icons larger than the imaging limit are refused while reading instead of being buffered in full
 	return &Client{
 		Host:       host,
 		Model:      model,
 		Timeout:    timeout,
-		HTTPClient: &fasthttp.Client{ReadTimeout: timeout, WriteTimeout: timeout, TLSConfig: &tls.Config{InsecureSkipVerify: true}},
+		HTTPClient: &fasthttp.Client{ReadTimeout: timeout, WriteTimeout: timeout, MaxResponseBodySize: imaging.MaxInputSize, TLSConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+}
+
+// RespectRobots makes the client honour robots.txt for icon downloads, fetching it once per host
+func (o *Client) RespectRobots() {
+	o.Robots = robots.NewChecker(o.fetchRobots)
+}
+
+// fetchRobots downloads a robots.txt file, identifying as favlens so host-specific groups apply
+func (o *Client) fetchRobots(url string) (int, []byte, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+	req.Header.SetUserAgent(robots.UserAgent)
+	if o.FreshConnections {
+		req.SetConnectionClose()
 	}
+	if err := o.do(o.downloadClient(), audit.KindRobots, req, resp, 5); err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode(), append([]byte(nil), resp.Body()...), nil
+}
+
+// FetchPage downloads a page from an icon host through the same route, credentials and robots.txt rules as icons
+func (o *Client) FetchPage(url string) (int, []byte, error) {
+	allowed, err := o.Robots.Allowed(url)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !allowed {
+		return 0, nil, fmt.Errorf("%s is disallowed by robots.txt", url)
+	}
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+	if o.FreshConnections {
+		req.SetConnectionClose()
+	}
+	if credential := o.Auth.Lookup(url); credential != nil && credential.Type != auth.TypeNTLM {
+		req.Header.Set("Authorization", credential.Header())
+	}
+	if cookie := o.Cookies.Header(url); cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	if err := o.do(o.downloadClient(), audit.KindPage, req, resp, 5); err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode(), append([]byte(nil), resp.Body()...), nil
+}
+
+// Probe reports whether url answers HTTP at all; any status counts, since only a dead host or the wrong scheme fails
+func (o *Client) Probe(url string) error {
+	allowed, err := o.Robots.Allowed(url)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("%s is disallowed by robots.txt", url)
+	}
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(url)
+	req.Header.SetMethod("HEAD")
+	if o.FreshConnections {
+		req.SetConnectionClose()
+	}
+	return o.do(o.downloadClient(), audit.KindProbe, req, resp, 0)
+}
+
+// do sends req following up to maxRedirects redirects and records the exchange in the audit log
+func (o *Client) do(client *fasthttp.Client, kind string, req *fasthttp.Request, resp *fasthttp.Response, maxRedirects int) error {
+	start := time.Now()
+	err := client.DoRedirects(req, resp, maxRedirects)
+	o.record(kind, req, resp, start, err)
+	if kind != audit.KindOllama {
+		o.Hosts.Record(hostOnly(string(req.URI().Host())), time.Since(start), failure(resp.StatusCode(), err))
+	}
+	return err
+}
+
+// failure describes a download that failed for the per-host statistics, or returns "" when it succeeded
+func failure(status int, err error) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case status >= 400:
+		return fmt.Sprintf("status %d", status)
+	}
+	return ""
+}
+
+// hostOnly drops the port from a host:port, leaving IPv6 literals unbracketed
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.Trim(hostport, "[]")
+}
+
+// record adds a fasthttp exchange to the audit log; write failures are only logged so they never abort a scan
+func (o *Client) record(kind string, req *fasthttp.Request, resp *fasthttp.Response, start time.Time, err error) {
+	if o.Audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		Kind:     kind,
+		Method:   string(req.Header.Method()),
+		URL:      req.URI().String(),
+		SourceIP: audit.IP(resp.LocalAddr()),
+		RemoteIP: audit.IP(resp.RemoteAddr()),
+	}
+	if err == nil {
+		entry.Status = resp.StatusCode()
+		entry.Bytes = len(resp.Body())
+	}
+	if err := o.Audit.Record(entry, start, err); err != nil {
+		gologger.Warning().Msgf("Failed to write audit log entry for %s: %v", entry.URL, err)
+	}
+}
+
+// SetDial makes icon and robots.txt downloads connect through dial, e.g. to bind a source address,
+// while requests to the Ollama API keep using the default dialer
+func (o *Client) SetDial(dial fasthttp.DialFunc) {
+	o.dial = dial
+	o.downloads = o.newDownloadClient()
+}
+
+// newDownloadClient returns a client for icon hosts with HTTPClient's limits and the download dialer and TLS config
+func (o *Client) newDownloadClient() *fasthttp.Client {
+	return &fasthttp.Client{
+		ReadTimeout:         o.HTTPClient.ReadTimeout,
+		WriteTimeout:        o.HTTPClient.WriteTimeout,
+		MaxResponseBodySize: o.HTTPClient.MaxResponseBodySize,
+		TLSConfig:           o.downloadTLSConfig(),
+		Dial:                o.dial,
+	}
+}
+
+// downloadTLSConfig returns the TLS config used for icon hosts
+func (o *Client) downloadTLSConfig() *tls.Config {
+	if o.downloadTLS != nil {
+		return o.downloadTLS
+	}
+	return o.HTTPClient.TLSConfig
+}
+
+// DownloadDial returns the dialer icon and robots.txt downloads connect through, so it can be wrapped and set again
+func (o *Client) DownloadDial() fasthttp.DialFunc {
+	if o.dial != nil {
+		return o.dial
+	}
+	return fasthttp.Dial
+}
+
+// downloadClient returns the client used for requests to icon hosts
+func (o *Client) downloadClient() *fasthttp.Client {
+	if o.downloads != nil {
+		return o.downloads
+	}
+	return o.HTTPClient
+}
+
+// SetClientCertificate presents cert to icon hosts that ask for mutual TLS. Downloads get their own copy of
+// the TLS config, so the certificate is never sent to the Ollama API.
+func (o *Client) SetClientCertificate(cert tls.Certificate) {
+	config := o.HTTPClient.TLSConfig.Clone()
+	config.Certificates = []tls.Certificate{cert}
+	o.downloadTLS = config
+	o.downloads = o.newDownloadClient()
 }
 
 type Result struct {
@@ -74,12 +336,8 @@ type Result struct {
 	Err   error
 }
 
-// CheckModelExists validates if the specified model is available in Ollama
-func (o *Client) CheckModelExists(debug bool) error {
-	if debug {
-		gologger.Debug().Msgf("Checking if model '%s' exists in Ollama", o.Model)
-	}
-
+// ListModels returns the models installed on the Ollama host
+func (o *Client) ListModels(debug bool) ([]Model, error) {
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseRequest(req)
@@ -88,18 +346,18 @@ func (o *Client) CheckModelExists(debug bool) error {
 	req.SetRequestURI(o.Host + "/api/tags")
 	req.Header.SetMethod("GET")
 
-	if err := o.HTTPClient.DoRedirects(req, resp, 3); err != nil {
+	if err := o.do(o.HTTPClient, audit.KindOllama, req, resp, 3); err != nil {
 		if debug {
 			gologger.Debug().Msgf("Failed to connect to Ollama API at %s: %v", o.Host, err)
 		}
-		return fmt.Errorf("failed to connect to Ollama API: %v", err)
+		return nil, fmt.Errorf("failed to connect to Ollama API: %v", err)
 	}
 
 	if resp.StatusCode() != 200 {
 		if debug {
 			gologger.Debug().Msgf("Received status %d from /api/tags", resp.StatusCode())
 		}
-		return fmt.Errorf("ollama API returned status %d", resp.StatusCode())
+		return nil, apiError("/api/tags", o.Model, resp.StatusCode(), resp.Body())
 	}
 
 	var modelsResp ModelsResponse
@@ -107,19 +365,30 @@ func (o *Client) CheckModelExists(debug bool) error {
 		if debug {
 			gologger.Debug().Msgf("Failed to parse models response: %v", err)
 		}
-		return fmt.Errorf("failed to parse models response: %v", err)
+		return nil, fmt.Errorf("failed to parse models response: %v", err)
+	}
+	return modelsResp.Models, nil
+}
+
+// CheckModelExists validates if the specified model is available in Ollama
+func (o *Client) CheckModelExists(debug bool) error {
+	if debug {
+		gologger.Debug().Msgf("Checking if model '%s' exists in Ollama", o.Model)
+	}
+
+	models, err := o.ListModels(debug)
+	if err != nil {
+		return err
 	}
 
 	// Check if the specified model exists
 	modelFound := false
-	availableModels := make([]string, 0, len(modelsResp.Models))
+	availableModels := make([]string, 0, len(models))
 
-	for _, model := range modelsResp.Models {
+	for _, model := range models {
 		availableModels = append(availableModels, model.Name)
 		// Check both exact name match and name without :latest suffix
-		if model.Name == o.Model ||
-			(strings.HasSuffix(model.Name, ":latest") && strings.TrimSuffix(model.Name, ":latest") == o.Model) ||
-			(strings.HasSuffix(o.Model, ":latest") && strings.TrimSuffix(o.Model, ":latest") == strings.TrimSuffix(model.Name, ":latest")) {
+		if ModelMatches(model.Name, o.Model) {
 			modelFound = true
 			if debug {
 				gologger.Debug().Msgf("Found model: %s (size: %d bytes, family: %s)", model.Name, model.Size, model.Details.Family)
@@ -143,21 +412,68 @@ func (o *Client) CheckModelExists(debug bool) error {
 
 // Download favicon from URL and return base64-encoded string
 func (o *Client) DownloadImageAsBase64(url string, debug bool) (string, error) {
+	icon, err := o.DownloadIcon(url, debug)
+	if err != nil {
+		return "", err
+	}
+	return icon.Base64, nil
+}
+
+// DownloadIcon downloads a favicon like DownloadImageAsBase64 and also reports where it was served from
+func (o *Client) DownloadIcon(url string, debug bool) (*Icon, error) {
 	if debug {
 		gologger.Debug().Msgf("Downloading image from: %s", url)
 	}
 
+	// Local files, given as a path or file:// URL, let extracted app icons serve as base images
+	if path, ok := localPath(url); ok {
+		data, err := readLocalIcon(path)
+		if err != nil {
+			return nil, err
+		}
+		return o.newIcon(url, data, nil, debug)
+	}
+
+	allowed, err := o.Robots.Allowed(url)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		if debug {
+			gologger.Debug().Msgf("Skipping %s, disallowed by robots.txt", url)
+		}
+		return nil, fmt.Errorf("%s is disallowed by robots.txt", url)
+	}
+
+	credential := o.Auth.Lookup(url)
+	if credential != nil && credential.Type == auth.TypeNTLM {
+		data, err := o.downloadNTLM(url, credential, debug)
+		if err != nil {
+			return nil, err
+		}
+		return o.newIcon(url, data, nil, debug)
+	}
+
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseRequest(req)
 	defer fasthttp.ReleaseResponse(resp)
 	req.SetRequestURI(url)
 	req.Header.SetMethod("GET")
-	if err := o.HTTPClient.DoRedirects(req, resp, 3); err != nil {
+	if o.FreshConnections {
+		req.SetConnectionClose()
+	}
+	if credential != nil {
+		req.Header.Set("Authorization", credential.Header())
+	}
+	if cookie := o.Cookies.Header(url); cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	if err := o.do(o.downloadClient(), audit.KindIcon, req, resp, 3); err != nil {
 		if debug {
 			gologger.Debug().Msgf("Failed to fetch %s: %v", url, err)
 		}
-		return "", fmt.Errorf("error fetching %s: %v", url, err)
+		return nil, fmt.Errorf("error fetching %s: %v", url, err)
 	}
 
 	if resp.StatusCode() == 301 {
@@ -166,22 +482,37 @@ func (o *Client) DownloadImageAsBase64(url string, debug bool) (string, error) {
 			if debug {
 				gologger.Debug().Msgf("Received redirect status %d from /api/tags, but no Location header", resp.StatusCode())
 			}
-			return "", fmt.Errorf("ollama API returned redirect status %d, but no Location header", resp.StatusCode())
+			return nil, fmt.Errorf("ollama API returned redirect status %d, but no Location header", resp.StatusCode())
 		}
 		req.SetRequestURI(redirectLocation)
-		if err := o.HTTPClient.DoRedirects(req, resp, 3); err != nil {
+		if err := o.do(o.downloadClient(), audit.KindIcon, req, resp, 3); err != nil {
 			if debug {
 				gologger.Debug().Msgf("Failed to connect to Ollama API at %s: %v", redirectLocation, err)
 			}
-			return "", fmt.Errorf("failed to connect to Ollama API: %v", err)
+			return nil, fmt.Errorf("failed to connect to Ollama API: %v", err)
 		}
 	}
 
+	// Remember any session cookies even from error responses, since login redirects often set them
+	if setCookies := resp.Header.PeekAll("Set-Cookie"); len(setCookies) > 0 {
+		values := make([]string, len(setCookies))
+		for i, value := range setCookies {
+			values[i] = string(value)
+		}
+		o.Cookies.Learn(url, values)
+	}
+
+	if err := rateLimitError(url, resp.StatusCode(), string(resp.Header.Peek("Retry-After"))); err != nil {
+		if debug {
+			gologger.Debug().Msgf("%v", err)
+		}
+		return nil, err
+	}
 	if resp.StatusCode() != 200 {
 		if debug {
 			gologger.Debug().Msgf("Bad status code for %s: %d", url, resp.StatusCode())
 		}
-		return "", fmt.Errorf("bad status for %s: %d", url, resp.StatusCode())
+		return nil, fmt.Errorf("bad status for %s: %d", url, resp.StatusCode())
 	}
 
 	// Read image bytes
@@ -192,44 +523,63 @@ func (o *Client) DownloadImageAsBase64(url string, debug bool) (string, error) {
 
 	gologger.Debug().Msgf("Redirect Location: %d\n", resp.StatusCode())
 
-	// Decode image to check format
-	img, format, err := image.Decode(bytes.NewReader(data))
+	// Convert straight from the response body; PNGs are encoded without an intermediate copy
+	return o.newIcon(url, data, resp, debug)
+}
+
+// Icon is a downloaded favicon with details of the response that served it
+type Icon struct {
+	Base64 string
+	// SHA256 is the hash of the bytes as downloaded, before any conversion
+	SHA256 string
+	// MMH3 is the Shodan and httpx favicon hash of the same bytes, and MMH3Raw their mmh3 without the base64 step
+	MMH3    string
+	MMH3Raw string
+	// Info holds the icon's format, size and color details
+	Info imaging.Info
+	// RemoteIP and Headers are only known for icons fetched over fasthttp
+	RemoteIP string
+	Headers  http.Header
+}
+
+// newIcon encodes downloaded bytes, keeping the remote address and headers of resp when there is one
+func (o *Client) newIcon(url string, data []byte, resp *fasthttp.Response, debug bool) (*Icon, error) {
+	b64, info, err := o.encodeIcon(url, data, debug)
 	if err != nil {
-		if debug {
-			gologger.Debug().Msgf("Failed to decode image from %s: %v", url, err)
-		}
-		return "", fmt.Errorf("error decoding image from %s: %v", url, err)
+		return nil, err
 	}
-
-	if debug {
-		gologger.Debug().Msgf("Decoded image format: %s, dimensions: %dx%d", format, img.Bounds().Dx(), img.Bounds().Dy())
+	sum := sha256.Sum256(data)
+	icon := &Icon{Base64: b64, SHA256: hex.EncodeToString(sum[:]), MMH3: favhash.MMH3(data), MMH3Raw: favhash.MMH3Raw(data), Info: info}
+	if resp != nil {
+		icon.RemoteIP = audit.IP(resp.RemoteAddr())
+		icon.Headers = make(http.Header)
+		for key, value := range resp.Header.All() {
+			icon.Headers.Add(string(key), string(value))
+		}
 	}
+	return icon, nil
+}
 
-	// Encode as PNG (more universally supported for APIs)
-	var buf bytes.Buffer
-	if format == "png" {
-		buf.Write(data) // already PNG, just reuse bytes
-		if debug {
-			gologger.Debug().Msgf("Image already in PNG format, reusing bytes")
-		}
-	} else {
-		if err := png.Encode(&buf, img); err != nil {
-			if debug {
-				gologger.Debug().Msgf("Failed to encode PNG: %v", err)
-			}
-			return "", fmt.Errorf("error encoding PNG: %v", err)
-		}
+// encodeIcon converts downloaded icon bytes into a base64 PNG
+func (o *Client) encodeIcon(url string, data []byte, debug bool) (string, imaging.Info, error) {
+	b64, info, err := imaging.Base64PNG(data, o.Normalize)
+	if err != nil {
 		if debug {
-			gologger.Debug().Msgf("Converted %s to PNG format", format)
+			gologger.Debug().Msgf("Failed to convert image from %s: %v", url, err)
 		}
+		return "", info, fmt.Errorf("error converting image from %s: %v", url, err)
 	}
 
-	// Convert to base64
-	b64 := base64.StdEncoding.EncodeToString(buf.Bytes())
 	if debug {
+		gologger.Debug().Msgf("Decoded image format: %s, dimensions: %dx%d", info.Format, info.Width, info.Height)
+		if info.Converted {
+			gologger.Debug().Msgf("Converted %s to PNG format", info.Format)
+		} else {
+			gologger.Debug().Msgf("Image already in PNG format, reusing bytes")
+		}
 		gologger.Debug().Msgf("Generated base64 string of length: %d", len(b64))
 	}
-	return b64, nil
+	return b64, info, nil
 }
 
 // Compare two favicons using Ollama chat API
@@ -237,42 +587,94 @@ func (o *Client) CompareFaviconsChatAPI(base64Base, base64Target string, debug b
 	if debug {
 		gologger.Debug().Msgf("Starting comparison with model: %s", o.Model)
 	}
+	if strings.EqualFold(o.Endpoint, EndpointEmbed) {
+		return o.compareEmbeddings(base64Base, base64Target, debug)
+	}
 
-	reqBody := ChatRequest{
+	answer, err := o.chat(o.Prompt(), []string{base64Base, base64Target}, debug)
+	if err != nil {
+		return false, err
+	}
+
+	match := strings.Contains(answer, "Yes")
+	if debug {
+		gologger.Debug().Msgf("Match result: %v", match)
+	}
+
+	return match, nil
+}
+
+// ExtractText asks the model to read the text shown in an icon; it returns "" when there is none
+func (o *Client) ExtractText(base64Icon string, debug bool) (string, error) {
+	if strings.EqualFold(o.Endpoint, EndpointEmbed) {
+		return "", fmt.Errorf("an embedding model can't read text; use --ollama-endpoint chat or generate")
+	}
+	answer, err := o.chat(textPrompt, []string{base64Icon}, debug)
+	if err != nil {
+		return "", err
+	}
+	text := strings.Trim(strings.TrimSpace(answer), `"'`)
+	if strings.EqualFold(strings.TrimRight(text, "."), "NONE") {
+		return "", nil
+	}
+	return text, nil
+}
+
+// chat sends one user message with images to the chat or generate API and returns the model's full streamed answer
+func (o *Client) chat(content string, images []string, debug bool) (string, error) {
+	endpoint := "/api/chat"
+	var reqBody requestEncoder = ChatRequest{
 		Model: o.Model,
 		Messages: []ChatMessage{
 			{
 				Role:    "user",
-				Content: "Compare these two favicons. Respond only with Yes if visually identical or same brand/logo, otherwise No.",
-				Images:  []string{base64Base, base64Target},
+				Content: content,
+				Images:  images,
 			},
 		},
-		Stream: true,
+		Stream:    true,
+		KeepAlive: o.KeepAlive,
+		Options:   o.Options,
 	}
-
-	body, _ := json.Marshal(reqBody)
-	if debug {
-		gologger.Debug().Msgf("Sending request to Ollama API, payload size: %d bytes", len(body))
+	if strings.EqualFold(o.Endpoint, EndpointGenerate) {
+		endpoint = "/api/generate"
+		reqBody = GenerateRequest{Model: o.Model, Prompt: content, Images: images, Stream: true, KeepAlive: o.KeepAlive, Options: o.Options}
 	}
 
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseRequest(req)
 	defer fasthttp.ReleaseResponse(resp)
-	req.SetRequestURI(o.Host + "/api/chat")
+	req.SetRequestURI(o.Host + endpoint)
 	req.Header.SetMethod("POST")
 	req.Header.SetContentType("application/json")
-	req.SetBody(body)
-	if err := o.HTTPClient.DoTimeout(req, resp, o.Timeout); err != nil {
+
+	// Encode straight into the pooled request body so the images are copied only once
+	if err := reqBody.EncodeTo(req.BodyWriter()); err != nil {
+		return "", fmt.Errorf("failed to encode %s request: %v", endpoint, err)
+	}
+	if debug {
+		gologger.Debug().Msgf("Sending request to Ollama API, payload size: %d bytes", len(req.Body()))
+	}
+	client, timeout := o.modelHTTP(debug)
+	start := time.Now()
+	err := client.DoTimeout(req, resp, timeout)
+	o.record(audit.KindOllama, req, resp, start, err)
+	if err != nil {
 		if debug {
 			gologger.Debug().Msgf("Failed to connect to Ollama API at %s: %v", o.Host, err)
 		}
-		return false, err
+		return "", err
 	}
 
 	if debug {
 		gologger.Debug().Msgf("Received response from Ollama, status: %d", resp.StatusCode())
 	}
+	// Treat server-side failures as errors so they count towards retries and the circuit breaker
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return "", apiError(endpoint, o.Model, resp.StatusCode(), resp.Body())
+	}
+	o.warm.Store(true)
 
 	responseText := string(resp.Body())
 	lines := strings.Split(responseText, "\n")
@@ -289,11 +691,19 @@ func (o *Client) CompareFaviconsChatAPI(base64Base, base64Target string, debug b
 			}
 			continue
 		}
+		// Failures after the stream has started, such as the runner running out of memory, arrive as an error chunk
+		if message, ok := chunk["error"].(string); ok && message != "" {
+			return "", apiError(endpoint, o.Model, resp.StatusCode(), []byte(line))
+		}
+		// Chat chunks carry a message, generate chunks a bare response
 		if msg, ok := chunk["message"].(map[string]any); ok {
 			if content, ok := msg["content"].(string); ok {
 				fullText.WriteString(content)
 			}
 		}
+		if response, ok := chunk["response"].(string); ok {
+			fullText.WriteString(response)
+		}
 		if done, ok := chunk["done"].(bool); ok && done {
 			if debug {
 				gologger.Debug().Msgf("Streaming response complete")
@@ -306,11 +716,111 @@ func (o *Client) CompareFaviconsChatAPI(base64Base, base64Target string, debug b
 	if debug {
 		gologger.Debug().Msgf("Model response: %s", answer)
 	}
+	return answer, nil
+}
 
-	match := strings.Contains(answer, "Yes")
-	if debug {
-		gologger.Debug().Msgf("Match result: %v", match)
+// localPath returns the file behind a file:// URL or an existing local path
+func localPath(url string) (string, bool) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return path, true
+	}
+	if strings.Contains(url, "://") {
+		return "", false
 	}
+	info, err := os.Stat(url)
+	return url, err == nil && info.Mode().IsRegular()
+}
 
-	return match, nil
+func readLocalIcon(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, imaging.MaxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// downloadNTLM fetches url with an NTLM handshake. fasthttp can't hold the connection-bound
+// handshake, so these hosts go through net/http instead.
+func (o *Client) downloadNTLM(url string, credential *auth.Credential, debug bool) (data []byte, err error) {
+	o.ntlmOnce.Do(func() {
+		transport := &http.Transport{TLSClientConfig: o.downloadTLSConfig().Clone()}
+		if o.dial != nil {
+			transport.DialContext = func(_ context.Context, _, addr string) (net.Conn, error) {
+				return o.dial(addr)
+			}
+		}
+		o.ntlmClient = &http.Client{
+			Timeout:   o.Timeout,
+			Transport: ntlmssp.Negotiator{RoundTripper: transport},
+		}
+	})
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %v", url, err)
+	}
+
+	// The handshake can open several connections; the audit entry keeps the addresses of the last one
+	entry := audit.Entry{Kind: audit.KindIcon, Method: req.Method, URL: url}
+	if o.Audit != nil {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				entry.SourceIP = audit.IP(info.Conn.LocalAddr())
+				entry.RemoteIP = audit.IP(info.Conn.RemoteAddr())
+			},
+		}))
+		start := time.Now()
+		defer func() {
+			entry.Bytes = len(data)
+			if err := o.Audit.Record(entry, start, err); err != nil {
+				gologger.Warning().Msgf("Failed to write audit log entry for %s: %v", url, err)
+			}
+		}()
+	}
+	req.SetBasicAuth(credential.Username, credential.Password)
+	if cookie := o.Cookies.Header(url); cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	start := time.Now()
+	resp, err := o.ntlmClient.Do(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	o.Hosts.Record(hostOnly(req.URL.Host), time.Since(start), failure(status, err))
+	if err != nil {
+		if debug {
+			gologger.Debug().Msgf("Failed to fetch %s with NTLM: %v", url, err)
+		}
+		return nil, fmt.Errorf("error fetching %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	entry.Status = resp.StatusCode
+	o.Cookies.Learn(url, resp.Header.Values("Set-Cookie"))
+
+	if err := rateLimitError(url, resp.StatusCode, resp.Header.Get("Retry-After")); err != nil {
+		if debug {
+			gologger.Debug().Msgf("%v", err)
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if debug {
+			gologger.Debug().Msgf("Bad status code for %s: %d", url, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("bad status for %s: %d", url, resp.StatusCode)
+	}
+	data, err = io.ReadAll(io.LimitReader(resp.Body, imaging.MaxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", url, err)
+	}
+	if debug {
+		gologger.Debug().Msgf("Downloaded %d bytes from %s with NTLM", len(data), url)
+	}
+	return data, nil
 }
@@ -0,0 +1,331 @@
+// Package ollamatest provides a fake Ollama server and an in-memory OllamaAPI stub, so code built on
+// package ollama can be tested without a real model. Both answer comparisons deterministically: two
+// identical images match, anything else doesn't.
+package ollamatest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+)
+
+// AnswerFunc returns the model's reply to a chat message carrying images
+type AnswerFunc func(images []string) string
+
+// DefaultAnswer says Yes when every image is identical and No otherwise; a single image has no text
+func DefaultAnswer(images []string) string {
+	if len(images) < 2 {
+		return "NONE"
+	}
+	for _, image := range images[1:] {
+		if image != images[0] {
+			return "No"
+		}
+	}
+	return "Yes"
+}
+
+// failure is a canned error response served instead of the real one
+type failure struct {
+	status  int
+	message string
+}
+
+// Server is a fake Ollama server listening on a loopback address. It implements /api/tags, /api/ps, /api/show,
+// /api/pull, /api/chat and /api/generate, streaming answers the way Ollama does, and /api/embed, whose vectors
+// are derived from the image bytes so identical images have a cosine similarity of 1.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	installed []string
+	answer    AnswerFunc
+	failures  []failure
+	chats     []ollama.ChatRequest
+	pulls     []string
+}
+
+// NewServer starts a fake server with models installed; call Close when done
+func NewServer(models ...string) *Server {
+	s := &Server{installed: models, answer: DefaultAnswer}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", s.tags)
+	mux.HandleFunc("GET /api/ps", s.ps)
+	mux.HandleFunc("POST /api/show", s.show)
+	mux.HandleFunc("POST /api/pull", s.pull)
+	mux.HandleFunc("POST /api/chat", s.chat)
+	mux.HandleFunc("POST /api/generate", s.generate)
+	mux.HandleFunc("POST /api/embed", s.embed)
+	s.Server = httptest.NewServer(s.failing(mux))
+	return s
+}
+
+// Client returns a client for model that talks to the server
+func (s *Server) Client(model string) *ollama.Client {
+	return ollama.NewClient(s.URL, model, 5*time.Second)
+}
+
+// Answer replaces how the server replies to chat requests
+func (s *Server) Answer(fn AnswerFunc) {
+	s.mu.Lock()
+	s.answer = fn
+	s.mu.Unlock()
+}
+
+// Install adds models as if they had been pulled
+func (s *Server) Install(models ...string) {
+	s.mu.Lock()
+	s.installed = append(s.installed, models...)
+	s.mu.Unlock()
+}
+
+// FailNext answers the next n requests, on any endpoint, with status and an Ollama error body
+func (s *Server) FailNext(n, status int, message string) {
+	s.mu.Lock()
+	for range n {
+		s.failures = append(s.failures, failure{status: status, message: message})
+	}
+	s.mu.Unlock()
+}
+
+// Chats returns every chat request received so far, including prompts and images. Generate requests are
+// recorded as a single user message.
+func (s *Server) Chats() []ollama.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ollama.ChatRequest(nil), s.chats...)
+}
+
+// Pulls returns the model names pulled so far
+func (s *Server) Pulls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.pulls...)
+}
+
+// failing serves queued failures before handing requests to next
+func (s *Server) failing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		var fail *failure
+		if len(s.failures) > 0 {
+			fail = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+		if fail != nil {
+			writeError(w, fail.status, fail.message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// model returns the installed name matching requested, or "" when it isn't installed
+func (s *Server) model(requested string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range s.installed {
+		if ollama.ModelMatches(name, requested) {
+			return name
+		}
+	}
+	return ""
+}
+
+func (s *Server) tags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	models := make([]ollama.Model, len(s.installed))
+	for i, name := range s.installed {
+		models[i] = fakeModel(name)
+	}
+	s.mu.Unlock()
+	writeJSON(w, ollama.ModelsResponse{Models: models})
+}
+
+// ps lists every installed model as loaded entirely into VRAM
+func (s *Server) ps(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	running := make([]ollama.RunningModel, len(s.installed))
+	for i, name := range s.installed {
+		running[i] = ollama.RunningModel{Name: name, Model: name, Size: 1 << 30, SizeVRAM: 1 << 30, ExpiresAt: time.Now().Add(5 * time.Minute), Details: fakeModel(name).Details}
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{"models": running})
+}
+
+func (s *Server) show(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := s.model(req.Model)
+	if name == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", req.Model))
+		return
+	}
+	writeJSON(w, ollama.ShowResponse{Details: fakeModel(name).Details, Capabilities: []string{"completion", "vision"}})
+}
+
+func (s *Server) pull(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Stream *bool  `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	s.pulls = append(s.pulls, req.Model)
+	s.mu.Unlock()
+	if s.model(req.Model) == "" {
+		s.Install(req.Model)
+	}
+
+	// Ollama streams progress unless asked not to
+	if req.Stream == nil || *req.Stream {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, status := range []string{"pulling manifest", "verifying sha256 digest", "success"} {
+			json.NewEncoder(w).Encode(map[string]string{"status": status})
+		}
+		return
+	}
+	writeJSON(w, map[string]string{"status": "success"})
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	var req ollama.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.reply(w, req, chatChunk)
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	var gen ollama.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&gen); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := ollama.ChatRequest{
+		Model:     gen.Model,
+		Messages:  []ollama.ChatMessage{{Role: "user", Content: gen.Prompt, Images: gen.Images}},
+		Stream:    gen.Stream,
+		KeepAlive: gen.KeepAlive,
+		Options:   gen.Options,
+	}
+	s.reply(w, req, generateChunk)
+}
+
+func (s *Server) embed(w http.ResponseWriter, r *http.Request) {
+	var req ollama.EmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.model(req.Model) == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model \"%s\" not found, try pulling it first", req.Model))
+		return
+	}
+	resp := ollama.EmbedResponse{Model: req.Model, Embeddings: make([][]float64, len(req.Images))}
+	for i, image := range req.Images {
+		resp.Embeddings[i] = fakeEmbedding(image)
+	}
+	writeJSON(w, resp)
+}
+
+// fakeEmbedding spreads the image's SHA-256 around zero, so different images are close to orthogonal
+func fakeEmbedding(image string) []float64 {
+	sum := sha256.Sum256([]byte(image))
+	vector := make([]float64, len(sum))
+	for i, b := range sum {
+		vector[i] = float64(b) - 127.5
+	}
+	return vector
+}
+
+// reply records req and answers it, streaming unless asked not to, with chunks built by chunk
+func (s *Server) reply(w http.ResponseWriter, req ollama.ChatRequest, chunk func(model, content string, done bool) map[string]any) {
+	s.mu.Lock()
+	s.chats = append(s.chats, req)
+	answer := s.answer
+	s.mu.Unlock()
+	if s.model(req.Model) == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model \"%s\" not found, try pulling it first", req.Model))
+		return
+	}
+
+	var images []string
+	for _, msg := range req.Messages {
+		images = append(images, msg.Images...)
+	}
+	reply := answer(images)
+
+	if !req.Stream {
+		writeJSON(w, chunk(req.Model, reply, true))
+		return
+	}
+	// Stream the reply a word at a time, ending with an empty done chunk as Ollama does
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, word := range strings.SplitAfter(reply, " ") {
+		if word != "" {
+			enc.Encode(chunk(req.Model, word, false))
+		}
+	}
+	enc.Encode(chunk(req.Model, "", true))
+}
+
+// chatChunk is one streamed /api/chat response line
+func chatChunk(model, content string, done bool) map[string]any {
+	return map[string]any{
+		"model":      model,
+		"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+		"message":    ollama.ChatMessage{Role: "assistant", Content: content},
+		"done":       done,
+	}
+}
+
+// generateChunk is one streamed /api/generate response line
+func generateChunk(model, content string, done bool) map[string]any {
+	return map[string]any{
+		"model":      model,
+		"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+		"response":   content,
+		"done":       done,
+	}
+}
+
+// fakeModel describes an installed model as a vision-capable gemma3
+func fakeModel(name string) ollama.Model {
+	return ollama.Model{
+		Name:    name,
+		Model:   name,
+		Digest:  fmt.Sprintf("%x", name),
+		Details: ollama.ModelDetails{Format: "gguf", Family: "gemma3", Families: []string{"gemma3"}},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with Ollama's error body, {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
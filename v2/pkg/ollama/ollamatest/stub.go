@@ -0,0 +1,114 @@
+package ollamatest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+)
+
+// Stub implements ollama.OllamaAPI in memory, for code that takes the interface, such as a pool built
+// with ollama.NewAPIPool. Set its fields before use.
+type Stub struct {
+	// Model is the model the stub is configured for
+	Model string
+	// Answer replies to comparisons and text extraction; nil uses DefaultAnswer
+	Answer AnswerFunc
+	// Err, when set, is returned by every call
+	Err error
+
+	mu        sync.Mutex
+	installed []string
+	calls     atomic.Int64
+}
+
+// NewStub returns a stub for model with installed models available
+func NewStub(model string, installed ...string) *Stub {
+	return &Stub{Model: model, installed: installed}
+}
+
+// Calls returns how many comparisons, text extractions and embeddings the stub has answered
+func (s *Stub) Calls() int64 {
+	return s.calls.Load()
+}
+
+func (s *Stub) ListModels(debug bool) ([]ollama.Model, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models := make([]ollama.Model, len(s.installed))
+	for i, name := range s.installed {
+		models[i] = fakeModel(name)
+	}
+	return models, nil
+}
+
+func (s *Stub) CheckModelExists(debug bool) error {
+	models, err := s.ListModels(debug)
+	if err != nil {
+		return err
+	}
+	available := make([]string, len(models))
+	for i, model := range models {
+		if ollama.ModelMatches(model.Name, s.Model) {
+			return nil
+		}
+		available[i] = model.Name
+	}
+	return fmt.Errorf("model '%s' not found. Available models: %v", s.Model, available)
+}
+
+func (s *Stub) PullModel(timeout time.Duration, debug bool) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.mu.Lock()
+	s.installed = append(s.installed, s.Model)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Stub) CompareFaviconsChatAPI(base64Base, base64Target string, debug bool) (bool, error) {
+	answer, err := s.reply(base64Base, base64Target)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(answer, "Yes"), nil
+}
+
+func (s *Stub) ExtractText(base64Icon string, debug bool) (string, error) {
+	answer, err := s.reply(base64Icon)
+	if err != nil || answer == "NONE" {
+		return "", err
+	}
+	return answer, nil
+}
+
+// Embed returns the same vector as the fake server's /api/embed
+func (s *Stub) Embed(base64Icon string, debug bool) ([]float64, error) {
+	s.calls.Add(1)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return fakeEmbedding(base64Icon), nil
+}
+
+// reply counts the call and answers it
+func (s *Stub) reply(images ...string) (string, error) {
+	s.calls.Add(1)
+	if s.Err != nil {
+		return "", s.Err
+	}
+	answer := s.Answer
+	if answer == nil {
+		answer = DefaultAnswer
+	}
+	return answer(images), nil
+}
+
+var _ ollama.OllamaAPI = (*Stub)(nil)
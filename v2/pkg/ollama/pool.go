@@ -0,0 +1,197 @@
+package ollama
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Load-balancing strategies for spreading comparisons over several Ollama hosts
+const (
+	StrategySticky       = "sticky"
+	StrategyRoundRobin   = "round-robin"
+	StrategyLeastLatency = "least-latency"
+)
+
+// Strategies lists every strategy accepted by NewPool
+var Strategies = []string{StrategySticky, StrategyRoundRobin, StrategyLeastLatency}
+
+// poolKeepAlive keeps the model loaded on every host between requests so hosts don't swap models in and out
+const poolKeepAlive = "30m"
+
+// latencyWeight is the share a new sample takes in a host's moving latency average
+const latencyWeight = 0.3
+
+// ValidateStrategy reports whether strategy is supported by NewPool
+func ValidateStrategy(strategy string) error {
+	for _, s := range Strategies {
+		if strings.EqualFold(strategy, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported load-balancing strategy '%s' (supported: %s)", strategy, strings.Join(Strategies, ", "))
+}
+
+// SplitHosts parses a comma-separated list of Ollama hosts
+func SplitHosts(hosts string) []string {
+	var out []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimRight(strings.TrimSpace(host), "/"); host != "" {
+			out = append(out, host)
+		}
+	}
+	return out
+}
+
+// HostStats summarises the comparisons sent to one host
+type HostStats struct {
+	Host       string
+	Requests   int64
+	AvgLatency time.Duration
+	// InFlight is how many calls to the host are waiting for an answer
+	InFlight int64
+}
+
+// poolHost tracks load and latency for one backend
+type poolHost struct {
+	name string
+	api  OllamaAPI
+	// client is nil for backends that aren't a Client, such as test stubs
+	client   *Client
+	inflight atomic.Int64
+	requests atomic.Int64
+	total    atomic.Int64
+
+	mu   sync.Mutex
+	ewma time.Duration
+}
+
+// Pool spreads comparisons over one or more Ollama hosts. With the sticky strategy each worker
+// always talks to the same host, so its keep-alive connection and the loaded model are reused.
+type Pool struct {
+	hosts    []*poolHost
+	strategy string
+	next     atomic.Uint64
+}
+
+// NewPool creates a client per host; every client keeps the model loaded between requests
+func NewPool(hosts []string, model string, timeout time.Duration, strategy string) *Pool {
+	p := &Pool{strategy: strings.ToLower(strategy)}
+	for _, host := range hosts {
+		client := NewClient(host, model, timeout)
+		client.KeepAlive = poolKeepAlive
+		client.HTTPClient.MaxIdleConnDuration = time.Minute
+		p.hosts = append(p.hosts, &poolHost{name: host, api: client, client: client})
+	}
+	return p
+}
+
+// NewAPIPool spreads comparisons over existing backends, such as clients configured elsewhere or the
+// stubs in package ollamatest. Clients are named by their host, other backends by their position.
+func NewAPIPool(strategy string, apis ...OllamaAPI) *Pool {
+	p := &Pool{strategy: strings.ToLower(strategy)}
+	for i, api := range apis {
+		h := &poolHost{name: fmt.Sprintf("backend %d", i+1), api: api}
+		if client, ok := api.(*Client); ok {
+			h.name, h.client = client.Host, client
+		}
+		p.hosts = append(p.hosts, h)
+	}
+	return p
+}
+
+// Clients returns the client for every host in the pool that is backed by a Client
+func (p *Pool) Clients() []*Client {
+	clients := make([]*Client, 0, len(p.hosts))
+	for _, h := range p.hosts {
+		if h.client != nil {
+			clients = append(clients, h.client)
+		}
+	}
+	return clients
+}
+
+// pick chooses the host for the next comparison made by worker
+func (p *Pool) pick(worker int) *poolHost {
+	if len(p.hosts) == 1 {
+		return p.hosts[0]
+	}
+	switch p.strategy {
+	case StrategyRoundRobin:
+		return p.hosts[(p.next.Add(1)-1)%uint64(len(p.hosts))]
+	case StrategyLeastLatency:
+		// Weigh the moving latency by the work already queued so a fast host isn't buried;
+		// hosts without samples score zero and are tried first
+		best := p.hosts[0]
+		bestScore := time.Duration(-1)
+		for _, h := range p.hosts {
+			h.mu.Lock()
+			score := h.ewma * time.Duration(h.inflight.Load()+1)
+			h.mu.Unlock()
+			if bestScore < 0 || score < bestScore {
+				best, bestScore = h, score
+			}
+		}
+		return best
+	default:
+		return p.hosts[worker%len(p.hosts)]
+	}
+}
+
+// Compare runs CompareFaviconsChatAPI on the host chosen for worker and records its latency
+func (p *Pool) Compare(worker int, base64Base, base64Target string, debug bool) (match bool, err error) {
+	p.run(worker, func(api OllamaAPI) {
+		match, err = api.CompareFaviconsChatAPI(base64Base, base64Target, debug)
+	})
+	return match, err
+}
+
+// ExtractText runs ExtractText on the host chosen for worker and records its latency
+func (p *Pool) ExtractText(worker int, base64Icon string, debug bool) (text string, err error) {
+	p.run(worker, func(api OllamaAPI) {
+		text, err = api.ExtractText(base64Icon, debug)
+	})
+	return text, err
+}
+
+// Embed runs Embed on the host chosen for worker and records its latency
+func (p *Pool) Embed(worker int, base64Icon string, debug bool) (embedding []float64, err error) {
+	p.run(worker, func(api OllamaAPI) {
+		embedding, err = api.Embed(base64Icon, debug)
+	})
+	return embedding, err
+}
+
+// run calls fn with the backend chosen for worker and records how long it took
+func (p *Pool) run(worker int, fn func(api OllamaAPI)) {
+	h := p.pick(worker)
+	h.inflight.Add(1)
+	start := time.Now()
+	fn(h.api)
+	elapsed := time.Since(start)
+	h.inflight.Add(-1)
+
+	h.requests.Add(1)
+	h.total.Add(int64(elapsed))
+	h.mu.Lock()
+	if h.ewma == 0 {
+		h.ewma = elapsed
+	} else {
+		h.ewma = time.Duration(latencyWeight*float64(elapsed) + (1-latencyWeight)*float64(h.ewma))
+	}
+	h.mu.Unlock()
+}
+
+// Stats returns per-host request counts, average latency and calls in flight
+func (p *Pool) Stats() []HostStats {
+	stats := make([]HostStats, len(p.hosts))
+	for i, h := range p.hosts {
+		stats[i] = HostStats{Host: h.name, Requests: h.requests.Load(), InFlight: h.inflight.Load()}
+		if stats[i].Requests > 0 {
+			stats[i].AvgLatency = time.Duration(h.total.Load() / stats[i].Requests)
+		}
+	}
+	return stats
+}
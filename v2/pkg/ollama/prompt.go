@@ -0,0 +1,162 @@
+package ollama
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Comparison modes, each asking the model a different question about the icon pair
+const (
+	// ModeEqual asks whether the target is the same icon or brand as the base
+	ModeEqual = "equal"
+	// ModeContains asks whether the base logo appears anywhere inside the target image
+	ModeContains = "contains"
+)
+
+// Modes lists every mode accepted by Client.Mode
+var Modes = []string{ModeEqual, ModeContains}
+
+// Strictness levels, trading missed redesigns against false positives from merely similar icons
+const (
+	// StrictnessLenient also accepts different artwork of the same brand and imitations of its logo
+	StrictnessLenient = "lenient"
+	// StrictnessNormal accepts identical icons and the same brand or logo
+	StrictnessNormal = "normal"
+	// StrictnessStrict only accepts visually identical icons
+	StrictnessStrict = "strict"
+)
+
+// Strictnesses lists every level accepted by Client.Strictness
+var Strictnesses = []string{StrictnessLenient, StrictnessNormal, StrictnessStrict}
+
+// What the target image of a comparison is
+const (
+	// TargetFavicon compares the target's favicon with the base icon
+	TargetFavicon = "favicon"
+	// TargetScreenshot asks whether a screenshot of the target page impersonates the base brand
+	TargetScreenshot = "screenshot"
+)
+
+// Targets lists every target accepted by Client.Target
+var Targets = []string{TargetFavicon, TargetScreenshot}
+
+// API endpoints prompts can be sent to
+const (
+	// EndpointChat sends each prompt as a user message through the model's chat template
+	EndpointChat = "chat"
+	// EndpointGenerate sends the raw prompt with images, for vision models without a usable chat template
+	EndpointGenerate = "generate"
+	// EndpointEmbed asks an image embedding model for a vector per icon and compares them by cosine similarity
+	// instead of prompting, far cheaper per pair than a chat
+	EndpointEmbed = "embed"
+)
+
+// Endpoints lists every endpoint accepted by Client.Endpoint
+var Endpoints = []string{EndpointChat, EndpointGenerate, EndpointEmbed}
+
+// DefaultPrompt is the question asked in equal mode with no brand name at normal strictness
+const DefaultPrompt = "Compare these two favicons. Respond only with Yes if visually identical or same brand/logo, otherwise No."
+
+// equalTemplate renders DefaultPrompt when no brand name or strictness is set
+var equalTemplate = template.Must(template.New("equal").Parse("Compare these two favicons." +
+	"{{if .Brand}} The first one is the logo of {{.Brand}}.{{with .Details}} {{.}}{{end}}{{end}} Respond only with Yes if " +
+	"{{if eq .Strictness \"strict\"}}visually identical" +
+	"{{else if eq .Strictness \"lenient\"}}same brand/logo{{if .Brand}} as {{.Brand}}{{end}}, even with different artwork, colors or style, or an imitation of it" +
+	"{{else}}visually identical or same brand/logo{{if .Brand}} as {{.Brand}}{{end}}{{end}}, otherwise No."))
+
+// containsTemplate catches logos composited into larger artwork, watermarks and page screenshots
+var containsTemplate = template.Must(template.New("contains").Parse("The first image is " +
+	"{{if .Brand}}the logo of {{.Brand}}.{{with .Details}} {{.}}{{end}}{{else}}a brand logo.{{end}} Does that logo appear anywhere within the second image, " +
+	"{{if eq .Strictness \"strict\"}}unaltered apart from its size" +
+	"{{else}}even if it is small, cropped, recolored, composited into other artwork or used as a watermark" +
+	"{{if eq .Strictness \"lenient\"}}, or is it imitated there{{end}}{{end}}? Respond only with Yes if it does, otherwise No."))
+
+// screenshotTemplate judges the whole page, since phishing pages often copy a brand's look without its favicon
+var screenshotTemplate = template.Must(template.New("screenshot").Parse("The first image is " +
+	"{{if .Brand}}the logo of {{.Brand}}.{{with .Details}} {{.}}{{end}}{{else}}a brand logo.{{end}} The second image is a screenshot of a web page. " +
+	"Does the page present itself as {{if .Brand}}{{.Brand}}{{else}}that brand{{end}}, " +
+	"{{if eq .Strictness \"strict\"}}showing its logo or name as its own" +
+	"{{else}}for example with its logo, name, colors or a copy of its login page" +
+	"{{if eq .Strictness \"lenient\"}}, or does it imitate its look{{end}}{{end}}? Respond only with Yes if it does, otherwise No."))
+
+// promptVars are the values interpolated into the comparison templates
+type promptVars struct {
+	Brand      string
+	Details    string
+	Strictness string
+}
+
+// textPrompt asks for the letters in an icon, which many letterform marks consist of
+const textPrompt = "Read any letters, words or numbers shown in this icon. Respond only with the text exactly as it appears, " +
+	"or NONE if the icon contains no text."
+
+// ValidateMode reports whether mode is empty or one of Modes
+func ValidateMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, m := range Modes {
+		if strings.EqualFold(mode, m) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported match mode '%s' (supported: %s)", mode, strings.Join(Modes, ", "))
+}
+
+// ValidateStrictness reports whether strictness is empty or one of Strictnesses
+func ValidateStrictness(strictness string) error {
+	if strictness == "" {
+		return nil
+	}
+	for _, s := range Strictnesses {
+		if strings.EqualFold(strictness, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported strictness '%s' (supported: %s)", strictness, strings.Join(Strictnesses, ", "))
+}
+
+// ValidateTarget reports whether target is empty or one of Targets
+func ValidateTarget(target string) error {
+	if target == "" {
+		return nil
+	}
+	for _, t := range Targets {
+		if strings.EqualFold(target, t) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported mode '%s' (supported: %s)", target, strings.Join(Targets, ", "))
+}
+
+// ValidateEndpoint reports whether endpoint is empty or one of Endpoints
+func ValidateEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+	for _, e := range Endpoints {
+		if strings.EqualFold(endpoint, e) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported Ollama endpoint '%s' (supported: %s)", endpoint, strings.Join(Endpoints, ", "))
+}
+
+// Prompt returns the question sent with every comparison, filled in with the brand name and strictness
+func (o *Client) Prompt() string {
+	tmpl := equalTemplate
+	switch {
+	case strings.EqualFold(o.Target, TargetScreenshot):
+		tmpl = screenshotTemplate
+	case strings.EqualFold(o.Mode, ModeContains):
+		tmpl = containsTemplate
+	}
+	var b strings.Builder
+	vars := promptVars{Brand: strings.TrimSpace(o.Brand), Details: strings.TrimSpace(o.BrandDetails), Strictness: strings.ToLower(o.Strictness)}
+	if err := tmpl.Execute(&b, vars); err != nil {
+		// The templates only print strings, so this cannot happen short of a broken template
+		panic(err)
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	audit "github.com/ethicalhackingplayground/favlens/v2/pkg/audit"
+	"github.com/projectdiscovery/gologger"
+	"github.com/valyala/fasthttp"
+)
+
+// defaultParallel is how many requests Ollama serves at once per loaded model on a GPU when OLLAMA_NUM_PARALLEL
+// isn't set; further requests queue on the server and time out on the client
+const defaultParallel = 4
+
+// RunningModel is a model loaded on an Ollama host, as listed by /api/ps
+type RunningModel struct {
+	Name      string       `json:"name"`
+	Model     string       `json:"model"`
+	Size      int64        `json:"size"`
+	SizeVRAM  int64        `json:"size_vram"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	Details   ModelDetails `json:"details"`
+}
+
+// Capacity estimates how many comparisons a host serves at once
+type Capacity struct {
+	Host string
+	// Loaded is false when the model isn't in memory, which leaves Parallel unknown
+	Loaded bool
+	// VRAM is the share of the model held in GPU memory, from 0 (CPU only) to 1
+	VRAM     float64
+	Parallel int
+}
+
+// RunningModels lists the models loaded on the host
+func (o *Client) RunningModels(debug bool) ([]RunningModel, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(o.Host + "/api/ps")
+	req.Header.SetMethod("GET")
+
+	if err := o.do(o.HTTPClient, audit.KindOllama, req, resp, 3); err != nil {
+		return nil, fmt.Errorf("failed to connect to Ollama API: %v", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, apiError("/api/ps", o.Model, resp.StatusCode(), resp.Body())
+	}
+	var running struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := json.Unmarshal(resp.Body(), &running); err != nil {
+		return nil, fmt.Errorf("failed to parse /api/ps response: %v", err)
+	}
+	if debug {
+		for _, m := range running.Models {
+			gologger.Debug().Msgf("%s has %s loaded: %d of %d bytes in VRAM", o.Host, m.Name, m.SizeVRAM, m.Size)
+		}
+	}
+	return running.Models, nil
+}
+
+// EstimateCapacity reads /api/ps to judge how many comparisons the host can serve at once. Ollama doesn't report
+// its parallelism, so it is inferred from where the model is loaded: fully on GPU it serves Ollama's default of
+// four requests at once, while a model partly or wholly in system memory is bound by the CPU and serves one.
+func (o *Client) EstimateCapacity(debug bool) (Capacity, error) {
+	capacity := Capacity{Host: o.Host}
+	running, err := o.RunningModels(debug)
+	if err != nil {
+		return capacity, err
+	}
+	for _, m := range running {
+		if !ModelMatches(m.Name, o.Model) && !ModelMatches(m.Model, o.Model) {
+			continue
+		}
+		capacity.Loaded = true
+		if m.Size > 0 {
+			capacity.VRAM = min(float64(m.SizeVRAM)/float64(m.Size), 1)
+		}
+		capacity.Parallel = 1
+		if capacity.VRAM >= 1 {
+			capacity.Parallel = defaultParallel
+		}
+		break
+	}
+	return capacity, nil
+}
@@ -0,0 +1,57 @@
+package ollama
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetryAfter is the wait applied to a 429 that carries no usable Retry-After header
+const defaultRetryAfter = 10 * time.Second
+
+// maxRetryAfter caps the wait a host can ask for, so one misconfigured host can't park targets for hours
+const maxRetryAfter = 5 * time.Minute
+
+// RateLimitError is returned when an icon host answers 429, or 503 with a Retry-After header.
+// The target is worth retrying once RetryAfter has passed rather than being recorded as failed.
+type RateLimitError struct {
+	URL        string
+	Status     int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited fetching %s: status %d, retry after %s", e.URL, e.Status, e.RetryAfter)
+}
+
+// rateLimitError returns a *RateLimitError for rate-limiting responses and nil for anything else
+func rateLimitError(url string, status int, retryAfter string) error {
+	wait, ok := parseRetryAfter(retryAfter, time.Now())
+	switch {
+	case status == http.StatusTooManyRequests:
+		if !ok {
+			wait = defaultRetryAfter
+		}
+	case status == http.StatusServiceUnavailable && ok:
+	default:
+		return nil
+	}
+	return &RateLimitError{URL: url, Status: status, RetryAfter: min(wait, maxRetryAfter)}
+}
+
+// parseRetryAfter accepts both forms of the header: delay-seconds and an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,40 @@
+package ollama
+
+import (
+	"fmt"
+	"time"
+
+	audit "github.com/ethicalhackingplayground/favlens/v2/pkg/audit"
+	"github.com/projectdiscovery/gologger"
+)
+
+// CaptureScreenshot loads a page in the client's headless browser and returns the screenshot as an Icon,
+// so it flows through the same comparison pipeline as a downloaded favicon
+func (o *Client) CaptureScreenshot(url string, debug bool) (*Icon, error) {
+	if o.Browser == nil {
+		return nil, fmt.Errorf("screenshots need a browser")
+	}
+	allowed, err := o.Robots.Allowed(url)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%s is disallowed by robots.txt", url)
+	}
+
+	if debug {
+		gologger.Debug().Msgf("Capturing screenshot of: %s", url)
+	}
+	start := time.Now()
+	data, err := o.Browser.Capture(url)
+	if o.Audit != nil {
+		entry := audit.Entry{Kind: audit.KindScreenshot, Method: "GET", URL: url, Bytes: len(data)}
+		if err := o.Audit.Record(entry, start, err); err != nil {
+			gologger.Warning().Msgf("Failed to write audit log entry for %s: %v", url, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o.newIcon(url, data, nil, debug)
+}
@@ -0,0 +1,85 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// warmIdle is how long warmed connections are kept open unused, so they survive until the first comparison
+const warmIdle = time.Minute
+
+// Warm resolves the Ollama host and opens connections to it up front, so the first comparisons don't pay
+// for DNS and connection setup under load. It fails with a clear error when the host can't be reached.
+func (o *Client) Warm(connections int) error {
+	u, err := url.Parse(o.Host)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid Ollama host '%s'", o.Host)
+	}
+	if net.ParseIP(u.Hostname()) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+		defer cancel()
+		if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+			return fmt.Errorf("can't resolve Ollama host %s: %v", u.Hostname(), err)
+		}
+	}
+
+	// Keep the pool large enough for every worker and its connections open until they are needed
+	connections = max(connections, 1)
+	if o.HTTPClient.MaxConnsPerHost > 0 && o.HTTPClient.MaxConnsPerHost < connections {
+		o.HTTPClient.MaxConnsPerHost = connections
+	}
+	if o.HTTPClient.MaxIdleConnDuration < warmIdle {
+		o.HTTPClient.MaxIdleConnDuration = warmIdle
+	}
+
+	// Concurrent requests can't share a connection, so each one leaves a connection idle in the pool
+	errs := make(chan error, connections)
+	for range connections {
+		go func() {
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+			req.SetRequestURI(o.Host + "/")
+			req.Header.SetMethod("GET")
+			errs <- o.HTTPClient.DoTimeout(req, resp, o.Timeout)
+		}()
+	}
+	for range connections {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("can't reach Ollama at %s: %v (is 'ollama serve' running?)", o.Host, err)
+	}
+	return nil
+}
+
+// Warm warms every host in the pool at once, opening connections for each; backends that aren't a
+// Client are skipped
+func (p *Pool) Warm(connections int) error {
+	clients := p.Clients()
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.Warm(connections)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Records reports whether the format writes result at all: json and parquet keep every result, the other formats
+// only matches
+func Records(format string, result types.Result) bool {
+	switch strings.ToLower(format) {
+	case FormatJSON, FormatParquet:
+		return true
+	}
+	return result.Err == nil && result.Match
+}
+
+// ChunkPath numbers a chunk of the output file, putting the number before the extension so tools still recognise
+// the format: results.json becomes results.0001.json
+func ChunkPath(file string, chunk int) string {
+	ext := path.Ext(file)
+	return fmt.Sprintf("%s.%04d%s", strings.TrimSuffix(file, ext), chunk, ext)
+}
+
+// ChunkWriter rolls the output over to a new numbered file every size records, so very large runs produce files
+// downstream tools can load. Every chunk is a complete document in the chosen format. The first chunk is created
+// even when nothing is recorded, so a run always leaves an output behind.
+type ChunkWriter struct {
+	format string
+	size   int
+	file   string
+	create func(path string) (io.WriteCloser, error)
+	info   ScanInfo
+
+	current Writer
+	closer  io.WriteCloser
+	count   int
+	paths   []string
+}
+
+// NewChunkWriter writes chunks of file through create, which opens a local file or starts an upload
+func NewChunkWriter(format string, size int, file string, create func(path string) (io.WriteCloser, error), info ScanInfo) *ChunkWriter {
+	return &ChunkWriter{format: format, size: size, file: file, create: create, info: info}
+}
+
+func (c *ChunkWriter) Write(result types.Result) error {
+	if !Records(c.format, result) {
+		return nil
+	}
+	if c.current == nil || c.count >= c.size {
+		if err := c.next(); err != nil {
+			return err
+		}
+	}
+	c.count++
+	return c.current.Write(result)
+}
+
+// next finishes the current chunk and starts the following one
+func (c *ChunkWriter) next() error {
+	if err := c.finish(); err != nil {
+		return err
+	}
+	chunk := ChunkPath(c.file, len(c.paths)+1)
+	closer, err := c.create(chunk)
+	if err != nil {
+		return fmt.Errorf("failed to create output chunk %s: %v", chunk, err)
+	}
+	writer, err := NewWriter(c.format, closer, c.info)
+	if err != nil {
+		closer.Close()
+		return err
+	}
+	c.current, c.closer, c.count = writer, closer, 0
+	c.paths = append(c.paths, chunk)
+	return nil
+}
+
+// finish closes the format writer of the current chunk, then its file
+func (c *ChunkWriter) finish() error {
+	if c.current == nil {
+		return nil
+	}
+	err := c.current.Close()
+	if closeErr := c.closer.Close(); err == nil {
+		err = closeErr
+	}
+	c.current, c.closer = nil, nil
+	return err
+}
+
+func (c *ChunkWriter) Close() error {
+	if len(c.paths) == 0 {
+		if err := c.next(); err != nil {
+			return err
+		}
+	}
+	return c.finish()
+}
+
+// Paths returns the chunk files written so far, in order
+func (c *ChunkWriter) Paths() []string {
+	return c.paths
+}
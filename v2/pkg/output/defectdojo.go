@@ -0,0 +1,127 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// DefectDojo Generic Findings Import structs
+type defectDojoReport struct {
+	Findings []defectDojoFinding `json:"findings"`
+}
+
+type defectDojoFinding struct {
+	Title             string               `json:"title"`
+	Description       string               `json:"description"`
+	Severity          string               `json:"severity"`
+	Mitigation        string               `json:"mitigation"`
+	Impact            string               `json:"impact"`
+	References        string               `json:"references"`
+	Date              string               `json:"date"`
+	Active            bool                 `json:"active"`
+	Verified          bool                 `json:"verified"`
+	FalsePositive     bool                 `json:"false_p"`
+	DynamicFinding    bool                 `json:"dynamic_finding"`
+	StaticFinding     bool                 `json:"static_finding"`
+	UniqueIDFromTool  string               `json:"unique_id_from_tool"`
+	VulnIDFromTool    string               `json:"vuln_id_from_tool"`
+	Endpoints         []defectDojoEndpoint `json:"endpoints,omitempty"`
+	ComponentName     string               `json:"component_name,omitempty"`
+	ServiceName       string               `json:"service,omitempty"`
+	NumberOccurrences int                  `json:"nb_occurences"`
+	Tags              []string             `json:"tags,omitempty"`
+}
+
+type defectDojoEndpoint struct {
+	Protocol string `json:"protocol,omitempty"`
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// DefectDojoWriter collects matches and writes a DefectDojo Generic Findings Import document on Close
+type DefectDojoWriter struct {
+	w        io.Writer
+	info     ScanInfo
+	findings []defectDojoFinding
+}
+
+func NewDefectDojoWriter(w io.Writer, info ScanInfo) *DefectDojoWriter {
+	return &DefectDojoWriter{w: w, info: info, findings: []defectDojoFinding{}}
+}
+
+func (d *DefectDojoWriter) Write(result types.Result) error {
+	if result.Err != nil || !result.Match {
+		return nil
+	}
+
+	finding := defectDojoFinding{
+		Title:             fmt.Sprintf("Favicon matches base brand: %s", result.URL),
+		Description:       fmt.Sprintf("The favicon at %s was judged by the vision model %s to be identical to, or the same brand as, the base favicon %s.", result.URL, d.info.Model, baseURL(result, d.info)),
+		Severity:          "Medium",
+		Mitigation:        "Confirm whether the host is an owned asset. If it is not, investigate it for brand impersonation and pursue takedown where appropriate.",
+		Impact:            "Hosts reusing a brand's favicon may be impersonating the brand for phishing or fraud.",
+		References:        toolURI,
+		Date:              d.info.now().Format("2006-01-02"),
+		Active:            true,
+		DynamicFinding:    true,
+		UniqueIDFromTool:  matchFingerprint(result.URL, baseURL(result, d.info)),
+		VulnIDFromTool:    sarifRuleID,
+		ComponentName:     toolName,
+		NumberOccurrences: 1,
+		Tags:              result.Tags,
+	}
+	if l := result.Lookalike; l != nil {
+		finding.Severity = "High"
+		finding.Description += " " + lookalikeDescription(l)
+	} else if len(result.SuspectReasons) > 0 {
+		finding.Severity = "Info"
+	}
+	if len(result.SuspectReasons) > 0 {
+		finding.Description += " " + suspectDescription(result.SuspectReasons)
+	}
+	if hashes := hashDescription(result, d.info); hashes != "" {
+		finding.Description += " " + hashes
+	}
+	if ep, ok := defectDojoEndpointFromURL(result.URL); ok {
+		finding.Endpoints = []defectDojoEndpoint{ep}
+	}
+	d.findings = append(d.findings, finding)
+	return nil
+}
+
+func (d *DefectDojoWriter) Close() error {
+	enc := json.NewEncoder(d.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(defectDojoReport{Findings: d.findings}); err != nil {
+		return fmt.Errorf("failed to write DefectDojo output: %v", err)
+	}
+	return nil
+}
+
+// defectDojoEndpointFromURL splits a target URL into the endpoint fields DefectDojo expects
+func defectDojoEndpointFromURL(raw string) (defectDojoEndpoint, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return defectDojoEndpoint{}, false
+	}
+	ep := defectDojoEndpoint{
+		Protocol: u.Scheme,
+		Host:     u.Hostname(),
+		Path:     strings.TrimPrefix(u.Path, "/"),
+	}
+	if p := u.Port(); p != "" {
+		ep.Port, _ = strconv.Atoi(p)
+	} else if u.Scheme == "https" {
+		ep.Port = 443
+	} else if u.Scheme == "http" {
+		ep.Port = 80
+	}
+	return ep, true
+}
@@ -0,0 +1,210 @@
+package output
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Bulk indexing defaults: results are sent in batches, and a partial batch is sent once it has waited a few
+// seconds so dashboards stay current during slow scans
+const (
+	sinkBatchSize     = 500
+	sinkFlushInterval = 5 * time.Second
+)
+
+// ElasticOptions configure an ElasticWriter
+type ElasticOptions struct {
+	// URL is the cluster's base URL, e.g. https://localhost:9200
+	URL   string
+	Index string
+	// Auth is user:pass for basic auth or an API key; empty sends no credentials
+	Auth    string
+	Timeout time.Duration
+}
+
+// ElasticWriter bulk-indexes every result into an Elasticsearch or OpenSearch index as the scan streams them,
+// as the same documents the JSON output writes
+type ElasticWriter struct {
+	opts   ElasticOptions
+	info   ScanInfo
+	client *http.Client
+
+	mu      sync.Mutex
+	batch   bytes.Buffer
+	pending int
+	// dropped counts results lost to failed bulk requests and firstErr is the first failure, both reported by Close
+	dropped  int
+	firstErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewElasticWriter checks that the cluster answers with the given credentials, so a bad URL or key fails the
+// scan before it starts rather than losing every result
+func NewElasticWriter(opts ElasticOptions, info ScanInfo) (*ElasticWriter, error) {
+	e := &ElasticWriter{
+		opts:   opts,
+		info:   info,
+		client: &http.Client{Timeout: opts.Timeout},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	e.opts.URL = strings.TrimRight(opts.URL, "/")
+	resp, err := e.send(http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't reach Elasticsearch at %s: %v", e.opts.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Elasticsearch at %s answered %s", e.opts.URL, resp.Status)
+	}
+	go e.flushPeriodically()
+	return e, nil
+}
+
+func (e *ElasticWriter) Write(result types.Result) error {
+	doc, err := json.Marshal(newJSONResult(result, e.info))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for Elasticsearch: %v", result.URL, err)
+	}
+	action, _ := json.Marshal(map[string]any{"index": map[string]string{"_index": e.opts.Index}})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batch.Write(action)
+	e.batch.WriteByte('\n')
+	e.batch.Write(doc)
+	e.batch.WriteByte('\n')
+	e.pending++
+	if e.pending >= sinkBatchSize {
+		return e.flush()
+	}
+	return nil
+}
+
+// Close sends the last partial batch and reports results that could not be indexed
+func (e *ElasticWriter) Close() error {
+	close(e.stop)
+	<-e.done
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.flush()
+	if e.dropped > 0 {
+		return fmt.Errorf("%d result(s) were not indexed in Elasticsearch: %v", e.dropped, e.firstErr)
+	}
+	return nil
+}
+
+// flushPeriodically sends partial batches until Close
+func (e *ElasticWriter) flushPeriodically() {
+	defer close(e.done)
+	ticker := time.NewTicker(sinkFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.mu.Lock()
+			e.flush()
+			e.mu.Unlock()
+		}
+	}
+}
+
+// elasticBulkResponse is the part of a _bulk response needed to find rejected documents
+type elasticBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// flush sends the pending batch with a single _bulk request; e.mu must be held. A failed batch is dropped
+// rather than retried, so a cluster outage can't grow memory without bound.
+func (e *ElasticWriter) flush() error {
+	if e.pending == 0 {
+		return nil
+	}
+	count := e.pending
+	err := e.bulk(e.batch.Bytes(), count)
+	e.batch.Reset()
+	e.pending = 0
+	if err != nil && e.firstErr == nil {
+		e.firstErr = err
+	}
+	return err
+}
+
+// bulk indexes one batch of count documents and counts the ones the cluster rejected
+func (e *ElasticWriter) bulk(body []byte, count int) error {
+	resp, err := e.send(http.MethodPost, "/_bulk", body)
+	if err != nil {
+		e.dropped += count
+		return fmt.Errorf("bulk request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		e.dropped += count
+		return fmt.Errorf("failed to read bulk response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		e.dropped += count
+		return fmt.Errorf("bulk request answered %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var bulk elasticBulkResponse
+	if err := json.Unmarshal(data, &bulk); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %v", err)
+	}
+	if !bulk.Errors {
+		return nil
+	}
+	rejected, reason := 0, ""
+	for _, item := range bulk.Items {
+		for _, op := range item {
+			if op.Error != nil {
+				rejected++
+				if reason == "" {
+					reason = fmt.Sprintf("%s: %s", op.Error.Type, op.Error.Reason)
+				}
+			}
+		}
+	}
+	e.dropped += rejected
+	return fmt.Errorf("Elasticsearch rejected %d of %d result(s): %s", rejected, count, reason)
+}
+
+// send makes an authenticated request to the cluster
+func (e *ElasticWriter) send(method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, e.opts.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	if auth := e.opts.Auth; auth != "" {
+		if strings.Contains(auth, ":") {
+			req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+		} else {
+			req.Header.Set("Authorization", "ApiKey "+auth)
+		}
+	}
+	return e.client.Do(req)
+}
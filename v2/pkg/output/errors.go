@@ -0,0 +1,102 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// ErrorsFileNone disables the errors file
+const ErrorsFileNone = "none"
+
+// ErrorsPath is where failed targets are written next to the -o file when --errors-file isn't given
+func ErrorsPath(output string) string {
+	return output + ".errors.txt"
+}
+
+// ErrorsWriter writes every failed target to a requeue file, one per line as text input reads it: the URL, the
+// base favicon when it was overridden, then a "#" comment with the error class and message. Feeding the file
+// back with -file scans just those targets again, e.g. at lower concurrency or through a proxy. The file is
+// only created once a target fails.
+type ErrorsWriter struct {
+	path     string
+	create   func(path string) (io.WriteCloser, error)
+	base     string
+	classify func(error) string
+
+	file   io.WriteCloser
+	w      *bufio.Writer
+	counts map[string]int
+}
+
+// NewErrorsWriter writes to path through create; results whose base favicon is base are written without it
+func NewErrorsWriter(path string, create func(path string) (io.WriteCloser, error), base string, classify func(error) string) *ErrorsWriter {
+	return &ErrorsWriter{path: path, create: create, base: base, classify: classify, counts: make(map[string]int)}
+}
+
+func (e *ErrorsWriter) Write(result types.Result) error {
+	if result.Err == nil {
+		return nil
+	}
+	if e.file == nil {
+		file, err := e.create(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to create errors file %s: %v", e.path, err)
+		}
+		e.file, e.w = file, bufio.NewWriter(file)
+		fmt.Fprintf(e.w, "# Targets that failed, with their error class; scan them again with -file %s\n", e.path)
+	}
+
+	class := e.classify(result.Err)
+	e.counts[class]++
+	line := result.URL
+	if result.BaseURL != "" && result.BaseURL != e.base {
+		line += "," + result.BaseURL
+	}
+	// Messages can span lines; the comment has to stay on the target's line
+	message := strings.Join(strings.Fields(result.Err.Error()), " ")
+	_, err := fmt.Fprintf(e.w, "%s # %s: %s\n", line, class, message)
+	return err
+}
+
+func (e *ErrorsWriter) Close() error {
+	if e.file == nil {
+		return nil
+	}
+	err := e.w.Flush()
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Path returns the errors file, or "" when no target failed and it wasn't created
+func (e *ErrorsWriter) Path() string {
+	if e.file == nil {
+		return ""
+	}
+	return e.path
+}
+
+// Summary counts the failed targets by class, most common first, e.g. "8 timeout, 4 dns"
+func (e *ErrorsWriter) Summary() string {
+	classes := make([]string, 0, len(e.counts))
+	for class := range e.counts {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if e.counts[classes[i]] != e.counts[classes[j]] {
+			return e.counts[classes[i]] > e.counts[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	parts := make([]string, len(classes))
+	for i, class := range classes {
+		parts[i] = fmt.Sprintf("%d %s", e.counts[class], class)
+	}
+	return strings.Join(parts, ", ")
+}
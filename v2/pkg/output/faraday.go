@@ -0,0 +1,123 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Faraday bulk_create structs
+type faradayReport struct {
+	Hosts []*faradayHost `json:"hosts"`
+}
+
+type faradayHost struct {
+	IP          string            `json:"ip"`
+	Description string            `json:"description"`
+	Hostnames   []string          `json:"hostnames"`
+	Services    []*faradayService `json:"services"`
+}
+
+type faradayService struct {
+	Name            string                 `json:"name"`
+	Port            int                    `json:"port"`
+	Protocol        string                 `json:"protocol"`
+	Status          string                 `json:"status"`
+	Vulnerabilities []faradayVulnerability `json:"vulnerabilities"`
+}
+
+type faradayVulnerability struct {
+	Name        string   `json:"name"`
+	Description string   `json:"desc"`
+	Severity    string   `json:"severity"`
+	Type        string   `json:"type"`
+	Website     string   `json:"website"`
+	Path        string   `json:"path"`
+	Method      string   `json:"method"`
+	Resolution  string   `json:"resolution"`
+	Refs        []string `json:"refs"`
+	Tags        []string `json:"tags"`
+	Status      string   `json:"status"`
+}
+
+// FaradayWriter collects matches grouped per host and writes a Faraday bulk_create document on Close
+type FaradayWriter struct {
+	w     io.Writer
+	info  ScanInfo
+	hosts []*faradayHost
+	index map[string]*faradayHost
+}
+
+func NewFaradayWriter(w io.Writer, info ScanInfo) *FaradayWriter {
+	return &FaradayWriter{w: w, info: info, hosts: []*faradayHost{}, index: make(map[string]*faradayHost)}
+}
+
+func (f *FaradayWriter) Write(result types.Result) error {
+	if result.Err != nil || !result.Match {
+		return nil
+	}
+	ep, ok := defectDojoEndpointFromURL(result.URL)
+	if !ok {
+		return fmt.Errorf("cannot derive host from %s", result.URL)
+	}
+
+	host, ok := f.index[ep.Host]
+	if !ok {
+		host = &faradayHost{IP: ep.Host, Description: "Discovered by favlens", Hostnames: []string{ep.Host}}
+		f.index[ep.Host] = host
+		f.hosts = append(f.hosts, host)
+	}
+
+	var service *faradayService
+	for _, s := range host.Services {
+		if s.Port == ep.Port {
+			service = s
+			break
+		}
+	}
+	if service == nil {
+		service = &faradayService{Name: ep.Protocol, Port: ep.Port, Protocol: "tcp", Status: "open", Vulnerabilities: []faradayVulnerability{}}
+		host.Services = append(host.Services, service)
+	}
+
+	vulnerability := faradayVulnerability{
+		Name:        "Favicon matches base brand",
+		Description: fmt.Sprintf("The favicon at %s was judged by the vision model %s to be identical to, or the same brand as, the base favicon %s.", result.URL, f.info.Model, baseURL(result, f.info)),
+		Severity:    "medium",
+		Type:        "VulnerabilityWeb",
+		Website:     ep.Host,
+		Path:        "/" + ep.Path,
+		Method:      "GET",
+		Resolution:  "Confirm whether the host is an owned asset. If it is not, investigate it for brand impersonation and pursue takedown where appropriate.",
+		Refs:        []string{toolURI},
+		Tags:        append([]string{toolName, "brand-impersonation"}, result.Tags...),
+		Status:      "open",
+	}
+	if l := result.Lookalike; l != nil {
+		vulnerability.Severity = "high"
+		vulnerability.Description += " " + lookalikeDescription(l)
+		vulnerability.Tags = append(vulnerability.Tags, "lookalike-domain")
+	} else if len(result.SuspectReasons) > 0 {
+		vulnerability.Severity = "info"
+	}
+	if len(result.SuspectReasons) > 0 {
+		vulnerability.Description += " " + suspectDescription(result.SuspectReasons)
+		vulnerability.Tags = append(vulnerability.Tags, "suspect-wildcard")
+	}
+	if hashes := hashDescription(result, f.info); hashes != "" {
+		vulnerability.Description += " " + hashes
+	}
+	service.Vulnerabilities = append(service.Vulnerabilities, vulnerability)
+	return nil
+}
+
+func (f *FaradayWriter) Close() error {
+	enc := json.NewEncoder(f.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(faradayReport{Hosts: f.hosts}); err != nil {
+		return fmt.Errorf("failed to write Faraday output: %v", err)
+	}
+	return nil
+}
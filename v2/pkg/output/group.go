@@ -0,0 +1,81 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// GroupApex groups matches by registered domain
+const GroupApex = "apex"
+
+// representativeHosts is how many hosts each group lists by name
+const representativeHosts = 5
+
+// ValidateGroupBy reports whether groupBy and format can be combined; only text and JSON output can be grouped
+func ValidateGroupBy(groupBy, format string) error {
+	if groupBy == "" {
+		return nil
+	}
+	if !strings.EqualFold(groupBy, GroupApex) {
+		return fmt.Errorf("unsupported grouping '%s' (supported: %s)", groupBy, GroupApex)
+	}
+	if format != "" && !strings.EqualFold(format, FormatText) && !strings.EqualFold(format, FormatJSON) {
+		return fmt.Errorf("grouped output is only available for the text and json formats, not %s", format)
+	}
+	return nil
+}
+
+// jsonGroup is the JSON lines representation of an apex domain's matches
+type jsonGroup struct {
+	Apex                string   `json:"apex"`
+	Matches             int      `json:"matches"`
+	Hosts               int      `json:"hosts"`
+	RepresentativeHosts []string `json:"representative_hosts"`
+	URLs                []string `json:"urls"`
+}
+
+// GroupWriter collects matches and writes one entry per apex domain when closed, with the number of matches
+// and a few representative hosts, so hundreds of wildcard subdomains don't bury the rest of the report
+type GroupWriter struct {
+	w       io.Writer
+	asJSON  bool
+	matches []types.Result
+}
+
+// NewGroupWriter returns a GroupWriter writing text lines, or JSON lines when format is json
+func NewGroupWriter(format string, w io.Writer) *GroupWriter {
+	return &GroupWriter{w: w, asJSON: strings.EqualFold(format, FormatJSON)}
+}
+
+func (g *GroupWriter) Write(result types.Result) error {
+	if result.Match && result.Err == nil {
+		g.matches = append(g.matches, result)
+	}
+	return nil
+}
+
+func (g *GroupWriter) Close() error {
+	enc := json.NewEncoder(g.w)
+	for _, group := range domain.GroupByApex(g.matches) {
+		if g.asJSON {
+			line := jsonGroup{Apex: group.Apex, Matches: len(group.URLs), Hosts: len(group.Hosts), RepresentativeHosts: group.Representative(representativeHosts), URLs: group.URLs}
+			if err := enc.Encode(line); err != nil {
+				return fmt.Errorf("failed to write JSON output: %v", err)
+			}
+			continue
+		}
+		hosts := strings.Join(group.Representative(representativeHosts), ", ")
+		if more := len(group.Hosts) - representativeHosts; more > 0 {
+			hosts += fmt.Sprintf(" (+%d more)", more)
+		}
+		if _, err := fmt.Fprintf(g.w, "%s\t%d match(es)\t%s\n", group.Apex, len(group.URLs), hosts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// jsonResult is the JSON lines representation of a result
+type jsonResult struct {
+	Timestamp       string         `json:"timestamp"`
+	URL             string         `json:"url"`
+	BaseURL         string         `json:"base_url"`
+	Model           string         `json:"model"`
+	Match           bool           `json:"match"`
+	Error           string         `json:"error,omitempty"`
+	Tags            []string       `json:"tags,omitempty"`
+	LookalikeDomain bool           `json:"lookalike_domain,omitempty"`
+	Confusability   float64        `json:"confusability,omitempty"`
+	UnicodeHost     string         `json:"unicode_host,omitempty"`
+	SuspectWildcard bool           `json:"suspect_wildcard,omitempty"`
+	SuspectReasons  []string       `json:"suspect_reasons,omitempty"`
+	Icon            *jsonIcon      `json:"icon,omitempty"`
+	IconText        string         `json:"icon_text,omitempty"`
+	TextScore       float64        `json:"text_score,omitempty"`
+	TextMatch       bool           `json:"text_match,omitempty"`
+	MatchedAsset    string         `json:"matched_asset,omitempty"`
+	FaviconMMH3     string         `json:"favicon_mmh3,omitempty"`
+	FaviconSHA256   string         `json:"favicon_sha256,omitempty"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+}
+
+// jsonIcon describes the target's favicon so low-detail icons can be filtered out
+type jsonIcon struct {
+	Format     string `json:"format"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Bytes      int    `json:"bytes"`
+	BitDepth   int    `json:"bit_depth,omitempty"`
+	Monochrome bool   `json:"monochrome"`
+}
+
+// JSONWriter writes every result, including errors and non-matches, as one JSON object per line
+type JSONWriter struct {
+	enc  *json.Encoder
+	info ScanInfo
+}
+
+func NewJSONWriter(w io.Writer, info ScanInfo) *JSONWriter {
+	return &JSONWriter{enc: json.NewEncoder(w), info: info}
+}
+
+func (j *JSONWriter) Write(result types.Result) error {
+	if err := j.enc.Encode(newJSONResult(result, j.info)); err != nil {
+		return fmt.Errorf("failed to write JSON output: %v", err)
+	}
+	return nil
+}
+
+func (j *JSONWriter) Close() error {
+	return nil
+}
+
+// newJSONResult renders result as it appears in JSON output
+func newJSONResult(result types.Result, info ScanInfo) jsonResult {
+	line := jsonResult{
+		Timestamp: info.now().UTC().Format(time.RFC3339),
+		URL:       result.URL,
+		BaseURL:   baseURL(result, info),
+		Model:     info.Model,
+		Match:     result.Match,
+		Tags:      result.Tags,
+		Metadata:  result.Metadata,
+	}
+	if result.Err != nil {
+		line.Error = result.Err.Error()
+	}
+	if l := result.Lookalike; l != nil {
+		line.LookalikeDomain = true
+		line.Confusability = l.Confusability
+		if l.IDN {
+			line.UnicodeHost = l.Unicode
+		}
+	}
+	if len(result.SuspectReasons) > 0 {
+		line.SuspectWildcard = true
+		line.SuspectReasons = result.SuspectReasons
+	}
+	if i := result.Icon; i != nil {
+		line.Icon = &jsonIcon{Format: i.Format, Width: i.Width, Height: i.Height, Bytes: i.Bytes, BitDepth: i.BitDepth, Monochrome: i.Monochrome}
+	}
+	if t := result.Text; t != nil {
+		line.IconText = t.Text
+		line.TextScore = t.Score
+		line.TextMatch = t.Match
+	}
+	line.MatchedAsset = result.Asset
+	line.FaviconMMH3, line.FaviconSHA256 = result.MMH3, result.SHA256
+	return line
+}
+
+// ParseJSONResult reads a line of JSON output back into a result, with the model and time it was written
+// with, so results from other favlens instances can be merged
+func ParseJSONResult(data []byte) (result types.Result, model string, at time.Time, err error) {
+	var line jsonResult
+	if err := json.Unmarshal(data, &line); err != nil {
+		return types.Result{}, "", time.Time{}, err
+	}
+	if line.URL == "" {
+		return types.Result{}, "", time.Time{}, fmt.Errorf("result has no url")
+	}
+	result = types.Result{
+		URL:            line.URL,
+		BaseURL:        line.BaseURL,
+		Match:          line.Match,
+		Tags:           line.Tags,
+		SuspectReasons: line.SuspectReasons,
+		Asset:          line.MatchedAsset,
+		MMH3:           line.FaviconMMH3,
+		SHA256:         line.FaviconSHA256,
+		Metadata:       line.Metadata,
+	}
+	if line.Error != "" {
+		result.Err = errors.New(line.Error)
+	}
+	if line.LookalikeDomain {
+		result.Lookalike = &types.Lookalike{Unicode: line.UnicodeHost, IDN: line.UnicodeHost != "", Confusability: line.Confusability}
+		if u, err := url.Parse(line.URL); err == nil && !result.Lookalike.IDN {
+			result.Lookalike.Unicode = u.Hostname()
+		}
+	}
+	if i := line.Icon; i != nil {
+		result.Icon = &types.IconInfo{Format: i.Format, Width: i.Width, Height: i.Height, Bytes: i.Bytes, BitDepth: i.BitDepth, Monochrome: i.Monochrome}
+	}
+	if line.IconText != "" || line.TextScore != 0 || line.TextMatch {
+		result.Text = &types.IconText{Text: line.IconText, Score: line.TextScore, Match: line.TextMatch}
+	}
+	at, _ = time.Parse(time.RFC3339, line.Timestamp)
+	return result, line.Model, at, nil
+}
@@ -0,0 +1,209 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	notify "github.com/ethicalhackingplayground/favlens/v2/pkg/notify"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+	"github.com/google/uuid"
+)
+
+// stixNamespace is the STIX 2.1 namespace for deterministic identifiers, which OpenCTI also uses, so the same
+// URL found by two scans deduplicates into one observable and indicator
+var stixNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// stixTime is the timestamp format STIX requires
+const stixTime = "2006-01-02T15:04:05.000Z"
+
+// tlpMarkings are the TLP marking definitions OpenCTI ships with; TLP:CLEAR shares TLP:WHITE's identifier
+var tlpMarkings = map[string]stixObject{
+	"TLP:CLEAR":        tlpMarking("marking-definition--613f2e26-407d-48c7-9eca-b8e91df99dc9", "TLP:CLEAR", "clear"),
+	"TLP:WHITE":        tlpMarking("marking-definition--613f2e26-407d-48c7-9eca-b8e91df99dc9", "TLP:CLEAR", "clear"),
+	"TLP:GREEN":        tlpMarking("marking-definition--34098fce-860f-48ae-8e50-ebd3cc5e41da", "TLP:GREEN", "green"),
+	"TLP:AMBER":        tlpMarking("marking-definition--f88d31f6-486f-44da-b317-01333bde0b82", "TLP:AMBER", "amber"),
+	"TLP:AMBER+STRICT": tlpMarking("marking-definition--826578e1-40ad-459f-bc73-ede076f81f37", "TLP:AMBER+STRICT", "amber+strict"),
+	"TLP:RED":          tlpMarking("marking-definition--5e57c739-391a-4eb3-b6be-7d15ca92d5ed", "TLP:RED", "red"),
+}
+
+// markingID matches a marking definition already defined in the platform, referenced by its STIX id
+var markingID = regexp.MustCompile(`^marking-definition--[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// stixObject is a STIX 2.1 object; its properties vary too much by type for one struct
+type stixObject map[string]any
+
+func tlpMarking(id, name, level string) stixObject {
+	return stixObject{
+		"type":            "marking-definition",
+		"spec_version":    "2.1",
+		"id":              id,
+		"created":         "2022-10-01T00:00:00.000Z",
+		"definition_type": "TLP",
+		"name":            name,
+		"definition":      map[string]string{"tlp": level},
+	}
+}
+
+// ValidateMarking reports whether a marking is a TLP level or a marking-definition id
+func ValidateMarking(marking string) error {
+	if _, ok := tlpMarkings[strings.ToUpper(marking)]; ok || markingID.MatchString(marking) {
+		return nil
+	}
+	return fmt.Errorf("unknown marking '%s' (use TLP:CLEAR, TLP:GREEN, TLP:AMBER, TLP:AMBER+STRICT, TLP:RED or a marking-definition--<uuid> id)", marking)
+}
+
+// OpenCTIWriter collects matches as a STIX 2.1 bundle for OpenCTI's connector ingestion: a URL observable and
+// an indicator based on it for every match, created by a favlens identity and carrying the scan's markings
+type OpenCTIWriter struct {
+	w        io.Writer
+	info     ScanInfo
+	identity stixObject
+	markings []stixObject
+	refs     []string
+	objects  []stixObject
+	seen     map[string]bool
+}
+
+func NewOpenCTIWriter(w io.Writer, info ScanInfo) *OpenCTIWriter {
+	o := &OpenCTIWriter{w: w, info: info, seen: make(map[string]bool)}
+	o.identity = stixObject{
+		"type":           "identity",
+		"spec_version":   "2.1",
+		"id":             stixID("identity", map[string]string{"name": toolName, "identity_class": "system"}),
+		"created":        info.now().UTC().Format(stixTime),
+		"modified":       info.now().UTC().Format(stixTime),
+		"name":           toolName,
+		"identity_class": "system",
+		"description":    "Favicon brand impersonation scanner, " + toolURI,
+	}
+	for _, marking := range info.Markings {
+		if definition, ok := tlpMarkings[strings.ToUpper(marking)]; ok {
+			o.markings = append(o.markings, definition)
+			o.refs = append(o.refs, definition["id"].(string))
+		} else {
+			// Other markings are referenced by id and must already exist in the platform
+			o.refs = append(o.refs, marking)
+		}
+	}
+	return o
+}
+
+func (o *OpenCTIWriter) Write(result types.Result) error {
+	if result.Err != nil || !result.Match {
+		return nil
+	}
+	now := o.info.now().UTC().Format(stixTime)
+	confidence := o.info.Confidence
+	if confidence == 0 {
+		confidence = notify.Confidence(result)
+	}
+
+	description := fmt.Sprintf("The favicon at %s was judged by the vision model %s to be identical to, or the same brand as, the base favicon %s.", result.URL, o.info.Model, baseURL(result, o.info))
+	labels := append([]string{toolName, "brand-impersonation"}, result.Tags...)
+	if l := result.Lookalike; l != nil {
+		description += " " + lookalikeDescription(l)
+		labels = append(labels, "lookalike-domain")
+	}
+	if len(result.SuspectReasons) > 0 {
+		description += " " + suspectDescription(result.SuspectReasons)
+		labels = append(labels, "suspect-wildcard")
+	}
+	if hashes := hashDescription(result, o.info); hashes != "" {
+		description += " " + hashes
+	}
+
+	observable := stixObject{
+		"type":                     "url",
+		"spec_version":             "2.1",
+		"id":                       stixID("url", map[string]string{"value": result.URL}),
+		"value":                    result.URL,
+		"x_opencti_score":          confidence,
+		"x_opencti_labels":         labels,
+		"x_opencti_created_by_ref": o.identity["id"],
+		"x_opencti_description":    description,
+	}
+	pattern := fmt.Sprintf("[url:value = '%s']", strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(result.URL))
+	indicator := stixObject{
+		"type":                           "indicator",
+		"spec_version":                   "2.1",
+		"id":                             stixID("indicator", map[string]string{"pattern": pattern}),
+		"created":                        now,
+		"modified":                       now,
+		"created_by_ref":                 o.identity["id"],
+		"name":                           result.URL,
+		"description":                    description,
+		"pattern":                        pattern,
+		"pattern_type":                   "stix",
+		"valid_from":                     now,
+		"indicator_types":                []string{"malicious-activity"},
+		"confidence":                     confidence,
+		"labels":                         labels,
+		"x_opencti_score":                confidence,
+		"x_opencti_main_observable_type": "Url",
+	}
+	relationship := stixObject{
+		"type":              "relationship",
+		"spec_version":      "2.1",
+		"id":                stixID("relationship", map[string]string{"relationship_type": "based-on", "source_ref": indicator["id"].(string), "target_ref": observable["id"].(string)}),
+		"created":           now,
+		"modified":          now,
+		"created_by_ref":    o.identity["id"],
+		"relationship_type": "based-on",
+		"source_ref":        indicator["id"],
+		"target_ref":        observable["id"],
+		"confidence":        confidence,
+	}
+	for _, object := range []stixObject{observable, indicator, relationship} {
+		if len(o.refs) > 0 {
+			object["object_marking_refs"] = o.refs
+		}
+		o.add(object)
+	}
+	return nil
+}
+
+// add appends an object once; a URL matched twice keeps its first observable and indicator
+func (o *OpenCTIWriter) add(object stixObject) {
+	id := object["id"].(string)
+	if o.seen[id] {
+		return
+	}
+	o.seen[id] = true
+	o.objects = append(o.objects, object)
+}
+
+func (o *OpenCTIWriter) Close() error {
+	objects := append([]stixObject{o.identity}, o.markings...)
+	objects = append(objects, o.objects...)
+	// The bundle id follows from its contents, so deterministic runs write identical bundles
+	ids := make([]string, len(objects))
+	for i, object := range objects {
+		ids[i] = object["id"].(string)
+	}
+	bundle := map[string]any{
+		"type":    "bundle",
+		"id":      "bundle--" + uuid.NewSHA1(stixNamespace, []byte(strings.Join(ids, ","))).String(),
+		"objects": objects,
+	}
+	enc := json.NewEncoder(o.w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(bundle); err != nil {
+		return fmt.Errorf("failed to write OpenCTI output: %v", err)
+	}
+	return nil
+}
+
+// stixID derives a deterministic identifier from an object's identifying properties, as a UUIDv5 over their
+// canonical JSON
+func stixID(kind string, properties map[string]string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(properties)
+	return kind + "--" + uuid.NewSHA1(stixNamespace, bytes.TrimSpace(buf.Bytes())).String()
+}
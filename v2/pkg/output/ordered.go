@@ -0,0 +1,61 @@
+package output
+
+import (
+	"sort"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// OrderedWriter passes results to another writer in input order rather than completion order.
+// Results that arrive early are buffered until every result before them has been written.
+type OrderedWriter struct {
+	w       Writer
+	next    int
+	pending map[int]types.Result
+}
+
+func NewOrderedWriter(w Writer) *OrderedWriter {
+	return &OrderedWriter{w: w, pending: make(map[int]types.Result)}
+}
+
+// Write passes result on once every earlier result has been written. A failed write still counts as written,
+// so one sink error doesn't hold back every later result; the first error is returned once the buffer is drained.
+func (o *OrderedWriter) Write(result types.Result) error {
+	if result.Index != o.next {
+		o.pending[result.Index] = result
+		return nil
+	}
+	first := o.w.Write(result)
+	o.next++
+	for {
+		result, ok := o.pending[o.next]
+		if !ok {
+			return first
+		}
+		delete(o.pending, o.next)
+		if err := o.w.Write(result); err != nil && first == nil {
+			first = err
+		}
+		o.next++
+	}
+}
+
+// Close writes any results still buffered behind gaps (jobs skipped when a run is cut short) and closes the wrapped writer
+func (o *OrderedWriter) Close() error {
+	indexes := make([]int, 0, len(o.pending))
+	for index := range o.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var first error
+	for _, index := range indexes {
+		if err := o.w.Write(o.pending[index]); err != nil && first == nil {
+			first = err
+		}
+	}
+	o.pending = nil
+	if err := o.w.Close(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
@@ -0,0 +1,187 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	favhash "github.com/ethicalhackingplayground/favlens/v2/pkg/favhash"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Supported output formats
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatSARIF      = "sarif"
+	FormatDefectDojo = "defectdojo"
+	FormatFaraday    = "faraday"
+	FormatParquet    = "parquet"
+	FormatOpenCTI    = "opencti"
+)
+
+// Formats lists every format accepted by NewWriter
+var Formats = []string{FormatText, FormatJSON, FormatSARIF, FormatDefectDojo, FormatFaraday, FormatParquet, FormatOpenCTI}
+
+// ScanInfo carries details about the scan that some formats embed in their output
+type ScanInfo struct {
+	BaseURL string
+	Model   string
+	// Confidence replaces the per-match confidence score in formats that carry one; 0 keeps the score
+	Confidence int
+	// Markings are the data markings, such as TLP:AMBER, that STIX output is labelled with
+	Markings []string
+	// HashInput is the --hash-input the mmh3 hashes were computed over, so they aren't mislabelled as Shodan's
+	HashInput string
+	// Clock supplies timestamps; nil means time.Now. Deterministic runs pin it so output is reproducible.
+	Clock func() time.Time
+}
+
+// now returns the current time according to the scan's clock
+func (i ScanInfo) now() time.Time {
+	if i.Clock != nil {
+		return i.Clock()
+	}
+	return time.Now()
+}
+
+// Writer consumes scan results and renders them in a specific format
+type Writer interface {
+	// Write handles a single result as soon as it is available
+	Write(result types.Result) error
+	// Close flushes any buffered output; it does not close the underlying io.Writer
+	Close() error
+}
+
+// ValidateFormat reports whether format is supported by NewWriter
+func ValidateFormat(format string) error {
+	for _, f := range Formats {
+		if strings.EqualFold(format, f) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format '%s' (supported: %s)", format, strings.Join(Formats, ", "))
+}
+
+// NewWriter returns a Writer for the given format that writes to w
+func NewWriter(format string, w io.Writer, info ScanInfo) (Writer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return NewTextWriter(w), nil
+	case FormatJSON:
+		return NewJSONWriter(w, info), nil
+	case FormatSARIF:
+		return NewSARIFWriter(w, info), nil
+	case FormatDefectDojo:
+		return NewDefectDojoWriter(w, info), nil
+	case FormatFaraday:
+		return NewFaradayWriter(w, info), nil
+	case FormatParquet:
+		return NewParquetWriter(w, info), nil
+	case FormatOpenCTI:
+		return NewOpenCTIWriter(w, info), nil
+	default:
+		return nil, ValidateFormat(format)
+	}
+}
+
+// baseURL returns the base favicon a result was compared against, falling back to the scan-wide base
+func baseURL(result types.Result, info ScanInfo) string {
+	if result.BaseURL != "" {
+		return result.BaseURL
+	}
+	return info.BaseURL
+}
+
+// matchFingerprint identifies a match by its target and base favicon, so the same finding keeps one identity
+// across runs however the results are ordered, and a target matched against several bases keeps one per base
+func matchFingerprint(target, base string) string {
+	sum := sha256.Sum256([]byte(target + "\n" + base))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookalikeDescription explains why a match on a lookalike domain is rated higher
+func lookalikeDescription(l *types.Lookalike) string {
+	if l.IDN {
+		return fmt.Sprintf("The host is an internationalized domain name displayed as %s (confusability %.2f with the brand domain), a common homoglyph phishing technique; prioritise takedown.", l.Unicode, l.Confusability)
+	}
+	return fmt.Sprintf("The host is visually confusable with the brand domain (confusability %.2f); prioritise takedown.", l.Confusability)
+}
+
+// suspectDescription explains why a match may come from parked or wildcard hosting rather than a deliberate copy
+func suspectDescription(reasons []string) string {
+	return fmt.Sprintf("The match may be hosting noise rather than impersonation (%s); verify before acting.", strings.Join(reasons, ", "))
+}
+
+// hashDescription names the target icon's hashes so findings can be joined with favicon intel, or returns ""
+// when neither is known
+func hashDescription(result types.Result, info ScanInfo) string {
+	mmh3 := "Shodan http.favicon.hash"
+	if info.HashInput == favhash.InputRaw {
+		mmh3 = "raw mmh3"
+	}
+	switch {
+	case result.MMH3 != "" && result.SHA256 != "":
+		return fmt.Sprintf("Favicon hashes: mmh3 %s (%s), sha256 %s.", result.MMH3, mmh3, result.SHA256)
+	case result.MMH3 != "":
+		return fmt.Sprintf("Favicon hash: mmh3 %s (%s).", result.MMH3, mmh3)
+	case result.SHA256 != "":
+		return fmt.Sprintf("Favicon hash: sha256 %s.", result.SHA256)
+	}
+	return ""
+}
+
+// TextWriter prints one matched URL per line
+type TextWriter struct {
+	w io.Writer
+}
+
+func NewTextWriter(w io.Writer) *TextWriter {
+	return &TextWriter{w: w}
+}
+
+func (t *TextWriter) Write(result types.Result) error {
+	if result.Err != nil || !result.Match {
+		return nil
+	}
+	_, err := fmt.Fprintln(t.w, result.URL)
+	return err
+}
+
+func (t *TextWriter) Close() error {
+	return nil
+}
+
+// MultiWriter fans every result out to several writers, e.g. a report file plus NDJSON on stdout
+type MultiWriter struct {
+	writers []Writer
+}
+
+func NewMultiWriter(writers ...Writer) *MultiWriter {
+	return &MultiWriter{writers: writers}
+}
+
+// Write passes result to every writer and returns the first error encountered
+func (m *MultiWriter) Write(result types.Result) error {
+	var first error
+	for _, w := range m.writers {
+		if err := w.Write(result); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes every writer and returns the first error encountered
+func (m *MultiWriter) Close() error {
+	var first error
+	for _, w := range m.writers {
+		if err := w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupRows bounds how many results are buffered in memory before they are flushed as a row group
+const parquetRowGroupRows = 50000
+
+// parquetResult is the Parquet schema. Columns mirror the JSON lines fields and are only ever appended to, so
+// tables built from earlier scans keep working; metadata is a JSON string since its keys vary by input source.
+type parquetResult struct {
+	Timestamp       time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	URL             string    `parquet:"url"`
+	BaseURL         string    `parquet:"base_url"`
+	Model           string    `parquet:"model"`
+	Match           bool      `parquet:"match"`
+	Error           *string   `parquet:"error"`
+	Tags            []string  `parquet:"tags,list"`
+	LookalikeDomain bool      `parquet:"lookalike_domain"`
+	Confusability   *float64  `parquet:"confusability"`
+	UnicodeHost     *string   `parquet:"unicode_host"`
+	SuspectWildcard bool      `parquet:"suspect_wildcard"`
+	SuspectReasons  []string  `parquet:"suspect_reasons,list"`
+	IconFormat      *string   `parquet:"icon_format"`
+	IconWidth       *int32    `parquet:"icon_width"`
+	IconHeight      *int32    `parquet:"icon_height"`
+	IconBytes       *int64    `parquet:"icon_bytes"`
+	IconBitDepth    *int32    `parquet:"icon_bit_depth"`
+	IconMonochrome  *bool     `parquet:"icon_monochrome"`
+	IconText        *string   `parquet:"icon_text"`
+	TextScore       *float64  `parquet:"text_score"`
+	TextMatch       bool      `parquet:"text_match"`
+	MatchedAsset    *string   `parquet:"matched_asset"`
+	FaviconMMH3     *string   `parquet:"favicon_mmh3"`
+	FaviconSHA256   *string   `parquet:"favicon_sha256"`
+	Metadata        *string   `parquet:"metadata"`
+}
+
+// ParquetWriter writes every result, including errors and non-matches, as a row of a zstd-compressed Parquet
+// file. The file footer is written on Close, so output is only readable once the scan has finished.
+type ParquetWriter struct {
+	pw   *parquet.GenericWriter[parquetResult]
+	info ScanInfo
+}
+
+func NewParquetWriter(w io.Writer, info ScanInfo) *ParquetWriter {
+	pw := parquet.NewGenericWriter[parquetResult](w,
+		parquet.Compression(&parquet.Zstd),
+		parquet.MaxRowsPerRowGroup(parquetRowGroupRows),
+		parquet.CreatedBy("favlens", "", ""),
+	)
+	return &ParquetWriter{pw: pw, info: info}
+}
+
+func (p *ParquetWriter) Write(result types.Result) error {
+	row := parquetResult{
+		Timestamp:      p.info.now().UTC(),
+		URL:            result.URL,
+		BaseURL:        baseURL(result, p.info),
+		Model:          p.info.Model,
+		Match:          result.Match,
+		Tags:           result.Tags,
+		SuspectReasons: result.SuspectReasons,
+		MatchedAsset:   optional(result.Asset),
+		FaviconMMH3:    optional(result.MMH3),
+		FaviconSHA256:  optional(result.SHA256),
+	}
+	if result.Err != nil {
+		row.Error = optional(result.Err.Error())
+	}
+	if l := result.Lookalike; l != nil {
+		row.LookalikeDomain = true
+		row.Confusability = &l.Confusability
+		if l.IDN {
+			row.UnicodeHost = optional(l.Unicode)
+		}
+	}
+	row.SuspectWildcard = len(result.SuspectReasons) > 0
+	if i := result.Icon; i != nil {
+		width, height, bitDepth, size := int32(i.Width), int32(i.Height), int32(i.BitDepth), int64(i.Bytes)
+		row.IconFormat = optional(i.Format)
+		row.IconWidth, row.IconHeight, row.IconBitDepth, row.IconBytes = &width, &height, &bitDepth, &size
+		row.IconMonochrome = &i.Monochrome
+	}
+	if t := result.Text; t != nil {
+		row.IconText = optional(t.Text)
+		row.TextScore = &t.Score
+		row.TextMatch = t.Match
+	}
+	if len(result.Metadata) > 0 {
+		metadata, err := json.Marshal(result.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata for %s: %v", result.URL, err)
+		}
+		row.Metadata = optional(string(metadata))
+	}
+	if _, err := p.pw.Write([]parquetResult{row}); err != nil {
+		return fmt.Errorf("failed to write Parquet output: %v", err)
+	}
+	return nil
+}
+
+// Close flushes the last row group and writes the file footer
+func (p *ParquetWriter) Close() error {
+	if err := p.pw.Close(); err != nil {
+		return fmt.Errorf("failed to write Parquet output: %v", err)
+	}
+	return nil
+}
+
+// optional returns a pointer to s, or nil for an empty string, for nullable columns
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,199 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifRuleID  = "favicon-match"
+	// sarifFingerprint names the partial fingerprint that identifies a match across runs
+	sarifFingerprint = "favlensMatch/v1"
+	toolName         = "favlens"
+	toolURI          = "https://github.com/ethicalhackingplayground/favlens"
+)
+
+// SARIF document structs (subset of the 2.1.0 schema)
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool         `json:"tool"`
+	Results    []sarifResult     `json:"results"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	ShortDescription sarifMessage      `json:"shortDescription"`
+	FullDescription  sarifMessage      `json:"fullDescription"`
+	Help             sarifMessage      `json:"help"`
+	Properties       map[string]any    `json:"properties,omitempty"`
+	DefaultConfig    map[string]string `json:"defaultConfiguration,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+// sarifLocation names the matched host logically; targets are web resources, not files in a checkout,
+// so there is no physical location to point at
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// SARIFWriter collects matches and writes a single SARIF log on Close
+type SARIFWriter struct {
+	w       io.Writer
+	info    ScanInfo
+	results []sarifResult
+}
+
+func NewSARIFWriter(w io.Writer, info ScanInfo) *SARIFWriter {
+	return &SARIFWriter{w: w, info: info, results: []sarifResult{}}
+}
+
+func (s *SARIFWriter) Write(result types.Result) error {
+	if result.Err != nil || !result.Match {
+		return nil
+	}
+	base := baseURL(result, s.info)
+	properties := map[string]any{
+		"targetUrl": result.URL,
+		"baseUrl":   base,
+		"model":     s.info.Model,
+	}
+	if len(result.Metadata) > 0 {
+		properties["metadata"] = result.Metadata
+	}
+	if len(result.Tags) > 0 {
+		properties["tags"] = result.Tags
+	}
+	// Brand favicons on lookalike domains are the clearest impersonation signal, so they are raised to errors
+	level := "warning"
+	if l := result.Lookalike; l != nil {
+		level = "error"
+		properties["lookalikeDomain"] = true
+		properties["confusability"] = l.Confusability
+		if l.IDN {
+			properties["unicodeHost"] = l.Unicode
+		}
+	} else if len(result.SuspectReasons) > 0 {
+		// Matches from parked or wildcard hosting are usually noise, so they drop to notes
+		level = "note"
+	}
+	if i := result.Icon; i != nil {
+		properties["icon"] = map[string]any{
+			"format":     i.Format,
+			"width":      i.Width,
+			"height":     i.Height,
+			"bytes":      i.Bytes,
+			"bitDepth":   i.BitDepth,
+			"monochrome": i.Monochrome,
+		}
+	}
+	if result.MMH3 != "" {
+		properties["faviconMmh3"] = result.MMH3
+	}
+	if result.SHA256 != "" {
+		properties["faviconSha256"] = result.SHA256
+	}
+	if t := result.Text; t != nil {
+		properties["iconText"] = t.Text
+		properties["textScore"] = t.Score
+		properties["textMatch"] = t.Match
+	}
+	if result.Asset != "" {
+		properties["matchedAsset"] = result.Asset
+	}
+	if len(result.SuspectReasons) > 0 {
+		properties["suspectWildcard"] = true
+		properties["suspectReasons"] = result.SuspectReasons
+	}
+	host := result.URL
+	if u, err := url.Parse(result.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	s.results = append(s.results, sarifResult{
+		RuleID:  sarifRuleID,
+		Level:   level,
+		Message: sarifMessage{Text: fmt.Sprintf("Favicon at %s matches the base favicon %s", result.URL, base)},
+		Locations: []sarifLocation{{
+			LogicalLocations: []sarifLogicalLocation{{Name: host, FullyQualifiedName: result.URL, Kind: "resource"}},
+		}},
+		PartialFingerprints: map[string]string{sarifFingerprint: matchFingerprint(result.URL, base)},
+		Properties:          properties,
+	})
+	return nil
+}
+
+func (s *SARIFWriter) Close() error {
+	doc := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           toolName,
+					InformationURI: toolURI,
+					Rules: []sarifRule{{
+						ID:               sarifRuleID,
+						Name:             "FaviconMatch",
+						ShortDescription: sarifMessage{Text: "Favicon matches the base favicon"},
+						FullDescription:  sarifMessage{Text: "The vision model judged the target favicon to be identical to, or the same brand as, the base favicon."},
+						Help:             sarifMessage{Text: "Review the matched host for brand impersonation or confirm it as an owned asset."},
+						Properties:       map[string]any{"tags": []string{"security", "brand-impersonation"}},
+						DefaultConfig:    map[string]string{"level": "warning"},
+					}},
+				},
+			},
+			Results: s.results,
+			Properties: map[string]string{
+				"baseUrl": s.info.BaseURL,
+				"model":   s.info.Model,
+			},
+		}},
+	}
+
+	enc := json.NewEncoder(s.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write SARIF output: %v", err)
+	}
+	return nil
+}
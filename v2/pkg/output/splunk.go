@@ -0,0 +1,188 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// SplunkOptions configure a SplunkWriter
+type SplunkOptions struct {
+	// URL is the HTTP Event Collector's base URL, e.g. https://splunk:8088
+	URL   string
+	Token string
+	// Index and SourceType override the token's defaults when set
+	Index      string
+	SourceType string
+	Timeout    time.Duration
+}
+
+// SplunkWriter sends every result to a Splunk HTTP Event Collector as the scan streams them, as the same
+// documents the JSON output writes. Events are batched like ElasticWriter's bulk requests.
+type SplunkWriter struct {
+	opts   SplunkOptions
+	info   ScanInfo
+	client *http.Client
+
+	mu       sync.Mutex
+	batch    bytes.Buffer
+	pending  int
+	dropped  int
+	firstErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// hecEvent is one event in a HEC request body
+type hecEvent struct {
+	Time       float64    `json:"time"`
+	Source     string     `json:"source"`
+	SourceType string     `json:"sourcetype,omitempty"`
+	Index      string     `json:"index,omitempty"`
+	Event      jsonResult `json:"event"`
+}
+
+// hecReply is HEC's answer to a request
+type hecReply struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
+// hecNoData is the code HEC answers an empty request with once the token is accepted
+const hecNoData = 5
+
+// NewSplunkWriter checks the token against the collector, so a bad URL or token fails the scan before it starts
+func NewSplunkWriter(opts SplunkOptions, info ScanInfo) (*SplunkWriter, error) {
+	s := &SplunkWriter{
+		opts:   opts,
+		info:   info,
+		client: &http.Client{Timeout: opts.Timeout},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.opts.URL = strings.TrimRight(opts.URL, "/")
+	if err := s.post(nil); err != nil {
+		var reply *hecError
+		if !errors.As(err, &reply) || reply.Code != hecNoData {
+			return nil, fmt.Errorf("Splunk HEC at %s: %v", s.opts.URL, err)
+		}
+	}
+	go s.flushPeriodically()
+	return s, nil
+}
+
+func (s *SplunkWriter) Write(result types.Result) error {
+	doc := newJSONResult(result, s.info)
+	event := hecEvent{
+		Time:       float64(s.info.now().UnixMilli()) / 1000,
+		Source:     toolName,
+		SourceType: s.opts.SourceType,
+		Index:      s.opts.Index,
+		Event:      doc,
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for Splunk: %v", result.URL, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.Write(data)
+	s.batch.WriteByte('\n')
+	s.pending++
+	if s.pending >= sinkBatchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// Close sends the last partial batch and reports results that could not be delivered
+func (s *SplunkWriter) Close() error {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush()
+	if s.dropped > 0 {
+		return fmt.Errorf("%d result(s) were not sent to Splunk: %v", s.dropped, s.firstErr)
+	}
+	return nil
+}
+
+func (s *SplunkWriter) flushPeriodically() {
+	defer close(s.done)
+	ticker := time.NewTicker(sinkFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flush()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// flush sends the pending batch; s.mu must be held. A failed batch is dropped rather than retried.
+func (s *SplunkWriter) flush() error {
+	if s.pending == 0 {
+		return nil
+	}
+	err := s.post(s.batch.Bytes())
+	if err != nil {
+		s.dropped += s.pending
+		if s.firstErr == nil {
+			s.firstErr = err
+		}
+	}
+	s.batch.Reset()
+	s.pending = 0
+	return err
+}
+
+// hecError is a request HEC refused, with its reason
+type hecError struct {
+	Status string
+	hecReply
+}
+
+func (e *hecError) Error() string {
+	if e.Text != "" {
+		return fmt.Sprintf("answered %s: %s (code %d)", e.Status, e.Text, e.Code)
+	}
+	return "answered " + e.Status
+}
+
+// post sends a batch of events to the collector
+func (s *SplunkWriter) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.opts.URL+"/services/collector/event", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.opts.Token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	e := &hecError{Status: resp.Status}
+	json.Unmarshal(data, &e.hecReply)
+	return e
+}
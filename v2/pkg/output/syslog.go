@@ -0,0 +1,178 @@
+package output
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	notify "github.com/ethicalhackingplayground/favlens/v2/pkg/notify"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Syslog message fields. Facility local0 leaves the standard facilities to the system, and 32473 is the
+// private enterprise number reserved for documentation, used for the structured data ID.
+const (
+	syslogFacility = 16
+	syslogSDID     = "favlens@32473"
+	// syslogWarning is the severity of matches, and syslogAlert of matches on lookalike domains
+	syslogWarning = 4
+	syslogAlert   = 1
+)
+
+// SyslogWriter sends every match to a syslog collector as an RFC 5424 message, so SIEMs can alert on them.
+// Non-matches are not sent: on large scans they would bury the alerts.
+type SyslogWriter struct {
+	network, addr string
+	tls           bool
+	timeout       time.Duration
+	hostname      string
+	info          ScanInfo
+
+	mu       sync.Mutex
+	conn     net.Conn
+	dropped  int
+	firstErr error
+}
+
+// NewSyslogWriter connects to a collector given as udp://, tcp:// or tls://host:port
+func NewSyslogWriter(rawURL string, timeout time.Duration, info ScanInfo) (*SyslogWriter, error) {
+	network, addr, secure, err := ParseSyslogURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	s := &SyslogWriter{network: network, addr: addr, tls: secure, timeout: timeout, hostname: hostname, info: info}
+	if err := s.connect(); err != nil {
+		return nil, fmt.Errorf("can't reach syslog collector %s: %v", rawURL, err)
+	}
+	return s, nil
+}
+
+// ParseSyslogURL splits a udp://, tcp:// or tls:// collector URL; the port defaults to 514, or 6514 for TLS
+func ParseSyslogURL(rawURL string) (network, addr string, secure bool, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", "", false, fmt.Errorf("syslog collector must look like udp://host:514, tcp://host:514 or tls://host:6514 (got '%s')", rawURL)
+	}
+	port := u.Port()
+	switch u.Scheme {
+	case "udp", "tcp":
+		network = u.Scheme
+		if port == "" {
+			port = "514"
+		}
+	case "tls":
+		network, secure = "tcp", true
+		if port == "" {
+			port = "6514"
+		}
+	default:
+		return "", "", false, fmt.Errorf("unsupported syslog transport '%s' (supported: udp, tcp, tls)", u.Scheme)
+	}
+	return network, net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+func (s *SyslogWriter) connect() error {
+	dialer := &net.Dialer{Timeout: s.timeout}
+	var err error
+	if s.tls {
+		host, _, _ := net.SplitHostPort(s.addr)
+		s.conn, err = tls.DialWithDialer(dialer, s.network, s.addr, &tls.Config{ServerName: host})
+	} else {
+		s.conn, err = dialer.Dial(s.network, s.addr)
+	}
+	return err
+}
+
+func (s *SyslogWriter) Write(result types.Result) error {
+	if result.Err != nil || !result.Match {
+		return nil
+	}
+	message := s.format(result)
+	// Stream transports frame messages by octet counting (RFC 6587); UDP sends one message per datagram
+	if s.network == "tcp" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.send(message)
+	if err != nil && s.network == "tcp" {
+		// Collectors drop idle connections; reconnect once before giving up on the message
+		s.conn.Close()
+		if err = s.connect(); err == nil {
+			err = s.send(message)
+		}
+	}
+	if err != nil {
+		s.dropped++
+		if s.firstErr == nil {
+			s.firstErr = err
+		}
+		return fmt.Errorf("failed to send %s to syslog: %v", result.URL, err)
+	}
+	return nil
+}
+
+func (s *SyslogWriter) send(message string) error {
+	if s.timeout > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+	_, err := s.conn.Write([]byte(message))
+	return err
+}
+
+// format renders a match as an RFC 5424 message with its details as structured data
+func (s *SyslogWriter) format(result types.Result) string {
+	severity := syslogWarning
+	text := "Favicon match: " + result.URL
+	params := []string{
+		sdParam("url", result.URL),
+		sdParam("base_url", baseURL(result, s.info)),
+		sdParam("confidence", fmt.Sprint(notify.Confidence(result))),
+		sdParam("model", s.info.Model),
+	}
+	if len(result.Tags) > 0 {
+		params = append(params, sdParam("tags", strings.Join(result.Tags, ",")))
+	}
+	if l := result.Lookalike; l != nil {
+		severity = syslogAlert
+		text += " (lookalike domain " + l.Unicode + ")"
+		params = append(params, sdParam("lookalike_domain", l.Unicode), sdParam("confusability", fmt.Sprintf("%.2f", l.Confusability)))
+	}
+	if len(result.SuspectReasons) > 0 {
+		params = append(params, sdParam("suspect_reasons", strings.Join(result.SuspectReasons, ",")))
+	}
+	if result.Asset != "" {
+		params = append(params, sdParam("matched_asset", result.Asset))
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d match [%s %s] %s",
+		syslogFacility*8+severity,
+		s.info.now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		s.hostname, toolName, os.Getpid(),
+		syslogSDID, strings.Join(params, " "), text)
+}
+
+// sdParam renders a structured data parameter, escaping the characters RFC 5424 reserves in values
+func sdParam(name, value string) string {
+	return name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value) + `"`
+}
+
+// Close reports matches that could not be sent and closes the connection
+func (s *SyslogWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn.Close()
+	if s.dropped > 0 {
+		return fmt.Errorf("%d match(es) were not sent to syslog: %v", s.dropped, s.firstErr)
+	}
+	return nil
+}
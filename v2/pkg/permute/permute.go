@@ -0,0 +1,235 @@
+package permute
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Fuzzers that produce permutations, recorded on each Permutation
+const (
+	FuzzerAddition      = "addition"
+	FuzzerBitsquatting  = "bitsquatting"
+	FuzzerHomoglyph     = "homoglyph"
+	FuzzerHyphenation   = "hyphenation"
+	FuzzerInsertion     = "insertion"
+	FuzzerOmission      = "omission"
+	FuzzerRepetition    = "repetition"
+	FuzzerReplacement   = "replacement"
+	FuzzerSubdomain     = "subdomain"
+	FuzzerTransposition = "transposition"
+	FuzzerVowelSwap     = "vowel-swap"
+	FuzzerTLDSwap       = "tld-swap"
+)
+
+// Permutation is a candidate lookalike of the original domain
+type Permutation struct {
+	// Domain is the ASCII (punycode) form that can be resolved and requested
+	Domain string
+	// Unicode is the display form; it differs from Domain only for homoglyph IDNs
+	Unicode string
+	Fuzzer  string
+}
+
+// keyboard maps each key to its neighbours on a QWERTY layout, for replacement and insertion typos
+var keyboard = map[rune]string{
+	'1': "2q", '2': "3wq1", '3': "4ew2", '4': "5re3", '5': "6tr4", '6': "7yt5", '7': "8uy6", '8': "9iu7", '9': "0oi8", '0': "po9",
+	'q': "12wa", 'w': "3esaq2", 'e': "4rdsw3", 'r': "5tfde4", 't': "6ygfr5", 'y': "7uhgt6", 'u': "8ijhy7", 'i': "9okju8", 'o': "0plki9", 'p': "lo0",
+	'a': "qwsz", 's': "edxzaw", 'd': "rfcxse", 'f': "tgvcdr", 'g': "yhbvft", 'h': "ujnbgy", 'j': "ikmnhu", 'k': "olmji", 'l': "kop",
+	'z': "asx", 'x': "zsdc", 'c': "xdfv", 'v': "cfgb", 'b': "vghn", 'n': "bhjm", 'm': "njk",
+}
+
+// homoglyphs maps ASCII letters to characters that render alike, including multi-letter ASCII lookalikes
+var homoglyphs = map[rune][]string{
+	'a': {"à", "á", "â", "ã", "ä", "å", "ɑ", "а"}, 'b': {"d", "lb", "ʙ", "ь"}, 'c': {"e", "ƈ", "ċ", "ć", "с"},
+	'd': {"b", "cl", "dl", "ď", "ԁ"}, 'e': {"c", "é", "ê", "ë", "ē", "ė", "е"}, 'f': {"ƒ"}, 'g': {"q", "ɢ", "ġ", "ğ"},
+	'h': {"lh", "ĥ", "һ"}, 'i': {"1", "l", "í", "ï", "ı", "і"}, 'j': {"ј", "ʝ"}, 'k': {"lk", "ik", "ĸ", "κ"},
+	'l': {"1", "i", "ɫ", "ł"}, 'm': {"n", "nn", "rn", "rr", "ṃ"}, 'n': {"m", "r", "ń", "ñ"}, 'o': {"0", "ò", "ó", "ö", "ø", "о"},
+	'p': {"ρ", "р"}, 'q': {"g", "ʠ"}, 'r': {"ʀ", "г"}, 's': {"ṡ", "ś", "ѕ"}, 't': {"ţ", "ŧ"}, 'u': {"ü", "ú", "ù", "μ", "υ"},
+	'v': {"ѵ", "ν"}, 'w': {"vv", "ŵ", "ԝ"}, 'x': {"х", "ҳ"}, 'y': {"ʏ", "ý", "у"}, 'z': {"ʐ", "ż", "ź"},
+}
+
+// swapTLDs are common alternatives registered by squatters
+var swapTLDs = []string{"com", "net", "org", "info", "biz", "co", "io", "app", "online", "site", "xyz", "top", "shop", "support", "live", "us", "co.uk", "de", "ru", "cn"}
+
+const vowels = "aeiou"
+
+// Generate returns every unique permutation of domain, excluding domain itself, sorted by domain.
+// The first label is permuted and the rest is treated as the public suffix, so "acme.co.uk" keeps ".co.uk".
+func Generate(domain string) ([]Permutation, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	name, suffix, ok := strings.Cut(domain, ".")
+	if !ok || name == "" || suffix == "" {
+		return nil, fmt.Errorf("'%s' is not a registrable domain", domain)
+	}
+
+	g := &generator{original: domain, seen: map[string]bool{domain: true}}
+	labels := map[string][]string{
+		FuzzerAddition:      addition(name),
+		FuzzerBitsquatting:  bitsquatting(name),
+		FuzzerHomoglyph:     homoglyph(name),
+		FuzzerHyphenation:   hyphenation(name),
+		FuzzerInsertion:     insertion(name),
+		FuzzerOmission:      omission(name),
+		FuzzerRepetition:    repetition(name),
+		FuzzerReplacement:   replacement(name),
+		FuzzerSubdomain:     subdomain(name),
+		FuzzerTransposition: transposition(name),
+		FuzzerVowelSwap:     vowelSwap(name),
+	}
+	fuzzers := make([]string, 0, len(labels))
+	for fuzzer := range labels {
+		fuzzers = append(fuzzers, fuzzer)
+	}
+	sort.Strings(fuzzers)
+	for _, fuzzer := range fuzzers {
+		for _, label := range labels[fuzzer] {
+			g.add(label+"."+suffix, fuzzer)
+		}
+	}
+	for _, tld := range swapTLDs {
+		g.add(name+"."+tld, FuzzerTLDSwap)
+	}
+
+	sort.Slice(g.out, func(i, j int) bool { return g.out[i].Domain < g.out[j].Domain })
+	return g.out, nil
+}
+
+type generator struct {
+	original string
+	seen     map[string]bool
+	out      []Permutation
+}
+
+// add keeps candidates that are valid hostnames once converted to punycode
+func (g *generator) add(candidate, fuzzer string) {
+	if strings.HasPrefix(candidate, "-") || strings.Contains(candidate, "-.") || strings.Contains(candidate, "..") {
+		return
+	}
+	ascii, err := idna.Lookup.ToASCII(candidate)
+	if err != nil || g.seen[ascii] {
+		return
+	}
+	g.seen[ascii] = true
+	g.out = append(g.out, Permutation{Domain: ascii, Unicode: candidate, Fuzzer: fuzzer})
+}
+
+func addition(name string) []string {
+	var out []string
+	for c := 'a'; c <= 'z'; c++ {
+		out = append(out, name+string(c))
+	}
+	for c := '0'; c <= '9'; c++ {
+		out = append(out, name+string(c))
+	}
+	return out
+}
+
+// bitsquatting flips each bit of each character, keeping results that are still valid hostname characters
+func bitsquatting(name string) []string {
+	var out []string
+	for i := 0; i < len(name); i++ {
+		for bit := 0; bit < 8; bit++ {
+			c := name[i] ^ (1 << bit)
+			if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' {
+				out = append(out, name[:i]+string(c)+name[i+1:])
+			}
+		}
+	}
+	return out
+}
+
+func homoglyph(name string) []string {
+	var out []string
+	runes := []rune(name)
+	for i, r := range runes {
+		for _, glyph := range homoglyphs[r] {
+			out = append(out, string(runes[:i])+glyph+string(runes[i+1:]))
+		}
+	}
+	return out
+}
+
+func hyphenation(name string) []string {
+	var out []string
+	for i := 1; i < len(name); i++ {
+		out = append(out, name[:i]+"-"+name[i:])
+	}
+	return out
+}
+
+// insertion adds a neighbouring key before or after each character
+func insertion(name string) []string {
+	var out []string
+	for i, r := range name {
+		for _, key := range keyboard[r] {
+			out = append(out, name[:i]+string(key)+name[i:], name[:i+1]+string(key)+name[i+1:])
+		}
+	}
+	return out
+}
+
+func omission(name string) []string {
+	var out []string
+	for i := 0; i < len(name); i++ {
+		out = append(out, name[:i]+name[i+1:])
+	}
+	return out
+}
+
+func repetition(name string) []string {
+	var out []string
+	for i := 0; i < len(name); i++ {
+		out = append(out, name[:i+1]+name[i:])
+	}
+	return out
+}
+
+// replacement swaps each character for a neighbouring key
+func replacement(name string) []string {
+	var out []string
+	for i, r := range name {
+		for _, key := range keyboard[r] {
+			out = append(out, name[:i]+string(key)+name[i+1:])
+		}
+	}
+	return out
+}
+
+// subdomain splits the name with a dot, e.g. "ac.me.com"
+func subdomain(name string) []string {
+	var out []string
+	for i := 1; i < len(name); i++ {
+		if name[i-1] != '-' && name[i] != '-' {
+			out = append(out, name[:i]+"."+name[i:])
+		}
+	}
+	return out
+}
+
+func transposition(name string) []string {
+	var out []string
+	for i := 0; i < len(name)-1; i++ {
+		if name[i] != name[i+1] {
+			out = append(out, name[:i]+string(name[i+1])+string(name[i])+name[i+2:])
+		}
+	}
+	return out
+}
+
+func vowelSwap(name string) []string {
+	var out []string
+	for i := 0; i < len(name); i++ {
+		if !strings.ContainsRune(vowels, rune(name[i])) {
+			continue
+		}
+		for _, v := range vowels {
+			if byte(v) != name[i] {
+				out = append(out, name[:i]+string(v)+name[i+1:])
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package permute
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Resolve looks up every permutation with workers concurrent queries and returns those that resolve,
+// sorted by domain. Unregistered names are the vast majority, so lookups that fail are simply dropped.
+func Resolve(perms []Permutation, workers int, timeout time.Duration) []Permutation {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan Permutation)
+	var (
+		mu   sync.Mutex
+		live []Permutation
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for perm := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				addrs, err := net.DefaultResolver.LookupHost(ctx, perm.Domain)
+				cancel()
+				if err != nil || len(addrs) == 0 {
+					continue
+				}
+				mu.Lock()
+				live = append(live, perm)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, perm := range perms {
+		jobs <- perm
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(live, func(i, j int) bool { return live[i].Domain < live[j].Domain })
+	return live
+}
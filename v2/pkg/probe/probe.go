@@ -0,0 +1,56 @@
+package probe
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Server exposes Kubernetes-style liveness (/healthz) and readiness (/readyz) endpoints
+type Server struct {
+	ready  atomic.Bool
+	server *http.Server
+}
+
+// Start listens on addr and serves the probe endpoints in the background; the server starts out not ready
+func Start(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok\n"))
+	})
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			listener.Close()
+		}
+	}()
+	return s, nil
+}
+
+// SetReady flips the readiness probe; liveness is unaffected
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Close stops serving probes
+func (s *Server) Close() error {
+	return s.server.Close()
+}
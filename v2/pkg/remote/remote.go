@@ -0,0 +1,141 @@
+// Package remote streams output files to object storage, so scans in ephemeral containers can write results
+// to s3:// and gs:// URLs without a persistent volume.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// partSize is the multipart upload part size; parts are buffered in memory and cap objects at 10,000 parts
+const partSize = 16 * 1024 * 1024
+
+// contentTypes maps output file extensions to the Content-Type objects are stored with
+var contentTypes = map[string]string{
+	".json":    "application/json",
+	".jsonl":   "application/x-ndjson",
+	".ndjson":  "application/x-ndjson",
+	".sarif":   "application/sarif+json",
+	".parquet": "application/vnd.apache.parquet",
+	".txt":     "text/plain",
+}
+
+// IsURL reports whether an output path is an object storage URL rather than a local file
+func IsURL(output string) bool {
+	return strings.HasPrefix(output, "s3://") || strings.HasPrefix(output, "gs://")
+}
+
+// Upload is an object being written. Data is uploaded in parts as it is written, and the object only
+// appears in the bucket once Close succeeds.
+type Upload struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// Create starts streaming an object to an s3://bucket/key or gs://bucket/key URL.
+//
+// S3 credentials come from the standard AWS environment variables, the shared credentials file or the
+// instance, container or EKS role, and AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL point at S3-compatible stores
+// such as MinIO. GCS is written through its S3-compatible XML API with HMAC keys from GCS_HMAC_ACCESS_KEY_ID
+// and GCS_HMAC_SECRET, or the AWS variables.
+func Create(rawURL string) (*Upload, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output URL '%s': %v", rawURL, err)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("output URL '%s' must name a bucket and an object, e.g. %s://bucket/results.jsonl", rawURL, u.Scheme)
+	}
+
+	var client *minio.Client
+	switch u.Scheme {
+	case "s3":
+		client, err = s3Client()
+	case "gs":
+		client, err = gcsClient()
+	default:
+		return nil, fmt.Errorf("unsupported output URL scheme '%s' (supported: s3, gs)", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s://%s: %v", u.Scheme, bucket, err)
+	}
+
+	contentType, ok := contentTypes[strings.ToLower(path.Ext(key))]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	pr, pw := io.Pipe()
+	upload := &Upload{pw: pw, done: make(chan error, 1)}
+	go func() {
+		_, err := client.PutObject(context.Background(), bucket, key, pr, -1, minio.PutObjectOptions{ContentType: contentType, PartSize: partSize})
+		if err != nil {
+			err = fmt.Errorf("upload to %s failed: %v", rawURL, err)
+		}
+		// Unblock writers if the upload gave up early
+		pr.CloseWithError(err)
+		upload.done <- err
+	}()
+	return upload, nil
+}
+
+// Write passes data to the upload; it fails once the upload has failed
+func (u *Upload) Write(p []byte) (int, error) {
+	return u.pw.Write(p)
+}
+
+// Close finishes the upload and waits for the object to be stored
+func (u *Upload) Close() error {
+	u.pw.Close()
+	return <-u.done
+}
+
+// s3Client connects to AWS S3, or the S3-compatible endpoint named by the AWS endpoint variables
+func s3Client() (*minio.Client, error) {
+	endpoint, secure, lookup := "s3.amazonaws.com", true, minio.BucketLookupAuto
+	if custom := orEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); custom != "" {
+		e, err := url.Parse(custom)
+		if err != nil || e.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint '%s'", custom)
+		}
+		// Self-hosted stores rarely have wildcard DNS for virtual-host bucket names
+		endpoint, secure, lookup = e.Host, e.Scheme != "http", minio.BucketLookupPath
+	}
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{},
+	})
+	return minio.New(endpoint, &minio.Options{Creds: creds, Secure: secure, Region: orEnv("AWS_REGION", "AWS_DEFAULT_REGION"), BucketLookup: lookup})
+}
+
+// gcsClient connects to Google Cloud Storage's S3-compatible XML API
+func gcsClient() (*minio.Client, error) {
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.Static{Value: credentials.Value{
+			AccessKeyID:     os.Getenv("GCS_HMAC_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("GCS_HMAC_SECRET"),
+			SignerType:      credentials.SignatureV4,
+		}},
+		&credentials.EnvAWS{},
+	})
+	return minio.New("storage.googleapis.com", &minio.Options{Creds: creds, Secure: true})
+}
+
+// orEnv returns the first of the environment variables that is set
+func orEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// UserAgent is the product token matched against robots.txt user-agent lines; "*" groups apply otherwise
+const UserAgent = "favlens"
+
+// Fetcher retrieves a robots.txt file and reports its status code
+type Fetcher func(url string) (status int, body []byte, err error)
+
+// rule is a single allow or disallow path pattern
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// Rules are the rules of the robots.txt group that applies to favlens
+type Rules struct {
+	rules []rule
+	// disallowAll is set when robots.txt could not be fetched because of a server error
+	disallowAll bool
+}
+
+// Parse extracts the rules of the group naming favlens, or the "*" group when there is none
+func Parse(body []byte) *Rules {
+	var (
+		ours, wildcard []rule
+		agents         []string
+		inRules        bool
+		current        []rule
+		foundOurs      bool
+	)
+	flush := func() {
+		for _, agent := range agents {
+			switch {
+			case strings.EqualFold(agent, UserAgent):
+				ours = append(ours, current...)
+				foundOurs = true
+			case agent == "*":
+				wildcard = append(wildcard, current...)
+			}
+		}
+		agents, current, inRules = nil, nil, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				flush()
+			}
+			agents = append(agents, value)
+		case "allow", "disallow":
+			inRules = true
+			// An empty disallow allows everything and adds no rule
+			if value != "" {
+				current = append(current, rule{pattern: value, allow: key == "allow"})
+			}
+		}
+	}
+	flush()
+
+	if foundOurs {
+		return &Rules{rules: ours}
+	}
+	return &Rules{rules: wildcard}
+}
+
+// Allowed applies the longest matching rule to path; allow wins ties and no match means allowed
+func (r *Rules) Allowed(path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !match(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allowed = n, rule.allow
+		}
+	}
+	return allowed
+}
+
+// match reports whether path matches a robots pattern, where * matches any run of characters and a
+// trailing $ anchors the end; unanchored patterns match as prefixes
+func match(pattern, path string) bool {
+	if anchored := strings.HasSuffix(pattern, "$"); anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	} else {
+		pattern += "*"
+	}
+
+	// Greedy wildcard matching that backtracks to the last * on a mismatch
+	p, s := 0, 0
+	star, mark := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, s
+			p++
+		case p < len(pattern) && pattern[p] == path[s]:
+			p++
+			s++
+		case star >= 0:
+			p = star + 1
+			mark++
+			s = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// Checker fetches robots.txt once per scheme and host and answers whether URLs may be requested
+type Checker struct {
+	fetch Fetcher
+
+	mu    sync.Mutex
+	hosts map[string]*hostRules
+}
+
+type hostRules struct {
+	once  sync.Once
+	rules *Rules
+}
+
+func NewChecker(fetch Fetcher) *Checker {
+	return &Checker{fetch: fetch, hosts: make(map[string]*hostRules)}
+}
+
+// Allowed reports whether rawURL may be fetched. Following RFC 9309, a missing robots.txt (4xx)
+// allows everything while an unreachable one (5xx or network error) disallows everything.
+func (c *Checker) Allowed(rawURL string) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, fmt.Errorf("invalid URL %s: %v", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return true, nil
+	}
+
+	key := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	entry, ok := c.hosts[key]
+	if !ok {
+		entry = &hostRules{}
+		c.hosts[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		status, body, err := c.fetch(key + "/robots.txt")
+		switch {
+		case err != nil || status >= 500:
+			entry.rules = &Rules{disallowAll: true}
+		case status >= 400:
+			entry.rules = &Rules{}
+		case status >= 200 && status < 300:
+			entry.rules = Parse(body)
+		default:
+			entry.rules = &Rules{}
+		}
+	})
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.rules.Allowed(path), nil
+}
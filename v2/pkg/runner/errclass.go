@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+)
+
+// Error classes a failed target is put in, from the cause most worth acting on to the least
+const (
+	ClassBase      = "base"
+	ClassModel     = "model"
+	ClassRateLimit = "rate-limit"
+	ClassRobots    = "robots"
+	ClassTimeout   = "timeout"
+	ClassDNS       = "dns"
+	ClassRefused   = "refused"
+	ClassReset     = "reset"
+	ClassTLS       = "tls"
+	ClassHTTP4xx   = "http-4xx"
+	ClassHTTP5xx   = "http-5xx"
+	ClassImage     = "image"
+	ClassPanic     = "panic"
+	ClassOther     = "other"
+)
+
+// badStatus finds the status code in "bad status for <url>: <code>" download errors
+var badStatus = regexp.MustCompile(`bad status for \S+: ([1-5])\d\d\b`)
+
+// errorClasses recognise classes from the messages of errors that only survive as text, in lowercase and in
+// order, so a timeout resolving a host is a timeout rather than a DNS failure
+var errorClasses = []struct {
+	class    string
+	patterns []string
+}{
+	{ClassBase, []string{"base favicon unavailable", "brand kit asset"}},
+	{ClassModel, []string{"ollama api", "ollama returned", "model "}},
+	{ClassRateLimit, []string{"rate limited", "rate-limited"}},
+	{ClassRobots, []string{"disallowed by robots.txt"}},
+	{ClassTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+	{ClassDNS, []string{"no such host", "server misbehaving", "lookup "}},
+	{ClassRefused, []string{"connection refused"}},
+	{ClassReset, []string{"connection reset", "broken pipe", "connection closed", "unexpected eof"}},
+	{ClassTLS, []string{"tls:", "x509:", "certificate"}},
+	{ClassImage, []string{"decoding image", "encoding png", "image dimensions", "byte limit", "unsupported image", "not an image"}},
+	{ClassPanic, []string{"panic"}},
+}
+
+// ErrorClass puts the error a target failed with in one of the Class values, so failures can be counted,
+// written out and retried by cause
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	var rateLimit *ollama.RateLimitError
+	var jobTimeout *JobTimeoutError
+	var api *ollama.APIError
+	switch {
+	case errors.As(err, &api):
+		return ClassModel
+	case errors.As(err, &rateLimit):
+		return ClassRateLimit
+	case errors.As(err, &jobTimeout):
+		return ClassTimeout
+	}
+
+	// The status is checked first, since the URL it names could contain any of the patterns
+	message := strings.ToLower(err.Error())
+	if match := badStatus.FindStringSubmatch(message); match != nil && !strings.HasPrefix(message, "base favicon") {
+		switch match[1] {
+		case "4":
+			return ClassHTTP4xx
+		case "5":
+			return ClassHTTP5xx
+		}
+	}
+	for _, class := range errorClasses {
+		for _, pattern := range class.patterns {
+			if strings.Contains(message, pattern) {
+				return class.class
+			}
+		}
+	}
+	return ClassOther
+}
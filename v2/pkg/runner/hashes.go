@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// hashKey identifies a verdict by the target icon's favicon hash and the base favicon it was compared with
+type hashKey struct {
+	hash, base string
+}
+
+// hashVerdicts remembers verdicts by favicon hash, so targets whose hash is already known from httpx output
+// are decided without downloading their icon. Icons identical to the base favicon are matches from the start;
+// other hashes are learned as their first icon is compared.
+type hashVerdicts struct {
+	mu       sync.Mutex
+	verdicts map[hashKey]bool
+	decided  atomic.Int64
+}
+
+// newHashVerdicts seeds the verdicts with the hashes of the base favicon and brand kit logos, which match base
+func newHashVerdicts(base string, brandHashes []string) *hashVerdicts {
+	h := &hashVerdicts{verdicts: make(map[hashKey]bool)}
+	for _, hash := range brandHashes {
+		h.verdicts[hashKey{hash, base}] = true
+	}
+	return h
+}
+
+// Lookup returns the verdict for an icon hash against base, if one is known
+func (h *hashVerdicts) Lookup(hash, base string) (match, ok bool) {
+	if hash == "" {
+		return false, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	match, ok = h.verdicts[hashKey{hash, base}]
+	if ok {
+		h.decided.Add(1)
+	}
+	return match, ok
+}
+
+// Learn records the verdict for an icon hash against base; verdicts already known are kept
+func (h *hashVerdicts) Learn(hash, base string, match bool) {
+	if hash == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.verdicts[hashKey{hash, base}]; !ok {
+		h.verdicts[hashKey{hash, base}] = match
+	}
+}
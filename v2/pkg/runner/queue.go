@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// queueInterval is how often --show-queue logs where jobs are in the pipeline
+const queueInterval = 10 * time.Second
+
+// queueSlowest is how many outstanding targets each --show-queue report names
+const queueSlowest = 3
+
+// Bottlenecks a QueueStats can point at
+const (
+	BottleneckNetwork = "network"
+	BottleneckModel   = "model"
+)
+
+// Outstanding is a job a worker is on, with how long it has been at it
+type Outstanding struct {
+	Worker  string
+	URL     string
+	Stage   string
+	Elapsed time.Duration
+}
+
+// QueueStats is a snapshot of where jobs are in the pipeline
+type QueueStats struct {
+	// Queued jobs wait for a download worker; Fetched jobs have their icons and wait for an inference worker
+	Queued  int
+	Fetched int
+	// Downloading and Comparing are the jobs in flight in each pool
+	Downloading int
+	Comparing   int
+	Completed   int64
+	// Bottleneck is BottleneckNetwork when every download worker is busy while inference workers sit idle,
+	// BottleneckModel when every inference worker is busy while downloaded jobs wait, and "" otherwise
+	Bottleneck string
+	// Slowest are the in-flight jobs, longest running first
+	Slowest []Outstanding
+}
+
+// Queue returns where jobs are in the pipeline; it is empty until Start is called
+func (r *Runner) Queue() QueueStats {
+	scan := r.scan
+	stats := QueueStats{Completed: r.completed.Load()}
+	if scan.requeue == nil {
+		return stats
+	}
+	stats.Queued = scan.requeue.Queued()
+	stats.Fetched = len(scan.handoff)
+	stats.Slowest = scan.watchdog.Busy()
+	for _, job := range stats.Slowest {
+		// Download workers are named "Downloader N" and inference workers "Worker N"
+		if strings.HasPrefix(job.Worker, "Downloader ") {
+			stats.Downloading++
+		} else {
+			stats.Comparing++
+		}
+	}
+	switch {
+	case stats.Comparing >= scan.args.InferWorkers && stats.Fetched > 0:
+		stats.Bottleneck = BottleneckModel
+	case stats.Downloading >= scan.args.DownloadWorkers && stats.Comparing < scan.args.InferWorkers:
+		stats.Bottleneck = BottleneckNetwork
+	}
+	return stats
+}
+
+// watchQueue logs the state of the pipeline every queueInterval until the returned function is called
+func (r *Runner) watchQueue() func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(queueInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.reportQueue()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (r *Runner) reportQueue() {
+	stats := r.Queue()
+	line := fmt.Sprintf("Queue: %d queued, %d downloading, %d awaiting the model, %d comparing, %d completed",
+		stats.Queued, stats.Downloading, stats.Fetched, stats.Comparing, stats.Completed)
+	switch stats.Bottleneck {
+	case BottleneckNetwork:
+		line += "; bottleneck: network (inference workers are idle while every download is in flight)"
+	case BottleneckModel:
+		line += "; bottleneck: model (downloaded icons are waiting for a free inference worker)"
+	}
+	gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprint(line))
+	for _, job := range stats.Slowest[:min(len(stats.Slowest), queueSlowest)] {
+		gologger.Info().Msg(color.New(color.Italic, color.FgCyan).Sprintf("  %s on %s for %s (%s)", job.Worker, job.URL, job.Elapsed.Round(time.Second), job.Stage))
+	}
+}
@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// requeue feeds workers from the producer's jobs plus jobs deferred because their host is rate limiting or
+// they failed in a way worth another try at the end of the run. Every job handed out is counted as pending
+// until a worker calls Done, so the queue only closes once the producer is finished and no deferred job is
+// still waiting.
+type requeue struct {
+	input   <-chan types.Job
+	queue   chan types.Job
+	stop    <-chan struct{}
+	pending sync.WaitGroup
+	// inputDone is closed once the producer's jobs have all been queued
+	inputDone chan struct{}
+	// maxAttempts caps consecutive rate-limited responses per host before its targets are marked failed
+	maxAttempts int
+
+	mu           sync.Mutex
+	attempts     map[string]int
+	blockedUntil map[string]time.Time
+	requeued     atomic.Int64
+	deferred     atomic.Int64
+	recovered    atomic.Int64
+}
+
+// deferDelay is how long deferred jobs wait after the input is exhausted, giving a flaky network or
+// overloaded host a moment before the second pass
+const deferDelay = 5 * time.Second
+
+// newRequeue starts forwarding jobs into the worker queue
+func newRequeue(jobs <-chan types.Job, size, maxAttempts int, stop <-chan struct{}) *requeue {
+	r := &requeue{
+		input:        jobs,
+		queue:        make(chan types.Job, size),
+		stop:         stop,
+		inputDone:    make(chan struct{}),
+		maxAttempts:  maxAttempts,
+		attempts:     make(map[string]int),
+		blockedUntil: make(map[string]time.Time),
+	}
+	// Hold the queue open while the producer is still running
+	r.pending.Add(1)
+	go func() {
+		for job := range jobs {
+			r.pending.Add(1)
+			r.queue <- job
+		}
+		close(r.inputDone)
+		r.pending.Done()
+	}()
+	go func() {
+		r.pending.Wait()
+		close(r.queue)
+	}()
+	return r
+}
+
+// Jobs is the channel workers read from
+func (r *requeue) Jobs() <-chan types.Job {
+	return r.queue
+}
+
+// Queued returns how many jobs are buffered for the download workers, in the producer's channel and the queue
+func (r *requeue) Queued() int {
+	return len(r.input) + len(r.queue)
+}
+
+// Done marks a job taken from Jobs as handled
+func (r *requeue) Done() {
+	r.pending.Done()
+}
+
+// Postpone defers job while its host is inside a Retry-After window, without sending it a request
+func (r *requeue) Postpone(job types.Job, host string) bool {
+	r.mu.Lock()
+	wait := time.Until(r.blockedUntil[host])
+	r.mu.Unlock()
+	if wait <= 0 {
+		return false
+	}
+	r.schedule(job, wait)
+	return true
+}
+
+// Retry requeues job after wait and pauses its host; it returns false once the host has been rate
+// limited maxAttempts times in a row, in which case the caller records the failure
+func (r *requeue) Retry(job types.Job, host string, wait time.Duration) bool {
+	r.mu.Lock()
+	r.attempts[host]++
+	if r.attempts[host] > r.maxAttempts {
+		r.mu.Unlock()
+		return false
+	}
+	if until := time.Now().Add(wait); until.After(r.blockedUntil[host]) {
+		r.blockedUntil[host] = until
+	}
+	r.mu.Unlock()
+
+	r.requeued.Add(1)
+	r.schedule(job, wait)
+	return true
+}
+
+// Resubmit puts job straight back on the queue, e.g. after it was abandoned for overrunning its deadline
+func (r *requeue) Resubmit(job types.Job) {
+	r.schedule(job, 0)
+}
+
+// Defer puts job back on the queue once the producer is finished, so a target that failed transiently is
+// tried again after the rest of the input rather than straight away
+func (r *requeue) Defer(job types.Job) {
+	r.deferred.Add(1)
+	r.pending.Add(1)
+	go func() {
+		select {
+		case <-r.inputDone:
+			timer := time.NewTimer(deferDelay)
+			select {
+			case <-timer.C:
+			case <-r.stop:
+				timer.Stop()
+			}
+		case <-r.stop:
+		}
+		r.queue <- job
+	}()
+}
+
+// Recovered records a deferred job that succeeded on a later attempt
+func (r *requeue) Recovered() {
+	r.recovered.Add(1)
+}
+
+// Succeeded resets the host's consecutive rate-limit count
+func (r *requeue) Succeeded(host string) {
+	r.mu.Lock()
+	delete(r.attempts, host)
+	r.mu.Unlock()
+}
+
+// Requeued returns how many times a job was put back because of rate limiting
+func (r *requeue) Requeued() int64 {
+	return r.requeued.Load()
+}
+
+// Deferred returns how many times a job was put off to the end of the run, and how many of those jobs then succeeded
+func (r *requeue) Deferred() (deferred, recovered int64) {
+	return r.deferred.Load(), r.recovered.Load()
+}
+
+// schedule puts job back on the queue after wait, or straight away once the scan is stopping so workers can drain it
+func (r *requeue) schedule(job types.Job, wait time.Duration) {
+	r.pending.Add(1)
+	go func() {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.stop:
+			timer.Stop()
+		}
+		r.queue <- job
+	}()
+}
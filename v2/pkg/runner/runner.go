@@ -0,0 +1,315 @@
+package runner
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	args "github.com/ethicalhackingplayground/favlens/v2/pkg/arguments"
+	cluster "github.com/ethicalhackingplayground/favlens/v2/pkg/cluster"
+	corpus "github.com/ethicalhackingplayground/favlens/v2/pkg/corpus"
+	deception "github.com/ethicalhackingplayground/favlens/v2/pkg/deception"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// Options are the clients and shared state a Runner scans with
+type Options struct {
+	Args      *args.Arguments
+	BaseIcons *ollama.IconCache
+	// Client downloads icons; comparisons go through Pool
+	Client  *ollama.Client
+	Pool    *ollama.Pool
+	Breaker *ollama.Breaker
+	// Verdicts reuses earlier verdicts for the same icon pair; nil disables the cache
+	Verdicts *store.VerdictCache
+	// Deception flags matches from parked and wildcard hosting; nil skips the checks
+	Deception *deception.Detector
+	// BrandHashes are the mmh3 hashes of the base favicon and brand kit logos; jobs carrying one of them match
+	// without a download
+	BrandHashes []string
+	// Clusters groups compared icons into equivalence classes; nil skips it
+	Clusters *cluster.Collector
+	// Fingerprints tags targets whose icon was labelled in an earlier cluster mapping; nil skips it
+	Fingerprints *cluster.Labels
+	// Corpus indexes the embedding of every compared icon for `favlens search`; nil skips it
+	Corpus *corpus.Corpus
+	// Stop is closed once no further jobs should be processed; in-flight jobs still finish
+	Stop <-chan struct{}
+}
+
+// Runner scans jobs through the download and inference pools and streams their results as they complete.
+// Hooks run on a single goroutine in completion order, before each result is delivered on Results, so
+// embedding applications can react to results in real time instead of waiting for the scan to end.
+//
+// Register hooks before Start, then either range over Results or call Wait; results are only delivered
+// while one of them is consuming.
+type Runner struct {
+	scan *scanContext
+
+	onResult []func(types.Result)
+	onMatch  []func(types.Result)
+	onError  []func(types.Result)
+
+	results chan types.Result
+	started atomic.Bool
+	skipped atomic.Int64
+	demoted atomic.Int64
+	// completed counts results delivered to hooks
+	completed atomic.Int64
+	// lastResult is when the latest result was delivered, in Unix nanoseconds, or when Start was called
+	lastResult atomic.Int64
+
+	mu sync.Mutex
+	// recentErrors are the latest failed results, oldest first
+	recentErrors []types.Result
+
+	// hooks is held while hooks run, so Abandon can wait for the one in progress
+	hooks sync.Mutex
+	// abandoned is closed by Abandon
+	abandoned   chan struct{}
+	abandonOnce sync.Once
+}
+
+// keptErrors is how many failed results RecentErrors returns
+const keptErrors = 5
+
+// New prepares a Runner. Pool sizes left at 0 in opts.Args fall back to Workers, and to 1 when that is unset too.
+func New(opts Options) *Runner {
+	arguments := *opts.Args
+	if arguments.DownloadWorkers <= 0 {
+		arguments.DownloadWorkers = max(arguments.Workers, 1)
+	}
+	if arguments.InferWorkers <= 0 {
+		arguments.InferWorkers = max(arguments.Workers, 1)
+	}
+
+	r := &Runner{results: make(chan types.Result), abandoned: make(chan struct{})}
+	r.scan = &scanContext{
+		args:      &arguments,
+		baseIcons: opts.BaseIcons,
+		client:    opts.Client,
+		pool:      opts.Pool,
+		breaker:   opts.Breaker,
+		verdicts:  opts.Verdicts,
+		deception: opts.Deception,
+		stop:      opts.Stop,
+		skipped:   &r.skipped,
+		demoted:   &r.demoted,
+		panics:    &panicLog{},
+		watchdog:  newWatchdog(opts.Args.JobTimeout),
+		hashes:    newHashVerdicts(opts.Args.BaseURL, opts.BrandHashes),
+		// Deferred retries run at a quarter of the download concurrency, so a struggling host isn't hit as hard again
+		retrySlots:   make(chan struct{}, max(1, opts.Args.DownloadWorkers/4)),
+		clusters:     opts.Clusters,
+		fingerprints: opts.Fingerprints,
+		corpus:       opts.Corpus,
+	}
+	// --keep-duplicates asks for every input line to be scanned on its own
+	if !arguments.KeepDuplicates {
+		r.scan.shared = newSharedIcons()
+	}
+	return r
+}
+
+// OnResult registers fn to be called with every result
+func (r *Runner) OnResult(fn func(types.Result)) {
+	r.onResult = append(r.onResult, fn)
+}
+
+// OnMatch registers fn to be called with every result whose icon matched
+func (r *Runner) OnMatch(fn func(types.Result)) {
+	r.onMatch = append(r.onMatch, fn)
+}
+
+// OnError registers fn to be called with every result that failed
+func (r *Runner) OnError(fn func(types.Result)) {
+	r.onError = append(r.onError, fn)
+}
+
+// Start scans jobs until the channel is closed and every job, including requeued ones, is finished.
+// It can only be called once.
+func (r *Runner) Start(jobs <-chan types.Job) error {
+	if !r.started.CompareAndSwap(false, true) {
+		return errors.New("runner already started")
+	}
+	scan := r.scan
+	args := scan.args
+	scan.requeue = newRequeue(jobs, args.DownloadWorkers*2, args.RateLimitRetries, scan.stop)
+	r.lastResult.Store(time.Now().UnixNano())
+
+	// Workers hand results to a bounded buffer so a slow hook doesn't immediately stall the model
+	completed := make(chan types.Result, args.InferWorkers*2)
+	var wg sync.WaitGroup
+	startPipeline(scan, completed, &wg)
+	stopWatch, stopQueue := func() {}, func() {}
+	if !args.Silent {
+		stopWatch = scan.watchdog.Watch()
+		if args.ShowQueue {
+			stopQueue = r.watchQueue()
+		}
+	}
+	go func() {
+		wg.Wait()
+		stopWatch()
+		stopQueue()
+		close(completed)
+	}()
+
+	go func() {
+		defer close(r.results)
+		for result := range completed {
+			r.completed.Add(1)
+			r.lastResult.Store(time.Now().UnixNano())
+			if result.Err != nil {
+				r.mu.Lock()
+				r.recentErrors = append(r.recentErrors, result)
+				if len(r.recentErrors) > keptErrors {
+					r.recentErrors = r.recentErrors[1:]
+				}
+				r.mu.Unlock()
+			}
+			r.deliver(result)
+		}
+	}()
+	return nil
+}
+
+// deliver runs result's hooks and passes it to Results, unless the scan has been abandoned
+func (r *Runner) deliver(result types.Result) {
+	r.hooks.Lock()
+	select {
+	case <-r.abandoned:
+		r.hooks.Unlock()
+		return
+	default:
+	}
+	r.dispatch(result)
+	r.hooks.Unlock()
+	select {
+	case r.results <- result:
+	case <-r.abandoned:
+	}
+}
+
+// dispatch runs the hooks registered for result
+func (r *Runner) dispatch(result types.Result) {
+	switch {
+	case result.Err != nil:
+		for _, fn := range r.onError {
+			fn(result)
+		}
+	case result.Match:
+		for _, fn := range r.onMatch {
+			fn(result)
+		}
+	}
+	for _, fn := range r.onResult {
+		fn(result)
+	}
+}
+
+// Results streams every result once its hooks have run, and is closed when the scan is finished
+func (r *Runner) Results() <-chan types.Result {
+	return r.results
+}
+
+// Wait consumes results until the scan is finished or abandoned, for callers that only use hooks
+func (r *Runner) Wait() {
+	for {
+		select {
+		case _, ok := <-r.results:
+			if !ok {
+				return
+			}
+		case <-r.abandoned:
+			return
+		}
+	}
+}
+
+// Abandon gives up on the jobs still in flight so the caller can shut down with what it has.
+// Wait returns and no hook runs once Abandon returns; workers still stuck on a target finish in the
+// background and their results are dropped. Results is only closed when those workers finish.
+func (r *Runner) Abandon() {
+	r.hooks.Lock()
+	defer r.hooks.Unlock()
+	r.abandonOnce.Do(func() { close(r.abandoned) })
+}
+
+// Abandoned reports whether Abandon has been called
+func (r *Runner) Abandoned() bool {
+	select {
+	case <-r.abandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// Skipped returns how many jobs were dropped unprocessed because the scan was stopping
+func (r *Runner) Skipped() int64 {
+	return r.skipped.Load()
+}
+
+// Demoted returns how many matches were reported as non-matches for scoring below --min-confidence
+func (r *Runner) Demoted() int64 {
+	return r.demoted.Load()
+}
+
+// SinceResult returns how long ago the latest result was delivered, or how long ago Start was called before the
+// first one; it is 0 until Start is called
+func (r *Runner) SinceResult() time.Duration {
+	last := r.lastResult.Load()
+	if last == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, last))
+}
+
+// RecentErrors returns the latest failed results, up to five, oldest first
+func (r *Runner) RecentErrors() []types.Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.recentErrors)
+}
+
+// HashDecided returns how many jobs were decided by their httpx favicon hash without downloading the icon
+func (r *Runner) HashDecided() int64 {
+	return r.scan.hashes.decided.Load()
+}
+
+// Shared returns how many jobs reused the download and comparison of an earlier job with the same favicon
+func (r *Runner) Shared() int64 {
+	return r.scan.shared.Shared()
+}
+
+// Requeued returns how many times a job was put back because of rate limiting
+func (r *Runner) Requeued() int64 {
+	if r.scan.requeue == nil {
+		return 0
+	}
+	return r.scan.requeue.Requeued()
+}
+
+// Deferred returns how many times a transiently failed job was put off to the end of the run, and how many of
+// those jobs then succeeded
+func (r *Runner) Deferred() (deferred, recovered int64) {
+	if r.scan.requeue == nil {
+		return 0, 0
+	}
+	return r.scan.requeue.Deferred()
+}
+
+// TimedOut returns how many job attempts overran the job timeout
+func (r *Runner) TimedOut() int64 {
+	return r.scan.watchdog.TimedOut()
+}
+
+// ReportPanics logs every job that panicked; stack traces are only shown when showStacks is set
+func (r *Runner) ReportPanics(showStacks bool) {
+	r.scan.panics.Report(showStacks)
+}
@@ -0,0 +1,158 @@
+package runner_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	args "github.com/ethicalhackingplayground/favlens/v2/pkg/arguments"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	"github.com/ethicalhackingplayground/favlens/v2/pkg/ollama/ollamatest"
+	runner "github.com/ethicalhackingplayground/favlens/v2/pkg/runner"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// iconServer serves a 16x16 icon of each color at /<name>.png and 404s everything else
+func iconServer(t *testing.T, colors map[string]color.RGBA) *httptest.Server {
+	t.Helper()
+	icons := make(map[string][]byte, len(colors))
+	for name, c := range colors {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		for y := range 16 {
+			for x := range 16 {
+				img.Set(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		icons["/"+name+".png"] = buf.Bytes()
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		icon, ok := icons[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(icon)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunnerScansJobs(t *testing.T) {
+	icons := iconServer(t, map[string]color.RGBA{"red": {R: 255, A: 255}, "blue": {B: 255, A: 255}})
+	backend := ollamatest.NewServer("gemma3:4b")
+	defer backend.Close()
+
+	pool := ollama.NewPool([]string{backend.URL}, "gemma3:4b", 5*time.Second, ollama.StrategySticky)
+	client := pool.Clients()[0]
+	base := icons.URL + "/red.png"
+	baseIcon, err := client.DownloadIcon(base, false)
+	if err != nil {
+		t.Fatalf("downloading base icon: %v", err)
+	}
+	baseIcons := ollama.NewIconCache(client)
+	baseIcons.Put(base, baseIcon.Base64)
+
+	arguments := &args.Arguments{BaseURL: base, Model: "gemma3:4b", Workers: 2, TimeoutSeconds: 5, Silent: true}
+	scan := runner.New(runner.Options{Args: arguments, BaseIcons: baseIcons, Client: client, Pool: pool})
+	var matched []string
+	scan.OnMatch(func(result types.Result) {
+		matched = append(matched, result.URL)
+	})
+
+	targets := []string{icons.URL + "/red.png", icons.URL + "/blue.png", icons.URL + "/missing.png"}
+	jobs := make(chan types.Job, len(targets))
+	for i, target := range targets {
+		jobs <- types.Job{URL: target, BaseURL: base, Index: i}
+	}
+	close(jobs)
+	if err := scan.Start(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]types.Result)
+	for result := range scan.Results() {
+		results[result.URL] = result
+	}
+	if len(results) != len(targets) {
+		t.Fatalf("got %d results, want %d", len(results), len(targets))
+	}
+	if r := results[targets[0]]; r.Err != nil || !r.Match {
+		t.Errorf("same icon: match %v, err %v; want a match", r.Match, r.Err)
+	}
+	if r := results[targets[1]]; r.Err != nil || r.Match {
+		t.Errorf("different icon: match %v, err %v; want no match", r.Match, r.Err)
+	}
+	if r := results[targets[2]]; r.Err == nil {
+		t.Error("missing icon succeeded, want an error")
+	}
+	if len(matched) != 1 || matched[0] != targets[0] {
+		t.Errorf("OnMatch saw %v, want [%s]", matched, targets[0])
+	}
+	// Only the two downloaded icons reach the model
+	if chats := len(backend.Chats()); chats != 2 {
+		t.Errorf("backend saw %d comparisons, want 2", chats)
+	}
+}
+
+func TestRunnerSharesIcons(t *testing.T) {
+	icons := iconServer(t, map[string]color.RGBA{"red": {R: 255, A: 255}})
+	backend := ollamatest.NewServer("gemma3:4b")
+	defer backend.Close()
+
+	pool := ollama.NewPool([]string{backend.URL}, "gemma3:4b", 5*time.Second, ollama.StrategySticky)
+	client := pool.Clients()[0]
+	base := icons.URL + "/red.png"
+	baseIcon, err := client.DownloadIcon(base, false)
+	if err != nil {
+		t.Fatalf("downloading base icon: %v", err)
+	}
+	baseIcons := ollama.NewIconCache(client)
+	baseIcons.Put(base, baseIcon.Base64)
+
+	arguments := &args.Arguments{BaseURL: base, Model: "gemma3:4b", Workers: 3, TimeoutSeconds: 5, Silent: true}
+	scan := runner.New(runner.Options{Args: arguments, BaseIcons: baseIcons, Client: client, Pool: pool})
+
+	// Three pages on one origin resolve to the same favicon
+	pages := []string{"login", "admin", "home"}
+	jobs := make(chan types.Job, len(pages))
+	for i, page := range pages {
+		jobs <- types.Job{URL: icons.URL + "/red.png", BaseURL: base, Index: i, Metadata: map[string]any{"page": page}, Tags: []string{page}}
+	}
+	close(jobs)
+	if err := scan.Start(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[int]types.Result)
+	for result := range scan.Results() {
+		seen[result.Index] = result
+	}
+	for i, page := range pages {
+		r, ok := seen[i]
+		if !ok {
+			t.Fatalf("no result for job %d", i)
+		}
+		if r.Err != nil || !r.Match {
+			t.Errorf("job %d: match %v, err %v; want a match", i, r.Match, r.Err)
+		}
+		if r.Metadata["page"] != page || len(r.Tags) != 1 || r.Tags[0] != page {
+			t.Errorf("job %d: metadata %v, tags %v; want its own %q", i, r.Metadata, r.Tags, page)
+		}
+	}
+	if chats := len(backend.Chats()); chats != 1 {
+		t.Errorf("backend saw %d comparisons, want 1", chats)
+	}
+	if shared := scan.Shared(); shared != 2 {
+		t.Errorf("Shared() = %d, want 2", shared)
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+)
+
+// sharedKept caps how many finished icons sharedIcons remembers, so memory stays flat on huge inputs
+const sharedKept = 4096
+
+// sharedIcon is one icon and base pair: the job downloading and comparing it, the jobs waiting for its
+// result and, once it is finished, the result they share
+type sharedIcon struct {
+	leader  int
+	waiting []types.Job
+	done    bool
+	result  types.Result
+}
+
+// sharedIcons lets pages that resolve to the same favicon, such as several paths on one origin, share a single
+// download and comparison. The first job for an icon does the work; jobs arriving while it is in flight wait
+// for its result, and later ones reuse it while it is still remembered. Every job still gets its own result,
+// with its own metadata, tags and position in the input.
+type sharedIcons struct {
+	mu      sync.Mutex
+	icons   map[uint64]*sharedIcon
+	leaders map[int]uint64
+	// finished holds the keys of finished icons, oldest first, so the oldest is forgotten past sharedKept
+	finished []uint64
+	shared   atomic.Int64
+}
+
+func newSharedIcons() *sharedIcons {
+	return &sharedIcons{icons: make(map[uint64]*sharedIcon), leaders: make(map[int]uint64)}
+}
+
+// sharedKey hashes the icon URLs a job tries, the base favicon and any brand kit variants
+func sharedKey(job types.Job) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(job.URL))
+	for _, part := range [][]string{job.Fallbacks, {job.BaseURL}, job.BaseVariants} {
+		h.Write([]byte{0})
+		h.Write([]byte(strings.Join(part, " ")))
+	}
+	return h.Sum64()
+}
+
+// Join reports whether job should be downloaded and compared. Otherwise another job is already handling its
+// icon: when that job has finished, result is its outcome to reuse and ready is true; when it is still in
+// flight, job waits and is finished by Resolve.
+func (s *sharedIcons) Join(job types.Job) (result types.Result, ready, run bool) {
+	if s == nil {
+		return types.Result{}, false, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leaders[job.Index]; ok {
+		return types.Result{}, false, true
+	}
+	key := sharedKey(job)
+	icon, ok := s.icons[key]
+	if !ok {
+		s.icons[key] = &sharedIcon{leader: job.Index}
+		s.leaders[job.Index] = key
+		return types.Result{}, false, true
+	}
+	s.shared.Add(1)
+	if icon.done {
+		return icon.result, true, false
+	}
+	icon.waiting = append(icon.waiting, job)
+	return types.Result{}, false, false
+}
+
+// Resolve records the result of job, if it was handling an icon for others, and returns the jobs that were
+// waiting for it. The result is kept without the job's own metadata and tags.
+func (s *sharedIcons) Resolve(job types.Job, result types.Result) []types.Job {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.leaders[job.Index]
+	if !ok {
+		return nil
+	}
+	delete(s.leaders, job.Index)
+	icon := s.icons[key]
+	waiting := icon.waiting
+	result.Metadata, result.Index = nil, 0
+	icon.waiting, icon.done, icon.result = nil, true, result
+
+	s.finished = append(s.finished, key)
+	if len(s.finished) > sharedKept {
+		delete(s.icons, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+	return waiting
+}
+
+// Drop forgets the icon job was handling because it was skipped, and returns the jobs that were waiting for it
+func (s *sharedIcons) Drop(job types.Job) []types.Job {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.leaders[job.Index]
+	if !ok {
+		return nil
+	}
+	delete(s.leaders, job.Index)
+	waiting := s.icons[key].waiting
+	delete(s.icons, key)
+	return waiting
+}
+
+// Shared returns how many jobs reused another job's download and comparison
+func (s *sharedIcons) Shared() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.shared.Load()
+}
@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Stages a job moves through, shown when its worker is blocked or its deadline passes
+const (
+	stageWaiting     = "waiting"
+	stageBaseIcon    = "downloading base icon"
+	stageDownloading = "downloading"
+	stageBreaker     = "waiting for circuit breaker"
+	stageComparing   = "comparing"
+	stageChecking    = "running suspect checks"
+	stageReading     = "reading icon text"
+)
+
+// blockedAfter is how long a worker may spend on one target before it is reported as blocked
+const blockedAfter = time.Minute
+
+// timeoutRetries is how many times a job that overran its deadline is requeued before it is recorded as failed
+const timeoutRetries = 1
+
+// JobTimeoutError is returned for a job abandoned because it overran --job-timeout
+type JobTimeoutError struct {
+	Timeout time.Duration
+	Stage   string
+}
+
+func (e *JobTimeoutError) Error() string {
+	return fmt.Sprintf("job timed out after %s while %s", e.Timeout, e.Stage)
+}
+
+// watchdog puts a hard deadline on every job, independent of HTTP timeouts, and tracks what each worker is
+// doing. A job that overruns is abandoned so its worker can move on; Go can't stop the goroutine still
+// running it, but its result is discarded whenever it finishes.
+type watchdog struct {
+	// timeout is the per-job deadline; 0 disables it
+	timeout time.Duration
+
+	mu sync.Mutex
+	// busy maps worker names, such as "Worker 3", to their current task
+	busy map[string]*task
+	// attempts counts timed-out attempts per job index
+	attempts map[int]int
+	timedOut atomic.Int64
+}
+
+// task is one attempt at a job. Abandoned attempts keep their own task, so a late stage change can't
+// overwrite what the worker reports about its next job.
+type task struct {
+	w      *watchdog
+	url    string
+	stage  string
+	since  time.Time
+	paused time.Time
+	// deadline moves back by the time spent waiting on the circuit breaker, which isn't the target's fault
+	deadline time.Time
+}
+
+func newWatchdog(timeout time.Duration) *watchdog {
+	return &watchdog{timeout: timeout, busy: make(map[string]*task), attempts: make(map[int]int)}
+}
+
+// Stage records what the job is doing now. Waiting on the circuit breaker stops its clock.
+func (t *task) Stage(stage string) {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+	now := time.Now()
+	if !t.paused.IsZero() {
+		t.deadline = t.deadline.Add(now.Sub(t.paused))
+		t.paused = time.Time{}
+	}
+	if stage == stageBreaker {
+		t.paused = now
+	}
+	t.stage = stage
+}
+
+// Run calls process for job on the named worker and gives up once the job overruns the deadline, returning
+// a *JobTimeoutError. process must only hand back its results through variables the caller reads when Run
+// returns nil, since an abandoned call is still running.
+func (w *watchdog) Run(worker string, job types.Job, process func(t *task)) error {
+	now := time.Now()
+	t := &task{w: w, url: job.URL, stage: stageWaiting, since: now, deadline: now.Add(w.timeout)}
+	w.mu.Lock()
+	w.busy[worker] = t
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.busy, worker)
+		w.mu.Unlock()
+	}()
+
+	if w.timeout <= 0 {
+		process(t)
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		process(t)
+		close(done)
+	}()
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-timer.C:
+			w.mu.Lock()
+			wait, stage := time.Until(t.deadline), t.stage
+			if !t.paused.IsZero() {
+				wait = w.timeout
+			}
+			w.mu.Unlock()
+			if wait > 0 {
+				timer.Reset(wait)
+				continue
+			}
+			w.timedOut.Add(1)
+			return &JobTimeoutError{Timeout: w.timeout, Stage: stage}
+		}
+	}
+}
+
+// Retry reports whether a timed-out job should be requeued rather than recorded as failed
+func (w *watchdog) Retry(job types.Job) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.attempts[job.Index]++
+	return w.attempts[job.Index] <= timeoutRetries
+}
+
+// TimedOut returns how many job attempts overran the deadline
+func (w *watchdog) TimedOut() int64 {
+	return w.timedOut.Load()
+}
+
+// Watch logs every worker that has been on one target for longer than blockedAfter, once a minute,
+// until the returned function is called
+func (w *watchdog) Watch() func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(blockedAfter)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				w.reportBlocked()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Busy returns the job every busy worker is on, longest running first
+func (w *watchdog) Busy() []Outstanding {
+	w.mu.Lock()
+	busy := make([]Outstanding, 0, len(w.busy))
+	for worker, t := range w.busy {
+		busy = append(busy, Outstanding{Worker: worker, URL: t.url, Stage: t.stage, Elapsed: time.Since(t.since)})
+	}
+	w.mu.Unlock()
+	sort.Slice(busy, func(i, j int) bool {
+		if busy[i].Elapsed != busy[j].Elapsed {
+			return busy[i].Elapsed > busy[j].Elapsed
+		}
+		return busy[i].Worker < busy[j].Worker
+	})
+	return busy
+}
+
+func (w *watchdog) reportBlocked() {
+	w.mu.Lock()
+	workers := make([]string, 0, len(w.busy))
+	for worker, t := range w.busy {
+		if time.Since(t.since) >= blockedAfter {
+			workers = append(workers, worker)
+		}
+	}
+	sort.Strings(workers)
+	lines := make([]string, 0, len(workers))
+	for _, worker := range workers {
+		t := w.busy[worker]
+		lines = append(lines, fmt.Sprintf("%s blocked for %s on %s (%s)", worker, time.Since(t.since).Round(time.Second), t.url, t.stage))
+	}
+	w.mu.Unlock()
+
+	for _, line := range lines {
+		gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprint(line))
+	}
+}
@@ -0,0 +1,648 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/url"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	args "github.com/ethicalhackingplayground/favlens/v2/pkg/arguments"
+	cluster "github.com/ethicalhackingplayground/favlens/v2/pkg/cluster"
+	corpus "github.com/ethicalhackingplayground/favlens/v2/pkg/corpus"
+	deception "github.com/ethicalhackingplayground/favlens/v2/pkg/deception"
+	domain "github.com/ethicalhackingplayground/favlens/v2/pkg/domain"
+	favhash "github.com/ethicalhackingplayground/favlens/v2/pkg/favhash"
+	lookalike "github.com/ethicalhackingplayground/favlens/v2/pkg/lookalike"
+	notify "github.com/ethicalhackingplayground/favlens/v2/pkg/notify"
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+	store "github.com/ethicalhackingplayground/favlens/v2/pkg/store"
+	types "github.com/ethicalhackingplayground/favlens/v2/pkg/types"
+	"github.com/fatih/color"
+	"github.com/projectdiscovery/gologger"
+)
+
+// scanContext holds the state shared by every worker
+type scanContext struct {
+	args      *args.Arguments
+	baseIcons *ollama.IconCache
+	// client downloads icons; comparisons go through pool
+	client   *ollama.Client
+	pool     *ollama.Pool
+	breaker  *ollama.Breaker
+	verdicts *store.VerdictCache
+	// stop is closed once no further jobs should be processed
+	stop    <-chan struct{}
+	skipped *atomic.Int64
+	// demoted counts matches scored below --min-confidence
+	demoted *atomic.Int64
+	panics  *panicLog
+	// requeue holds back targets on hosts that answered with a rate limit
+	requeue *requeue
+	// deception flags matches from parked and wildcard hosting; nil when --suspect-checks is off
+	deception *deception.Detector
+	// watchdog abandons jobs that overrun --job-timeout and tracks blocked workers
+	watchdog *watchdog
+	// hashes decides jobs whose favicon hash has already been judged
+	hashes *hashVerdicts
+	// shared lets targets with the same favicon share one download and comparison; nil with --keep-duplicates
+	shared *sharedIcons
+	// retrySlots limits how many deferred jobs download at once
+	retrySlots chan struct{}
+	// clusters collects icon equivalence classes and fingerprints labels known icons; either may be nil
+	clusters     *cluster.Collector
+	fingerprints *cluster.Labels
+	// corpus indexes icon embeddings; nil unless --corpus is set
+	corpus *corpus.Corpus
+	// handoff buffers downloaded jobs for the inference workers
+	handoff chan *fetched
+}
+
+// panicRecord describes a job whose processing panicked
+type panicRecord struct {
+	URL   string
+	Value any
+	Stack []byte
+}
+
+// panicLog collects panicked jobs so they can be reported at the end of the run
+type panicLog struct {
+	mu      sync.Mutex
+	records []panicRecord
+}
+
+func (p *panicLog) Add(record panicRecord) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record)
+}
+
+// Report logs every panicked job; stack traces are only shown in debug mode
+func (p *panicLog) Report(showStacks bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.records) == 0 {
+		return
+	}
+	gologger.Error().Msg(color.New(color.Bold, color.FgRed).Sprintf("%d job(s) panicked and were recorded as errors:", len(p.records)))
+	for _, record := range p.records {
+		gologger.Error().Msg(color.New(color.FgRed).Sprintf("  %s: %v", record.URL, record.Value))
+		if showStacks {
+			gologger.Debug().Msg(string(record.Stack))
+		}
+	}
+}
+
+// fetched is a job whose icons are downloaded and waiting for the model
+type fetched struct {
+	job      types.Job
+	baseIcon string
+	// variants are the icons of job.BaseVariants, in the same order
+	variants []string
+	icon     *ollama.Icon
+}
+
+// startPipeline runs the download and inference worker pools. Downloads are network bound and comparisons GPU
+// bound, so each pool is sized on its own, with a bounded buffer between them so downloads can't run far ahead.
+// wg is done once both pools have finished.
+func startPipeline(scan *scanContext, results chan<- types.Result, wg *sync.WaitGroup) {
+	args := scan.args
+	handoff := make(chan *fetched, args.InferWorkers*2)
+	scan.handoff = handoff
+
+	var downloads sync.WaitGroup
+	for i := 0; i < args.DownloadWorkers; i++ {
+		downloads.Add(1)
+		go downloadWorker(i, scan.requeue.Jobs(), handoff, results, scan, &downloads)
+	}
+	for i := 0; i < args.InferWorkers; i++ {
+		wg.Add(1)
+		go inferWorker(i, handoff, results, scan, wg)
+	}
+
+	// Requeued jobs are downloaded again, so the handoff only closes once the job queue has drained
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		downloads.Wait()
+		close(handoff)
+	}()
+}
+
+// downloadWorker fetches the icons for each job and hands them to the inference pool
+func downloadWorker(id int, jobs <-chan types.Job, handoff chan<- *fetched, results chan<- types.Result, scan *scanContext, wg *sync.WaitGroup) {
+	defer wg.Done()
+	args := scan.args
+
+	if args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloader %d started", id))
+	}
+
+	fetchedCount := 0
+	for job := range jobs {
+		item := fetchJob(id, job, results, scan, fetchedCount+1)
+		if item == nil {
+			scan.requeue.Done()
+			continue
+		}
+		// The inference worker marks the job done once it is compared
+		fetchedCount++
+		handoff <- item
+	}
+
+	if args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloader %d finished, fetched %d jobs", id, fetchedCount))
+	}
+}
+
+// inferWorker compares downloaded icons with the model
+func inferWorker(id int, handoff <-chan *fetched, results chan<- types.Result, scan *scanContext, wg *sync.WaitGroup) {
+	defer wg.Done()
+	args := scan.args
+
+	if args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d started", id))
+	}
+
+	processedCount := 0
+	for item := range handoff {
+		if compareJob(id, item, results, scan) {
+			processedCount++
+		}
+		scan.requeue.Done()
+	}
+
+	if args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d finished, processed %d jobs", id, processedCount))
+	}
+}
+
+// fetchJob downloads one job's icons. It returns nil when the job was skipped, deferred or finished early,
+// e.g. because the download failed, in which case its result has already been sent.
+func fetchJob(id int, job types.Job, results chan<- types.Result, scan *scanContext, count int) *fetched {
+	args := scan.args
+
+	// Once the runtime budget is spent, drain the remaining jobs without processing them
+	select {
+	case <-scan.stop:
+		// Targets waiting on this job's icon are skipped along with it
+		scan.skipped.Add(1 + int64(len(scan.shared.Drop(job))))
+		return nil
+	default:
+	}
+
+	// An icon whose hash was already judged gets the same verdict, without contacting its host
+	if match, ok := scan.hashes.Lookup(job.FaviconHash, job.BaseURL); ok {
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloader %d decided %s by favicon hash %s: match=%v", id, job.URL, job.FaviconHash, match))
+		}
+		result := types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: match, Metadata: job.Metadata}
+		// httpx reports the Shodan hash, which can't be turned into the raw one without the icon
+		if args.HashInput != favhash.InputRaw {
+			result.MMH3 = job.FaviconHash
+		}
+		if label := scan.fingerprints.Label("", job.FaviconHash); label != "" {
+			result.Tags = []string{label}
+		}
+		finishJob(fmt.Sprintf("Downloader %d", id), job, result, results, scan)
+		return nil
+	}
+
+	// Pages that resolve to the same favicon, e.g. several paths on one origin, share one download and comparison
+	if shared, ready, run := scan.shared.Join(job); !run {
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloader %d sharing the result for %s with an earlier target", id, job.URL))
+		}
+		if ready {
+			shared.Metadata = job.Metadata
+			results <- jobResult(fmt.Sprintf("Downloader %d", id), job, shared, scan)
+		}
+		return nil
+	}
+
+	// Don't knock on a host that asked us to back off; its targets wait out the Retry-After window
+	if scan.requeue.Postpone(job, Hostname(job.URL)) {
+		return nil
+	}
+
+	if args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloader %d fetching job %d: %s", id, count, job.URL))
+	}
+
+	name := fmt.Sprintf("Downloader %d", id)
+	if job.Deferrals > 0 {
+		scan.retrySlots <- struct{}{}
+		defer func() { <-scan.retrySlots }()
+	}
+	var item *fetched
+	var result types.Result
+	if err := scan.watchdog.Run(name, job, func(t *task) {
+		item, result = downloadIcons(id, job, scan, t)
+	}); err != nil {
+		finishJob(name, job, types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: false, Err: err, Metadata: job.Metadata}, results, scan)
+		return nil
+	}
+	if item == nil {
+		finishJob(name, job, result, results, scan)
+	}
+	return item
+}
+
+// compareJob runs the model on one downloaded job and sends its result; it reports false when the job was
+// skipped or requeued
+func compareJob(id int, item *fetched, results chan<- types.Result, scan *scanContext) bool {
+	job, name := item.job, fmt.Sprintf("Worker %d", id)
+	var result types.Result
+	var ok bool
+	if err := scan.watchdog.Run(name, job, func(t *task) {
+		result, ok = compareIcons(id, item, scan, t)
+	}); err != nil {
+		return finishJob(name, job, types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: false, Err: err, Metadata: job.Metadata}, results, scan)
+	}
+	if !ok {
+		scan.skipped.Add(1 + int64(len(scan.shared.Drop(job))))
+		return false
+	}
+	// Later targets with the same icon, by the hash httpx reports, reuse this verdict
+	if result.Err == nil && !strings.EqualFold(scan.args.Mode, ollama.TargetScreenshot) {
+		scan.hashes.Learn(item.icon.MMH3, job.BaseURL, result.Match)
+	}
+	return finishJob(name, job, result, results, scan)
+}
+
+// finishJob sends a job's result, unless the job is requeued because its host is rate limiting, it overran
+// the job timeout or it failed transiently and is deferred to the end of the run; it reports false when the
+// job was requeued
+func finishJob(worker string, job types.Job, result types.Result, results chan<- types.Result, scan *scanContext) bool {
+	args := scan.args
+	host := Hostname(job.URL)
+
+	var rateLimited *ollama.RateLimitError
+	if errors.As(result.Err, &rateLimited) {
+		if scan.requeue.Retry(job, host, rateLimited.RetryAfter) {
+			if args.Debug {
+				gologger.Debug().Msg(color.New(color.Italic, color.FgYellow).Sprintf("%s requeued %s for %s after status %d", worker, job.URL, rateLimited.RetryAfter, rateLimited.Status))
+			}
+			return false
+		}
+		result.Err = fmt.Errorf("%v; giving up after %d consecutive rate-limited responses from %s", result.Err, args.RateLimitRetries, host)
+	} else if timeout := (*JobTimeoutError)(nil); errors.As(result.Err, &timeout) {
+		// A stuck model call or pathological image may be a one-off, so the target gets another worker before it fails
+		if scan.watchdog.Retry(job) {
+			if !args.Silent {
+				gologger.Info().Msg(color.New(color.Italic, color.FgYellow).Sprintf("%s abandoned %s after %s while %s, requeued", worker, job.URL, timeout.Timeout, timeout.Stage))
+			}
+			scan.requeue.Resubmit(job)
+			return false
+		}
+	} else if result.Err == nil {
+		scan.requeue.Succeeded(host)
+		if job.Deferrals > 0 {
+			scan.requeue.Recovered()
+		}
+	}
+	// Timeouts, dropped connections and server errors often clear up, so the target is tried again after the rest
+	// of the input instead of failing; errors that would only repeat themselves, like a 404, fail straight away
+	if result.Err != nil && job.Deferrals < args.DeferredRetries && deferrable(result.Err) && !stopping(scan) {
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgYellow).Sprintf("%s deferred %s to the end of the run: %v", worker, job.URL, result.Err))
+		}
+		job.Deferrals++
+		scan.requeue.Defer(job)
+		return false
+	}
+
+	// Targets that were waiting on the same icon get the same outcome, each with its own metadata and tags
+	waiting := scan.shared.Resolve(job, result)
+	results <- jobResult(worker, job, result, scan)
+	for _, other := range waiting {
+		shared := result
+		shared.Metadata = other.Metadata
+		results <- jobResult(worker, other, shared, scan)
+	}
+	return true
+}
+
+// jobResult completes result with what belongs to job itself: its input position, tags and lookalike check
+func jobResult(worker string, job types.Job, result types.Result, scan *scanContext) types.Result {
+	args := scan.args
+	result.Index = job.Index
+	// Tags from --tag rules come first, then a fingerprint label found for the icon
+	result.Tags = append(slices.Clip(job.Tags), result.Tags...)
+	if result.Match {
+		result.Lookalike = checkLookalike(job, args.BrandDomain)
+		// The threshold is usually learned from review decisions, so matches that analysts kept rejecting drop out
+		if confidence := notify.Confidence(result); confidence < args.MinConfidence {
+			if args.Debug {
+				gologger.Debug().Msg(color.New(color.Italic, color.FgYellow).Sprintf("%s demoted %s: confidence %d is below --min-confidence %d", worker, job.URL, confidence, args.MinConfidence))
+			}
+			result.Match, result.Lookalike = false, nil
+			scan.demoted.Add(1)
+		}
+	}
+	return result
+}
+
+// indexIcon adds the target icon's embedding to the corpus. The comparison has just embedded it, so the vector
+// usually comes from the client's recent embeddings rather than another model call.
+func indexIcon(id int, scan *scanContext, job types.Job, icon *ollama.Icon, match bool) {
+	embedding, err := scan.pool.Embed(id, icon.Base64, scan.args.Debug)
+	if err == nil {
+		err = scan.corpus.Add(job.URL, icon.SHA256, icon.MMH3, match, embedding)
+	}
+	if err != nil && scan.args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Worker %d failed to add %s to the corpus: %v", id, job.URL, err))
+	}
+}
+
+// checkLookalike compares a matched target's host with the brand domain, taken from --brand-domain or the base favicon's host
+func checkLookalike(job types.Job, brandDomain string) *types.Lookalike {
+	if brandDomain == "" {
+		brandDomain = Hostname(job.BaseURL)
+	}
+	analysis, ok := lookalike.Analyze(Hostname(job.URL), brandDomain)
+	if !ok {
+		return nil
+	}
+	return &types.Lookalike{Unicode: analysis.Unicode, IDN: analysis.IDN, Confusability: math.Round(analysis.Confusability*100) / 100}
+}
+
+// Hostname returns the host of an http(s) URL, or "" for local paths and unparsable URLs
+func Hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// retryBackoff is the base wait between retry attempts; it grows linearly with each attempt
+const retryBackoff = 500 * time.Millisecond
+
+// withRetries runs fn up to retries+1 times, waiting a little longer before each new attempt
+func withRetries[T any](retries int, fn func() (T, error)) (T, error) {
+	value, err := fn()
+	var rateLimited *ollama.RateLimitError
+	// Rate-limited targets are requeued for after their Retry-After window instead of being hammered here,
+	// and a missing model or an oversized request fails the same way however often it is retried
+	for attempt := 1; err != nil && attempt <= retries && !errors.As(err, &rateLimited) && !permanent(err); attempt++ {
+		time.Sleep(time.Duration(attempt) * retryBackoff)
+		value, err = fn()
+	}
+	return value, err
+}
+
+// permanent reports errors that retrying can't fix: a missing model, an oversized request, and targets that
+// answered 4xx, were disallowed by robots.txt or aren't a usable image
+func permanent(err error) bool {
+	if errors.Is(err, ollama.ErrModelNotLoaded) || errors.Is(err, ollama.ErrContextLength) {
+		return true
+	}
+	switch ErrorClass(err) {
+	case ClassHTTP4xx, ClassRobots, ClassImage:
+		return true
+	}
+	return false
+}
+
+// stopping reports whether the scan is draining its remaining jobs
+func stopping(scan *scanContext) bool {
+	select {
+	case <-scan.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// deferrable reports errors worth another attempt later in the run, when the network or host may have recovered
+func deferrable(err error) bool {
+	switch ErrorClass(err) {
+	case ClassTimeout, ClassReset, ClassHTTP5xx:
+		return true
+	}
+	return false
+}
+
+// recoverJob turns a panic in a stage into an error result, so one bad icon can't kill the pool. Each stage
+// defers it with its own named results; ok may be nil.
+func recoverJob(id int, job types.Job, scan *scanContext, result *types.Result, ok *bool) {
+	if r := recover(); r != nil {
+		scan.panics.Add(panicRecord{URL: job.URL, Value: r, Stack: debug.Stack()})
+		if scan.args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Worker %d recovered from panic on %s: %v", id, job.URL, r))
+		}
+		*result = types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: false, Err: fmt.Errorf("panic while processing: %v", r), Metadata: job.Metadata}
+		if ok != nil {
+			*ok = true
+		}
+	}
+}
+
+// downloadIcons fetches the base and target icons of a job. A nil item means the job is finished with result.
+func downloadIcons(id int, job types.Job, scan *scanContext, t *task) (item *fetched, result types.Result) {
+	defer recoverJob(id, job, scan, &result, nil)
+	args := scan.args
+
+	// Optional delay between requests, plus random jitter so request timing is less predictable
+	delay := time.Duration(args.DelayMs) * time.Millisecond
+	if args.JitterMs > 0 {
+		delay += time.Duration(rand.IntN(args.JitterMs+1)) * time.Millisecond
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	// Base icons are shared between jobs, so each distinct base URL is only downloaded once
+	t.Stage(stageBaseIcon)
+	baseIcon, err := scan.baseIcons.Get(job.BaseURL, args.Debug)
+	if err != nil {
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Downloader %d failed to download base favicon %s: %v", id, job.BaseURL, err))
+		}
+		return nil, types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: false, Err: fmt.Errorf("base favicon unavailable: %v", err), Metadata: job.Metadata}
+	}
+	variants := make([]string, len(job.BaseVariants))
+	for i, variant := range job.BaseVariants {
+		if variants[i], err = scan.baseIcons.Get(variant, args.Debug); err != nil {
+			return nil, types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: false, Err: fmt.Errorf("brand kit asset %s unavailable: %v", variant, err), Metadata: job.Metadata}
+		}
+	}
+
+	t.Stage(stageDownloading)
+	icon, err := withRetries(args.Retries, func() (*ollama.Icon, error) {
+		if strings.EqualFold(args.Mode, ollama.TargetScreenshot) {
+			return scan.client.CaptureScreenshot(job.URL, args.Debug)
+		}
+		return scan.client.DownloadIcon(job.URL, args.Debug)
+	})
+	// A fallback that works becomes the job's URL, so the result names the icon that was compared
+	for _, fallback := range job.Fallbacks {
+		var rateLimited *ollama.RateLimitError
+		if err == nil || errors.As(err, &rateLimited) {
+			break
+		}
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Downloader %d trying %s after %s failed: %v", id, fallback, job.URL, err))
+		}
+		fallbackIcon, fallbackErr := withRetries(args.Retries, func() (*ollama.Icon, error) {
+			return scan.client.DownloadIcon(fallback, args.Debug)
+		})
+		if fallbackErr == nil {
+			job.URL, job.Fallbacks, icon, err = fallback, nil, fallbackIcon, nil
+		}
+	}
+	if err != nil {
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Downloader %d failed to download %s: %v", id, job.URL, err))
+		}
+		return nil, types.Result{URL: job.URL, BaseURL: job.BaseURL, Match: false, Err: err, Metadata: job.Metadata}
+	}
+	return &fetched{job: job, baseIcon: baseIcon, variants: variants, icon: icon}, types.Result{}
+}
+
+// compareIcons asks the model about a downloaded job; ok is false when the job was abandoned because the scan is stopping.
+// Brand kit jobs are compared against each logo variant in turn until one matches.
+func compareIcons(id int, item *fetched, scan *scanContext, t *task) (result types.Result, ok bool) {
+	job, icon := item.job, item.icon
+	defer recoverJob(id, job, scan, &result, &ok)
+
+	bases := append([]string{job.BaseURL}, job.BaseVariants...)
+	baseIcons := append([]string{item.baseIcon}, item.variants...)
+	result = types.Result{URL: job.URL, BaseURL: job.BaseURL, Metadata: job.Metadata}
+	for i, baseIcon := range baseIcons {
+		match, ok, err := compareBase(id, scan, t, job, baseIcon, icon.Base64)
+		if !ok {
+			return types.Result{}, false
+		}
+		// A variant that failed can't rule the target out, but a later one can still match it
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		if match {
+			result.Match, result.Err = true, nil
+			if len(job.BaseVariants) > 0 {
+				result.Asset = filepath.Base(bases[i])
+			}
+			break
+		}
+	}
+	return describeIcon(id, scan, job, t, icon, result), true
+}
+
+// compareBase asks the model whether the target icon matches one base icon; ok is false when the scan is stopping
+func compareBase(id int, scan *scanContext, t *task, job types.Job, baseIcon, targetIcon string) (match, ok bool, err error) {
+	args := scan.args
+
+	// Reuse an earlier verdict for the same icon pair when a cache is configured
+	if scan.verdicts != nil {
+		match, ok, err := scan.verdicts.Get(baseIcon, targetIcon)
+		if err != nil && args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Worker %d cache lookup failed for %s: %v", id, job.URL, err))
+		}
+		if ok {
+			if args.Debug {
+				gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d used cached verdict for %s: match=%v", id, job.URL, match))
+			}
+			return match, true, nil
+		}
+	}
+
+	// Hold off while the circuit breaker is open rather than turning every remaining target into an error
+	t.Stage(stageBreaker)
+	if !scan.breaker.Wait(scan.stop) {
+		return false, false, nil
+	}
+	t.Stage(stageComparing)
+	match, err = withRetries(args.Retries, func() (match bool, err error) {
+		// A panicking probe must still be reported, or the breaker would stay half-open
+		defer func() {
+			if r := recover(); r != nil {
+				scan.breaker.Record(fmt.Errorf("panic: %v", r))
+				panic(r)
+			}
+		}()
+		match, err = scan.pool.Compare(id, baseIcon, targetIcon, args.Debug)
+		scan.breaker.Record(err)
+		return match, err
+	})
+	if err == nil && scan.verdicts != nil {
+		if err := scan.verdicts.Put(baseIcon, targetIcon, match); err != nil && args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Worker %d failed to cache verdict for %s: %v", id, job.URL, err))
+		}
+	}
+	if args.Debug {
+		gologger.Debug().Msg(color.New(color.Italic, color.FgCyan).Sprintf("Worker %d completed comparison for %s: match=%v, err=%v", id, job.URL, match, err))
+	}
+	return match, true, err
+}
+
+// describeIcon adds what is known about the target icon to its result: metadata, deception checks and icon text
+func describeIcon(id int, scan *scanContext, job types.Job, t *task, icon *ollama.Icon, result types.Result) types.Result {
+	info := icon.Info
+	result.Icon = &types.IconInfo{Format: info.Format, Width: info.Width, Height: info.Height, Bytes: info.Bytes, BitDepth: info.BitDepth, Monochrome: info.Monochrome}
+	result.MMH3, result.SHA256 = icon.MMH3, icon.SHA256
+	if scan.args.HashInput == favhash.InputRaw {
+		result.MMH3 = icon.MMH3Raw
+	}
+	scan.clusters.Add(job.URL, icon.SHA256, icon.MMH3, icon.Base64, result.Match)
+	if label := scan.fingerprints.Label(icon.SHA256, icon.MMH3); label != "" {
+		result.Tags = []string{label}
+	}
+	if scan.corpus != nil && result.Err == nil {
+		indexIcon(id, scan, job, icon, result.Match)
+	}
+
+	// Deception heuristics only matter for matches, so analysts can discount parked and wildcard hosting
+	if result.Match && scan.deception != nil {
+		t.Stage(stageChecking)
+		result.SuspectReasons = scan.deception.Check(deception.Sample{URL: job.URL, IconHash: icon.SHA256, RemoteIP: icon.RemoteIP, Headers: icon.Headers})
+	}
+	if scan.args.OCR {
+		t.Stage(stageReading)
+		result.Text = readIconText(id, scan, job, icon.Base64)
+	}
+	return result
+}
+
+// readIconText has the model read the target icon's text and scores it against the brand name. Failures only
+// cost the signal, never the result.
+func readIconText(id int, scan *scanContext, job types.Job, base64Icon string) *types.IconText {
+	args := scan.args
+	text, err := scan.pool.ExtractText(id, base64Icon, args.Debug)
+	if err != nil {
+		if args.Debug {
+			gologger.Debug().Msg(color.New(color.Italic, color.FgRed).Sprintf("Worker %d failed to read text from %s: %v", id, job.URL, err))
+		}
+		return nil
+	}
+	if text == "" {
+		return nil
+	}
+	iconText := &types.IconText{Text: text}
+	if brand := brandName(job, args.Brand, args.BrandDomain); brand != "" {
+		iconText.Score = math.Round(lookalike.TextScore(text, brand)*100) / 100
+		iconText.Match = iconText.Score >= lookalike.Threshold
+	}
+	return iconText
+}
+
+// brandName is --brand, or else the registrable label of the brand domain, e.g. "acme" for www.acme.co.uk.
+// Base URLs on bare IP addresses have no brand name.
+func brandName(job types.Job, brand, brandDomain string) string {
+	if brand != "" {
+		return brand
+	}
+	if brandDomain == "" {
+		brandDomain = Hostname(job.BaseURL)
+	}
+	return domain.Label(brandDomain)
+}
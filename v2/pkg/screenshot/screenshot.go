@@ -0,0 +1,118 @@
+// Package screenshot captures web pages with a locally installed headless Chrome or Chromium, so pages
+// can be compared against a brand as well as their favicons.
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// Default viewport, large enough to show a login form and its branding above the fold
+const (
+	DefaultWidth  = 1280
+	DefaultHeight = 800
+)
+
+// candidates are the browser binaries tried when no path is given, in order
+var candidates = []string{
+	"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome", "microsoft-edge",
+	"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	"/Applications/Chromium.app/Contents/MacOS/Chromium",
+	`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+}
+
+// Browser captures pages by running a headless browser once per screenshot, so a crashing page can't
+// take other captures down with it
+type Browser struct {
+	Path    string
+	Width   int
+	Height  int
+	Timeout time.Duration
+	// Proxy, when set, is passed to the browser as --proxy-server, e.g. socks5://127.0.0.1:9050
+	Proxy string
+}
+
+// Find returns the browser to use: path when given, otherwise the first known Chrome or Chromium binary
+func Find(path string) (string, error) {
+	if path != "" {
+		if resolved, err := exec.LookPath(path); err == nil {
+			return resolved, nil
+		}
+		return "", fmt.Errorf("browser '%s' not found", path)
+	}
+	for _, candidate := range candidates {
+		if resolved, err := exec.LookPath(candidate); err == nil {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome or Chromium found; install one or pass its path with --browser")
+}
+
+// Capture loads url and returns a PNG of the viewport
+func (b *Browser) Capture(url string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "favlens-screenshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "page.png")
+
+	width, height := b.Width, b.Height
+	if width <= 0 || height <= 0 {
+		width, height = DefaultWidth, DefaultHeight
+	}
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		"--mute-audio",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--ignore-certificate-errors",
+		// Each capture gets its own profile, so parallel workers don't fight over a profile lock
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		fmt.Sprintf("--window-size=%d,%d", width, height),
+		"--screenshot=" + out,
+	}
+	if runtime.GOOS == "linux" && os.Geteuid() == 0 {
+		// Chrome refuses to run as root with its sandbox enabled, which is common in containers
+		args = append(args, "--no-sandbox")
+	}
+	if b.Proxy != "" {
+		args = append(args, "--proxy-server="+b.Proxy)
+	}
+	if b.Timeout > 0 {
+		args = append(args, fmt.Sprintf("--timeout=%d", b.Timeout.Milliseconds()))
+	}
+	args = append(args, url)
+
+	// The browser's own timeout only covers page load, so allow extra time for start-up and encoding
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout+30*time.Second)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.Path, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("screenshot of %s timed out", url)
+		}
+		return nil, fmt.Errorf("screenshot of %s failed: %v: %s", url, err, lastLine(stderr.Bytes()))
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("screenshot of %s produced no image: %s", url, lastLine(stderr.Bytes()))
+	}
+	return data, nil
+}
+
+// lastLine returns the final non-empty line of browser output, which usually names the failure
+func lastLine(output []byte) string {
+	lines := bytes.Split(bytes.TrimSpace(output), []byte("\n"))
+	return string(bytes.TrimSpace(lines[len(lines)-1]))
+}
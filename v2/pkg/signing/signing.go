@@ -0,0 +1,163 @@
+// Package signing produces and checks detached signatures over result files, so findings handed to takedown
+// or legal processes can be shown to be unmodified.
+//
+// Signatures cover a digest of the whole file, computed as it is written, so outputs of any size and remote
+// outputs can be signed without reading them back. RSA keys sign SHA-256 digests with PKCS #1 v1.5 and ECDSA
+// keys sign SHA-256 digests as ASN.1, which is what `openssl dgst -sha256 -verify` checks; Ed25519 keys use
+// Ed25519ph over SHA-512 (RFC 8032).
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// Extension is appended to an output path to name its signature file
+const Extension = ".sig"
+
+// Writer passes output through to an underlying writer while hashing it for signing
+type Writer struct {
+	w    io.Writer
+	key  crypto.Signer
+	hash crypto.Hash
+	h    hash.Hash
+}
+
+func NewWriter(w io.Writer, key crypto.Signer) *Writer {
+	algorithm := hashFor(key.Public())
+	return &Writer{w: w, key: key, hash: algorithm, h: algorithm.New()}
+}
+
+// Write writes p and hashes the part of it that was written
+func (s *Writer) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.h.Write(p[:n])
+	return n, err
+}
+
+// Sign returns the signature over everything written so far
+func (s *Writer) Sign() ([]byte, error) {
+	digest := s.h.Sum(nil)
+	var opts crypto.SignerOpts = s.hash
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		opts = &ed25519.Options{Hash: crypto.SHA512}
+	}
+	return s.key.Sign(rand.Reader, digest, opts)
+}
+
+// Verify checks sig over everything in r against a public key
+func Verify(r io.Reader, sig []byte, pub crypto.PublicKey) error {
+	h := hashFor(pub).New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	digest := h.Sum(nil)
+	var err error
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, sig) {
+			err = errors.New("verification failed")
+		}
+	case ed25519.PublicKey:
+		err = ed25519.VerifyWithOptions(key, digest, sig, &ed25519.Options{Hash: crypto.SHA512})
+	default:
+		return fmt.Errorf("unsupported key type %T", pub)
+	}
+	if err != nil {
+		return errors.New("signature does not match: the file was modified or signed with another key")
+	}
+	return nil
+}
+
+// hashFor is the digest signed for a key: SHA-512 for Ed25519ph, SHA-256 otherwise
+func hashFor(pub crypto.PublicKey) crypto.Hash {
+	if _, ok := pub.(ed25519.PublicKey); ok {
+		return crypto.SHA512
+	}
+	return crypto.SHA256
+}
+
+// LoadKey reads an unencrypted RSA, ECDSA or Ed25519 private key from a PEM file
+func LoadKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		return nil, fmt.Errorf("%s is encrypted; decrypt it with `openssl pkey -in %s -out key.pem`", path, path)
+	default:
+		return nil, fmt.Errorf("%s holds a %s, not a private key", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %v", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T in %s", key, path)
+	}
+	switch signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return signer, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T in %s", key, path)
+}
+
+// LoadPublicKey reads the key to verify with from a PEM public key, certificate or private key
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key in %s: %v", path, err)
+		}
+		return pub, nil
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate in %s: %v", path, err)
+		}
+		return cert.PublicKey, nil
+	}
+	key, err := LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.Public(), nil
+}
+
+// readPEM returns the first PEM block in a file
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,91 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+)
+
+// dnsdbEndpoint is the DNSDB API v2
+const dnsdbEndpoint = "https://api.dnsdb.info/dnsdb/v2"
+
+// dnsdbPageSize is the number of records requested per page
+const dnsdbPageSize = 1000
+
+// DNSDB enumerates every name historically seen under Domain, then runs a flexible search for
+// names containing its brand label. Both lookups page with offset until the provider runs dry.
+type DNSDB struct {
+	Domain string
+	APIKey string
+	// Endpoint overrides the public API
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// dnsdbRecord is one line of the streaming (SAF) response; cond lines mark the stream's begin and end
+type dnsdbRecord struct {
+	Cond string `json:"cond"`
+	Obj  struct {
+		RRName string `json:"rrname"`
+	} `json:"obj"`
+}
+
+func (d *DNSDB) Name() string {
+	return ProviderDNSDB
+}
+
+func (d *DNSDB) Fetch(limit int) ([]input.Target, error) {
+	endpoint := d.Endpoint
+	if endpoint == "" {
+		endpoint = dnsdbEndpoint
+	}
+	hosts := newHostCollector(d.Name(), d.Domain, limit)
+
+	lookups := []string{
+		fmt.Sprintf("%s/lookup/rrset/name/%s/ANY", endpoint, url.PathEscape("*."+d.Domain)),
+		fmt.Sprintf("%s/regex/rrnames/%s/ANY", endpoint, url.PathEscape(regexp.QuoteMeta(brandLabel(d.Domain)))),
+	}
+	for _, lookup := range lookups {
+		more, err := d.page(lookup, hosts)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+	}
+	return hosts.targets, nil
+}
+
+// page walks one lookup with offset paging; it returns false once the collector is full
+func (d *DNSDB) page(lookup string, hosts *hostCollector) (bool, error) {
+	client := newHTTPClient(d.Timeout)
+	headers := map[string]string{"X-API-Key": d.APIKey, "Accept": "application/x-ndjson"}
+	for offset := 0; ; offset += dnsdbPageSize {
+		body, err := request(client, "GET", fmt.Sprintf("%s?swclient=favlens&limit=%d&offset=%d", lookup, dnsdbPageSize, offset), headers, nil)
+		if err != nil {
+			return false, err
+		}
+		records := 0
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			var record dnsdbRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Obj.RRName == "" {
+				continue
+			}
+			records++
+			if !hosts.add(record.Obj.RRName) {
+				return false, nil
+			}
+		}
+		if records < dnsdbPageSize {
+			return true, nil
+		}
+	}
+}
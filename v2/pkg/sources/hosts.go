@@ -0,0 +1,87 @@
+package sources
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+)
+
+// HostProber turns hostname input, such as subfinder output, into URLs by probing each host for a scheme that
+// answers. Hosts are probed concurrently as they are read, so targets reach the scan while the input still
+// streams in; they come out in the order their probes finish.
+type HostProber struct {
+	targets chan input.Target
+	err     error
+	dead    atomic.Int64
+}
+
+// ProbeHosts starts probing the hosts next returns with workers concurrent probes. probe reports whether a URL
+// answers; the first of a host's URLs that does becomes the target.
+func ProbeHosts(next func() (input.Target, error), probe func(url string) error, workers int) *HostProber {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &HostProber{targets: make(chan input.Target, workers)}
+	hosts := make(chan input.Target)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range hosts {
+				if url, ok := probeHost(target.URL, probe); ok {
+					target.URL = url
+					p.targets <- target
+					continue
+				}
+				p.dead.Add(1)
+			}
+		}()
+	}
+
+	go func() {
+		for {
+			target, err := next()
+			if err != nil {
+				if err != io.EOF {
+					p.err = err
+				}
+				break
+			}
+			hosts <- target
+		}
+		close(hosts)
+		wg.Wait()
+		close(p.targets)
+	}()
+	return p
+}
+
+// probeHost returns the first URL of host that answers
+func probeHost(host string, probe func(url string) error) (string, bool) {
+	for _, url := range input.HostURLs(host) {
+		if probe(url) == nil {
+			return url, true
+		}
+	}
+	return "", false
+}
+
+// Next returns the next live target, or io.EOF once every host has been probed
+func (p *HostProber) Next() (input.Target, error) {
+	target, ok := <-p.targets
+	if ok {
+		return target, nil
+	}
+	if p.err != nil {
+		return input.Target{}, p.err
+	}
+	return input.Target{}, io.EOF
+}
+
+// Dead returns how many hosts answered on no scheme and were dropped
+func (p *HostProber) Dead() int64 {
+	return p.dead.Load()
+}
@@ -0,0 +1,89 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+)
+
+// Passive DNS providers accepted by NewPassiveDNS
+const (
+	ProviderSecurityTrails = "securitytrails"
+	ProviderDNSDB          = "dnsdb"
+)
+
+// PassiveDNSProviders lists every provider accepted by NewPassiveDNS
+var PassiveDNSProviders = []string{ProviderSecurityTrails, ProviderDNSDB}
+
+// Relations between a passive DNS hit and the legitimate domain, recorded in the "relation" metadata
+const (
+	RelationSubdomain = "subdomain"
+	RelationLookalike = "lookalike"
+)
+
+// NewPassiveDNS returns the source for provider that enumerates hosts around the legitimate domain
+func NewPassiveDNS(provider, domain, apiKey string, timeout time.Duration) (Source, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" || !strings.Contains(domain, ".") {
+		return nil, fmt.Errorf("'%s' is not a registrable domain", domain)
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("an API key is required for %s", provider)
+	}
+	switch strings.ToLower(provider) {
+	case ProviderSecurityTrails:
+		return &SecurityTrails{Domain: domain, APIKey: apiKey, Timeout: timeout}, nil
+	case ProviderDNSDB:
+		return &DNSDB{Domain: domain, APIKey: apiKey, Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unsupported passive DNS provider '%s' (supported: %s)", provider, strings.Join(PassiveDNSProviders, ", "))
+	}
+}
+
+// brandLabel returns the label a lookalike search looks for, e.g. "acme" for "acme.co.uk"
+func brandLabel(domain string) string {
+	label, _, _ := strings.Cut(domain, ".")
+	return label
+}
+
+// relationTo classifies host as a subdomain of domain or a lookalike of it
+func relationTo(host, domain string) string {
+	if host == domain || strings.HasSuffix(host, "."+domain) {
+		return RelationSubdomain
+	}
+	return RelationLookalike
+}
+
+// hostCollector turns passive DNS hostnames into unique https targets
+type hostCollector struct {
+	source  string
+	domain  string
+	limit   int
+	seen    map[string]bool
+	targets []input.Target
+}
+
+func newHostCollector(source, domain string, limit int) *hostCollector {
+	return &hostCollector{source: source, domain: domain, limit: limit, seen: make(map[string]bool)}
+}
+
+// add queues host unless it was seen before; it reports false once the limit is reached
+func (c *hostCollector) add(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	// Wildcard records can't be scanned directly
+	if host == "" || strings.Contains(host, "*") || c.seen[host] {
+		return !c.full()
+	}
+	c.seen[host] = true
+	c.targets = append(c.targets, input.Target{URL: "https://" + host, Metadata: map[string]any{
+		"source":   c.source,
+		"relation": relationTo(host, c.domain),
+	}})
+	return !c.full()
+}
+
+func (c *hostCollector) full() bool {
+	return c.limit > 0 && len(c.targets) >= c.limit
+}
@@ -0,0 +1,46 @@
+package sources
+
+import (
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+	permute "github.com/ethicalhackingplayground/favlens/v2/pkg/permute"
+)
+
+// Permutations generates typosquat, homoglyph and bitflip variants of Domain and keeps those that resolve
+type Permutations struct {
+	Domain string
+	// Resolvers is the number of concurrent DNS lookups
+	Resolvers int
+	// Timeout bounds each DNS lookup (default: 5s)
+	Timeout time.Duration
+}
+
+func (p *Permutations) Name() string {
+	return "permute"
+}
+
+func (p *Permutations) Fetch(limit int) ([]input.Target, error) {
+	perms, err := permute.Generate(p.Domain)
+	if err != nil {
+		return nil, err
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	live := permute.Resolve(perms, p.Resolvers, timeout)
+
+	var targets []input.Target
+	for _, perm := range live {
+		if limit > 0 && len(targets) >= limit {
+			break
+		}
+		metadata := map[string]any{"source": p.Name(), "fuzzer": perm.Fuzzer}
+		if perm.Unicode != perm.Domain {
+			metadata["unicode"] = perm.Unicode
+		}
+		targets = append(targets, input.Target{URL: "https://" + perm.Domain, Metadata: metadata})
+	}
+	return targets, nil
+}
@@ -0,0 +1,89 @@
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+)
+
+// securityTrailsEndpoint is the SecurityTrails v1 API
+const securityTrailsEndpoint = "https://api.securitytrails.com/v1"
+
+// securityTrailsMaxPages is the deepest page the domain search API serves
+const securityTrailsMaxPages = 100
+
+// SecurityTrails enumerates current and historical subdomains of Domain, then searches for
+// registered domains containing its brand label (e.g. "acme-login.com" for "acme.com").
+type SecurityTrails struct {
+	Domain string
+	APIKey string
+	// Endpoint overrides the public API
+	Endpoint string
+	Timeout  time.Duration
+}
+
+type securityTrailsSubdomains struct {
+	Subdomains []string `json:"subdomains"`
+}
+
+type securityTrailsSearch struct {
+	Records []struct {
+		Hostname string `json:"hostname"`
+	} `json:"records"`
+	Meta struct {
+		TotalPages int `json:"total_pages"`
+	} `json:"meta"`
+}
+
+func (s *SecurityTrails) Name() string {
+	return ProviderSecurityTrails
+}
+
+func (s *SecurityTrails) Fetch(limit int) ([]input.Target, error) {
+	client := newHTTPClient(s.Timeout)
+	headers := map[string]string{"APIKEY": s.APIKey}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = securityTrailsEndpoint
+	}
+	hosts := newHostCollector(s.Name(), s.Domain, limit)
+
+	// Subdomains include names that no longer resolve, which is where forgotten phishing kits tend to live
+	var subdomains securityTrailsSubdomains
+	uri := fmt.Sprintf("%s/domain/%s/subdomains?children_only=false&include_inactive=true", endpoint, url.PathEscape(s.Domain))
+	if err := getJSON(client, uri, headers, &subdomains); err != nil {
+		return nil, err
+	}
+	for _, sub := range subdomains.Subdomains {
+		if !hosts.add(sub + "." + s.Domain) {
+			return hosts.targets, nil
+		}
+	}
+
+	body, err := json.Marshal(map[string]any{"filter": map[string]string{"keyword": brandLabel(s.Domain)}})
+	if err != nil {
+		return nil, err
+	}
+	for page := 1; page <= securityTrailsMaxPages; page++ {
+		raw, err := request(client, "POST", fmt.Sprintf("%s/domains/list?page=%d", endpoint, page), headers, body)
+		if err != nil {
+			return nil, err
+		}
+		var search securityTrailsSearch
+		if err := json.Unmarshal(raw, &search); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %v", err)
+		}
+		for _, record := range search.Records {
+			if !hosts.add(record.Hostname) {
+				return hosts.targets, nil
+			}
+		}
+		if len(search.Records) == 0 || page >= search.Meta.TotalPages {
+			break
+		}
+	}
+	return hosts.targets, nil
+}
@@ -0,0 +1,91 @@
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+	"github.com/valyala/fasthttp"
+)
+
+// maxResponseSize bounds a single page of search results
+const maxResponseSize = 16 * 1024 * 1024
+
+// Source queries an external service for candidate URLs to scan
+type Source interface {
+	// Name identifies the source in logs and in the "source" metadata of its targets
+	Name() string
+	// Fetch returns up to limit targets, following the service's pagination
+	Fetch(limit int) ([]input.Target, error)
+}
+
+// Collect fetches up to limit targets from every source, dropping URLs an earlier source already returned
+func Collect(sources []Source, limit int) ([]input.Target, error) {
+	seen := make(map[string]bool)
+	var targets []input.Target
+	for _, source := range sources {
+		fetched, err := source.Fetch(limit)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", source.Name(), err)
+		}
+		for _, target := range fetched {
+			if seen[target.URL] {
+				continue
+			}
+			seen[target.URL] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+func newHTTPClient(timeout time.Duration) *fasthttp.Client {
+	return &fasthttp.Client{ReadTimeout: timeout, WriteTimeout: timeout, MaxResponseBodySize: maxResponseSize}
+}
+
+// getJSON sends a GET request with the given headers and decodes a 200 response into v
+func getJSON(client *fasthttp.Client, uri string, headers map[string]string, v any) error {
+	body, err := request(client, "GET", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	return nil
+}
+
+// request sends a request and returns the body of a 200 response; other statuses become errors
+func request(client *fasthttp.Client, method, uri string, headers map[string]string, body []byte) ([]byte, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(uri)
+	req.Header.SetMethod(method)
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if body != nil {
+		req.Header.SetContentType("application/json")
+		req.SetBody(body)
+	}
+
+	if err := client.DoRedirects(req, resp, 3); err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	switch resp.StatusCode() {
+	case fasthttp.StatusOK:
+		return append([]byte(nil), resp.Body()...), nil
+	case fasthttp.StatusUnauthorized, fasthttp.StatusForbidden:
+		return nil, fmt.Errorf("API key rejected (status %d)", resp.StatusCode())
+	case fasthttp.StatusTooManyRequests:
+		return nil, fmt.Errorf("rate limited (status 429), try again later or lower the limit")
+	default:
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode(), strings.TrimSpace(string(resp.Body())))
+	}
+}
@@ -0,0 +1,100 @@
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+)
+
+// urlscanEndpoint is the public urlscan.io search API
+const urlscanEndpoint = "https://urlscan.io/api/v1/search/"
+
+// urlscanPageSize is the largest page the search API returns
+const urlscanPageSize = 100
+
+// URLScan searches urlscan.io, e.g. for scans sharing a favicon hash ("hash:<sha256>") or
+// mentioning a brand ("page.title:acme"). Anonymous searches work but see fewer results.
+type URLScan struct {
+	Query  string
+	APIKey string
+	// Endpoint overrides the public API, e.g. for an on-premise instance
+	Endpoint string
+	Timeout  time.Duration
+}
+
+type urlscanResponse struct {
+	Results []struct {
+		ID   string `json:"_id"`
+		Page struct {
+			URL    string `json:"url"`
+			Domain string `json:"domain"`
+		} `json:"page"`
+		Task struct {
+			Time string `json:"time"`
+		} `json:"task"`
+		Sort []json.RawMessage `json:"sort"`
+	} `json:"results"`
+	HasMore bool `json:"has_more"`
+}
+
+func (u *URLScan) Name() string {
+	return "urlscan"
+}
+
+// Fetch pages through search results with search_after until limit URLs are collected
+func (u *URLScan) Fetch(limit int) ([]input.Target, error) {
+	client := newHTTPClient(u.Timeout)
+	headers := map[string]string{}
+	if u.APIKey != "" {
+		headers["API-Key"] = u.APIKey
+	}
+	endpoint := u.Endpoint
+	if endpoint == "" {
+		endpoint = urlscanEndpoint
+	}
+
+	var targets []input.Target
+	seen := make(map[string]bool)
+	searchAfter := ""
+	for limit <= 0 || len(targets) < limit {
+		params := url.Values{"q": {u.Query}, "size": {fmt.Sprint(urlscanPageSize)}}
+		if searchAfter != "" {
+			params.Set("search_after", searchAfter)
+		}
+		var page urlscanResponse
+		if err := getJSON(client, endpoint+"?"+params.Encode(), headers, &page); err != nil {
+			return nil, err
+		}
+
+		for _, result := range page.Results {
+			if result.Page.URL == "" || seen[result.Page.URL] {
+				continue
+			}
+			seen[result.Page.URL] = true
+			metadata := map[string]any{"source": u.Name(), "urlscan_uuid": result.ID}
+			if result.Task.Time != "" {
+				metadata["scanned_at"] = result.Task.Time
+			}
+			targets = append(targets, input.Target{URL: result.Page.URL, Metadata: metadata})
+			if limit > 0 && len(targets) >= limit {
+				break
+			}
+		}
+
+		if !page.HasMore || len(page.Results) == 0 {
+			break
+		}
+		// The next page starts after the sort values of the last result
+		last := page.Results[len(page.Results)-1].Sort
+		values := make([]string, len(last))
+		for i, raw := range last {
+			values[i] = strings.Trim(string(raw), `"`)
+		}
+		searchAfter = strings.Join(values, ",")
+	}
+	return targets, nil
+}
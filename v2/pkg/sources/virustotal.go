@@ -0,0 +1,100 @@
+package sources
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	input "github.com/ethicalhackingplayground/favlens/v2/pkg/input"
+)
+
+// virusTotalEndpoint is the VirusTotal Intelligence search API
+const virusTotalEndpoint = "https://www.virustotal.com/api/v3/intelligence/search"
+
+// virusTotalPageSize is the largest page the search API returns
+const virusTotalPageSize = 300
+
+// VirusTotal runs a VirusTotal Intelligence search, e.g. "entity:url main_icon_dhash:<dhash>" for pages
+// sharing a favicon or "entity:domain acme" for brand keywords. Intelligence searches need a premium API key.
+type VirusTotal struct {
+	Query  string
+	APIKey string
+	// Endpoint overrides the public API
+	Endpoint string
+	Timeout  time.Duration
+}
+
+type virusTotalResponse struct {
+	Data []struct {
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		Attributes struct {
+			URL          string `json:"url"`
+			LastFinalURL string `json:"last_final_url"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Cursor string `json:"cursor"`
+	} `json:"meta"`
+}
+
+func (v *VirusTotal) Name() string {
+	return "virustotal"
+}
+
+// Fetch follows the response cursor until limit URLs are collected; domain and IP hits are scanned over https
+func (v *VirusTotal) Fetch(limit int) ([]input.Target, error) {
+	if v.APIKey == "" {
+		return nil, fmt.Errorf("an API key is required")
+	}
+	client := newHTTPClient(v.Timeout)
+	headers := map[string]string{"x-apikey": v.APIKey}
+	endpoint := v.Endpoint
+	if endpoint == "" {
+		endpoint = virusTotalEndpoint
+	}
+
+	var targets []input.Target
+	seen := make(map[string]bool)
+	cursor := ""
+	for limit <= 0 || len(targets) < limit {
+		params := url.Values{"query": {v.Query}, "limit": {fmt.Sprint(virusTotalPageSize)}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var page virusTotalResponse
+		if err := getJSON(client, endpoint+"?"+params.Encode(), headers, &page); err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Data {
+			var target string
+			switch object.Type {
+			case "url":
+				target = object.Attributes.URL
+				if target == "" {
+					target = object.Attributes.LastFinalURL
+				}
+			case "domain", "ip_address":
+				target = "https://" + object.ID
+			}
+			if target == "" || seen[target] {
+				continue
+			}
+			seen[target] = true
+			targets = append(targets, input.Target{URL: target, Metadata: map[string]any{
+				"source":        v.Name(),
+				"virustotal_id": object.ID,
+			}})
+			if limit > 0 && len(targets) >= limit {
+				break
+			}
+		}
+
+		if page.Meta.Cursor == "" || len(page.Data) == 0 {
+			break
+		}
+		cursor = page.Meta.Cursor
+	}
+	return targets, nil
+}
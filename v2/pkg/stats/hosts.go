@@ -0,0 +1,156 @@
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Host aggregates the downloads sent to one target host
+type Host struct {
+	Host      string `json:"host"`
+	Attempts  int64  `json:"attempts"`
+	Successes int64  `json:"successes"`
+	Failures  int64  `json:"failures"`
+	// Connections counts the connections opened to the host; the other attempts reused one
+	Connections int64     `json:"connections"`
+	Reused      int64     `json:"reused"`
+	AvgLatency  float64   `json:"avg_latency_ms"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+
+	latency time.Duration
+	// dialFailures are attempts that neither opened nor reused a connection
+	dialFailures int64
+}
+
+// Hosts tracks download attempts per target host while a run is in progress, so a CDN that is slow, failing
+// or blocking the scan shows up before the run ends. It is safe for concurrent use.
+type Hosts struct {
+	mu    sync.Mutex
+	hosts map[string]*Host
+}
+
+func NewHosts() *Hosts {
+	return &Hosts{hosts: make(map[string]*Host)}
+}
+
+// host returns the entry for name, creating it; the caller holds the lock
+func (h *Hosts) host(name string) *Host {
+	entry, ok := h.hosts[name]
+	if !ok {
+		entry = &Host{Host: name}
+		h.hosts[name] = entry
+	}
+	return entry
+}
+
+// Record adds one attempt on host that took latency; failure describes why it failed and is empty on success
+func (h *Hosts) Record(host string, latency time.Duration, failure string) {
+	if h == nil || host == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry := h.host(host)
+	entry.Attempts++
+	entry.latency += latency
+	if failure == "" {
+		entry.Successes++
+		return
+	}
+	entry.Failures++
+	entry.LastError, entry.LastErrorAt = failure, time.Now()
+}
+
+// Dial wraps dial so every connection it opens is counted against the host it connects to
+func (h *Hosts) Dial(dial func(addr string) (net.Conn, error)) func(addr string) (net.Conn, error) {
+	return func(addr string) (net.Conn, error) {
+		conn, err := dial(addr)
+		host, _, splitErr := net.SplitHostPort(addr)
+		if splitErr != nil {
+			host = addr
+		}
+		h.mu.Lock()
+		if err == nil {
+			h.host(host).Connections++
+		} else {
+			h.host(host).dialFailures++
+		}
+		h.mu.Unlock()
+		return conn, err
+	}
+}
+
+// Snapshot returns every host with its averages filled in, the most attempted first
+func (h *Hosts) Snapshot() []Host {
+	h.mu.Lock()
+	hosts := make([]Host, 0, len(h.hosts))
+	for _, entry := range h.hosts {
+		host := *entry
+		if host.Attempts > 0 {
+			host.AvgLatency = math.Round(float64(host.latency.Microseconds())/float64(host.Attempts)/10) / 100
+		}
+		host.Reused = max(host.Attempts-host.Connections-host.dialFailures, 0)
+		hosts = append(hosts, host)
+	}
+	h.mu.Unlock()
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Attempts != hosts[j].Attempts {
+			return hosts[i].Attempts > hosts[j].Attempts
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	return hosts
+}
+
+// Failing returns up to n hosts with failures, the most failures first
+func (h *Hosts) Failing(n int) []Host {
+	var failing []Host
+	for _, host := range h.Snapshot() {
+		if host.Failures > 0 {
+			failing = append(failing, host)
+		}
+	}
+	sort.SliceStable(failing, func(i, j int) bool {
+		return failing[i].Failures > failing[j].Failures
+	})
+	if len(failing) > n {
+		failing = failing[:n]
+	}
+	return failing
+}
+
+// WriteJSON replaces path with the current per-host statistics. The file is written beside path and renamed
+// over it, so a reader polling it mid-run never sees a partial document.
+func (h *Hosts) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(struct {
+		Updated time.Time `json:"updated"`
+		Hosts   []Host    `json:"hosts"`
+	}{time.Now().UTC(), h.Snapshot()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(append(data, '\n')); err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
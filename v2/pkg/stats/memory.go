@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryTracker samples heap usage in the background and remembers the peak
+type MemoryTracker struct {
+	peak atomic.Uint64
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartMemoryTracker begins sampling the heap every interval until Stop is called
+func StartMemoryTracker(interval time.Duration) *MemoryTracker {
+	m := &MemoryTracker{stop: make(chan struct{}), done: make(chan struct{})}
+	m.sample()
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sample()
+			case <-m.stop:
+				m.sample()
+				return
+			}
+		}
+	}()
+	return m
+}
+
+func (m *MemoryTracker) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	for {
+		current := m.peak.Load()
+		if ms.HeapAlloc <= current || m.peak.CompareAndSwap(current, ms.HeapAlloc) {
+			return
+		}
+	}
+}
+
+// Peak returns the highest heap allocation observed so far, in bytes
+func (m *MemoryTracker) Peak() uint64 {
+	return m.peak.Load()
+}
+
+// Stop ends sampling and returns the peak heap allocation in bytes
+func (m *MemoryTracker) Stop() uint64 {
+	close(m.stop)
+	<-m.done
+	return m.Peak()
+}
+
+// FormatBytes renders a byte count using binary units
+func FormatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
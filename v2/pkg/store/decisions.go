@@ -0,0 +1,69 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// decisionNamespace prefixes every key written by DecisionLog
+const decisionNamespace = "decision/"
+
+// Decision is an analyst's verdict on a match: accepted as a true positive or rejected as a false one
+type Decision struct {
+	URL string `json:"url"`
+	// Run is the run the match was reviewed in and Confidence the score it was given there
+	Run        string    `json:"run"`
+	Confidence int       `json:"confidence"`
+	Accepted   bool      `json:"accepted"`
+	Note       string    `json:"note,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// DecisionLog records review decisions in a Store. A URL keeps only its latest decision, so an analyst who
+// changes their mind doesn't leave both verdicts in the labelled data.
+type DecisionLog struct {
+	store Store
+}
+
+func NewDecisionLog(s Store) *DecisionLog {
+	return &DecisionLog{store: s}
+}
+
+// key hashes the URL, which may be longer than a file name allows
+func (l *DecisionLog) key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return decisionNamespace + hex.EncodeToString(sum[:])
+}
+
+// Record saves a decision, replacing any earlier one for the same URL
+func (l *DecisionLog) Record(d Decision) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return l.store.Put(l.key(d.URL), data)
+}
+
+// List returns every recorded decision, oldest first
+func (l *DecisionLog) List() ([]Decision, error) {
+	var decisions []Decision
+	err := l.store.Scan(decisionNamespace, func(key string, value []byte) error {
+		var d Decision
+		if err := json.Unmarshal(value, &d); err != nil {
+			return fmt.Errorf("corrupt decision record %s: %v", key, err)
+		}
+		decisions = append(decisions, d)
+		return nil
+	})
+	sort.Slice(decisions, func(i, j int) bool {
+		if !decisions[i].At.Equal(decisions[j].At) {
+			return decisions[i].At.Before(decisions[j].At)
+		}
+		return decisions[i].URL < decisions[j].URL
+	})
+	return decisions, err
+}
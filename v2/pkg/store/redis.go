@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps keys in Redis under a "favlens:" prefix so it can share a database with other data
+type RedisStore struct {
+	client *redis.Client
+}
+
+const redisKeyPrefix = "favlens:"
+
+func OpenRedis(dsn string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis store %s: %v", dsn, err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis store: %v", err)
+	}
+	return &RedisStore{client: client}, nil
+}
+
+func (r *RedisStore) Get(key string) ([]byte, error) {
+	value, err := r.client.Get(context.Background(), redisKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (r *RedisStore) Put(key string, value []byte) error {
+	return r.client.Set(context.Background(), redisKeyPrefix+key, value, 0).Err()
+}
+
+func (r *RedisStore) Delete(key string) error {
+	return r.client.Del(context.Background(), redisKeyPrefix+key).Err()
+}
+
+func (r *RedisStore) Scan(prefix string, fn func(key string, value []byte) error) error {
+	ctx := context.Background()
+	iter := r.client.Scan(ctx, 0, redisKeyPrefix+escapeGlob(prefix)+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		value, err := r.client.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(strings.TrimPrefix(key, redisKeyPrefix), value); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+// escapeGlob escapes the characters Redis MATCH patterns treat specially
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,303 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runNamespace prefixes every key written by RunLog
+const runNamespace = "run/"
+
+// runIDFormat makes run IDs sort by the second the runs started
+const runIDFormat = "20060102T150405Z"
+
+// Run is a finished scan recorded for comparison with later runs
+type Run struct {
+	ID           string     `json:"id"`
+	Started      time.Time  `json:"started"`
+	Finished     time.Time  `json:"finished"`
+	BaseURL      string     `json:"base_url"`
+	Model        string     `json:"model"`
+	ConfigDigest string     `json:"config_digest"`
+	InputDigest  string     `json:"input_digest"`
+	Targets      int        `json:"targets"`
+	Errors       int        `json:"errors"`
+	Partial      bool       `json:"partial"`
+	Matches      []RunMatch `json:"matches"`
+}
+
+// RunMatch is one matched target of a run
+type RunMatch struct {
+	URL        string `json:"url"`
+	Confidence int    `json:"confidence"`
+}
+
+// Host is the host a match was found on, which is what runs are compared by
+func (m RunMatch) Host() string {
+	if u, err := url.Parse(m.URL); err == nil && u.Hostname() != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return m.URL
+}
+
+// Merge adds another run's results to r. A URL matched in both keeps its higher confidence, and digests that
+// differ are cleared since the combined run no longer has a single input list or configuration.
+func (r *Run) Merge(other *Run) {
+	index := make(map[string]int, len(r.Matches))
+	for i, m := range r.Matches {
+		index[m.URL] = i
+	}
+	for _, m := range other.Matches {
+		if i, ok := index[m.URL]; ok {
+			r.Matches[i].Confidence = max(r.Matches[i].Confidence, m.Confidence)
+			continue
+		}
+		index[m.URL] = len(r.Matches)
+		r.Matches = append(r.Matches, m)
+	}
+	if other.Started.Before(r.Started) || r.Started.IsZero() {
+		r.Started = other.Started
+	}
+	if other.Finished.After(r.Finished) {
+		r.Finished = other.Finished
+	}
+	if r.InputDigest != other.InputDigest {
+		r.InputDigest = ""
+	}
+	if r.ConfigDigest != other.ConfigDigest {
+		r.ConfigDigest = ""
+	}
+	r.Targets += other.Targets
+	r.Errors += other.Errors
+	r.Partial = r.Partial || other.Partial
+}
+
+// NewRunID names a run started at t; the random suffix keeps runs started in the same second apart
+func NewRunID(t time.Time) string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return t.UTC().Format(runIDFormat) + "-" + hex.EncodeToString(suffix)
+}
+
+// RunLog records runs in a Store
+type RunLog struct {
+	store Store
+}
+
+func NewRunLog(s Store) *RunLog {
+	return &RunLog{store: s}
+}
+
+// Save records a run under its ID
+func (l *RunLog) Save(run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return l.store.Put(runNamespace+run.ID, data)
+}
+
+// List returns every recorded run, oldest first
+func (l *RunLog) List() ([]*Run, error) {
+	var runs []*Run
+	err := l.store.Scan(runNamespace, func(key string, value []byte) error {
+		var run Run
+		if err := json.Unmarshal(value, &run); err != nil {
+			return fmt.Errorf("corrupt run record %s: %v", key, err)
+		}
+		runs = append(runs, &run)
+		return nil
+	})
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].Started.Equal(runs[j].Started) {
+			return runs[i].Started.Before(runs[j].Started)
+		}
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, err
+}
+
+// Load returns a run by ID. "latest" is the most recent run and "latest~N" the one N runs before it.
+func (l *RunLog) Load(id string) (*Run, error) {
+	if back, ok := strings.CutPrefix(id, "latest"); ok {
+		n := 0
+		if back != "" {
+			var err error
+			if n, err = strconv.Atoi(strings.TrimPrefix(back, "~")); err != nil || !strings.HasPrefix(back, "~") || n < 0 {
+				return nil, fmt.Errorf("invalid run reference '%s' (use latest or latest~N)", id)
+			}
+		}
+		runs, err := l.List()
+		if err != nil {
+			return nil, err
+		}
+		if n >= len(runs) {
+			return nil, fmt.Errorf("run '%s' not found: %d run(s) recorded", id, len(runs))
+		}
+		return runs[len(runs)-1-n], nil
+	}
+	data, err := l.store.Get(runNamespace + id)
+	if err == ErrNotFound {
+		return nil, fmt.Errorf("run '%s' not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("corrupt run record %s: %v", id, err)
+	}
+	return &run, nil
+}
+
+// Retention says which runs to keep: those that started within Age, or the Count most recent
+type Retention struct {
+	Age   time.Duration
+	Count int
+}
+
+// ParseRetention reads a retention as an age such as 90d, 12w or 72h, or as a number of runs such as 50
+func ParseRetention(s string) (Retention, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return Retention{}, fmt.Errorf("retention '%s' must keep at least 1 run", s)
+		}
+		return Retention{Count: n}, nil
+	}
+	var age time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			unit *= 7
+		}
+		var n int
+		n, err = strconv.Atoi(s[:len(s)-1])
+		age = time.Duration(n) * unit
+	default:
+		age, err = time.ParseDuration(s)
+	}
+	if err != nil || age <= 0 {
+		return Retention{}, fmt.Errorf("invalid retention '%s' (use an age like 90d, 12w or 72h, or a number of runs)", s)
+	}
+	return Retention{Age: age}, nil
+}
+
+// Prune deletes the runs outside a retention and returns how many it deleted. The latest run is always kept,
+// so a long gap between scans never leaves nothing to compare against.
+func (l *RunLog) Prune(keep Retention, now time.Time) (int, error) {
+	runs, err := l.List()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for i, run := range runs[:max(len(runs)-1, 0)] {
+		expired := keep.Count > 0 && i < len(runs)-keep.Count
+		if keep.Age > 0 {
+			expired = now.Sub(run.Started) > keep.Age
+		}
+		if !expired {
+			continue
+		}
+		if err := l.store.Delete(runNamespace + run.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// HostChange is a host whose matches differ between two runs
+type HostChange struct {
+	Host string `json:"host"`
+	// URLs are the host's matched URLs in the run it matched in, or the newer run for drift
+	URLs []string `json:"urls"`
+	// Before and After are the host's highest confidence in each run; 0 when it didn't match
+	Before int `json:"confidence_before"`
+	After  int `json:"confidence_after"`
+}
+
+// RunDiff compares the matches of two runs
+type RunDiff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// New hosts matched only in the newer run, Gone hosts only in the older one, and Drift hosts in both whose
+	// confidence changed
+	New   []HostChange `json:"new"`
+	Gone  []HostChange `json:"gone"`
+	Drift []HostChange `json:"drift"`
+	// SameInput and SameConfig say whether both runs scanned the same list with the same settings
+	SameInput  bool `json:"same_input"`
+	SameConfig bool `json:"same_config"`
+}
+
+// Diff compares two runs by host. Drift is ordered by the size of the change, largest first.
+func Diff(from, to *Run) RunDiff {
+	before, after := hostMatches(from), hostMatches(to)
+	d := RunDiff{
+		From:       from.ID,
+		To:         to.ID,
+		New:        []HostChange{},
+		Gone:       []HostChange{},
+		Drift:      []HostChange{},
+		SameInput:  from.InputDigest != "" && from.InputDigest == to.InputDigest,
+		SameConfig: from.ConfigDigest != "" && from.ConfigDigest == to.ConfigDigest,
+	}
+	for host, a := range after {
+		b, ok := before[host]
+		switch {
+		case !ok:
+			d.New = append(d.New, HostChange{Host: host, URLs: a.URLs, After: a.After})
+		case a.After != b.After:
+			d.Drift = append(d.Drift, HostChange{Host: host, URLs: a.URLs, Before: b.After, After: a.After})
+		}
+	}
+	for host, b := range before {
+		if _, ok := after[host]; !ok {
+			d.Gone = append(d.Gone, HostChange{Host: host, URLs: b.URLs, Before: b.After})
+		}
+	}
+	byHost := func(changes []HostChange) {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Host < changes[j].Host })
+	}
+	byHost(d.New)
+	byHost(d.Gone)
+	sort.SliceStable(d.Drift, func(i, j int) bool {
+		di, dj := abs(d.Drift[i].After-d.Drift[i].Before), abs(d.Drift[j].After-d.Drift[j].Before)
+		if di != dj {
+			return di > dj
+		}
+		return d.Drift[i].Host < d.Drift[j].Host
+	})
+	return d
+}
+
+// hostMatches groups a run's matches by host, keeping each host's highest confidence in After
+func hostMatches(run *Run) map[string]HostChange {
+	hosts := map[string]HostChange{}
+	for _, m := range run.Matches {
+		host := m.Host()
+		h := hosts[host]
+		h.Host = host
+		h.URLs = append(h.URLs, m.URL)
+		h.After = max(h.After, m.Confidence)
+		hosts[host] = h
+	}
+	return hosts
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
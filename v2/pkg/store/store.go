@@ -0,0 +1,44 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when a key has no value
+var ErrNotFound = errors.New("key not found")
+
+// Store is a minimal key/value abstraction shared by caches and run state.
+// Keys are namespaced by convention with a "<namespace>/" prefix so Scan can list one namespace.
+type Store interface {
+	// Get returns the value for key, or ErrNotFound
+	Get(key string) ([]byte, error)
+	// Put stores value under key, replacing any previous value
+	Put(key string, value []byte) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(key string) error
+	// Scan calls fn for every key starting with prefix; returning an error from fn stops the scan
+	Scan(prefix string, fn func(key string, value []byte) error) error
+	Close() error
+}
+
+// Open creates a Store from a DSN:
+//
+//	/path/to/dir or file:///path/to/dir  filesystem store
+//	sqlite:///path/to/favlens.db         SQLite store
+//	redis://[:password@]host:port/db     Redis store
+func Open(dsn string) (Store, error) {
+	switch {
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return OpenSQLite(strings.TrimPrefix(dsn, "sqlite://"))
+	case strings.HasPrefix(dsn, "redis://"), strings.HasPrefix(dsn, "rediss://"):
+		return OpenRedis(dsn)
+	case strings.HasPrefix(dsn, "file://"):
+		return OpenFS(strings.TrimPrefix(dsn, "file://"))
+	case strings.Contains(dsn, "://"):
+		return nil, fmt.Errorf("unsupported store '%s' (use a directory path, file://, sqlite:// or redis://)", dsn)
+	default:
+		return OpenFS(dsn)
+	}
+}
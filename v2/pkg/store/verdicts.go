@@ -0,0 +1,57 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// verdictNamespace prefixes every key written by VerdictCache
+const verdictNamespace = "verdict/"
+
+// VerdictCache remembers model verdicts for icon pairs so repeated scans skip the model call.
+// Entries are keyed by the model, the prompt when it isn't the default one and the exact bytes of both icons.
+type VerdictCache struct {
+	store  Store
+	model  string
+	prompt string
+}
+
+// NewVerdictCache keys verdicts by model and, unless it is empty, by prompt, so verdicts given to a different
+// question are never reused. An empty prompt keeps the keys written before prompts could change.
+func NewVerdictCache(s Store, model, prompt string) *VerdictCache {
+	return &VerdictCache{store: s, model: model, prompt: prompt}
+}
+
+func (c *VerdictCache) key(baseIcon, targetIcon string) string {
+	h := sha256.New()
+	h.Write([]byte(c.model))
+	h.Write([]byte{0})
+	if c.prompt != "" {
+		h.Write([]byte(c.prompt))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(baseIcon))
+	h.Write([]byte{0})
+	h.Write([]byte(targetIcon))
+	return verdictNamespace + hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached verdict; ok is false when the pair has not been compared before
+func (c *VerdictCache) Get(baseIcon, targetIcon string) (match bool, ok bool, err error) {
+	value, err := c.store.Get(c.key(baseIcon, targetIcon))
+	if err == ErrNotFound {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return string(value) == "1", true, nil
+}
+
+func (c *VerdictCache) Put(baseIcon, targetIcon string, match bool) error {
+	value := []byte("0")
+	if match {
+		value = []byte("1")
+	}
+	return c.store.Put(c.key(baseIcon, targetIcon), value)
+}
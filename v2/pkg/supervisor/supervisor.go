@@ -0,0 +1,192 @@
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// Supported ways of running Ollama
+const (
+	ModeAuto    = "auto"
+	ModeProcess = "process"
+	ModeDocker  = "docker"
+)
+
+// DefaultImage is the container image used in docker mode
+const DefaultImage = "ollama/ollama"
+
+// Ollama is a locally spawned Ollama server owned by this run
+type Ollama struct {
+	// Host is the base URL clients should use, e.g. http://127.0.0.1:41234
+	Host string
+	Mode string
+
+	// mu serialises Stop, which the signal handler and the normal exit path can race to call
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	container string
+	exited    chan struct{}
+}
+
+// Options control how Ollama is spawned
+type Options struct {
+	Mode  string
+	Image string
+	// Volume is the docker volume used for model storage so pulls survive between runs
+	Volume string
+	Debug  bool
+}
+
+// freePort asks the kernel for an unused loopback port
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Start launches Ollama on a free loopback port, either as a child process or a docker container.
+// In auto mode a local `ollama` binary is preferred and docker is the fallback.
+func Start(opts Options) (*Ollama, error) {
+	mode := strings.ToLower(opts.Mode)
+	if mode == "" {
+		mode = ModeAuto
+	}
+	if mode == ModeAuto {
+		if _, err := exec.LookPath("ollama"); err == nil {
+			mode = ModeProcess
+		} else if _, err := exec.LookPath("docker"); err == nil {
+			mode = ModeDocker
+		} else {
+			return nil, fmt.Errorf("neither an ollama binary nor docker was found in PATH")
+		}
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a free port: %v", err)
+	}
+	o := &Ollama{Host: fmt.Sprintf("http://127.0.0.1:%d", port), Mode: mode, exited: make(chan struct{})}
+
+	switch mode {
+	case ModeProcess:
+		err = o.startProcess(port, opts)
+	case ModeDocker:
+		err = o.startDocker(port, opts)
+	default:
+		return nil, fmt.Errorf("unsupported spawn mode '%s' (use auto, process or docker)", opts.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *Ollama) startProcess(port int, opts Options) error {
+	cmd := exec.Command("ollama", "serve")
+	cmd.Env = append(os.Environ(), "OLLAMA_HOST=127.0.0.1:"+strconv.Itoa(port))
+	if opts.Debug {
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ollama serve: %v", err)
+	}
+	o.cmd = cmd
+	go func() {
+		cmd.Wait()
+		close(o.exited)
+	}()
+	return nil
+}
+
+func (o *Ollama) startDocker(port int, opts Options) error {
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	volume := opts.Volume
+	if volume == "" {
+		volume = "ollama"
+	}
+	name := fmt.Sprintf("favlens-ollama-%d", os.Getpid())
+
+	args := []string{"run", "-d", "--rm", "--name", name,
+		"-p", fmt.Sprintf("127.0.0.1:%d:11434", port),
+		"-v", volume + ":/root/.ollama"}
+	// Pass GPUs through when the NVIDIA runtime is available; docker rejects --gpus otherwise
+	if _, err := exec.LookPath("nvidia-smi"); err == nil {
+		args = append(args, "--gpus", "all")
+	}
+	args = append(args, image)
+
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to start ollama container: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	o.container = name
+	if opts.Debug {
+		gologger.Debug().Msgf("Started ollama container %s (%s)", name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// WaitHealthy polls the API until it answers or the timeout expires
+func (o *Ollama) WaitHealthy(timeout time.Duration) error {
+	client := &http.Client{Timeout: 2 * time.Second}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-o.exited:
+			return fmt.Errorf("ollama exited before becoming healthy")
+		default:
+		}
+		resp, err := client.Get(o.Host + "/api/tags")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("ollama did not become healthy within %s", timeout)
+}
+
+// Stop tears down the spawned server; it is safe to call more than once
+func (o *Ollama) Stop() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cmd != nil && o.cmd.Process != nil {
+		// Interrupt is not supported on Windows, so fall straight through to Kill there
+		if err := o.cmd.Process.Signal(os.Interrupt); err != nil {
+			o.cmd.Process.Kill()
+		}
+		select {
+		case <-o.exited:
+		case <-time.After(10 * time.Second):
+			o.cmd.Process.Kill()
+			<-o.exited
+		}
+		o.cmd = nil
+	}
+	if o.container != "" {
+		out, err := exec.Command("docker", "stop", o.container).CombinedOutput()
+		o.container = ""
+		if err != nil {
+			return fmt.Errorf("failed to stop ollama container: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
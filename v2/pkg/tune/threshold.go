@@ -0,0 +1,81 @@
+package tune
+
+import "sort"
+
+// Labelled is a reviewed match: the confidence it was given and whether an analyst accepted it
+type Labelled struct {
+	Confidence int
+	Accepted   bool
+}
+
+// Threshold is how a minimum confidence would have split the reviewed matches
+type Threshold struct {
+	MinConfidence int
+	// Kept counts the accepted and rejected matches at or above MinConfidence
+	KeptAccepted, KeptRejected int
+	Precision, Recall, F1      float64
+}
+
+// RecommendThreshold picks the minimum confidence that best separates accepted from rejected matches, by F1
+// score over the labelled data. Ties go to the higher threshold, so equally good cut-offs tighten the scan
+// rather than loosen it. It reports false without at least one accepted and one rejected match to learn from.
+func RecommendThreshold(labelled []Labelled) (Threshold, bool) {
+	accepted, rejected := 0, 0
+	candidates := make(map[int]bool)
+	for _, l := range labelled {
+		if l.Accepted {
+			accepted++
+		} else {
+			rejected++
+		}
+		candidates[l.Confidence] = true
+	}
+	if accepted == 0 || rejected == 0 {
+		return Threshold{}, false
+	}
+
+	levels := make([]int, 0, len(candidates))
+	for c := range candidates {
+		levels = append(levels, c)
+	}
+	sort.Ints(levels)
+
+	var best Threshold
+	for _, level := range levels {
+		t := Evaluate(labelled, level)
+		if t.F1 >= best.F1 {
+			best = t
+		}
+	}
+	return best, true
+}
+
+// Evaluate scores minConfidence against the labelled data: precision is the share of kept matches that were
+// accepted, recall the share of accepted matches that were kept
+func Evaluate(labelled []Labelled, minConfidence int) Threshold {
+	t := Threshold{MinConfidence: minConfidence}
+	accepted := 0
+	for _, l := range labelled {
+		if l.Accepted {
+			accepted++
+		}
+		if l.Confidence < minConfidence {
+			continue
+		}
+		if l.Accepted {
+			t.KeptAccepted++
+		} else {
+			t.KeptRejected++
+		}
+	}
+	if kept := t.KeptAccepted + t.KeptRejected; kept > 0 {
+		t.Precision = float64(t.KeptAccepted) / float64(kept)
+	}
+	if accepted > 0 {
+		t.Recall = float64(t.KeptAccepted) / float64(accepted)
+	}
+	if t.Precision+t.Recall > 0 {
+		t.F1 = 2 * t.Precision * t.Recall / (t.Precision + t.Recall)
+	}
+	return t
+}
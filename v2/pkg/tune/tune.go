@@ -0,0 +1,142 @@
+// Package tune measures how many concurrent comparisons an Ollama backend sustains, so the inference
+// pool can be sized from data instead of trial and error.
+package tune
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"sync"
+	"time"
+
+	ollama "github.com/ethicalhackingplayground/favlens/v2/pkg/ollama"
+)
+
+// Options control the load applied at each concurrency level
+type Options struct {
+	// MaxWorkers is the highest concurrency tried; levels double from 1 up to it
+	MaxWorkers int
+	// Step is how long each level runs
+	Step time.Duration
+	// Debug is passed through to the comparisons
+	Debug bool
+	// Progress, when set, is called after each level finishes
+	Progress func(Step)
+}
+
+// Step is the measured outcome of one concurrency level
+type Step struct {
+	Workers     int
+	Comparisons int
+	Errors      int
+	Throughput  float64 // comparisons per second
+	P50, P95    time.Duration
+}
+
+// plateau is the throughput gain below which an extra level isn't worth its added latency
+const plateau = 1.10
+
+// Run compares synthetic icon pairs at doubling concurrency until throughput stops improving, errors
+// appear or MaxWorkers is reached. The first comparison is made alone and not measured, since it
+// includes loading the model.
+func Run(pool *ollama.Pool, opts Options) ([]Step, error) {
+	base, target := syntheticIcons()
+	if _, err := pool.Compare(0, base, target, opts.Debug); err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	best := 0.0
+	for workers := 1; workers <= max(opts.MaxWorkers, 1); workers *= 2 {
+		step := measure(pool, workers, opts.Step, base, target, opts.Debug)
+		steps = append(steps, step)
+		if opts.Progress != nil {
+			opts.Progress(step)
+		}
+		if step.Errors > 0 || step.Throughput < best*plateau {
+			break
+		}
+		best = step.Throughput
+	}
+	return steps, nil
+}
+
+// Recommend picks the smallest level that reached 95% of the best error-free throughput, trading
+// the last few percent of speed for lower latency and headroom on the GPU
+func Recommend(steps []Step) (Step, bool) {
+	var peak float64
+	for _, step := range steps {
+		if step.Errors == 0 {
+			peak = max(peak, step.Throughput)
+		}
+	}
+	for _, step := range steps {
+		if step.Errors == 0 && peak > 0 && step.Throughput >= peak*0.95 {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+// measure keeps workers comparisons in flight for duration
+func measure(pool *ollama.Pool, workers int, duration time.Duration, base, target string, debug bool) Step {
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		failed    int
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	deadline := start.Add(duration)
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				began := time.Now()
+				_, err := pool.Compare(worker, base, target, debug)
+				elapsed := time.Since(began)
+				mu.Lock()
+				if err != nil {
+					failed++
+				} else {
+					latencies = append(latencies, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	step := Step{Workers: workers, Comparisons: len(latencies), Errors: failed}
+	step.Throughput = float64(len(latencies)) / time.Since(start).Seconds()
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		step.P50 = latencies[len(latencies)*50/100]
+		step.P95 = latencies[len(latencies)*95/100]
+	}
+	return step
+}
+
+// syntheticIcons returns two different favicon-sized PNGs, base64-encoded like downloaded icons
+func syntheticIcons() (string, string) {
+	encode := func(fg color.RGBA) string {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for y := range 64 {
+			for x := range 64 {
+				c := color.RGBA{255, 255, 255, 255}
+				if (x-32)*(x-32)+(y-32)*(y-32) < 24*24 {
+					c = fg
+				}
+				img.Set(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return encode(color.RGBA{200, 30, 30, 255}), encode(color.RGBA{30, 30, 200, 255})
+}
@@ -1,11 +1,73 @@
 package types
 
 type Job struct {
-	URL string
+	URL      string
+	BaseURL  string
+	Metadata map[string]any
+	// Fallbacks are further icon URLs tried in order when URL can't be downloaded
+	Fallbacks []string
+	// BaseVariants are further brand kit logos compared after BaseURL; a match on any of them is a match
+	BaseVariants []string
+	// Tags are labels from --tag rules that matched the target URL
+	Tags []string
+	// Index is the job's position in dispatch order
+	Index int
+	// FaviconHash is the target icon's mmh3 hash when httpx already reported it, letting known icons skip the download
+	FaviconHash string
+	// Deferrals counts the times the job was put off to the end of the run after a transient failure
+	Deferrals int
 }
 
 type Result struct {
-	URL   string
+	URL      string
+	BaseURL  string
+	Match    bool
+	Err      error
+	Metadata map[string]any
+	Tags     []string
+	// Index is copied from the job so results can be put back in input order
+	Index int
+	// Lookalike is set on matches whose host is an IDN or visually confusable with the brand domain
+	Lookalike *Lookalike
+	// SuspectReasons explain why a match looks like parked, wildcard or mass hosting rather than a deliberate copy
+	SuspectReasons []string
+	// Icon describes the target's favicon; nil when it could not be downloaded or decoded
+	Icon *IconInfo
+	// Text is what --ocr read from the target icon; nil when OCR is off or the icon has no text
+	Text *IconText
+	// Asset names the brand kit logo the target matched; empty when not scanning with a brand kit
+	Asset string
+	// MMH3 is the target icon's Shodan-compatible favicon hash and SHA256 the hash of its bytes as downloaded.
+	// A target decided by the favicon hash httpx reported only has MMH3; both are empty when no icon was read.
+	MMH3   string
+	SHA256 string
+}
+
+// IconText is text read from an icon, scored against the brand name
+type IconText struct {
+	Text string
+	// Score runs from 0 (unrelated) to 1 (spells the brand name); it is 0 when no brand name is known
+	Score float64
 	Match bool
-	Err   error
+}
+
+// IconInfo describes a downloaded favicon. Tiny or monochrome icons carry little brand detail, so matches on
+// them deserve less confidence.
+type IconInfo struct {
+	Format string
+	Width  int
+	Height int
+	Bytes  int
+	// BitDepth is bits per pixel, or 0 when unknown
+	BitDepth   int
+	Monochrome bool
+}
+
+// Lookalike flags a matched host that imitates the brand's domain, which makes it a takedown priority
+type Lookalike struct {
+	// Unicode is the host as users see it; it differs from the URL's host for IDNs
+	Unicode string
+	IDN     bool
+	// Confusability runs from 0 (unrelated) to 1 (renders identically to the brand domain)
+	Confusability float64
 }
@@ -0,0 +1,293 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	releasesURL = "https://api.github.com/repos/ethicalhackingplayground/favlens/releases/latest"
+	projectName = "favlens"
+)
+
+// Release is the subset of the GitHub release API used for updates
+type Release struct {
+	TagName string  `json:"tag_name"`
+	HTMLURL string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// LatestRelease fetches the newest published release from GitHub
+func LatestRelease() (*Release, error) {
+	req, err := http.NewRequest(http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query GitHub releases: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub releases API returned status %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub release: %v", err)
+	}
+	return &release, nil
+}
+
+// IsNewer reports whether latest is a higher semantic version than current.
+// Development builds ("dev" or anything unparsable) are always considered out of date.
+func IsNewer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion extracts major.minor.patch from strings like "v2.1.0" or "2.1.0-rc1"
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i != -1 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// archiveName returns the goreleaser archive name for this platform
+func archiveName() string {
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	osName := strings.ToUpper(runtime.GOOS[:1]) + runtime.GOOS[1:]
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("%s_%s_%s%s", projectName, osName, arch, ext)
+}
+
+func (r *Release) asset(match func(name string) bool) (*Asset, bool) {
+	for i := range r.Assets {
+		if match(r.Assets[i].Name) {
+			return &r.Assets[i], true
+		}
+	}
+	return nil, false
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s returned status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Apply downloads the release archive for this platform, verifies it against the release
+// checksums file and replaces the running executable with the binary it contains.
+func (r *Release) Apply() error {
+	name := archiveName()
+	archive, ok := r.asset(func(n string) bool { return n == name })
+	if !ok {
+		return fmt.Errorf("release %s has no archive for %s/%s (expected %s)", r.TagName, runtime.GOOS, runtime.GOARCH, name)
+	}
+	checksums, ok := r.asset(func(n string) bool { return strings.HasSuffix(n, "checksums.txt") })
+	if !ok {
+		return fmt.Errorf("release %s has no checksums file, refusing to update", r.TagName)
+	}
+
+	sums, err := download(checksums.BrowserDownloadURL)
+	if err != nil {
+		return err
+	}
+	expected, err := findChecksum(sums, name)
+	if err != nil {
+		return err
+	}
+	data, err := download(archive.BrowserDownloadURL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+
+	binary, err := extractBinary(name, data)
+	if err != nil {
+		return err
+	}
+	return replaceExecutable(binary)
+}
+
+// findChecksum looks up name in a goreleaser "<sha256>  <file>" checksums file
+func findChecksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum listed for %s", name)
+}
+
+// extractBinary pulls the favlens executable out of a .tar.gz or .zip archive
+func extractBinary(archiveName string, data []byte) ([]byte, error) {
+	binaryName := projectName
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+
+	if strings.HasSuffix(archiveName, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", archiveName, err)
+		}
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) != binaryName {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+		return nil, fmt.Errorf("%s not found in %s", binaryName, archiveName)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", archiveName, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", archiveName, err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
+			return io.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("%s not found in %s", binaryName, archiveName)
+}
+
+// replaceExecutable swaps the running binary for the new one. The new file is written next to the
+// current one and renamed into place; the old binary is moved aside first so this also works on Windows.
+func replaceExecutable(binary []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot locate current executable: %v", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".favlens-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s (try running with elevated permissions): %v", dir, err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, info.Mode().Perm()|0o111); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to move current binary aside: %v", err)
+	}
+	if err := os.Rename(tmpName, exe); err != nil {
+		// Put the original back so the install is never left without a binary
+		os.Rename(old, exe)
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to install new binary: %v", err)
+	}
+	// Removing the old binary fails on Windows while it is running; it is cleaned up on the next update
+	os.Remove(old)
+	return nil
+}